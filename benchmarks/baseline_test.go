@@ -0,0 +1,81 @@
+package benchmarks
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmji/qs"
+)
+
+var update = flag.Bool("update", false, "update the allocation baseline in testdata")
+
+const baselineFile = "allocs.json"
+
+type allocsCase struct {
+	name string
+	fn   func() error
+}
+
+var allocsCases = []allocsCase{
+	{"MarshalFlat", func() error { _, err := qs.MarshalValues(flatValue); return err }},
+	{"UnmarshalFlat", func() error { return qs.UnmarshalValues(&flatStruct{}, flatQuery) }},
+	{"MarshalNested", func() error { _, err := qs.MarshalValues(nestedValue); return err }},
+	{"UnmarshalNested", func() error { return qs.UnmarshalValues(&nestedStruct{}, nestedQuery) }},
+	{"MarshalLargeSlice", func() error { _, err := qs.MarshalValues(largeSliceValue); return err }},
+	{"UnmarshalLargeSlice", func() error { return qs.UnmarshalValues(&largeSliceStruct{}, largeSliceQuery) }},
+	{"MarshalMap", func() error { _, err := qs.MarshalValues(mapValue); return err }},
+	{"UnmarshalMap", func() error { return qs.UnmarshalValues(&map[string]int{}, mapQuery) }},
+}
+
+// TestAllocsBaseline compares the allocations per operation with the
+// baseline in testdata. Unlike timings, allocation counts are deterministic
+// so the test can run in CI without flakiness.
+func TestAllocsBaseline(t *testing.T) {
+	path := filepath.Join("testdata", baselineFile)
+
+	measured := make(map[string]float64, len(allocsCases))
+	for _, c := range allocsCases {
+		if err := c.fn(); err != nil {
+			t.Fatalf("%s :: %v", c.name, err)
+		}
+		measured[c.name] = testing.AllocsPerRun(100, func() {
+			_ = c.fn()
+		})
+	}
+
+	if *update {
+		data, err := json.MarshalIndent(measured, "", "\t")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error reading the baseline (run with -update to create it) :: %v", err)
+	}
+	var baseline map[string]float64
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range allocsCases {
+		want, ok := baseline[c.name]
+		if !ok {
+			t.Errorf("%s :: missing from the baseline (run with -update)", c.name)
+			continue
+		}
+		if got := measured[c.name]; got > want {
+			t.Errorf("%s :: %v allocs/op, baseline is %v", c.name, got, want)
+		} else if got < want {
+			t.Logf("%s :: %v allocs/op, better than the baseline (%v), consider running with -update", c.name, got, want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package benchmarks
+
+import (
+	"testing"
+
+	"github.com/dmji/qs"
+)
+
+func BenchmarkMarshalFlat(b *testing.B) {
+	benchmarkMarshal(b, flatValue)
+}
+
+func BenchmarkUnmarshalFlat(b *testing.B) {
+	benchmarkUnmarshal(b, flatQuery, func() interface{} { return &flatStruct{} })
+}
+
+func BenchmarkMarshalNested(b *testing.B) {
+	benchmarkMarshal(b, nestedValue)
+}
+
+func BenchmarkUnmarshalNested(b *testing.B) {
+	benchmarkUnmarshal(b, nestedQuery, func() interface{} { return &nestedStruct{} })
+}
+
+func BenchmarkMarshalLargeSlice(b *testing.B) {
+	benchmarkMarshal(b, largeSliceValue)
+}
+
+func BenchmarkUnmarshalLargeSlice(b *testing.B) {
+	benchmarkUnmarshal(b, largeSliceQuery, func() interface{} { return &largeSliceStruct{} })
+}
+
+func BenchmarkMarshalMap(b *testing.B) {
+	benchmarkMarshal(b, mapValue)
+}
+
+func BenchmarkUnmarshalMap(b *testing.B) {
+	benchmarkUnmarshal(b, mapQuery, func() interface{} { return &map[string]int{} })
+}
+
+func BenchmarkMarshalString(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := qs.Marshal(flatValue); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalString(b *testing.B) {
+	s := flatQuery.Encode()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := qs.Unmarshal(&flatStruct{}, s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkMarshal(b *testing.B, v interface{}) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := qs.MarshalValues(v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkUnmarshal(b *testing.B, vs map[string][]string, newTarget func() interface{}) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := qs.UnmarshalValues(newTarget(), vs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
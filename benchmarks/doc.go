@@ -0,0 +1,20 @@
+// Package benchmarks contains the benchmark suite of the qs package and a
+// regression harness for its allocation counts.
+//
+// The benchmarks cover the marshaling and unmarshaling of small flat structs,
+// nested (embedded) structs, large slices and maps:
+//
+//	go test ./benchmarks -run xxx -bench . -benchmem
+//
+// The results of two revisions can be compared with benchstat:
+//
+//	go test ./benchmarks -run xxx -bench . -benchmem -count 10 > old.txt
+//	go test ./benchmarks -run xxx -bench . -benchmem -count 10 > new.txt
+//	benchstat old.txt new.txt
+//
+// TestAllocsBaseline fails if an operation allocates more than the baseline
+// recorded in testdata/allocs.json. After a change that intentionally reduces
+// (or knowingly increases) the allocations the baseline can be updated with:
+//
+//	go test ./benchmarks -run TestAllocsBaseline -update
+package benchmarks
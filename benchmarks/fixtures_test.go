@@ -0,0 +1,117 @@
+package benchmarks
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/dmji/qs"
+)
+
+type flatStruct struct {
+	Search     string
+	Page       int
+	PageSize   int
+	Offset     uint
+	Score      float64
+	Active     bool
+	Lang       string
+	Sort       string
+	Since      time.Time
+	Categories []string `qs:"category"`
+}
+
+var (
+	flatValue = &flatStruct{
+		Search:     "woof",
+		Page:       2,
+		PageSize:   50,
+		Offset:     100,
+		Score:      0.5,
+		Active:     true,
+		Lang:       "en-US",
+		Sort:       "name",
+		Since:      time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Categories: []string{"a", "b", "c"},
+	}
+	flatQuery = mustMarshal(flatValue)
+)
+
+// The qs package supports nesting through embedded structs and pointers.
+type (
+	NestedLevel3 struct {
+		Deep     string
+		DeepList []int
+	}
+	NestedLevel2 struct {
+		*NestedLevel3
+		Middle  string
+		Enabled bool
+	}
+	NestedLevel1 struct {
+		NestedLevel2
+		Top   string
+		Limit *int
+	}
+	nestedStruct struct {
+		*NestedLevel1
+		Name string
+	}
+)
+
+var (
+	nestedValue = &nestedStruct{
+		NestedLevel1: &NestedLevel1{
+			NestedLevel2: NestedLevel2{
+				NestedLevel3: &NestedLevel3{
+					Deep:     "deep",
+					DeepList: []int{1, 2, 3},
+				},
+				Middle:  "middle",
+				Enabled: true,
+			},
+			Top:   "top",
+			Limit: new(int),
+		},
+		Name: "name",
+	}
+	nestedQuery = mustMarshal(nestedValue)
+)
+
+type largeSliceStruct struct {
+	IDs []int `qs:"id"`
+}
+
+var (
+	largeSliceValue = newLargeSliceValue(1000)
+	largeSliceQuery = mustMarshal(largeSliceValue)
+)
+
+func newLargeSliceValue(n int) *largeSliceStruct {
+	v := &largeSliceStruct{IDs: make([]int, n)}
+	for i := range v.IDs {
+		v.IDs[i] = i
+	}
+	return v
+}
+
+var (
+	mapValue = newMapValue(100)
+	mapQuery = mustMarshal(mapValue)
+)
+
+func newMapValue(n int) map[string]int {
+	m := make(map[string]int, n)
+	for i := 0; i < n; i++ {
+		m["key"+strconv.Itoa(i)] = i
+	}
+	return m
+}
+
+func mustMarshal(v interface{}) url.Values {
+	vs, err := qs.MarshalValues(v)
+	if err != nil {
+		panic(err)
+	}
+	return vs
+}
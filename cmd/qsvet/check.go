@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"math/big"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dmji/qs"
+)
+
+// tagKey is the struct tag key of the default marshaler and unmarshaler.
+const tagKey = "qs"
+
+type checker struct {
+	fset    *token.FileSet
+	imports map[string]string
+	diags   []diagnostic
+}
+
+func (c *checker) report(pos token.Pos, format string, args ...interface{}) {
+	c.diags = append(c.diags, diagnostic{
+		Pos:     c.fset.Position(pos),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *checker) checkStruct(st *ast.StructType) {
+	for _, field := range st.Fields.List {
+		if field.Tag == nil {
+			continue
+		}
+		s, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			continue
+		}
+		tag := reflect.StructTag(s)
+		v, ok := tag.Lookup(tagKey)
+		if !ok {
+			continue
+		}
+
+		// The marker field of the struct defaults (`qs:"opts:..."`).
+		if len(field.Names) == 1 && field.Names[0].Name == "_" && strings.HasPrefix(v, "opts:") {
+			continue
+		}
+
+		msgs := qs.LintTag(tag)
+		for _, msg := range msgs {
+			c.report(field.Tag.Pos(), "%s", msg)
+		}
+
+		name, _, _ := strings.Cut(v, ",")
+		if name == "-" {
+			continue
+		}
+		for _, id := range field.Names {
+			if !id.IsExported() {
+				c.report(id.Pos(), "unexported field %s has a %s tag", id.Name, tagKey)
+			}
+		}
+		if len(msgs) > 0 || len(field.Names) == 0 {
+			continue
+		}
+
+		// The named codecs and value transformers are registered at runtime.
+		if strings.Contains(v, ",codec=") || strings.Contains(v, ",transform=") {
+			continue
+		}
+
+		if t, ok := c.resolveType(field.Type); ok {
+			if err := checkFieldType(t, tag); err != nil {
+				c.reportFieldTypeError(field.Type, err)
+			}
+		}
+	}
+}
+
+// reportFieldTypeError reports the last part of the error chain of
+// checkFieldType because the other parts refer to the temporary struct of
+// the check.
+func (c *checker) reportFieldTypeError(e ast.Expr, err error) {
+	msg := err.Error()
+	if i := strings.LastIndex(msg, " :: "); i >= 0 {
+		msg = msg[i+len(" :: "):]
+	}
+	if strings.HasPrefix(msg, "unhandled type: ") {
+		c.report(e.Pos(), "unsupported field type: %s", types.ExprString(e))
+		return
+	}
+	c.report(e.Pos(), "%s", msg)
+}
+
+// checkFieldType checks whether a struct field of type t with the given tag
+// is supported by the default marshaler and unmarshaler.
+func checkFieldType(t reflect.Type, tag reflect.StructTag) error {
+	st := reflect.StructOf([]reflect.StructField{{
+		Name: "F",
+		Type: t,
+		Tag:  tag,
+	}})
+	if err := qs.CheckMarshalType(st); err != nil {
+		return err
+	}
+	return qs.CheckUnmarshalType(reflect.PointerTo(st))
+}
+
+// predeclaredTypes maps the predeclared type names to their types. qsvet
+// assumes that the source files don't redeclare these names.
+var predeclaredTypes = map[string]reflect.Type{
+	"any":        reflect.TypeFor[any](),
+	"bool":       reflect.TypeFor[bool](),
+	"byte":       reflect.TypeFor[byte](),
+	"complex64":  reflect.TypeFor[complex64](),
+	"complex128": reflect.TypeFor[complex128](),
+	"float32":    reflect.TypeFor[float32](),
+	"float64":    reflect.TypeFor[float64](),
+	"int":        reflect.TypeFor[int](),
+	"int8":       reflect.TypeFor[int8](),
+	"int16":      reflect.TypeFor[int16](),
+	"int32":      reflect.TypeFor[int32](),
+	"int64":      reflect.TypeFor[int64](),
+	"rune":       reflect.TypeFor[rune](),
+	"string":     reflect.TypeFor[string](),
+	"uint":       reflect.TypeFor[uint](),
+	"uint8":      reflect.TypeFor[uint8](),
+	"uint16":     reflect.TypeFor[uint16](),
+	"uint32":     reflect.TypeFor[uint32](),
+	"uint64":     reflect.TypeFor[uint64](),
+	"uintptr":    reflect.TypeFor[uintptr](),
+}
+
+// packageTypes holds the types of other packages that qsvet can resolve.
+var packageTypes = map[string]reflect.Type{
+	"time.Time":      reflect.TypeFor[time.Time](),
+	"time.Duration":  reflect.TypeFor[time.Duration](),
+	"net/url.URL":    reflect.TypeFor[url.URL](),
+	"math/big.Int":   reflect.TypeFor[big.Int](),
+	"math/big.Float": reflect.TypeFor[big.Float](),
+	"math/big.Rat":   reflect.TypeFor[big.Rat](),
+}
+
+// fileImports maps the package names used by f to the import paths.
+func fileImports(f *ast.File) map[string]string {
+	imports := make(map[string]string)
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := path[strings.LastIndexByte(path, '/')+1:]
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = path
+	}
+	return imports
+}
+
+// resolveType returns the type of the type expression e if it is built from
+// types known by qsvet.
+func (c *checker) resolveType(e ast.Expr) (reflect.Type, bool) {
+	switch e := e.(type) {
+	case *ast.Ident:
+		t, ok := predeclaredTypes[e.Name]
+		return t, ok
+	case *ast.SelectorExpr:
+		pkg, ok := e.X.(*ast.Ident)
+		if !ok {
+			return nil, false
+		}
+		t, ok := packageTypes[c.imports[pkg.Name]+"."+e.Sel.Name]
+		return t, ok
+	case *ast.ParenExpr:
+		return c.resolveType(e.X)
+	case *ast.StarExpr:
+		elem, ok := c.resolveType(e.X)
+		if !ok {
+			return nil, false
+		}
+		return reflect.PointerTo(elem), true
+	case *ast.ArrayType:
+		elem, ok := c.resolveType(e.Elt)
+		if !ok {
+			return nil, false
+		}
+		if e.Len == nil {
+			return reflect.SliceOf(elem), true
+		}
+		lit, ok := e.Len.(*ast.BasicLit)
+		if !ok || lit.Kind != token.INT {
+			return nil, false
+		}
+		n, err := strconv.Atoi(lit.Value)
+		if err != nil {
+			return nil, false
+		}
+		return reflect.ArrayOf(n, elem), true
+	case *ast.MapType:
+		key, ok := c.resolveType(e.Key)
+		if !ok {
+			return nil, false
+		}
+		value, ok := c.resolveType(e.Value)
+		if !ok {
+			return nil, false
+		}
+		return reflect.MapOf(key, value), true
+	case *ast.ChanType:
+		// Channels aren't supported whatever their element type is.
+		return reflect.TypeFor[chan struct{}](), true
+	case *ast.FuncType:
+		return reflect.TypeFor[func()](), true
+	}
+	return nil, false
+}
@@ -0,0 +1,141 @@
+// Command qsvet checks the qs tags of the struct types of Go source files
+// without compiling them. It reports:
+//   - unknown tag options (e.g. typos like "omitemtpy") and surplus commas
+//   - conflicting tag options (e.g. omitempty and keepempty)
+//   - unexported fields with a qs tag
+//   - fields whose type isn't supported by the default marshaler or
+//     unmarshaler (e.g. channels and funcs)
+//
+// The tag options are checked with qs.LintTag. The field types are checked
+// with the default factories of the qs package but only if the type is built
+// from predeclared types (and time.Time, time.Duration, url.URL and the
+// math/big types) because
+// the other types can't be resolved without compiling the package. The
+// fields that use a codec or a value transformer registered at runtime
+// aren't checked either. Note that the options of custom marshalers (e.g.
+// the nesting of map fields) aren't known by qsvet.
+//
+// Usage:
+//
+//	qsvet [dir | dir/... | file.go]...
+//
+// The default argument is the current directory. The exit status is 1 if a
+// problem has been found and 2 if a file can't be read or parsed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: qsvet [dir | dir/... | file.go]...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+
+	exitCode := 0
+	fset := token.NewFileSet()
+	for _, arg := range args {
+		files, err := sourceFiles(arg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = 2
+			continue
+		}
+		for _, filename := range files {
+			diags, err := checkFile(fset, filename, nil)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exitCode = 2
+				continue
+			}
+			for _, d := range diags {
+				fmt.Println(d)
+				if exitCode == 0 {
+					exitCode = 1
+				}
+			}
+		}
+	}
+	os.Exit(exitCode)
+}
+
+// sourceFiles returns the Go source files selected by arg. A "/..." suffix
+// selects the files of the subdirectories too except the testdata, vendor
+// and hidden directories like the go command does.
+func sourceFiles(arg string) ([]string, error) {
+	if strings.HasSuffix(arg, ".go") {
+		return []string{arg}, nil
+	}
+
+	root, recursive := strings.CutSuffix(arg, "/...")
+	if root == "" {
+		root = "."
+	}
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path == root {
+				return nil
+			}
+			name := d.Name()
+			if !recursive || name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, ".go") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// diagnostic is a problem found in a source file.
+type diagnostic struct {
+	Pos     token.Position
+	Message string
+}
+
+func (d diagnostic) String() string {
+	return fmt.Sprintf("%v: %s", d.Pos, d.Message)
+}
+
+// checkFile checks the qs tags of the struct types of a source file. The
+// src parameter is passed to parser.ParseFile.
+func checkFile(fset *token.FileSet, filename string, src interface{}) ([]diagnostic, error) {
+	f, err := parser.ParseFile(fset, filename, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &checker{
+		fset:    fset,
+		imports: fileImports(f),
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if st, ok := n.(*ast.StructType); ok {
+			c.checkStruct(st)
+		}
+		return true
+	})
+	return c.diags, nil
+}
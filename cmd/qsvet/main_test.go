@@ -0,0 +1,59 @@
+package main
+
+import (
+	"go/token"
+	"reflect"
+	"testing"
+)
+
+const testSource = `package x
+
+import (
+	neturl "net/url"
+	"time"
+)
+
+type Query struct {
+	_      struct{}          ` + "`qs:\"opts:omitempty\"`" + `
+	A      string            ` + "`qs:\"a,omitemtpy\"`" + `
+	B      int               ` + "`qs:\"b,omitempty,keepempty\"`" + `
+	C      chan int          ` + "`qs:\"c\"`" + `
+	d      string            ` + "`qs:\"d\"`" + `
+	E      time.Time         ` + "`qs:\"e\"`" + `
+	F      *neturl.URL       ` + "`qs:\"f\"`" + `
+	G      []time.Duration   ` + "`qs:\"g,comma\"`" + `
+	H      Other             ` + "`qs:\"h\"`" + `
+	I      string            ` + "`qs:\"i,codec=upper\"`" + `
+	J      func()            ` + "`qs:\"-\"`" + `
+	K      struct {
+		L complex64 ` + "`qs:\"l\"`" + `
+	}
+}
+`
+
+func TestCheckFile(t *testing.T) {
+	fset := token.NewFileSet()
+	diags, err := checkFile(fset, "x.go", testSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, d := range diags {
+		got = append(got, d.String())
+	}
+	want := []string{
+		`x.go:10:27: unknown option "omitemtpy"`,
+		`x.go:11:27: only one OptionPresence option is allwed - you've specified at least two: omitempty, keepempty`,
+		`x.go:12:9: unsupported field type: chan int`,
+		`x.go:13:2: unexported field d has a qs tag`,
+		`x.go:21:5: unsupported field type: complex64`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := checkFile(fset, "y.go", "package"); err == nil {
+		t.Error("unexpected success")
+	}
+}
@@ -3,17 +3,30 @@ package qs
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/url"
 	"reflect"
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode"
 )
 
+// tagKey is the default struct tag key of the field options. It can be
+// changed with the TagKey field of MarshalOptions and
+// UnmarshalerDefaultOptions.
 const tagKey = "qs"
 
+// tagOptionAliases maps the tag options of other libraries to the equivalent
+// qs tag options. It is keyed by the struct tag key of the library.
+var tagOptionAliases = map[string]map[string]string{
+	"schema": {
+		"required": UnmarshalPresenceReq.String(),
+	},
+}
+
 // A NameTransformFunc is used to derive the query string keys from the field
 // names of the struct.
 // NameTransformFunc is the type of the DefaultNameTransform,
@@ -32,42 +45,188 @@ var (
 type ParsedTagInfo struct {
 	Name            string
 	MarshalPresence MarshalPresence
+	MarshalOpts     *MarshalTagOptions
 	UnmarshalOpts   *UnmarshalTagOptions
 	CommonOpts      *CommonTagOptions
 }
 
-func getStructFieldInfo(field reflect.StructField, nt NameTransformFunc, defaultMarshalTagOptions *MarshalTagOptions, defaultUnmarshalTagOptions *UnmarshalTagOptions, defaultCommonTagOptions *CommonTagOptions) (*ParsedTagInfo, error) {
+// fieldNaming holds the options that determine the query string keys of
+// struct fields.
+type fieldNaming struct {
+	tagKey       string
+	fallbackKeys []string
+	nt           NameTransformFunc
+
+	// requestTags is true for the unmarshaler that binds the fields with a
+	// path, header or cookie tag (see requestTagName) to the path values,
+	// headers and cookies of requests. The marshaler skips these fields.
+	requestTags bool
+}
+
+// promotedIndex returns the index path of a field of an embedded struct
+// relative to the struct that embeds it at index i.
+func promotedIndex(i int, index []int) []int {
+	return append([]int{i}, index...)
+}
+
+// fieldOwner returns the type of the struct that has the field with the given
+// index path in struct type t. It is t unless the field is promoted from an
+// embedded struct.
+func fieldOwner(t reflect.Type, index []int) reflect.Type {
+	if len(index) > 1 {
+		return t.FieldByIndex(index[:len(index)-1]).Type
+	}
+	return t
+}
+
+// isUnexportedField reports whether the field is unexported and isn't an
+// embedded struct. Like with encoding/json, the exported fields of embedded
+// structs (and struct pointers) are promoted even if the type of the embedded
+// struct is unexported but the unexported embedded non-struct types are
+// ignored.
+func isUnexportedField(field reflect.StructField) bool {
+	if field.PkgPath == "" {
+		return false
+	}
+	if !field.Anonymous {
+		return true
+	}
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() != reflect.Struct
+}
+
+func getStructFieldInfo(field reflect.StructField, naming fieldNaming, defaults tagDefaults) (*ParsedTagInfo, error) {
 	// Skipping unexported fields.
-	if field.PkgPath != "" && !field.Anonymous {
+	if isUnexportedField(field) {
 		return nil, nil
 	}
 
-	tag, err := parseFieldTag(field.Tag, defaultMarshalTagOptions, defaultUnmarshalTagOptions, defaultCommonTagOptions)
+	tag, err := parseFieldTag(field.Tag, naming.tagKey, defaults.marshal, defaults.unmarshal, defaults.common)
 	if err != nil {
-		err = fmt.Errorf("invalid tag: %q :: %v", field.Tag, err)
+		err = fmt.Errorf("invalid tag: %q :: %w", field.Tag, err)
 		return nil, err
 	}
 
+	// Taking the name from the fallback tags if the field has no qs tag.
+	if _, ok := field.Tag.Lookup(naming.tagKey); !ok {
+		tag.Name = fallbackTagName(field.Tag, naming.fallbackKeys)
+		if tag.Name == "" {
+			if name, bs, ok := requestTagName(field.Tag); ok {
+				if !naming.requestTags {
+					return nil, nil
+				}
+				tag.Name = name
+				tag.UnmarshalOpts.Source = bs
+			}
+		}
+	}
+
 	// Skipping this field if the tag specifies "-" as field name.
 	if tag.Name == "-" {
 		return nil, nil
 	}
 
 	if tag.Name == "" {
-		tag.Name = nt(field.Name)
+		tag.Name = naming.nt(field.Name)
 	}
 
 	return tag, nil
 }
 
+// fallbackTagName returns the name part of the first tag found under one of
+// the given keys (e.g. "json"). The options of these tags are ignored.
+func fallbackTagName(tag reflect.StructTag, keys []string) string {
+	for _, key := range keys {
+		if v, ok := tag.Lookup(key); ok {
+			name, _, _ := strings.Cut(v, ",")
+			return name
+		}
+	}
+	return ""
+}
+
+// requestTagName returns the name of the path, header or cookie tag of a
+// field (e.g. `path:"id"`, `header:"X-Trace-Id"` or `cookie:"session"`) and
+// the BindSource of the tag. The options of these tags are ignored.
+func requestTagName(tag reflect.StructTag) (string, BindSource, bool) {
+	if v, ok := tag.Lookup("path"); ok {
+		name, _, _ := strings.Cut(v, ",")
+		return name, BindSourcePath, true
+	}
+	if v, ok := tag.Lookup("header"); ok {
+		name, _, _ := strings.Cut(v, ",")
+		return name, BindSourceHeader, true
+	}
+	if v, ok := tag.Lookup("cookie"); ok {
+		name, _, _ := strings.Cut(v, ",")
+		return name, BindSourceCookie, true
+	}
+	return "", BindSourceBSUnspecified, false
+}
+
+// structDefaultsPrefix starts the tag of the marker field that overrides the
+// tag option defaults of the marshaler for the fields of a struct:
+//
+//	type Query struct {
+//		_ struct{} `qs:"opts:omitempty,comma"`
+//		...
+//	}
+const structDefaultsPrefix = "opts:"
+
+// tagDefaults holds the tag option defaults used to parse the field tags of
+// a struct.
+type tagDefaults struct {
+	marshal   *MarshalTagOptions
+	unmarshal *UnmarshalTagOptions
+	common    *CommonTagOptions
+}
+
+// structTagDefaults returns the tag option defaults of the fields of struct
+// type t. These are the given marshaler-level defaults overridden by the
+// options of the marker field of the struct (see structDefaultsPrefix).
+func structTagDefaults(t reflect.Type, key string, d tagDefaults) (tagDefaults, error) {
+	var marker *reflect.StructField
+	for i, numField := 0, t.NumField(); i < numField; i++ {
+		sf := t.Field(i)
+		if sf.Name != "_" || !strings.HasPrefix(sf.Tag.Get(key), structDefaultsPrefix) {
+			continue
+		}
+		if marker != nil {
+			return d, fmt.Errorf("struct %v has more than one %q marker field", t, structDefaultsPrefix)
+		}
+		marker = &sf
+	}
+	if marker == nil {
+		return d, nil
+	}
+
+	options := strings.TrimPrefix(marker.Tag.Get(key), structDefaultsPrefix)
+	if options == "" {
+		return d, nil
+	}
+	tag, err := parseFieldTag(reflect.StructTag(key+`:",`+options+`"`), key, d.marshal, d.unmarshal, d.common)
+	if err != nil {
+		return d, fmt.Errorf("invalid struct defaults tag: %q :: %w", marker.Tag, err)
+	}
+	return tagDefaults{
+		marshal:   tag.MarshalOpts,
+		unmarshal: tag.UnmarshalOpts,
+		common:    tag.CommonOpts,
+	}, nil
+}
+
 const fmtOptionNotUniqueError = "only one %s option is allwed - you've specified at least two: %v, %v"
 
-func parseFieldTag(tagStr reflect.StructTag, defaultMarshalTagOptions *MarshalTagOptions, defaultUnmarshalTagOptions *UnmarshalTagOptions, defaultCommonTagOptions *CommonTagOptions) (*ParsedTagInfo, error) {
-	v := tagStr.Get(tagKey)
+func parseFieldTag(tagStr reflect.StructTag, key string, defaultMarshalTagOptions *MarshalTagOptions, defaultUnmarshalTagOptions *UnmarshalTagOptions, defaultCommonTagOptions *CommonTagOptions) (*ParsedTagInfo, error) {
+	v := tagStr.Get(key)
 	nameAndOptions := strings.Split(v, ",")
 	tag := &ParsedTagInfo{
 		Name:            nameAndOptions[0],
 		MarshalPresence: MarshalPresenceMPUnspecified,
+		MarshalOpts:     NewUndefinedMarshalTagOptions(),
 		UnmarshalOpts:   NewUndefinedUnmarshalTagOptions(),
 		CommonOpts:      NewUndefinedCommonTagOptions(),
 	}
@@ -77,7 +236,11 @@ func parseFieldTag(tagStr reflect.StructTag, defaultMarshalTagOptions *MarshalTa
 		return nil, errors.New("tag string contains a surplus comma")
 	}
 
+	aliases := tagOptionAliases[key]
 	for _, option := range options {
+		if alias, ok := aliases[option]; ok {
+			option = alias
+		}
 
 		bCommonOptFound, err := tag.CommonOpts.ParseOption(option)
 		if err != nil {
@@ -89,14 +252,9 @@ func parseFieldTag(tagStr reflect.StructTag, defaultMarshalTagOptions *MarshalTa
 			return nil, err
 		}
 
-		// MarshalPresence
-		bMarshalOptFound := false
-		if value, err := MarshalPresenceFromString(option); err == nil {
-			if tag.MarshalPresence != MarshalPresenceMPUnspecified {
-				return nil, fmt.Errorf(fmtOptionNotUniqueError, "MarshalPresence", tag.MarshalPresence, v)
-			}
-			tag.MarshalPresence = value
-			bMarshalOptFound = true
+		bMarshalOptFound, err := tag.MarshalOpts.ParseOption(option)
+		if err != nil {
+			return nil, err
 		}
 
 		// Error specified option name is invalid
@@ -105,9 +263,8 @@ func parseFieldTag(tagStr reflect.StructTag, defaultMarshalTagOptions *MarshalTa
 		}
 	}
 
-	if tag.MarshalPresence == MarshalPresenceMPUnspecified {
-		tag.MarshalPresence = defaultMarshalTagOptions.Presence
-	}
+	tag.MarshalOpts.ApplyDefaults(defaultMarshalTagOptions)
+	tag.MarshalPresence = tag.MarshalOpts.Presence
 
 	tag.UnmarshalOpts.ApplyDefaults(defaultUnmarshalTagOptions)
 	tag.CommonOpts.ApplyDefaults(defaultCommonTagOptions)
@@ -115,6 +272,12 @@ func parseFieldTag(tagStr reflect.StructTag, defaultMarshalTagOptions *MarshalTa
 	return tag, nil
 }
 
+// fieldName is the NameTransformFunc of the compatibility presets of
+// libraries that use the Go field names as keys.
+func fieldName(s string) string {
+	return s
+}
+
 // snakeCase converts CamelCase names to snake_case with lowercase letters and
 // underscores. Names already in snake_case are left untouched.
 func snakeCase(s string) string {
@@ -137,23 +300,211 @@ func snakeCase(s string) string {
 	return string(out)
 }
 
-func cacher[TRes any, TOpt any](wrapped func(t reflect.Type, opts *TOpt) (TRes, error), cache *sync.Map, t reflect.Type, opts *TOpt) (TRes, error) {
+// typeCache is the storage of the factory caches. It memoizes the objects
+// (and errors) returned by a factory per type in a Cache.
+type typeCache struct {
+	items Cache
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+
+	// gen is incremented by purge. Objects created by a factory during a purge
+	// may depend on registrations that have been replaced so they are stored
+	// only if gen hasn't changed since the factory was called.
+	gen atomic.Uint64
+	mu  sync.Mutex
+}
+
+// newTypeCache creates a typeCache that stores the items in a Cache created
+// by newCache or by NewCache if newCache is nil.
+func newTypeCache(newCache func() Cache) *typeCache {
+	if newCache == nil {
+		newCache = NewCache
+	}
+	return &typeCache{
+		items: newCache(),
+	}
+}
+
+func (c *typeCache) load(t reflect.Type) (interface{}, bool) {
+	item, ok := c.items.Get(t)
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+	return item, ok
+}
+
+func (c *typeCache) store(t reflect.Type, gen uint64, item interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen.Load() == gen {
+		c.items.Put(t, item)
+	}
+}
+
+// purge removes every item from the cache.
+func (c *typeCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen.Add(1)
+	c.items.Purge()
+}
+
+// evict removes the item of the given type from the cache.
+func (c *typeCache) evict(t reflect.Type) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen.Add(1)
+	c.items.Delete(t)
+}
+
+func (c *typeCache) stats() CacheStats {
+	return CacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Len:    c.items.Len(),
+	}
+}
+
+// funcID identifies a func (e.g. a NameTransformer) in cache variant keys
+// because funcs aren't comparable.
+type funcID struct {
+	_ int8 // non-zero size to make every allocated ID unique
+}
+
+// snakeCaseID is the funcID of the default NameTransformer.
+var snakeCaseID = new(funcID)
+
+// maxCacheVariants is the number of cache variants kept by a variantCache.
+// The oldest variant is dropped when a new one is added to a full cache so
+// that deriving marshalers with new name transformers (e.g. with
+// MarshalWith) doesn't grow the cache forever.
+const maxCacheVariants = 16
+
+// variantCache holds a typeCache per cache variant: a comparable snapshot of
+// the options that affect the compiled ValuesMarshaler and ValuesUnmarshaler
+// objects (e.g. marshalCacheVariantKey). Marshalers with the same options
+// (e.g. marshalers derived with With that don't change these options) share
+// the compiled objects. It is used by the values factory caches.
+type variantCache struct {
+	m        sync.Map
+	newCache func() Cache
+
+	mu       sync.Mutex
+	variants []interface{} // in the order they were added
+	dropped  CacheStats    // the counters of the dropped variants
+}
+
+func (c *variantCache) get(variant interface{}) *typeCache {
+	if tc, ok := c.m.Load(variant); ok {
+		return tc.(*typeCache)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if tc, ok := c.m.Load(variant); ok {
+		return tc.(*typeCache)
+	}
+	if len(c.variants) == maxCacheVariants {
+		if tc, ok := c.m.LoadAndDelete(c.variants[0]); ok {
+			s := tc.(*typeCache).stats()
+			c.dropped = c.dropped.add(CacheStats{Hits: s.Hits, Misses: s.Misses})
+		}
+		c.variants = append(c.variants[:0], c.variants[1:]...)
+	}
+	tc := newTypeCache(c.newCache)
+	c.m.Store(variant, tc)
+	c.variants = append(c.variants, variant)
+	return tc
+}
+
+func (c *variantCache) stats() CacheStats {
+	c.mu.Lock()
+	s := c.dropped
+	c.mu.Unlock()
+	c.m.Range(func(_, tc interface{}) bool {
+		s = s.add(tc.(*typeCache).stats())
+		return true
+	})
+	return s
+}
+
+func (c *variantCache) purge() {
+	c.m.Range(func(_, tc interface{}) bool {
+		tc.(*typeCache).purge()
+		return true
+	})
+}
+
+func (c *variantCache) evict(t reflect.Type) {
+	c.m.Range(func(_, tc interface{}) bool {
+		tc.(*typeCache).evict(t)
+		return true
+	})
+}
+
+// factoryCache is implemented by the factory caches.
+type factoryCache interface {
+	purge()
+	evict(t reflect.Type)
+	stats() CacheStats
+}
+
+// purgeCache purges the cache of the given factory if it has one.
+func purgeCache(factory interface{}) {
+	if c, ok := factory.(factoryCache); ok {
+		c.purge()
+	}
+}
+
+// cacheStats returns the counters of the cache of the given factory if it has
+// one.
+func cacheStats(factory interface{}) CacheStats {
+	if c, ok := factory.(factoryCache); ok {
+		return c.stats()
+	}
+	return CacheStats{}
+}
+
+// evictCache evicts t from the cache of the given factory if it has one.
+func evictCache(factory interface{}, t reflect.Type) {
+	if c, ok := factory.(factoryCache); ok {
+		c.evict(t)
+	}
+}
+
+// cacher returns the object of type t from the cache or creates it with
+// wrapped and stores it. The cache hits and the creation of the objects are
+// logged to log if it isn't nil.
+func cacher[TRes any, TOpt any](wrapped func(t reflect.Type, opts *TOpt) (TRes, error), cache *typeCache, t reflect.Type, opts *TOpt, log *slog.Logger) (TRes, error) {
 	var (
 		m   TRes
 		err error
 	)
-	if item, ok := cache.Load(t); ok {
+	if item, ok := cache.load(t); ok {
+		if log != nil {
+			log.Debug("qs: cache hit", "object", reflect.TypeFor[TRes](), "type", t)
+		}
 		if m, ok = item.(TRes); ok {
 			return m, nil
 		}
 		return m, item.(error)
 	}
 
+	gen := cache.gen.Load()
 	m, err = wrapped(t, opts)
+	if log != nil {
+		if err != nil {
+			log.Debug("qs: creation failed", "object", reflect.TypeFor[TRes](), "type", t, "error", err)
+		} else {
+			log.Debug("qs: created", "object", reflect.TypeFor[TRes](), "type", t)
+		}
+	}
 	if err != nil {
-		cache.Store(t, err)
+		cache.store(t, gen, err)
 	} else {
-		cache.Store(t, m)
+		cache.store(t, gen, m)
 	}
 	return m, err
 }
@@ -0,0 +1,109 @@
+package qs
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+)
+
+// The math/big types are marshaled and unmarshaled by default so
+// arbitrary-precision values (e.g. amounts of money) don't need custom
+// marshalers. The fields are usually pointers (*big.Int) but the types can be
+// used as values too.
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+	bigRatType   = reflect.TypeOf(big.Rat{})
+)
+
+// addressable returns v or an addressable copy of v so the methods with
+// pointer receivers of the math/big types can be called.
+func addressable(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v
+	}
+	c := reflect.New(v.Type()).Elem()
+	c.Set(v)
+	return c
+}
+
+// marshalBigInt marshals big.Int values in base 10.
+func marshalBigInt(v reflect.Value, opts *MarshalOptions) (string, error) {
+	if v.Type() != bigIntType {
+		return "", &WrongTypeError{Actual: v.Type(), Expected: bigIntType}
+	}
+	return addressable(v).Addr().Interface().(*big.Int).String(), nil
+}
+
+// marshalBigFloat marshals big.Float values with the shortest representation
+// that is exact at the precision of the value unless the float options of the
+// field (floatfmt and prec) say otherwise.
+func marshalBigFloat(v reflect.Value, opts *MarshalOptions) (string, error) {
+	if v.Type() != bigFloatType {
+		return "", &WrongTypeError{Actual: v.Type(), Expected: bigFloatType}
+	}
+	f := addressable(v).Addr().Interface().(*big.Float)
+
+	tagOpts := opts.TagOptionsDefaults
+	if tagOpts.FloatPrecision <= 0 {
+		return f.Text('g', -1), nil
+	}
+	s := f.Text(tagOpts.FloatFormat.verb(), tagOpts.FloatPrecision)
+	if tagOpts.FloatZeros == MarshalFloatZerosTrimZeros {
+		s = trimFloatZeros(s)
+	}
+	return s, nil
+}
+
+// marshalBigRat marshals big.Rat values as fractions ("1/3") or integers
+// ("3"). If the field has a precision (prec=<n>) then the value is marshaled
+// as a decimal number rounded to n digits after the decimal point.
+func marshalBigRat(v reflect.Value, opts *MarshalOptions) (string, error) {
+	if v.Type() != bigRatType {
+		return "", &WrongTypeError{Actual: v.Type(), Expected: bigRatType}
+	}
+	r := addressable(v).Addr().Interface().(*big.Rat)
+
+	tagOpts := opts.TagOptionsDefaults
+	if tagOpts.FloatPrecision <= 0 {
+		return r.RatString(), nil
+	}
+	s := r.FloatString(tagOpts.FloatPrecision)
+	if tagOpts.FloatZeros == MarshalFloatZerosTrimZeros {
+		s = trimFloatZeros(s)
+	}
+	return s, nil
+}
+
+// unmarshalBigInt accepts the integers in base 10 and with the 0x, 0o and 0b
+// prefixes like the unmarshaler of the int kinds.
+func unmarshalBigInt(v reflect.Value, s string, opts *UnmarshalOptions) error {
+	if v.Type() != bigIntType {
+		return &WrongTypeError{Actual: v.Type(), Expected: bigIntType}
+	}
+	if _, ok := v.Addr().Interface().(*big.Int).SetString(s, 0); !ok {
+		return fmt.Errorf("invalid big.Int value: %q", s)
+	}
+	return nil
+}
+
+func unmarshalBigFloat(v reflect.Value, s string, opts *UnmarshalOptions) error {
+	if v.Type() != bigFloatType {
+		return &WrongTypeError{Actual: v.Type(), Expected: bigFloatType}
+	}
+	if _, ok := v.Addr().Interface().(*big.Float).SetString(s); !ok {
+		return fmt.Errorf("invalid big.Float value: %q", s)
+	}
+	return nil
+}
+
+// unmarshalBigRat accepts fractions ("1/3") and decimal numbers ("0.25").
+func unmarshalBigRat(v reflect.Value, s string, opts *UnmarshalOptions) error {
+	if v.Type() != bigRatType {
+		return &WrongTypeError{Actual: v.Type(), Expected: bigRatType}
+	}
+	if _, ok := v.Addr().Interface().(*big.Rat).SetString(s); !ok {
+		return fmt.Errorf("invalid big.Rat value: %q", s)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package qs
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Cache stores the Marshaler, ValuesMarshaler, Unmarshaler and
+// ValuesUnmarshaler objects compiled by the factories of a marshaler or an
+// unmarshaler (and the errors returned by the factories) by type. The
+// implementations have to be safe for concurrent use.
+//
+// A Cache may drop items at any time (e.g. to limit its size): the dropped
+// objects are compiled again when they are needed. A marshaler uses several
+// Cache objects created by the func set with WithMarshalCache (one for the
+// Marshaler objects and one per option variant for the ValuesMarshaler
+// objects), the same applies to unmarshalers.
+type Cache interface {
+	// Get returns the item stored for t.
+	Get(t reflect.Type) (interface{}, bool)
+
+	// Put stores the item of t replacing the previous item of t.
+	Put(t reflect.Type, item interface{})
+
+	// Delete removes the item of t.
+	Delete(t reflect.Type)
+
+	// Len returns the number of stored items.
+	Len() int
+
+	// Purge removes every item.
+	Purge()
+}
+
+// NewCache returns the default unbounded Cache implementation.
+func NewCache() Cache {
+	return &syncMapCache{}
+}
+
+// syncMapCache is the default Cache.
+type syncMapCache struct {
+	m sync.Map
+}
+
+func (c *syncMapCache) Get(t reflect.Type) (interface{}, bool) {
+	return c.m.Load(t)
+}
+
+func (c *syncMapCache) Put(t reflect.Type, item interface{}) {
+	c.m.Store(t, item)
+}
+
+func (c *syncMapCache) Delete(t reflect.Type) {
+	c.m.Delete(t)
+}
+
+func (c *syncMapCache) Len() int {
+	n := 0
+	c.m.Range(func(_, _ interface{}) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+func (c *syncMapCache) Purge() {
+	c.m.Clear()
+}
+
+// CacheStats holds the counters of the caches of a marshaler or an
+// unmarshaler. The marshalers (and unmarshalers) derived with With share the
+// caches and the counters of the original.
+type CacheStats struct {
+	// Hits is the number of lookups that found a compiled object.
+	Hits uint64
+
+	// Misses is the number of lookups that had to call a factory.
+	Misses uint64
+
+	// Len is the number of cached objects and errors.
+	Len int
+}
+
+func (s CacheStats) add(o CacheStats) CacheStats {
+	return CacheStats{
+		Hits:   s.Hits + o.Hits,
+		Misses: s.Misses + o.Misses,
+		Len:    s.Len + o.Len,
+	}
+}
@@ -0,0 +1,178 @@
+package qs
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// RegisterNamedCodec registers a codec with the given name with the
+// DefaultMarshaler and the DefaultUnmarshaler. A struct field selects the
+// codec with the codec tag option and the codec marshals and unmarshals the
+// value of the field instead of the Marshaler and Unmarshaler of its type:
+//
+//	type Query struct {
+//		Token string `qs:"token,codec=upper"`
+//	}
+//
+//	err := qs.RegisterNamedCodec("upper", marshalUpper, unmarshalUpper)
+//
+// This makes it possible to use a custom encoding for a single field without
+// overriding the type globally with RegisterCustomType. If the field is a
+// pointer then the codec receives the value pointed to by the field. See
+// CommonTagOptions.Codec for the builtin codecs.
+func RegisterNamedCodec(name string, mfn PrimitiveMarshalerFunc, umfn PrimitiveUnmarshalerFunc) error {
+	if err := DefaultMarshaler.RegisterNamedCodec(name, mfn); err != nil {
+		return err
+	}
+	return DefaultUnmarshaler.RegisterNamedCodec(name, umfn)
+}
+
+// RegisterNamedCodec registers the marshal func of the codec with the given
+// name. See the global RegisterNamedCodec func. A registration replaces the
+// previous codec with the same name.
+func (p *QSMarshaler) RegisterNamedCodec(name string, fn PrimitiveMarshalerFunc) error {
+	if fn == nil {
+		return fmt.Errorf("nil marshal func for codec %q", name)
+	}
+	if !validRegistryName(name) {
+		return fmt.Errorf("invalid codec name: %q", name)
+	}
+	p.opts.codecs.register(name, fn)
+	p.purgeValuesCache()
+	return nil
+}
+
+// RegisterNamedCodec registers the unmarshal func of the codec with the given
+// name. See the global RegisterNamedCodec func. A registration replaces the
+// previous codec with the same name.
+func (p *QSUnmarshaler) RegisterNamedCodec(name string, fn PrimitiveUnmarshalerFunc) error {
+	if fn == nil {
+		return fmt.Errorf("nil unmarshal func for codec %q", name)
+	}
+	if !validRegistryName(name) {
+		return fmt.Errorf("invalid codec name: %q", name)
+	}
+	p.opts.codecs.register(name, fn)
+	p.purgeValuesCache()
+	return nil
+}
+
+// builtinMarshalCodecs and builtinUnmarshalCodecs are the codecs available
+// without registration. Their names can be used as tag options without the
+// codec= prefix (e.g. `qs:"sig,base64url"`).
+var (
+	builtinMarshalCodecs   = newBuiltinMarshalCodecs()
+	builtinUnmarshalCodecs = newBuiltinUnmarshalCodecs()
+)
+
+func newBuiltinMarshalCodecs() map[string]PrimitiveMarshalerFunc {
+	codecs := map[string]PrimitiveMarshalerFunc{
+		"json": marshalJSONCodec,
+	}
+	for name, enc := range byteEncodings {
+		codecs[name] = byteMarshalCodec(name, enc)
+	}
+	return codecs
+}
+
+func newBuiltinUnmarshalCodecs() map[string]PrimitiveUnmarshalerFunc {
+	codecs := map[string]PrimitiveUnmarshalerFunc{
+		"json": unmarshalJSONCodec,
+	}
+	for name, enc := range byteEncodings {
+		codecs[name] = byteUnmarshalCodec(name, enc)
+	}
+	return codecs
+}
+
+// namedRegistry holds the named codecs or value transformers of a marshaler
+// or an unmarshaler. Like the factories it is copy-on-write so the lookups
+// don't need locking.
+type namedRegistry[F any] struct {
+	items atomic.Pointer[map[string]F]
+	mu    sync.Mutex
+
+	// builtin holds the items available without registration (e.g. the hex
+	// codec). The registered items override them.
+	builtin map[string]F
+}
+
+// validRegistryName reports whether name can be used in tag options like
+// codec=<name>.
+func validRegistryName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, ",=")
+}
+
+func (r *namedRegistry[F]) register(name string, item F) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var items map[string]F
+	if p := r.items.Load(); p != nil {
+		items = maps.Clone(*p)
+	} else {
+		items = make(map[string]F)
+	}
+	items[name] = item
+	r.items.Store(&items)
+}
+
+func (r *namedRegistry[F]) lookup(name string) (F, bool) {
+	if r != nil {
+		if p := r.items.Load(); p != nil {
+			if item, ok := (*p)[name]; ok {
+				return item, true
+			}
+		}
+		if item, ok := r.builtin[name]; ok {
+			return item, true
+		}
+	}
+	var zero F
+	return zero, false
+}
+
+// codecMarshaler returns the Marshaler of the fields of type t that use the
+// named codec. Pointers are dereferenced before calling the codec.
+func (o *MarshalOptions) codecMarshaler(name string, t reflect.Type) (Marshaler, error) {
+	fn, ok := o.codecs.lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown codec: %q", name)
+	}
+	return newCodecMarshaler(t, fn), nil
+}
+
+func newCodecMarshaler(t reflect.Type, fn PrimitiveMarshalerFunc) Marshaler {
+	if t.Kind() == reflect.Ptr {
+		return &ptrMarshaler{
+			Type:          t,
+			ElemMarshaler: newCodecMarshaler(t.Elem(), fn),
+		}
+	}
+	return &primitiveMarshalerFunc{fn}
+}
+
+// codecUnmarshaler returns the Unmarshaler of the fields of type t that use
+// the named codec. Nil pointers are allocated before calling the codec.
+func (o *UnmarshalerDefaultOptions) codecUnmarshaler(name string, t reflect.Type) (Unmarshaler, error) {
+	fn, ok := o.codecs.lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown codec: %q", name)
+	}
+	return newCodecUnmarshaler(t, fn), nil
+}
+
+func newCodecUnmarshaler(t reflect.Type, fn PrimitiveUnmarshalerFunc) Unmarshaler {
+	if t.Kind() == reflect.Ptr {
+		return &ptrUnmarshaler{
+			Type:            t,
+			ElemType:        t.Elem(),
+			ElemUnmarshaler: newCodecUnmarshaler(t.Elem(), fn),
+		}
+	}
+	return &primitiveUnmarshalerFunc{fn}
+}
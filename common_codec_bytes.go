@@ -0,0 +1,87 @@
+package qs
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+)
+
+// byteEncoding is a binary-to-text encoding of the builtin byte codecs.
+type byteEncoding interface {
+	EncodeToString(src []byte) string
+	DecodeString(s string) ([]byte, error)
+}
+
+type hexEncoding struct{}
+
+func (hexEncoding) EncodeToString(src []byte) string {
+	return hex.EncodeToString(src)
+}
+
+func (hexEncoding) DecodeString(s string) ([]byte, error) {
+	return hex.DecodeString(s)
+}
+
+// byteEncodings are the encodings of the builtin codecs of byte slices and
+// byte arrays.
+var byteEncodings = map[string]byteEncoding{
+	"hex":          hexEncoding{},
+	"base64":       base64.StdEncoding,
+	"base64url":    base64.URLEncoding,
+	"rawbase64":    base64.RawStdEncoding,
+	"rawbase64url": base64.RawURLEncoding,
+}
+
+// byteMarshalCodec returns the marshal func of the builtin byte codec with
+// the given name.
+func byteMarshalCodec(name string, enc byteEncoding) PrimitiveMarshalerFunc {
+	return func(v reflect.Value, opts *MarshalOptions) (string, error) {
+		b, err := byteValue(name, v)
+		if err != nil {
+			return "", err
+		}
+		return enc.EncodeToString(b), nil
+	}
+}
+
+// byteUnmarshalCodec returns the unmarshal func of the builtin byte codec
+// with the given name.
+func byteUnmarshalCodec(name string, enc byteEncoding) PrimitiveUnmarshalerFunc {
+	return func(v reflect.Value, s string, opts *UnmarshalOptions) error {
+		if !isByteSliceOrArray(v.Type()) {
+			return fmt.Errorf("%v codec expects a byte slice or array, got %v", name, v.Type())
+		}
+		b, err := enc.DecodeString(s)
+		if err != nil {
+			return fmt.Errorf("invalid %v value :: %w", name, err)
+		}
+		if v.Kind() == reflect.Array {
+			if len(b) != v.Len() {
+				return fmt.Errorf("decoded %v bytes into %v", len(b), v.Type())
+			}
+			reflect.Copy(v, reflect.ValueOf(b))
+			return nil
+		}
+		v.SetBytes(b)
+		return nil
+	}
+}
+
+// byteValue returns the bytes of a byte slice or array.
+func byteValue(codec string, v reflect.Value) ([]byte, error) {
+	if !isByteSliceOrArray(v.Type()) {
+		return nil, fmt.Errorf("%v codec expects a byte slice or array, got %v", codec, v.Type())
+	}
+	if v.Kind() == reflect.Slice {
+		return v.Bytes(), nil
+	}
+	b := make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(b), v)
+	return b, nil
+}
+
+func isByteSliceOrArray(t reflect.Type) bool {
+	k := t.Kind()
+	return (k == reflect.Slice || k == reflect.Array) && t.Elem().Kind() == reflect.Uint8
+}
@@ -0,0 +1,26 @@
+package qs
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// marshalJSONCodec is the marshal func of the builtin json codec.
+func marshalJSONCodec(v reflect.Value, opts *MarshalOptions) (string, error) {
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return "", fmt.Errorf("error marshaling %v as JSON :: %w", v.Type(), err)
+	}
+	return string(b), nil
+}
+
+// unmarshalJSONCodec is the unmarshal func of the builtin json codec.
+func unmarshalJSONCodec(v reflect.Value, s string, opts *UnmarshalOptions) error {
+	pv := reflect.New(v.Type())
+	if err := json.Unmarshal([]byte(s), pv.Interface()); err != nil {
+		return fmt.Errorf("error unmarshaling %v from JSON :: %w", v.Type(), err)
+	}
+	v.Set(pv.Elem())
+	return nil
+}
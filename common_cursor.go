@@ -0,0 +1,70 @@
+package qs
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+)
+
+// Cursor embeds typed pagination state in a single query string parameter.
+// The Value is marshaled into a query string with the DefaultMarshaler and
+// the query string is encoded with unpadded URL-safe base64, so the cursor
+// is opaque to the clients:
+//
+//	type Page struct {
+//		AfterID int64
+//		Sort    string
+//	}
+//
+//	type Query struct {
+//		Cursor qs.Cursor[Page]
+//		Limit  int
+//	}
+//
+// A cursor with a zero Value (e.g. the cursor of the first page) is
+// marshaled as a missing parameter.
+//
+// The cursor is unmarshaled with the DefaultUnmarshaler. Note that base64 is
+// an encoding, not encryption: combine the cursor with a ValueTransformer
+// (the transform tag option) to seal or sign it.
+type Cursor[T any] struct {
+	Value T
+}
+
+// NewCursor returns a Cursor holding v.
+func NewCursor[T any](v T) Cursor[T] {
+	return Cursor[T]{Value: v}
+}
+
+// MarshalQS implements the MarshalQS interface.
+func (c Cursor[T]) MarshalQS(opts *MarshalOptions) ([]string, error) {
+	if reflect.ValueOf(&c.Value).Elem().IsZero() {
+		return nil, nil
+	}
+	s, err := DefaultMarshaler.Marshal(&c.Value)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling cursor :: %w", err)
+	}
+	return []string{base64.RawURLEncoding.EncodeToString([]byte(s))}, nil
+}
+
+// UnmarshalQS implements the UnmarshalQS interface.
+func (c *Cursor[T]) UnmarshalQS(a []string, opts *UnmarshalOptions) error {
+	if a == nil {
+		return nil
+	}
+	s, err := opts.SliceToString(a)
+	if err != nil {
+		return err
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("invalid cursor %q :: %w", s, err)
+	}
+	var v T
+	if err := DefaultUnmarshaler.Unmarshal(&v, string(b)); err != nil {
+		return fmt.Errorf("invalid cursor %q :: %w", s, err)
+	}
+	c.Value = v
+	return nil
+}
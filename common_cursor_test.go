@@ -0,0 +1,52 @@
+package qs
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+type cursorPage struct {
+	AfterID int64
+	Sort    string
+}
+
+func TestCursor(t *testing.T) {
+	type query struct {
+		Cursor Cursor[cursorPage] `qs:"cursor"`
+		Limit  int
+	}
+
+	q := query{Cursor: NewCursor(cursorPage{AfterID: 42, Sort: "-date"}), Limit: 10}
+	vs, err := MarshalValues(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// base64url of "after_id=42&sort=-date"
+	want := url.Values{"cursor": {"YWZ0ZXJfaWQ9NDImc29ydD0tZGF0ZQ"}, "limit": {"10"}}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	var q2 query
+	if err := UnmarshalValues(&q2, vs); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(q2, q) {
+		t.Errorf("got %+v, want %+v", q2, q)
+	}
+
+	vs, err = MarshalValues(&query{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := vs["cursor"]; ok {
+		t.Errorf("unexpected cursor in %v", vs)
+	}
+
+	for _, s := range []string{"cursor=!!!", "cursor=YWZ0ZXJfaWQ9eA"} {
+		if err := Unmarshal(&q2, s); err == nil {
+			t.Errorf("Unmarshal(%q) :: unexpected success", s)
+		}
+	}
+}
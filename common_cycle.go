@@ -0,0 +1,58 @@
+package qs
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// typeDependency is a field of a struct type through which the
+// ValuesMarshaler or ValuesUnmarshaler of the struct is built from the one of
+// another struct type: an embedded struct or a nested field.
+type typeDependency struct {
+	field string
+	t     reflect.Type
+}
+
+// appendStructDependency appends the dependency on the field with the given
+// name and type if it is a struct or a pointer to a struct.
+func appendStructDependency(deps []typeDependency, field string, t reflect.Type) []typeDependency {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return deps
+	}
+	return append(deps, typeDependency{field: field, t: t})
+}
+
+// checkTypeCycle returns an error if struct type t depends on itself through
+// the dependencies returned by deps. Building the codec of such a type (e.g.
+// type Node struct { Next *Node } with nesting enabled) would never finish.
+// Cycles that don't contain t are reported when the codecs of their types are
+// built.
+func checkTypeCycle(t reflect.Type, deps func(reflect.Type) []typeDependency) error {
+	var path []string
+	visited := make(map[reflect.Type]bool)
+
+	var visit func(reflect.Type) bool
+	visit = func(vt reflect.Type) bool {
+		if visited[vt] {
+			return false
+		}
+		visited[vt] = true
+		for _, d := range deps(vt) {
+			path = append(path, vt.String()+"."+d.field)
+			if d.t == t || visit(d.t) {
+				return true
+			}
+			path = path[:len(path)-1]
+		}
+		return false
+	}
+
+	if visit(t) {
+		return fmt.Errorf("recursive type %v isn't supported: %v", t, strings.Join(path, " -> "))
+	}
+	return nil
+}
@@ -1,6 +1,6 @@
 package qs
 
-//go:generate go run github.com/dmji/go-stringer@latest -type=OptionSliceSeparator --trimprefix=@me -output common_enum_string.go -nametransform=lower -fromstringgenfn
+//go:generate go run github.com/dmji/go-stringer@latest -type=OptionSliceSeparator,OptionSliceKeys,OptionSliceDuplicates,NestingMode,MergeStrategy,DiagnosticKind --trimprefix=@me -output common_enum_string.go -nametransform=lower -fromstringgenfn
 
 type OptionSliceSeparator int8
 
@@ -11,3 +11,133 @@ const (
 	OptionSliceSeparatorSemicolon
 	OptionSliceSeparatorSpace
 )
+
+// OptionSliceKeys is a tag option that selects the keys of the items of
+// slice and array fields when they aren't joined with a separator.
+type OptionSliceKeys int8
+
+const (
+	// OptionSliceKeysSKUnspecified is the zero value of OptionSliceKeys. It
+	// results in using the default OptionSliceKeys which is Repeat.
+	OptionSliceKeysSKUnspecified OptionSliceKeys = iota
+
+	// OptionSliceKeysRepeat repeats the key of the field: "ids=1&ids=2".
+	OptionSliceKeysRepeat
+
+	// OptionSliceKeysBrackets repeats the key of the field with a "[]"
+	// suffix: "ids[]=1&ids[]=2".
+	OptionSliceKeysBrackets
+
+	// OptionSliceKeysNumbered appends the index of the item to the key of
+	// the field: "ids0=1&ids1=2".
+	OptionSliceKeysNumbered
+)
+
+// OptionSliceDuplicates is a tag option that controls the duplicate items of
+// slice fields.
+type OptionSliceDuplicates int8
+
+const (
+	// OptionSliceDuplicatesSDUnspecified is the zero value of
+	// OptionSliceDuplicates. It results in using the default
+	// OptionSliceDuplicates which is KeepDuplicates.
+	OptionSliceDuplicatesSDUnspecified OptionSliceDuplicates = iota
+
+	// OptionSliceDuplicatesKeepDuplicates marshals and unmarshals all items.
+	OptionSliceDuplicatesKeepDuplicates
+
+	// OptionSliceDuplicatesUnique gives slices set semantics: the marshaler
+	// and the unmarshaler drop the items whose marshaled value is the same as
+	// that of a preceding item.
+	OptionSliceDuplicatesUnique
+)
+
+// NestingMode is an enum that controls how the keys of the fields of nested
+// structs and of the items of slices of structs are built. It is set by the
+// Nesting field of MarshalOptions and UnmarshalerDefaultOptions.
+type NestingMode int8
+
+const (
+	// NestingModeNMUnspecified is the zero value of NestingMode. It results
+	// in using the default NestingMode which is None.
+	NestingModeNMUnspecified NestingMode = iota
+
+	// NestingModeNone disables nesting: struct fields are marshaled and
+	// unmarshaled like any other field type, by the Marshaler and Unmarshaler
+	// objects of the factories (e.g. when the struct implements MarshalQS).
+	NestingModeNone
+
+	// NestingModeDots builds the keys with dots only: "address.city" and
+	// "phones.0.number". This is the syntax of gorilla/schema.
+	NestingModeDots
+
+	// NestingModeDotsIndexBrackets uses dots for struct fields and brackets
+	// for indices: "address.city", "phones[0].number" and "tags[0]". This is
+	// the syntax of go-playground/form.
+	NestingModeDotsIndexBrackets
+
+	// NestingModeBrackets uses brackets for struct fields, map entries and
+	// indices: "address[city]", "phones[0][number]" and "filter[status]".
+	// This is the syntax of Rack (Rails), PHP, the qs package of npm and of
+	// the APIs that parse their keys like Stripe ("items[0][price]"). The
+	// unmarshaler accepts empty indices too: the values of "phones[][number]"
+	// and "tags[]" are assigned to the items in their order.
+	NestingModeBrackets
+
+	// NestingModeBracketsAllowDots is the allowDots option of the qs package
+	// of npm: the marshaler uses dots for struct fields and map entries and
+	// brackets for indices ("address.city", "phones[0].number") and the
+	// unmarshaler accepts the keys of NestingModeBrackets too.
+	NestingModeBracketsAllowDots
+)
+
+// MergeStrategy is an enum that controls how MergeValues and
+// QSMarshaler.MarshalInto handle the keys present in both the destination
+// and the source.
+type MergeStrategy int8
+
+const (
+	// MergeStrategyMSUnspecified is the zero value of MergeStrategy. It
+	// results in using the default MergeStrategy which is Replace.
+	MergeStrategyMSUnspecified MergeStrategy = iota
+
+	// MergeStrategyReplace replaces the values of the destination with the
+	// values of the source.
+	MergeStrategyReplace
+
+	// MergeStrategyAppend appends the values of the source to the values of
+	// the destination.
+	MergeStrategyAppend
+
+	// MergeStrategyKeepExisting keeps the values of the destination. Only
+	// the keys missing from the destination are copied from the source.
+	MergeStrategyKeepExisting
+)
+
+// DiagnosticKind is an enum that identifies the notices passed to the
+// Diagnostics hooks of MarshalOptions and UnmarshalerDefaultOptions.
+type DiagnosticKind int8
+
+const (
+	// DiagnosticKindDKUnspecified is the zero value of DiagnosticKind. It
+	// isn't passed to the hooks.
+	DiagnosticKindDKUnspecified DiagnosticKind = iota
+
+	// DiagnosticKindSkippedField reports a struct field ignored by
+	// SkipUnsupportedFields.
+	DiagnosticKindSkippedField
+
+	// DiagnosticKindUnknownKey reports a key of the input that doesn't match
+	// any field of the struct it is unmarshaled into.
+	DiagnosticKindUnknownKey
+
+	// DiagnosticKindDroppedValues reports the values that the unmarshaler
+	// dropped: the invalid items of slices with the skip option and the
+	// values ignored by the dupkeys=first and dupkeys=last options.
+	DiagnosticKindDroppedValues
+
+	// DiagnosticKindLenientParse reports a value that was accepted only
+	// because of a lenient option, e.g. an out of range number clamped by
+	// the clamp option.
+	DiagnosticKindLenientParse
+)
@@ -0,0 +1,127 @@
+package qs
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// EnumType is the constraint of the enum types accepted by RegisterEnum.
+type EnumType interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~string
+}
+
+// RegisterEnum registers the enum type T with the DefaultMarshaler and the
+// DefaultUnmarshaler. The values of T are marshaled as their names in m and
+// unmarshaled from these names without regard to letter case:
+//
+//	type Color int
+//
+//	const (
+//		Red Color = iota
+//		Green
+//	)
+//
+//	err := qs.RegisterEnum(map[Color]string{Red: "red", Green: "green"})
+//
+// Marshaling a value that isn't in m fails and so does unmarshaling an
+// unknown name. The error of the latter lists the valid names. An error is
+// returned if m is empty or contains two names that differ only in letter
+// case.
+func RegisterEnum[T EnumType](m map[T]string) error {
+	mfn, err := EnumMarshalerFunc(m)
+	if err != nil {
+		return err
+	}
+	umfn, err := EnumUnmarshalerFunc(m)
+	if err != nil {
+		return err
+	}
+
+	t := reflect.TypeFor[T]()
+	if err := DefaultMarshaler.RegisterCustomType(t, mfn); err != nil {
+		return err
+	}
+	return DefaultUnmarshaler.RegisterCustomType(t, umfn)
+}
+
+// EnumMarshalerFunc returns the PrimitiveMarshalerFunc that RegisterEnum uses
+// to marshal T. It can be registered with custom marshalers.
+func EnumMarshalerFunc[T EnumType](m map[T]string) (PrimitiveMarshalerFunc, error) {
+	if _, err := enumValues(m); err != nil {
+		return nil, err
+	}
+	names := make(map[T]string, len(m))
+	for value, name := range m {
+		names[value] = name
+	}
+	t := reflect.TypeFor[T]()
+
+	return func(v reflect.Value, opts *MarshalOptions) (string, error) {
+		if v.Type() != t {
+			return "", &WrongTypeError{Actual: v.Type(), Expected: t}
+		}
+		var value T
+		if v.CanAddr() {
+			value = *v.Addr().Interface().(*T)
+		} else {
+			value = v.Interface().(T)
+		}
+		name, ok := names[value]
+		if !ok {
+			return "", fmt.Errorf("invalid %v value: %v", t, value)
+		}
+		return name, nil
+	}, nil
+}
+
+// EnumUnmarshalerFunc returns the PrimitiveUnmarshalerFunc that RegisterEnum
+// uses to unmarshal T. It can be registered with custom unmarshalers.
+func EnumUnmarshalerFunc[T EnumType](m map[T]string) (PrimitiveUnmarshalerFunc, error) {
+	values, err := enumValues(m)
+	if err != nil {
+		return nil, err
+	}
+	valid := make([]string, 0, len(m))
+	for _, name := range m {
+		valid = append(valid, name)
+	}
+	sort.Strings(valid)
+	t := reflect.TypeFor[T]()
+
+	return func(v reflect.Value, s string, opts *UnmarshalOptions) error {
+		if v.Type() != t {
+			return &WrongTypeError{Actual: v.Type(), Expected: t}
+		}
+		value, ok := values[strings.ToLower(s)]
+		if !ok {
+			return fmt.Errorf("invalid %v value: %q, valid values: %v", t, s, strings.Join(valid, ", "))
+		}
+		if v.CanAddr() {
+			*v.Addr().Interface().(*T) = value
+		} else {
+			v.Set(reflect.ValueOf(value))
+		}
+		return nil
+	}, nil
+}
+
+// enumValues returns the values of an enum keyed by their lowercase names.
+func enumValues[T EnumType](m map[T]string) (map[string]T, error) {
+	if len(m) == 0 {
+		return nil, fmt.Errorf("no values for enum type %v", reflect.TypeFor[T]())
+	}
+	values := make(map[string]T, len(m))
+	for value, name := range m {
+		key := strings.ToLower(name)
+		if other, ok := values[key]; ok {
+			return nil, fmt.Errorf("enum type %v has ambiguous names: %q (%v) and %q (%v)",
+				reflect.TypeFor[T](), m[other], other, name, value)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,63 @@
+package qs
+
+import (
+	"strings"
+	"testing"
+)
+
+type testColor int
+
+const (
+	testColorRed testColor = iota
+	testColorGreen
+)
+
+type testShape string
+
+func TestRegisterEnum(t *testing.T) {
+	if err := RegisterEnum(map[testColor]string{testColorRed: "red", testColorGreen: "green"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterEnum(map[testShape]string{"sq": "square", "ci": "circle"}); err != nil {
+		t.Fatal(err)
+	}
+
+	type query struct {
+		Color  testColor
+		Colors []testColor
+		Shape  *testShape
+	}
+
+	var q query
+	if err := Unmarshal(&q, "color=GREEN&colors=red&colors=Green&shape=circle"); err != nil {
+		t.Fatal(err)
+	}
+	if q.Color != testColorGreen || len(q.Colors) != 2 || q.Colors[1] != testColorGreen || q.Shape == nil || *q.Shape != "ci" {
+		t.Errorf("unexpected result: %+v", q)
+	}
+
+	s, err := Marshal(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "color=green&colors=red&colors=green&shape=circle"; s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+
+	err = Unmarshal(&q, "color=blue")
+	if err == nil || !strings.Contains(err.Error(), "valid values: green, red") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := Marshal(&query{Color: 5}); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestRegisterEnumInvalid(t *testing.T) {
+	if _, err := EnumMarshalerFunc(map[testColor]string{}); err == nil {
+		t.Error("unexpected success")
+	}
+	if _, err := EnumUnmarshalerFunc(map[testColor]string{testColorRed: "red", testColorGreen: "RED"}); err == nil {
+		t.Error("unexpected success")
+	}
+}
@@ -1,4 +1,4 @@
-// Code generated by "go-stringer -type=OptionSliceSeparator --trimprefix=@me -output common_enum_string.go -nametransform=lower -fromstringgenfn"; DO NOT EDIT.
+// Code generated by "go-stringer -type=OptionSliceSeparator,OptionSliceKeys,OptionSliceDuplicates,NestingMode,MergeStrategy,DiagnosticKind --trimprefix=@me -output common_enum_string.go -nametransform=lower -fromstringgenfn"; DO NOT EDIT.
 
 package qs
 
@@ -36,3 +36,145 @@ func OptionSliceSeparatorFromString(s string) (OptionSliceSeparator, error) {
 	}
 	return OptionSliceSeparator(0), errors.New("cannot deternime OptionSliceSeparator from string")
 }
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[OptionSliceKeysSKUnspecified-0]
+	_ = x[OptionSliceKeysRepeat-1]
+	_ = x[OptionSliceKeysBrackets-2]
+	_ = x[OptionSliceKeysNumbered-3]
+}
+
+const _OptionSliceKeys_name = "skunspecifiedrepeatbracketsnumbered"
+
+var _OptionSliceKeys_index = [...]uint8{0, 13, 19, 27, 35}
+
+func (i OptionSliceKeys) String() string {
+	if i < 0 || i >= OptionSliceKeys(len(_OptionSliceKeys_index)-1) {
+		return "OptionSliceKeys(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _OptionSliceKeys_name[_OptionSliceKeys_index[i]:_OptionSliceKeys_index[i+1]]
+}
+func OptionSliceKeysFromString(s string) (OptionSliceKeys, error) {
+	for i := 0; i < 4; i++ {
+		if e := OptionSliceKeys(i + 0); s == e.String() {
+			return e, nil
+		}
+	}
+	return OptionSliceKeys(0), errors.New("cannot deternime OptionSliceKeys from string")
+}
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[OptionSliceDuplicatesSDUnspecified-0]
+	_ = x[OptionSliceDuplicatesKeepDuplicates-1]
+	_ = x[OptionSliceDuplicatesUnique-2]
+}
+
+const _OptionSliceDuplicates_name = "sdunspecifiedkeepduplicatesunique"
+
+var _OptionSliceDuplicates_index = [...]uint8{0, 13, 27, 33}
+
+func (i OptionSliceDuplicates) String() string {
+	if i < 0 || i >= OptionSliceDuplicates(len(_OptionSliceDuplicates_index)-1) {
+		return "OptionSliceDuplicates(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _OptionSliceDuplicates_name[_OptionSliceDuplicates_index[i]:_OptionSliceDuplicates_index[i+1]]
+}
+func OptionSliceDuplicatesFromString(s string) (OptionSliceDuplicates, error) {
+	for i := 0; i < 3; i++ {
+		if e := OptionSliceDuplicates(i + 0); s == e.String() {
+			return e, nil
+		}
+	}
+	return OptionSliceDuplicates(0), errors.New("cannot deternime OptionSliceDuplicates from string")
+}
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[NestingModeNMUnspecified-0]
+	_ = x[NestingModeNone-1]
+	_ = x[NestingModeDots-2]
+	_ = x[NestingModeDotsIndexBrackets-3]
+	_ = x[NestingModeBrackets-4]
+	_ = x[NestingModeBracketsAllowDots-5]
+}
+
+const _NestingMode_name = "nmunspecifiednonedotsdotsindexbracketsbracketsbracketsallowdots"
+
+var _NestingMode_index = [...]uint8{0, 13, 17, 21, 38, 46, 63}
+
+func (i NestingMode) String() string {
+	if i < 0 || i >= NestingMode(len(_NestingMode_index)-1) {
+		return "NestingMode(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _NestingMode_name[_NestingMode_index[i]:_NestingMode_index[i+1]]
+}
+func NestingModeFromString(s string) (NestingMode, error) {
+	for i := 0; i < 6; i++ {
+		if e := NestingMode(i + 0); s == e.String() {
+			return e, nil
+		}
+	}
+	return NestingMode(0), errors.New("cannot deternime NestingMode from string")
+}
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[MergeStrategyMSUnspecified-0]
+	_ = x[MergeStrategyReplace-1]
+	_ = x[MergeStrategyAppend-2]
+	_ = x[MergeStrategyKeepExisting-3]
+}
+
+const _MergeStrategy_name = "msunspecifiedreplaceappendkeepexisting"
+
+var _MergeStrategy_index = [...]uint8{0, 13, 20, 26, 38}
+
+func (i MergeStrategy) String() string {
+	if i < 0 || i >= MergeStrategy(len(_MergeStrategy_index)-1) {
+		return "MergeStrategy(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _MergeStrategy_name[_MergeStrategy_index[i]:_MergeStrategy_index[i+1]]
+}
+func MergeStrategyFromString(s string) (MergeStrategy, error) {
+	for i := 0; i < 4; i++ {
+		if e := MergeStrategy(i + 0); s == e.String() {
+			return e, nil
+		}
+	}
+	return MergeStrategy(0), errors.New("cannot deternime MergeStrategy from string")
+}
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[DiagnosticKindDKUnspecified-0]
+	_ = x[DiagnosticKindSkippedField-1]
+	_ = x[DiagnosticKindUnknownKey-2]
+	_ = x[DiagnosticKindDroppedValues-3]
+	_ = x[DiagnosticKindLenientParse-4]
+}
+
+const _DiagnosticKind_name = "dkunspecifiedskippedfieldunknownkeydroppedvalueslenientparse"
+
+var _DiagnosticKind_index = [...]uint8{0, 13, 25, 35, 48, 60}
+
+func (i DiagnosticKind) String() string {
+	if i < 0 || i >= DiagnosticKind(len(_DiagnosticKind_index)-1) {
+		return "DiagnosticKind(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _DiagnosticKind_name[_DiagnosticKind_index[i]:_DiagnosticKind_index[i+1]]
+}
+func DiagnosticKindFromString(s string) (DiagnosticKind, error) {
+	for i := 0; i < 5; i++ {
+		if e := DiagnosticKind(i + 0); s == e.String() {
+			return e, nil
+		}
+	}
+	return DiagnosticKind(0), errors.New("cannot deternime DiagnosticKind from string")
+}
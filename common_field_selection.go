@@ -0,0 +1,99 @@
+package qs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// FieldSelection holds the sparse fieldset parameters of APIs that let the
+// clients select the fields of the responses ("fields=id,name" and
+// "exclude=notes"). It can be embedded into query structs:
+//
+//	type GetUser struct {
+//		qs.FieldSelection
+//		ID int `qs:"id"`
+//	}
+//
+//	var q GetUser
+//	err := qs.Unmarshal(&q, "id=1&fields=id,name")
+//	...
+//	err = q.Validate(reflect.TypeFor[User]())
+//
+// The keys of the selected fields are validated by Validate against the
+// query string keys of a struct type, e.g. the response type.
+type FieldSelection struct {
+	// Include lists the selected fields. All fields are selected if it is
+	// empty.
+	Include []string `qs:"fields,comma,omitempty"`
+
+	// Exclude lists the fields that aren't selected even if they are
+	// included.
+	Exclude []string `qs:"exclude,comma,omitempty"`
+}
+
+// Selects reports whether the field with the given key is selected.
+func (s FieldSelection) Selects(key string) bool {
+	if len(s.Include) != 0 && !slices.Contains(s.Include, key) {
+		return false
+	}
+	return !slices.Contains(s.Exclude, key)
+}
+
+// Validate checks that the included and excluded keys are the keys of the
+// fields of struct type t as seen by the DefaultMarshaler. See
+// QSMarshaler.ValidateFieldSelection.
+func (s FieldSelection) Validate(t reflect.Type) error {
+	return DefaultMarshaler.ValidateFieldSelection(s, t)
+}
+
+// ValidateFieldSelection checks that the included and excluded keys of s are
+// the keys of the fields of struct type t. The keys of embedded structs are
+// valid too and the key of a nested struct field is valid but the keys of
+// its fields aren't. The error lists the first unknown key.
+func (p *QSMarshaler) ValidateFieldSelection(s FieldSelection, t reflect.Type) error {
+	if t == nil {
+		return errors.New("nil type")
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return &WrongKindError{Expected: reflect.Struct, Actual: t}
+	}
+	vm, err := p.CompileType(t)
+	if err != nil {
+		return err
+	}
+	fields, err := marshalerSchema(vm, p.opts.Nesting, nil)
+	if err != nil {
+		return err
+	}
+
+	known := func(key string) bool {
+		return slices.ContainsFunc(fields, func(f schemaField) bool {
+			return f.key == key && !f.nested
+		})
+	}
+	for _, key := range s.Include {
+		if !known(key) {
+			return fmt.Errorf("unknown field %q in the selected fields of %v", key, t)
+		}
+	}
+	for _, key := range s.Exclude {
+		if !known(key) {
+			return fmt.Errorf("unknown field %q in the excluded fields of %v", key, t)
+		}
+	}
+	return nil
+}
+
+// FieldFilter returns a func for MarshalOptions.FieldFilter (see
+// WithMarshalFieldFilter) that omits the fields that aren't selected. The
+// fields of nested structs are filtered by their own keys.
+func (s FieldSelection) FieldFilter() func(field FieldInfo, value reflect.Value) bool {
+	return func(field FieldInfo, value reflect.Value) bool {
+		return s.Selects(field.Tag.Name)
+	}
+}
@@ -0,0 +1,56 @@
+package qs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFieldSelection(t *testing.T) {
+	type getUser struct {
+		FieldSelection
+		ID int `qs:"id"`
+	}
+	type Audit struct {
+		CreatedBy string `qs:"created_by"`
+	}
+	type user struct {
+		ID    int    `qs:"id"`
+		Name  string `qs:"name"`
+		Notes string `qs:"notes"`
+		Audit
+	}
+	ut := reflect.TypeFor[user]()
+
+	var q getUser
+	if err := Unmarshal(&q, "id=1&fields=id,name,notes&exclude=notes"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(q.Include, []string{"id", "name", "notes"}) || !reflect.DeepEqual(q.Exclude, []string{"notes"}) {
+		t.Errorf("got %+v", q)
+	}
+	if err := q.Validate(ut); err != nil {
+		t.Error(err)
+	}
+	if !q.Selects("name") || q.Selects("notes") || q.Selects("created_by") {
+		t.Errorf("unexpected selection %+v", q.FieldSelection)
+	}
+	if s, err := Marshal(&q); err != nil || s != "exclude=notes&fields=id%2Cname%2Cnotes&id=1" {
+		t.Errorf("got %q, %v", s, err)
+	}
+
+	m := NewMarshaler(nil, WithMarshalFieldFilter(q.FieldFilter()))
+	if s, err := m.Marshal(&user{ID: 1, Name: "bob", Notes: "x", Audit: Audit{CreatedBy: "admin"}}); err != nil || s != "id=1&name=bob" {
+		t.Errorf("got %q, %v", s, err)
+	}
+
+	// The keys of embedded structs are known.
+	if err := (FieldSelection{Exclude: []string{"created_by"}}).Validate(ut); err != nil {
+		t.Error(err)
+	}
+	if err := (FieldSelection{Include: []string{"id", "email"}}).Validate(ut); err == nil {
+		t.Error("unexpected success")
+	}
+	if err := (FieldSelection{}).Validate(reflect.TypeFor[int]()); err == nil {
+		t.Error("unexpected success")
+	}
+}
@@ -0,0 +1,66 @@
+package qs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// PersistedQuery is the query string of a GET request of a persisted GraphQL
+// query (the automatic persisted queries of Apollo and the other clients
+// that send the hash of the query document instead of the document). The
+// variables and the extensions are JSON encoded parameters:
+//
+//	operationName=GetUser&variables={"id":1}&extensions={"persistedQuery":{"version":1,"sha256Hash":"..."}}
+//
+// V is the type of the variables, usually a struct with json tags. Use
+// QueryString to build the query string of the request and Unmarshal to
+// parse it on the server side.
+type PersistedQuery[V any] struct {
+	OperationName string                   `qs:"operationName,omitempty"`
+	Variables     V                        `qs:"variables,codec=json"`
+	Extensions    PersistedQueryExtensions `qs:"extensions,codec=json"`
+}
+
+// PersistedQueryExtensions is the extensions parameter of a PersistedQuery.
+type PersistedQueryExtensions struct {
+	PersistedQuery PersistedQueryHash `json:"persistedQuery"`
+}
+
+// PersistedQueryHash identifies the query document of a PersistedQuery.
+type PersistedQueryHash struct {
+	Version    int    `json:"version"`
+	SHA256Hash string `json:"sha256Hash"`
+}
+
+// NewPersistedQuery returns the PersistedQuery of the given query document
+// with its version 1 SHA-256 hash.
+func NewPersistedQuery[V any](query, operationName string, variables V) PersistedQuery[V] {
+	return PersistedQuery[V]{
+		OperationName: operationName,
+		Variables:     variables,
+		Extensions: PersistedQueryExtensions{
+			PersistedQuery: PersistedQueryHash{Version: 1, SHA256Hash: PersistedQueryDocumentHash(query)},
+		},
+	}
+}
+
+// PersistedQueryDocumentHash returns the hex encoded SHA-256 hash of a
+// GraphQL query document that identifies it in persisted queries.
+func PersistedQueryDocumentHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// Matches reports whether the hash of the query is the hash of the given
+// query document.
+func (q PersistedQuery[V]) Matches(query string) bool {
+	return q.Extensions.PersistedQuery.SHA256Hash == PersistedQueryDocumentHash(query)
+}
+
+// QueryString marshals the query with the DefaultMarshaler into its
+// canonical query string (see MarshalCanonical). The same query and
+// variables always result in the same query string so the responses can be
+// cached by CDNs and HTTP caches.
+func (q PersistedQuery[V]) QueryString() (string, error) {
+	return DefaultMarshaler.MarshalCanonical(&q)
+}
@@ -0,0 +1,44 @@
+package qs
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestPersistedQuery(t *testing.T) {
+	type variables struct {
+		ID    int      `json:"id"`
+		Roles []string `json:"roles,omitempty"`
+	}
+	const document = "query GetUser($id: ID!) { user(id: $id) { name } }"
+
+	q := NewPersistedQuery(document, "GetUser", variables{ID: 1, Roles: []string{"admin"}})
+	s, err := q.QueryString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs, err := url.ParseQuery(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{
+		"operationName": {"GetUser"},
+		"variables":     {`{"id":1,"roles":["admin"]}`},
+		"extensions":    {`{"persistedQuery":{"version":1,"sha256Hash":"` + PersistedQueryDocumentHash(document) + `"}}`},
+	}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+	if s2, err := q.QueryString(); err != nil || s2 != s {
+		t.Errorf("got %q, %v, want %q", s2, err, s)
+	}
+
+	var parsed PersistedQuery[variables]
+	if err := Unmarshal(&parsed, s); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, q) || !parsed.Matches(document) || parsed.Matches("query { x }") {
+		t.Errorf("got %+v, want %+v", parsed, q)
+	}
+}
@@ -0,0 +1,79 @@
+package qs
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// HookEvent describes a finished marshal or unmarshal call. It is passed to
+// the hooks set with WithMarshalHook and WithUnmarshalHook that can export
+// metrics (e.g. with expvar or Prometheus) without wrapping every call site.
+type HookEvent struct {
+	// Type is the type of the marshaled object or of the object that the
+	// query string was unmarshaled into. Pointers are dereferenced once so a
+	// call with a *Query reports Query. Type is nil if the call received a
+	// nil interface.
+	Type reflect.Type
+
+	// Duration is the time spent in the call including the lookup of the
+	// compiled objects and the parsing or encoding of the query string.
+	Duration time.Duration
+
+	// Err is the error returned by the call.
+	Err error
+}
+
+// HookFunc is called after each marshal or unmarshal call of the marshaler
+// or unmarshaler it is set on. It is called synchronously by the goroutine
+// of the call so it has to be fast and safe for concurrent use.
+type HookFunc func(e HookEvent)
+
+// report calls the hook with the event of a call started at start that
+// returned *err. It is deferred by the instrumented calls.
+func (fn HookFunc) report(t reflect.Type, start time.Time, err *error) {
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	fn(HookEvent{
+		Type:     t,
+		Duration: time.Since(start),
+		Err:      *err,
+	})
+}
+
+// Diagnostic is a non-fatal notice of a marshal or unmarshal call, e.g. a
+// skipped field or an unknown key. It is passed to the hooks set with
+// WithMarshalDiagnostics and WithUnmarshalDiagnostics that can log the
+// notices in production without failing the calls.
+type Diagnostic struct {
+	// Kind identifies the notice.
+	Kind DiagnosticKind
+
+	// Type is the type of the struct of a skipped field or the type of the
+	// value that the notice is about. It is nil if it isn't known (e.g. for
+	// the unknown keys of the input).
+	Type reflect.Type
+
+	// Key is the query string key of the notice. It is empty for skipped
+	// fields.
+	Key string
+
+	// Message describes the notice.
+	Message string
+}
+
+// DiagnosticFunc receives the notices of the marshaler or unmarshaler it is
+// set on. It is called synchronously by the goroutine of the call so it has
+// to be fast and safe for concurrent use.
+type DiagnosticFunc func(d Diagnostic)
+
+// skippedFieldDiagnostic returns the notice of the field of struct type t
+// skipped by SkipUnsupportedFields because of err.
+func skippedFieldDiagnostic(t reflect.Type, sf reflect.StructField, err error) Diagnostic {
+	return Diagnostic{
+		Kind:    DiagnosticKindSkippedField,
+		Type:    t,
+		Message: fmt.Sprintf("skipped field %v.%v :: %v", t, sf.Name, err),
+	}
+}
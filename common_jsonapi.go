@@ -0,0 +1,64 @@
+package qs
+
+import (
+	"strings"
+)
+
+// JSONAPIQuery holds the query parameters defined by the JSON:API
+// specification. It can be embedded into query structs that add the filter
+// parameters of the endpoint. Use it with the marshalers and unmarshalers
+// configured by WithMarshalJSONAPICompat and WithUnmarshalJSONAPICompat:
+//
+//	type ListArticles struct {
+//		qs.JSONAPIQuery
+//		Filter struct {
+//			Author string `qs:"author,omitempty"`
+//		} `qs:"filter"`
+//	}
+//
+//	um := qs.NewUnmarshaler(nil, qs.WithUnmarshalJSONAPICompat())
+//	var q ListArticles
+//	err := um.Unmarshal(&q, "include=author&fields[articles]=title,body&sort=-created&page[number]=2&filter[author]=bob")
+type JSONAPIQuery struct {
+	// Include lists the relationship paths of the included resources
+	// ("include=author,comments.author").
+	Include []string `qs:"include,comma,omitempty"`
+
+	// Fields are the sparse fieldsets by resource type
+	// ("fields[articles]=title,body").
+	Fields map[string][]string `qs:"fields,comma,omitempty"`
+
+	// Sort lists the sort fields. A "-" prefix means descending order
+	// ("sort=-created,title"). See SortFields.
+	Sort []string `qs:"sort,comma,omitempty"`
+
+	// Page holds the pagination parameters ("page[number]=2&page[size]=10").
+	Page JSONAPIPage `qs:"page,omitempty"`
+}
+
+// JSONAPIPage holds the pagination parameters of a JSON:API query. The
+// specification doesn't define the pagination strategy so the parameters of
+// the page, offset and cursor based strategies are all supported.
+type JSONAPIPage struct {
+	Number int    `qs:"number,omitempty"`
+	Size   int    `qs:"size,omitempty"`
+	Offset int    `qs:"offset,omitempty"`
+	Limit  int    `qs:"limit,omitempty"`
+	Cursor string `qs:"cursor,omitempty"`
+}
+
+// JSONAPISortField is a sort field of a JSON:API query.
+type JSONAPISortField struct {
+	Field      string
+	Descending bool
+}
+
+// SortFields returns the parsed sort fields of the query.
+func (q JSONAPIQuery) SortFields() []JSONAPISortField {
+	fields := make([]JSONAPISortField, 0, len(q.Sort))
+	for _, s := range q.Sort {
+		field, desc := strings.CutPrefix(s, "-")
+		fields = append(fields, JSONAPISortField{Field: field, Descending: desc})
+	}
+	return fields
+}
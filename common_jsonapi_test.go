@@ -0,0 +1,50 @@
+package qs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONAPIQuery(t *testing.T) {
+	type articleFilter struct {
+		Author string   `qs:"author,omitempty"`
+		Tags   []string `qs:"tags,omitempty"`
+	}
+	type listArticles struct {
+		JSONAPIQuery
+		Filter articleFilter `qs:"filter,omitempty"`
+	}
+
+	const query = "fields%5Barticles%5D=title%2Cbody&fields%5Bpeople%5D=name&filter%5Bauthor%5D=bob&filter%5Btags%5D=go%2Cweb&" +
+		"include=author%2Ccomments.author&page%5Bnumber%5D=2&page%5Bsize%5D=10&sort=-created%2Ctitle"
+
+	um := NewUnmarshaler(nil, WithUnmarshalJSONAPICompat())
+	var q listArticles
+	if err := um.Unmarshal(&q, query); err != nil {
+		t.Fatal(err)
+	}
+	want := listArticles{
+		JSONAPIQuery: JSONAPIQuery{
+			Include: []string{"author", "comments.author"},
+			Fields:  map[string][]string{"articles": {"title", "body"}, "people": {"name"}},
+			Sort:    []string{"-created", "title"},
+			Page:    JSONAPIPage{Number: 2, Size: 10},
+		},
+		Filter: articleFilter{Author: "bob", Tags: []string{"go", "web"}},
+	}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("got %+v, want %+v", q, want)
+	}
+	wantSort := []JSONAPISortField{{Field: "created", Descending: true}, {Field: "title"}}
+	if got := q.SortFields(); !reflect.DeepEqual(got, wantSort) {
+		t.Errorf("got %+v, want %+v", got, wantSort)
+	}
+
+	m := NewMarshaler(nil, WithMarshalJSONAPICompat())
+	if s, err := m.Marshal(&q); err != nil || s != query {
+		t.Errorf("got %q, %v", s, err)
+	}
+	if s, err := m.Marshal(&listArticles{}); err != nil || s != "" {
+		t.Errorf("got %q, %v", s, err)
+	}
+}
@@ -0,0 +1,221 @@
+package qs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LanguageTag is a BCP 47 (RFC 5646) language tag like "en", "en-US" or
+// "zh-Hant-TW". It can be used as the type of lang= or locale= struct fields
+// without registering a custom type: unmarshaling validates the
+// well-formedness of the tag and stores it in its canonical letter case.
+// Underscores are accepted as subtag separators ("en_US") and are converted
+// to hyphens.
+//
+// Only the syntax of the tag is checked, the subtags aren't validated against
+// the IANA language subtag registry. The empty tag is valid and represents a
+// missing value.
+type LanguageTag string
+
+// languageTagParts holds the subtags of a parsed LanguageTag that are
+// exposed by its accessor methods.
+type languageTagParts struct {
+	language string
+	script   string
+	region   string
+}
+
+// ParseLanguageTag checks the syntax of the given BCP 47 language tag and
+// returns it in canonical form.
+func ParseLanguageTag(s string) (LanguageTag, error) {
+	if s == "" {
+		return "", nil
+	}
+	subtags, _, err := parseLanguageTag(s)
+	if err != nil {
+		return "", err
+	}
+	return LanguageTag(strings.Join(subtags, "-")), nil
+}
+
+// MustParseLanguageTag is like ParseLanguageTag but panics if the tag is
+// invalid. It simplifies the initialisation of global variables.
+func MustParseLanguageTag(s string) LanguageTag {
+	t, err := ParseLanguageTag(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// String returns the tag as a string.
+func (t LanguageTag) String() string {
+	return string(t)
+}
+
+// Base returns the primary language subtag (e.g. "en" for "en-US") or an
+// empty string if the tag is empty, invalid or private use only.
+func (t LanguageTag) Base() string {
+	return t.parts().language
+}
+
+// Script returns the script subtag (e.g. "Hant" for "zh-Hant-TW") or an
+// empty string if the tag has no script subtag.
+func (t LanguageTag) Script() string {
+	return t.parts().script
+}
+
+// Region returns the region subtag (e.g. "US" for "en-US") or an empty
+// string if the tag has no region subtag.
+func (t LanguageTag) Region() string {
+	return t.parts().region
+}
+
+func (t LanguageTag) parts() languageTagParts {
+	if t == "" {
+		return languageTagParts{}
+	}
+	_, parts, err := parseLanguageTag(string(t))
+	if err != nil {
+		return languageTagParts{}
+	}
+	return parts
+}
+
+// MarshalQS implements the MarshalQS interface. It fails if the tag isn't
+// well-formed.
+func (t LanguageTag) MarshalQS(opts *MarshalOptions) ([]string, error) {
+	canonical, err := ParseLanguageTag(string(t))
+	if err != nil {
+		return nil, err
+	}
+	return []string{string(canonical)}, nil
+}
+
+// UnmarshalQS implements the UnmarshalQS interface.
+func (t *LanguageTag) UnmarshalQS(a []string, opts *UnmarshalOptions) error {
+	if a == nil {
+		return nil
+	}
+	s, err := opts.SliceToString(a)
+	if err != nil {
+		return err
+	}
+	canonical, err := ParseLanguageTag(s)
+	if err != nil {
+		return err
+	}
+	*t = canonical
+	return nil
+}
+
+// parseLanguageTag implements the langtag and privateuse productions of
+// RFC 5646. It returns the subtags in canonical letter case.
+func parseLanguageTag(s string) ([]string, languageTagParts, error) {
+	var parts languageTagParts
+	subtags := strings.Split(strings.ReplaceAll(s, "_", "-"), "-")
+
+	invalid := func(reason string) error {
+		return fmt.Errorf("invalid language tag %q :: %v", s, reason)
+	}
+
+	for i, st := range subtags {
+		if len(st) == 0 || len(st) > 8 || !isAlphaNum(st) {
+			return nil, parts, invalid(fmt.Sprintf("malformed subtag %q", st))
+		}
+		subtags[i] = strings.ToLower(st)
+	}
+
+	i := 0
+	if subtags[0] == "x" {
+		return subtags, parts, parsePrivateUseSubtags(subtags[1:], invalid)
+	}
+
+	// language
+	if !isAlpha(subtags[i]) || len(subtags[i]) < 2 {
+		return nil, parts, invalid("the primary language subtag must contain 2-8 letters")
+	}
+	parts.language = subtags[i]
+	i++
+
+	// extlang
+	if len(parts.language) <= 3 {
+		for n := 0; n < 3 && i < len(subtags) && len(subtags[i]) == 3 && isAlpha(subtags[i]); n++ {
+			i++
+		}
+	}
+
+	// script
+	if i < len(subtags) && len(subtags[i]) == 4 && isAlpha(subtags[i]) {
+		subtags[i] = strings.ToUpper(subtags[i][:1]) + subtags[i][1:]
+		parts.script = subtags[i]
+		i++
+	}
+
+	// region
+	if i < len(subtags) && ((len(subtags[i]) == 2 && isAlpha(subtags[i])) || (len(subtags[i]) == 3 && isDigit(subtags[i]))) {
+		subtags[i] = strings.ToUpper(subtags[i])
+		parts.region = subtags[i]
+		i++
+	}
+
+	// variants
+	for i < len(subtags) && (len(subtags[i]) >= 5 || (len(subtags[i]) == 4 && isDigit(subtags[i][:1]))) {
+		i++
+	}
+
+	// extensions
+	for i < len(subtags) && len(subtags[i]) == 1 && subtags[i] != "x" {
+		n := 0
+		for i++; i < len(subtags) && len(subtags[i]) >= 2; i++ {
+			n++
+		}
+		if n == 0 {
+			return nil, parts, invalid("empty extension")
+		}
+	}
+
+	// private use
+	if i < len(subtags) && subtags[i] == "x" {
+		return subtags, parts, parsePrivateUseSubtags(subtags[i+1:], invalid)
+	}
+
+	if i < len(subtags) {
+		return nil, parts, invalid(fmt.Sprintf("unexpected subtag %q", subtags[i]))
+	}
+	return subtags, parts, nil
+}
+
+func parsePrivateUseSubtags(subtags []string, invalid func(string) error) error {
+	if len(subtags) == 0 {
+		return invalid("empty private use section")
+	}
+	return nil
+}
+
+func isAlpha(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphaNum(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package qs
+
+import (
+	"testing"
+)
+
+func TestParseLanguageTag(t *testing.T) {
+	validTags := map[string]string{
+		"":                   "",
+		"en":                 "en",
+		"EN-us":              "en-US",
+		"en_GB":              "en-GB",
+		"zh-hant-tw":         "zh-Hant-TW",
+		"es-419":             "es-419",
+		"sl-rozaj-biske":     "sl-rozaj-biske",
+		"de-CH-1901":         "de-CH-1901",
+		"zh-yue-HK":          "zh-yue-HK",
+		"en-US-u-ca-gregory": "en-US-u-ca-gregory",
+		"en-x-private":       "en-x-private",
+		"x-whatever":         "x-whatever",
+	}
+	for input, want := range validTags {
+		tag, err := ParseLanguageTag(input)
+		if err != nil {
+			t.Errorf("ParseLanguageTag(%q) :: unexpected error :: %v", input, err)
+			continue
+		}
+		if string(tag) != want {
+			t.Errorf("ParseLanguageTag(%q) == %q, want %q", input, tag, want)
+		}
+	}
+
+	invalidTags := []string{
+		"e",
+		"en-",
+		"-en",
+		"en--US",
+		"123",
+		"en-US-u",
+		"en-x",
+		"en-toolongsubtag",
+		"en-US!",
+	}
+	for _, input := range invalidTags {
+		if _, err := ParseLanguageTag(input); err == nil {
+			t.Errorf("ParseLanguageTag(%q) :: unexpected success", input)
+		}
+	}
+}
+
+func TestLanguageTagParts(t *testing.T) {
+	tag := MustParseLanguageTag("zh-Hant-TW")
+	if tag.Base() != "zh" || tag.Script() != "Hant" || tag.Region() != "TW" {
+		t.Errorf("unexpected parts of %q: %q %q %q", tag, tag.Base(), tag.Script(), tag.Region())
+	}
+}
+
+func TestLanguageTagField(t *testing.T) {
+	type query struct {
+		Lang   LanguageTag
+		Locale *LanguageTag
+	}
+
+	var q query
+	if err := Unmarshal(&q, "lang=en_us&locale=PT-br"); err != nil {
+		t.Fatal(err)
+	}
+	if q.Lang != "en-US" {
+		t.Errorf("Lang == %q, want %q", q.Lang, "en-US")
+	}
+	if q.Locale == nil || *q.Locale != "pt-BR" {
+		t.Errorf("Locale == %v, want %q", q.Locale, "pt-BR")
+	}
+
+	s, err := Marshal(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "lang=en-US&locale=pt-BR"; s != want {
+		t.Errorf("Marshal() == %q, want %q", s, want)
+	}
+
+	if err := Unmarshal(&q, "lang=not+a+tag"); err == nil {
+		t.Error("unexpected success")
+	}
+	if _, err := Marshal(&query{Lang: "not a tag"}); err == nil {
+		t.Error("unexpected success")
+	}
+}
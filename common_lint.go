@@ -0,0 +1,212 @@
+package qs
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// LintIssue is a problem found in the qs tags of a struct by LintStruct.
+type LintIssue struct {
+	// Struct is the struct type that declares the field.
+	Struct reflect.Type
+
+	// Field is the path of the field from the struct passed to LintStruct
+	// (e.g. "Paging.Limit" for the Limit field of an embedded Paging
+	// struct).
+	Field string
+
+	// Message describes the problem.
+	Message string
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%v: field %s: %s", i.Struct, i.Field, i.Message)
+}
+
+// LintStruct checks the qs tags of struct type t (or of the struct pointed to
+// by t) and of the structs embedded, inlined or nested in it. Unlike
+// CheckMarshalType and CheckUnmarshalType it doesn't stop at the first
+// problem and it reports problems that don't fail the compilation of the
+// type:
+//   - unknown tag options and surplus commas
+//   - conflicting options (e.g. omitempty and keepempty or opt and req)
+//   - fields resolved to the same query string key
+//   - unexported fields with a qs tag (these are ignored)
+//
+// The names of the fields are resolved like the DefaultMarshaler resolves
+// them. LintStruct is meant to be called from the tests of a project:
+//
+//	func TestQueryTags(t *testing.T) {
+//		for _, issue := range qs.LintStruct(reflect.TypeFor[Query]()) {
+//			t.Error(issue)
+//		}
+//	}
+func LintStruct(t reflect.Type) []LintIssue {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return []LintIssue{{Struct: t, Message: fmt.Sprintf("expected a struct, got %v", t)}}
+	}
+
+	l := &structLinter{
+		naming:  DefaultMarshaler.opts.fieldNaming(),
+		visited: make(map[reflect.Type]bool),
+	}
+	l.lintStruct(t, "")
+	return l.issues
+}
+
+type structLinter struct {
+	naming  fieldNaming
+	visited map[reflect.Type]bool
+	issues  []LintIssue
+}
+
+func (l *structLinter) report(t reflect.Type, field, format string, args ...interface{}) {
+	l.issues = append(l.issues, LintIssue{
+		Struct:  t,
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// lintStruct checks the fields of struct type t. The keys of the fields of
+// embedded and inlined structs share the namespace of t.
+func (l *structLinter) lintStruct(t reflect.Type, path string) {
+	if l.visited[t] {
+		return
+	}
+	l.visited[t] = true
+	l.lintFields(t, path, make(map[string]string), func(name string) string { return name })
+}
+
+// lintFields checks the fields of struct type t. key returns the key of a
+// field from its name: the name in the namespaces of the embedded structs
+// that have the ns tag option.
+func (l *structLinter) lintFields(t reflect.Type, path string, names map[string]string, key func(string) string) {
+	for i, numField := 0, t.NumField(); i < numField; i++ {
+		sf := t.Field(i)
+		fieldPath := path + sf.Name
+
+		v, tagged := sf.Tag.Lookup(l.naming.tagKey)
+		if sf.Name == "_" && strings.HasPrefix(v, structDefaultsPrefix) {
+			continue
+		}
+		if isUnexportedField(sf) {
+			if tagged && v != "-" {
+				l.report(t, fieldPath, "unexported field has a %s tag", l.naming.tagKey)
+			}
+			continue
+		}
+
+		name, opts, msgs := lintTag(v, l.naming.tagKey)
+		for _, msg := range msgs {
+			l.report(t, fieldPath, "%s", msg)
+		}
+		if name == "-" {
+			continue
+		}
+		if !tagged {
+			name = fallbackTagName(sf.Tag, l.naming.fallbackKeys)
+		}
+
+		ft := sf.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if (sf.Anonymous || opts.Inline) && ft.Kind() == reflect.Struct {
+			fieldKey := key
+			if ns := opts.Namespace; ns != "" {
+				fieldKey = func(name string) string { return key(namespaceKey(ns, name)) }
+			}
+			l.lintFields(ft, fieldPath+".", names, fieldKey)
+			continue
+		}
+		if opts.Inline {
+			l.report(t, fieldPath, "the inline option requires a struct field, got %v", sf.Type)
+		}
+		if opts.Namespace != "" {
+			l.report(t, fieldPath, "the ns option requires an embedded or inlined struct field")
+		}
+
+		if name == "" {
+			name = l.naming.nt(sf.Name)
+		}
+		name = key(name)
+		if other, ok := names[name]; ok {
+			l.report(t, fieldPath, "duplicate key %q (used by field %s)", name, other)
+		} else {
+			names[name] = fieldPath
+		}
+
+		if opts.Codec != "" {
+			continue
+		}
+		if _, err := DefaultMarshaler.opts.MarshalerFactory.Marshaler(sf.Type, DefaultMarshaler.opts); err == nil {
+			continue
+		}
+		if nt, _, ok := nestedFieldType(sf.Type); ok {
+			if nt.Kind() == reflect.Ptr {
+				nt = nt.Elem()
+			}
+			if nt.Kind() == reflect.Struct {
+				l.lintStruct(nt, fieldPath+".")
+			}
+		}
+	}
+}
+
+// LintTag returns the problems of the options of the qs tag of a struct
+// field (unknown options, surplus commas and conflicting options). It
+// doesn't need the type of the field so it can be used by tools that check
+// source code without compiling it. See LintStruct.
+func LintTag(tag reflect.StructTag) []string {
+	key := DefaultMarshaler.opts.TagKey
+	_, _, msgs := lintTag(tag.Get(key), key)
+	return msgs
+}
+
+// lintTag parses the options of a qs tag (v is the value of the tag key)
+// like parseFieldTag but it reports every problem instead of stopping at the
+// first one. It returns the name part of the tag, the common options and the
+// problems.
+func lintTag(v, key string) (string, *CommonTagOptions, []string) {
+	nameAndOptions := strings.Split(v, ",")
+	marshalOpts := NewUndefinedMarshalTagOptions()
+	unmarshalOpts := NewUndefinedUnmarshalTagOptions()
+	commonOpts := NewUndefinedCommonTagOptions()
+	parsers := []func(string) (bool, error){
+		commonOpts.ParseOption,
+		unmarshalOpts.ParseOption,
+		marshalOpts.ParseOption,
+	}
+
+	var msgs []string
+	aliases := tagOptionAliases[key]
+	for _, option := range nameAndOptions[1:] {
+		if option == "" {
+			msgs = append(msgs, "tag string contains a surplus comma")
+			continue
+		}
+		if alias, ok := aliases[option]; ok {
+			option = alias
+		}
+
+		found := false
+		for _, parse := range parsers {
+			ok, err := parse(option)
+			if err != nil {
+				msgs = append(msgs, err.Error())
+				found = true
+				break
+			}
+			found = found || ok
+		}
+		if !found {
+			msgs = append(msgs, fmt.Sprintf("unknown option %q", option))
+		}
+	}
+	return nameAndOptions[0], commonOpts, msgs
+}
@@ -0,0 +1,133 @@
+package qs
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// RegisterMapKeyType registers the funcs that convert the keys of maps with
+// key type t into query string keys and back with the DefaultMarshaler and
+// the DefaultUnmarshaler. The registered funcs take precedence over the
+// builtin conversions:
+//   - the keys of string kind are used as they are
+//   - the keys of other types are converted by the Marshaler and Unmarshaler
+//     of their type (e.g. map[int]string or map[time.Time]string)
+//
+// Registering string makes it possible to normalize the keys of all maps
+// with string keys (e.g. to lowercase them).
+func RegisterMapKeyType(t reflect.Type, mfn PrimitiveMarshalerFunc, umfn PrimitiveUnmarshalerFunc) error {
+	if err := DefaultMarshaler.RegisterMapKeyType(t, mfn); err != nil {
+		return err
+	}
+	return DefaultUnmarshaler.RegisterMapKeyType(t, umfn)
+}
+
+// RegisterMapKeyType registers the func that converts the keys of maps with
+// key type t into query string keys. See the global RegisterMapKeyType func.
+func (p *QSMarshaler) RegisterMapKeyType(t reflect.Type, fn PrimitiveMarshalerFunc) error {
+	if t == nil {
+		return errors.New("nil map key type")
+	}
+	if fn == nil {
+		return fmt.Errorf("nil marshal func for map key type %v", t)
+	}
+	p.opts.mapKeys.register(t, fn)
+	p.purgeValuesCache()
+	return nil
+}
+
+// RegisterMapKeyType registers the func that converts query string keys into
+// the keys of maps with key type t. See the global RegisterMapKeyType func.
+func (p *QSUnmarshaler) RegisterMapKeyType(t reflect.Type, fn PrimitiveUnmarshalerFunc) error {
+	if t == nil {
+		return errors.New("nil map key type")
+	}
+	if fn == nil {
+		return fmt.Errorf("nil unmarshal func for map key type %v", t)
+	}
+	p.opts.mapKeys.register(t, fn)
+	p.purgeValuesCache()
+	return nil
+}
+
+// typeRegistry holds the map key funcs of a marshaler or an unmarshaler. Like
+// namedRegistry it is copy-on-write so the lookups don't need locking.
+type typeRegistry[F any] struct {
+	items atomic.Pointer[map[reflect.Type]F]
+	mu    sync.Mutex
+}
+
+func (r *typeRegistry[F]) register(t reflect.Type, item F) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var items map[reflect.Type]F
+	if p := r.items.Load(); p != nil {
+		items = maps.Clone(*p)
+	} else {
+		items = make(map[reflect.Type]F)
+	}
+	items[t] = item
+	r.items.Store(&items)
+}
+
+func (r *typeRegistry[F]) lookup(t reflect.Type) (F, bool) {
+	if r != nil {
+		if p := r.items.Load(); p != nil {
+			if item, ok := (*p)[t]; ok {
+				return item, true
+			}
+		}
+	}
+	var zero F
+	return zero, false
+}
+
+// mapKeyMarshaler returns the func that converts the keys of type t into
+// query string keys. It returns nil for the keys of string kind without a
+// registered func because these are used as they are.
+func (o *MarshalOptions) mapKeyMarshaler(t reflect.Type) (PrimitiveMarshalerFunc, error) {
+	if fn, ok := o.mapKeys.lookup(t); ok {
+		return fn, nil
+	}
+	if t.Kind() == reflect.String {
+		return nil, nil
+	}
+	m, err := o.MarshalerFactory.Marshaler(t, o)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported map key type %v :: %w", t, err)
+	}
+	return func(v reflect.Value, opts *MarshalOptions) (string, error) {
+		a, err := m.Marshal(v, opts)
+		if err != nil {
+			return "", err
+		}
+		if len(a) != 1 {
+			return "", fmt.Errorf("map key %v marshaled into %d values", v, len(a))
+		}
+		return a[0], nil
+	}, nil
+}
+
+// mapKeyUnmarshaler returns the func that converts query string keys into
+// keys of type t. It returns nil for the keys of string kind without a
+// registered func because these are converted directly.
+func (o *UnmarshalerDefaultOptions) mapKeyUnmarshaler(t reflect.Type) (PrimitiveUnmarshalerFunc, error) {
+	if fn, ok := o.mapKeys.lookup(t); ok {
+		return fn, nil
+	}
+	if t.Kind() == reflect.String {
+		return nil, nil
+	}
+	um, err := o.UnmarshalerFactory.Unmarshaler(t, NewUnmarshalOptions(o, nil))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported map key type %v :: %w", t, err)
+	}
+	return func(v reflect.Value, s string, opts *UnmarshalOptions) error {
+		return um.Unmarshal(v, []string{s}, opts)
+	}, nil
+}
@@ -0,0 +1,97 @@
+package qs
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// matrixEncoding escapes the keys and values of matrix parameters. The
+// characters that are allowed in path segments stay unescaped except the ";"
+// and "=" delimiters of the parameters, "+" that some servers decode as a
+// space and "&" that separates the pairs encoded by QueryEncoding.Encode.
+var matrixEncoding = QueryEncoding{
+	Unescaped: "-._~!$'()*,:@",
+}
+
+// ParseMatrixParams splits a path segment with matrix parameters (e.g.
+// "cars;color=red;year=2020") into the unescaped name of the segment ("cars")
+// and the parameters. The values of repeated keys are collected in their
+// order and a key without "=" has an empty value. A comma separated list
+// (";tags=a,b") is a single value that can be split by the comma separator
+// tag option of the field.
+func ParseMatrixParams(segment string) (string, url.Values, error) {
+	name, params, _ := strings.Cut(segment, ";")
+	name, err := url.PathUnescape(name)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid path segment %q :: %w", segment, err)
+	}
+
+	values := make(url.Values)
+	for params != "" {
+		var param string
+		param, params, _ = strings.Cut(params, ";")
+		if param == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(param, "=")
+		if k, err = url.PathUnescape(k); err != nil {
+			return "", nil, fmt.Errorf("invalid matrix parameter %q :: %w", param, err)
+		}
+		if k == "" {
+			return "", nil, fmt.Errorf("invalid matrix parameter %q :: empty key", param)
+		}
+		if v, err = url.PathUnescape(v); err != nil {
+			return "", nil, fmt.Errorf("invalid matrix parameter %q :: %w", param, err)
+		}
+		values[k] = append(values[k], v)
+	}
+	return name, values, nil
+}
+
+// EncodeMatrixParams encodes the values into matrix parameters sorted by key
+// (e.g. ";color=red;year=2020") that can be appended to a path segment. It
+// returns an empty string if there are no values.
+func EncodeMatrixParams(values url.Values) string {
+	s := matrixEncoding.Encode(values)
+	if s == "" {
+		return ""
+	}
+	return ";" + strings.ReplaceAll(s, "&", ";")
+}
+
+// MarshalMatrix marshals an object into matrix parameters with the
+// DefaultMarshaler. See QSMarshaler.MarshalMatrix.
+func MarshalMatrix(i interface{}) (string, error) {
+	return DefaultMarshaler.MarshalMatrix(i)
+}
+
+// MarshalMatrix marshals an object into the matrix parameters of a path
+// segment (e.g. ";color=red;year=2020") for the services that read them
+// instead of the query string like JAX-RS with @MatrixParam. The parameters
+// are built like a query string by the fields of the object and encoded with
+// EncodeMatrixParams.
+func (p *QSMarshaler) MarshalMatrix(i interface{}) (string, error) {
+	values, err := p.MarshalValues(i)
+	if err != nil {
+		return "", err
+	}
+	return EncodeMatrixParams(values), nil
+}
+
+// UnmarshalMatrix unmarshals the matrix parameters of a path segment with the
+// DefaultUnmarshaler. See QSUnmarshaler.UnmarshalMatrix.
+func UnmarshalMatrix(into interface{}, segment string) error {
+	return DefaultUnmarshaler.UnmarshalMatrix(into, segment)
+}
+
+// UnmarshalMatrix unmarshals the matrix parameters of a path segment (e.g.
+// "cars;color=red;year=2020") like a query string. The name of the segment
+// (the part before the first ";") is ignored. See ParseMatrixParams.
+func (p *QSUnmarshaler) UnmarshalMatrix(into interface{}, segment string) error {
+	_, values, err := ParseMatrixParams(segment)
+	if err != nil {
+		return err
+	}
+	return p.UnmarshalValues(into, values)
+}
@@ -0,0 +1,67 @@
+package qs
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+type matrixQuery struct {
+	Color string   `qs:"color"`
+	Year  int      `qs:"year,omitempty"`
+	Tags  []string `qs:"tags,comma"`
+	Note  string   `qs:"note,omitempty"`
+}
+
+func TestParseMatrixParams(t *testing.T) {
+	name, values, err := ParseMatrixParams("my%20cars;color=red;year=2020;;tags=a,b;flag;color=a%3Bb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "my cars" {
+		t.Errorf("got name %q", name)
+	}
+	want := url.Values{
+		"color": {"red", "a;b"},
+		"year":  {"2020"},
+		"tags":  {"a,b"},
+		"flag":  {""},
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("got %v, want %v", values, want)
+	}
+
+	for _, s := range []string{"cars;=1", "cars;a=%zz", "%zz;a=1"} {
+		if _, _, err := ParseMatrixParams(s); err == nil {
+			t.Errorf("%q :: unexpected success", s)
+		}
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	q := matrixQuery{Color: "dark red", Tags: []string{"a", "b+c"}, Note: "x;y=z&"}
+	s, err := MarshalMatrix(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := ";color=dark%20red;note=x%3By%3Dz%26;tags=a,b%2Bc"; s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+
+	var q2 matrixQuery
+	if err := UnmarshalMatrix(&q2, "cars"+s); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(q2, q) {
+		t.Errorf("got %+v, want %+v", q2, q)
+	}
+
+	var q3 matrixQuery
+	if err := UnmarshalMatrix(&q3, "cars;year=x"); err == nil {
+		t.Error("unexpected success")
+	}
+
+	if s, err := MarshalMatrix(&struct{}{}); err != nil || s != "" {
+		t.Errorf("got %q, %v", s, err)
+	}
+}
@@ -0,0 +1,145 @@
+package qs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// namespaceKey returns the key of a field of a struct embedded with the
+// ns=<namespace> tag option. See CommonTagOptions.Namespace.
+func namespaceKey(ns, key string) string {
+	if name, ok := strings.CutSuffix(ns, "[]"); ok {
+		return name + "[" + key + "]"
+	}
+	return ns + "_" + key
+}
+
+// checkNamespace returns an error if the field with the given tag has the ns
+// tag option but it isn't an embedded or inlined field.
+func checkNamespace(sf reflect.StructField, tag *ParsedTagInfo) error {
+	if tag.CommonOpts.Namespace == "" || sf.Anonymous || tag.CommonOpts.Inline {
+		return nil
+	}
+	return errors.New("the ns tag option requires an embedded or inlined field")
+}
+
+// namespacedMarshaler returns a copy of the ValuesMarshaler of an embedded
+// struct whose keys are in the namespace ns. The marshalers of the factories
+// are cached and shared so they can't be modified.
+func namespacedMarshaler(vm ValuesMarshaler, ns string) (ValuesMarshaler, error) {
+	switch vm := vm.(type) {
+	case *structMarshaler:
+		c := *vm
+		c.Fields = make([]*fieldMarshaler, len(vm.Fields))
+		for i, fm := range vm.Fields {
+			c.Fields[i] = fm.namespaced(ns)
+		}
+		c.EmbeddedFields = make([]embeddedFieldMarshaler, len(vm.EmbeddedFields))
+		for i, ef := range vm.EmbeddedFields {
+			evm, err := namespacedMarshaler(ef.ValuesMarshaler, ns)
+			if err != nil {
+				return nil, err
+			}
+			c.EmbeddedFields[i] = embeddedFieldMarshaler{Index: ef.Index, ValuesMarshaler: evm, Tag: ef.Tag}
+		}
+		c.escapes = c.fieldEscapes()
+		return &c, nil
+	case *ptrValuesMarshaler:
+		em, err := namespacedMarshaler(vm.ElemMarshaler, ns)
+		if err != nil {
+			return nil, err
+		}
+		return &ptrValuesMarshaler{Type: vm.Type, ElemMarshaler: em}, nil
+	}
+	return nil, fmt.Errorf("the ns tag option isn't supported by %T", vm)
+}
+
+// namespaced returns a copy of fm with a key in the namespace ns.
+func (fm *fieldMarshaler) namespaced(ns string) *fieldMarshaler {
+	p := *fm
+	tag := *fm.Tag
+	tag.Name = namespaceKey(ns, tag.Name)
+	p.Tag = &tag
+	if fm.build != nil {
+		p.build = sync.OnceValue(func() error {
+			if err := fm.build(); err != nil {
+				return err
+			}
+			p.Marshaler, p.Nested, p.Indexed = fm.Marshaler, fm.Nested, fm.Indexed
+			return nil
+		})
+	}
+	return &p
+}
+
+// namespacedUnmarshaler returns a copy of the ValuesUnmarshaler of an
+// embedded struct whose keys are in the namespace ns. See
+// namespacedMarshaler.
+func namespacedUnmarshaler(vum ValuesUnmarshaler, ns string) (ValuesUnmarshaler, error) {
+	switch vum := vum.(type) {
+	case *structUnmarshaler:
+		c := *vum
+		c.Fields = make([]*fieldUnmarshaler, len(vum.Fields))
+		for i, fum := range vum.Fields {
+			c.Fields[i] = fum.namespaced(ns)
+		}
+		c.EmbeddedFields = make([]embeddedFieldUnmarshaler, len(vum.EmbeddedFields))
+		for i, ef := range vum.EmbeddedFields {
+			evum, err := namespacedUnmarshaler(ef.ValuesUnmarshaler, ns)
+			if err != nil {
+				return nil, err
+			}
+			c.EmbeddedFields[i] = embeddedFieldUnmarshaler{Index: ef.Index, ValuesUnmarshaler: evum}
+		}
+		c.ranges = make([]rangeField, len(vum.ranges))
+		for i, rf := range vum.ranges {
+			c.ranges[i] = rangeField{Index: rf.Index, Key: namespaceKey(ns, rf.Key)}
+		}
+		// The dependencies refer to the copied fields.
+		c.dependencies = nil
+		if err := c.resolveDependencies(); err != nil {
+			return nil, err
+		}
+		return &c, nil
+	case *ptrValuesUnmarshaler:
+		eu, err := namespacedUnmarshaler(vum.ElemUnmarshaler, ns)
+		if err != nil {
+			return nil, err
+		}
+		return &ptrValuesUnmarshaler{Type: vum.Type, ElemType: vum.ElemType, ElemUnmarshaler: eu}, nil
+	}
+	return nil, fmt.Errorf("the ns tag option isn't supported by %T", vum)
+}
+
+// namespaced returns a copy of fum with a key in the namespace ns. The keys
+// of the requires and requiredwith tag options are moved to the namespace
+// too.
+func (fum *fieldUnmarshaler) namespaced(ns string) *fieldUnmarshaler {
+	p := *fum
+	tag := *fum.Tag
+	tag.Name = namespaceKey(ns, tag.Name)
+	if tag.UnmarshalOpts.Requires != "" || tag.UnmarshalOpts.RequiredWith != "" {
+		opts := *tag.UnmarshalOpts
+		if opts.Requires != "" {
+			opts.Requires = namespaceKey(ns, opts.Requires)
+		}
+		if opts.RequiredWith != "" {
+			opts.RequiredWith = namespaceKey(ns, opts.RequiredWith)
+		}
+		tag.UnmarshalOpts = &opts
+	}
+	p.Tag = &tag
+	if fum.build != nil {
+		p.build = sync.OnceValue(func() error {
+			if err := fum.build(); err != nil {
+				return err
+			}
+			p.Unmarshaler, p.Nested, p.Indexed = fum.Unmarshaler, fum.Nested, fum.Indexed
+			return nil
+		})
+	}
+	return &p
+}
@@ -0,0 +1,85 @@
+package qs
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNamespace(t *testing.T) {
+	type tracking struct {
+		Source string `qs:"source,omitempty"`
+		Medium string `qs:"medium,omitempty,requires=source"`
+	}
+	type Partner struct {
+		ID string `qs:"id,omitempty"`
+	}
+	type query struct {
+		tracking `qs:",ns=utm"`
+		*Partner `qs:",ns=ref[]"`
+		Vendor   tracking `qs:",inline,ns=x_vendor"`
+		Source   string   `qs:"source,omitempty"`
+	}
+
+	q := query{
+		tracking: tracking{Source: "news", Medium: "email"},
+		Partner:  &Partner{ID: "p1"},
+		Vendor:   tracking{Source: "ads"},
+		Source:   "web",
+	}
+	vs, err := MarshalValues(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{
+		"utm_source":      {"news"},
+		"utm_medium":      {"email"},
+		"ref[id]":         {"p1"},
+		"x_vendor_source": {"ads"},
+		"source":          {"web"},
+	}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	var got query
+	if err := UnmarshalValues(&got, want); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, q) {
+		t.Errorf("got %+v, want %+v", got, q)
+	}
+
+	// The requires option refers to the namespaced key.
+	err = UnmarshalValues(&got, url.Values{"utm_medium": {"email"}})
+	if _, ok := IsRequiredFieldError(err); !ok || !strings.Contains(err.Error(), `"utm_source"`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	type named struct {
+		Tracking tracking `qs:"tracking,ns=utm"`
+	}
+	if _, err := MarshalValues(&named{}); err == nil || !strings.Contains(err.Error(), "ns tag option") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := UnmarshalValues(&named{}, url.Values{}); err == nil || !strings.Contains(err.Error(), "ns tag option") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	type invalid struct {
+		tracking `qs:",ns=utm[x]"`
+	}
+	if _, err := MarshalValues(&invalid{}); err == nil || !strings.Contains(err.Error(), "invalid namespace") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	type conflict struct {
+		tracking  `qs:",ns=utm"`
+		UTMSource string `qs:"utm_source"`
+	}
+	issues := LintStruct(reflect.TypeFor[conflict]())
+	if len(issues) != 1 || issues[0].Message != `duplicate key "utm_source" (used by field tracking.Source)` {
+		t.Errorf("unexpected issues: %v", issues)
+	}
+}
@@ -0,0 +1,280 @@
+package qs
+
+import (
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxNestedIndex is the largest slice index accepted in the keys of nested
+// fields. It protects the unmarshaler from allocating huge slices because of
+// keys like "items.999999999.name". This is the default limit of
+// go-playground/form.
+const maxNestedIndex = 10000
+
+// nestedFieldType reports whether a field of type t is marshaled and
+// unmarshaled with nested keys when nesting is enabled. It returns the type of
+// the nested values: t itself for structs, pointers to structs and maps with
+// string keys, the item type for slices and arrays of (pointers to) structs.
+// indexed is true in the latter case.
+func nestedFieldType(t reflect.Type) (nt reflect.Type, indexed bool, ok bool) {
+	if t.Kind() == reflect.Map {
+		return t, false, t.Key() == stringType
+	}
+	if k := t.Kind(); k == reflect.Slice || k == reflect.Array {
+		t = t.Elem()
+		indexed = true
+	}
+	et := t
+	if et.Kind() == reflect.Ptr {
+		et = et.Elem()
+	}
+	if et.Kind() != reflect.Struct {
+		return nil, false, false
+	}
+	return t, indexed, true
+}
+
+// enabled reports whether the fields of nested structs get nested keys.
+func (m NestingMode) enabled() bool {
+	switch m {
+	case NestingModeDots, NestingModeDotsIndexBrackets, NestingModeBrackets, NestingModeBracketsAllowDots:
+		return true
+	default:
+		return false
+	}
+}
+
+// fieldKey returns the key of the field with the given key of a nested
+// struct stored under prefix.
+func (m NestingMode) fieldKey(prefix, key string) string {
+	if m == NestingModeBrackets {
+		// The first segment of the key goes into brackets: "b[c]" under
+		// "a" is "a[b][c]".
+		if i := strings.IndexByte(key, '['); i > 0 {
+			return prefix + "[" + key[:i] + "]" + key[i:]
+		}
+		return prefix + "[" + key + "]"
+	}
+	return prefix + "." + key
+}
+
+// cutField returns the key relative to a nested struct from the rest of a
+// key after the prefix of the struct (e.g. ".b.c" or "[b][c]"). It is the
+// inverse of fieldKey.
+func (m NestingMode) cutField(rest string) (string, bool) {
+	if m == NestingModeBrackets || (m == NestingModeBracketsAllowDots && rest != "" && rest[0] == '[') {
+		if len(rest) < 3 || rest[0] != '[' {
+			return "", false
+		}
+		field, tail, ok := strings.Cut(rest[1:], "]")
+		if !ok || field == "" {
+			return "", false
+		}
+		return field + tail, true
+	}
+	if len(rest) < 2 || rest[0] != '.' {
+		return "", false
+	}
+	return rest[1:], true
+}
+
+// indexKey returns the key of the i-th item of a slice stored under prefix.
+func (m NestingMode) indexKey(prefix string, i int) string {
+	if m != NestingModeDots {
+		return prefix + "[" + strconv.Itoa(i) + "]"
+	}
+	return prefix + "." + strconv.Itoa(i)
+}
+
+// keyDepth returns the number of nesting levels of key: the number of field
+// and index separators in it.
+func (m NestingMode) keyDepth(key string) int {
+	switch m {
+	case NestingModeBrackets:
+		return strings.Count(key, "[")
+	case NestingModeDotsIndexBrackets, NestingModeBracketsAllowDots:
+		return strings.Count(key, ".") + strings.Count(key, "[")
+	default:
+		return strings.Count(key, ".")
+	}
+}
+
+// splitKey splits a nested key into its field and index segments (e.g.
+// "a.b[0]" into "a", "b" and "0" with NestingModeDotsIndexBrackets). An empty
+// bracketed segment (e.g. "a[]") is kept as an empty segment. It reports
+// false if key isn't a well-formed nested key of the mode (e.g. "a[b" or
+// "a..b") and for the modes without nesting. A key without separators is a
+// single segment.
+func (m NestingMode) splitKey(key string) ([]string, bool) {
+	if !m.enabled() {
+		return nil, false
+	}
+	dots := m != NestingModeBrackets
+	brackets := m != NestingModeDots
+
+	var segments []string
+	i := strings.IndexFunc(key, func(r rune) bool {
+		return (dots && r == '.') || (brackets && r == '[')
+	})
+	if i < 0 {
+		return []string{key}, true
+	}
+	if i == 0 {
+		return nil, false
+	}
+	segments = append(segments, key[:i])
+	for rest := key[i:]; rest != ""; {
+		switch {
+		case rest[0] == '[':
+			segment, tail, ok := strings.Cut(rest[1:], "]")
+			if !ok {
+				return nil, false
+			}
+			segments = append(segments, segment)
+			rest = tail
+		case rest[0] == '.':
+			rest = rest[1:]
+			j := strings.IndexFunc(rest, func(r rune) bool {
+				return r == '.' || (brackets && r == '[')
+			})
+			if j < 0 {
+				j = len(rest)
+			}
+			if j == 0 {
+				return nil, false
+			}
+			segments = append(segments, rest[:j])
+			rest = rest[j:]
+		default:
+			return nil, false
+		}
+	}
+	return segments, true
+}
+
+// cutIndex parses a key built by indexKey and optionally followed by the key
+// of a nested field. It returns the index and the key of the nested field
+// that is empty if key is the key of the item itself. The index is -1 if it
+// is empty (e.g. "a[]" or "a[][b]") which is accepted only by
+// NestingModeBrackets and NestingModeBracketsAllowDots.
+func (m NestingMode) cutIndex(key, prefix string) (i int, rest string, ok bool) {
+	rest, ok = strings.CutPrefix(key, prefix)
+	if !ok || rest == "" {
+		return 0, "", false
+	}
+
+	var idx string
+	switch m {
+	case NestingModeDotsIndexBrackets, NestingModeBrackets, NestingModeBracketsAllowDots:
+		if rest[0] != '[' {
+			return 0, "", false
+		}
+		idx, rest, ok = strings.Cut(rest[1:], "]")
+		if !ok {
+			return 0, "", false
+		}
+		if rest != "" {
+			if rest, ok = m.cutField(rest); !ok {
+				return 0, "", false
+			}
+		}
+		if idx == "" && m != NestingModeDotsIndexBrackets {
+			return -1, rest, true
+		}
+	default:
+		if rest[0] != '.' {
+			return 0, "", false
+		}
+		idx, rest, _ = strings.Cut(rest[1:], ".")
+	}
+
+	i, err := strconv.Atoi(idx)
+	if err != nil || i < 0 || i > maxNestedIndex || idx[0] == '+' {
+		return 0, "", false
+	}
+	return i, rest, true
+}
+
+// nestedValues returns the values of the fields of the nested struct stored
+// under prefix with keys relative to the nested struct.
+func (m NestingMode) nestedValues(vs url.Values, prefix string) url.Values {
+	var nvs url.Values
+	for k, a := range vs {
+		rest, ok := strings.CutPrefix(k, prefix)
+		if !ok {
+			continue
+		}
+		if field, ok := m.cutField(rest); ok {
+			if nvs == nil {
+				nvs = make(url.Values)
+			}
+			nvs[field] = a
+		}
+	}
+	return nvs
+}
+
+// indexedValues returns the values of the fields of the nested structs stored
+// in the items of the slice under prefix grouped by index.
+func (m NestingMode) indexedValues(vs url.Values, prefix string) map[int]url.Values {
+	var items map[int]url.Values
+	for k, a := range vs {
+		i, rest, ok := m.cutIndex(k, prefix)
+		if !ok || rest == "" {
+			continue
+		}
+		if items == nil {
+			items = make(map[int]url.Values)
+		}
+		if i < 0 {
+			// The j-th value of "a[][b]" belongs to the j-th item.
+			for j, s := range a {
+				if j > maxNestedIndex {
+					break
+				}
+				if items[j] == nil {
+					items[j] = make(url.Values)
+				}
+				items[j][rest] = append(items[j][rest], s)
+			}
+			continue
+		}
+		if items[i] == nil {
+			items[i] = make(url.Values)
+		}
+		items[i][rest] = a
+	}
+	return items
+}
+
+// indexedItems returns the values of the items of the slice under prefix
+// stored with indexed keys (e.g. "tags[0]=a&tags[1]=b") in the order of their
+// indices. It reports false if there is no such key.
+func (m NestingMode) indexedItems(vs url.Values, prefix string) ([]string, bool) {
+	var indices []int
+	var items map[int][]string
+	for k, a := range vs {
+		i, rest, ok := m.cutIndex(k, prefix)
+		if !ok || rest != "" {
+			continue
+		}
+		if items == nil {
+			items = make(map[int][]string)
+		}
+		indices = append(indices, i)
+		items[i] = a
+	}
+	if items == nil {
+		return nil, false
+	}
+
+	sort.Ints(indices)
+	var a []string
+	for _, i := range indices {
+		a = append(a, items[i]...)
+	}
+	return a, true
+}
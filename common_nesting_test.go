@@ -0,0 +1,463 @@
+package qs
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type nestingPhone struct {
+	Label  string `schema:"label" form:"label"`
+	Number string `schema:"number,required" form:"number"`
+}
+
+type nestingAddress struct {
+	City string `schema:"city" form:"city"`
+}
+
+type nestingQuery struct {
+	Name     string          `schema:"name" form:"name"`
+	Tags     []string        `schema:"tags" form:"tags"`
+	Address  nestingAddress  `schema:"address" form:"address"`
+	Billing  *nestingAddress `schema:"billing" form:"billing"`
+	Phones   []nestingPhone  `schema:"phones" form:"phones"`
+	Skipped  string          `schema:"-" form:"-"`
+	Untagged int
+}
+
+func TestSchemaCompat(t *testing.T) {
+	m := NewMarshaler(nil, WithMarshalSchemaCompat())
+	um := NewUnmarshaler(nil, WithUnmarshalSchemaCompat())
+
+	q := nestingQuery{
+		Name:     "n",
+		Tags:     []string{"a", "b"},
+		Address:  nestingAddress{City: "x"},
+		Phones:   []nestingPhone{{Label: "home", Number: "1"}, {Number: "2"}},
+		Skipped:  "s",
+		Untagged: 3,
+	}
+	vs, err := m.MarshalValues(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{
+		"name":            {"n"},
+		"tags":            {"a", "b"},
+		"address.city":    {"x"},
+		"phones.0.label":  {"home"},
+		"phones.0.number": {"1"},
+		"phones.1.label":  {""},
+		"phones.1.number": {"2"},
+		"Untagged":        {"3"},
+	}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	var q2 nestingQuery
+	if err := um.UnmarshalValues(&q2, vs); err != nil {
+		t.Fatal(err)
+	}
+	q.Skipped = ""
+	q.Billing = &nestingAddress{}
+	if !reflect.DeepEqual(q2, q) {
+		t.Errorf("got %+v, want %+v", q2, q)
+	}
+
+	var q3 nestingQuery
+	if err := um.Unmarshal(&q3, "phones.1.label=x"); err == nil {
+		t.Error("unexpected success")
+	}
+	var q4 nestingQuery
+	if err := um.Unmarshal(&q4, "phones.100000.number=1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(q4.Phones) != 0 {
+		t.Errorf("unexpected phones: %v", q4.Phones)
+	}
+}
+
+func TestFormCompat(t *testing.T) {
+	m := NewMarshaler(nil, WithMarshalFormCompat())
+	um := NewUnmarshaler(nil, WithUnmarshalFormCompat())
+
+	vs, err := m.MarshalValues(&nestingQuery{
+		Phones:  []nestingPhone{{Number: "1"}},
+		Billing: &nestingAddress{City: "y"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"phones[0].number", "billing.city", "address.city"} {
+		if _, ok := vs[key]; !ok {
+			t.Errorf("missing key %q in %v", key, vs)
+		}
+	}
+
+	var q nestingQuery
+	err = um.Unmarshal(&q, "tags[1]=b&tags[0]=a&phones[1].number=2&phones[0].number=1&address.city=x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := nestingQuery{
+		Tags:    []string{"a", "b"},
+		Address: nestingAddress{City: "x"},
+		Billing: &nestingAddress{},
+		Phones:  []nestingPhone{{Number: "1"}, {Number: "2"}},
+	}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("got %+v, want %+v", q, want)
+	}
+}
+
+type rackPhone struct {
+	Label  string
+	Number string
+}
+
+type rackUser struct {
+	Name    string
+	Roles   []string
+	Address nestingAddress `qs:"address"`
+	Phones  []rackPhone
+	Meta    map[string]string
+}
+
+type rackQuery struct {
+	User rackUser
+	Tags []string
+	Page int
+}
+
+func TestRackCompat(t *testing.T) {
+	m := NewMarshaler(nil, WithMarshalRackCompat(), WithMarshalTagKey("form"))
+	um := NewUnmarshaler(nil, WithUnmarshalRackCompat(), WithUnmarshalTagKey("form"))
+
+	q := rackQuery{
+		User: rackUser{
+			Name:    "n",
+			Roles:   []string{"admin", "dev"},
+			Address: nestingAddress{City: "x"},
+			Phones:  []rackPhone{{Label: "home", Number: "1"}, {Number: "2"}},
+			Meta:    map[string]string{"k": "v"},
+		},
+		Tags: []string{"a", "b"},
+		Page: 2,
+	}
+	vs, err := m.MarshalValues(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{
+		"user[name]":              {"n"},
+		"user[roles][]":           {"admin", "dev"},
+		"user[address][city]":     {"x"},
+		"user[phones][0][label]":  {"home"},
+		"user[phones][0][number]": {"1"},
+		"user[phones][1][label]":  {""},
+		"user[phones][1][number]": {"2"},
+		"user[meta][k]":           {"v"},
+		"tags[]":                  {"a", "b"},
+		"page":                    {"2"},
+	}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	var q2 rackQuery
+	if err := um.UnmarshalValues(&q2, vs); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(q2, q) {
+		t.Errorf("got %+v, want %+v", q2, q)
+	}
+
+	// The syntax of Rails forms with empty indices.
+	var q3 rackQuery
+	err = um.Unmarshal(&q3, "user[phones][][label]=home&user[phones][][number]=1&user[phones][][label]=work&user[phones][][number]=2&user[roles][]=admin&tags=a&tags[]=b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantUser := rackUser{
+		Roles:  []string{"admin"},
+		Phones: []rackPhone{{Label: "home", Number: "1"}, {Label: "work", Number: "2"}},
+		Meta:   map[string]string{},
+	}
+	if !reflect.DeepEqual(q3.User, wantUser) || !reflect.DeepEqual(q3.Tags, []string{"a", "b"}) {
+		t.Errorf("got %+v", q3)
+	}
+
+	um = NewUnmarshaler(nil, WithUnmarshalRackCompat(), WithUnmarshalMaxNestingDepth(2))
+	if err := um.Unmarshal(&q3, "user[phones][0][label]=x"); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestIndexedSliceOfStructs(t *testing.T) {
+	type tier struct {
+		UpTo   int `qs:"up_to"`
+		Amount int `qs:"amount,omitempty"`
+	}
+	type item struct {
+		Price string `qs:"price,omitempty"`
+		Qty   int    `qs:"qty,omitempty"`
+		Tiers []tier `qs:"tiers"`
+	}
+	type order struct {
+		Items []item  `qs:"items"`
+		Refs  []*item `qs:"refs"`
+	}
+
+	enc := QueryEncoding{SpaceAsPlus: true, Unescaped: "-._~[]"}
+	m := NewMarshaler(nil, WithMarshalNesting(NestingModeBrackets), WithMarshalQueryEncoding(enc))
+	um := NewUnmarshaler(nil, WithUnmarshalNesting(NestingModeBrackets))
+
+	q := order{
+		Items: []item{
+			{Price: "10", Qty: 2, Tiers: []tier{}},
+			{Price: "5", Tiers: []tier{{UpTo: 1}, {UpTo: 2, Amount: 3}}},
+		},
+		Refs: []*item{{Price: "7", Tiers: []tier{}}},
+	}
+	s, err := m.Marshal(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "items[0][price]=10&items[0][qty]=2&items[1][price]=5&items[1][tiers][0][up_to]=1" +
+		"&items[1][tiers][1][amount]=3&items[1][tiers][1][up_to]=2&refs[0][price]=7"
+	if s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+
+	var q2 order
+	if err := um.Unmarshal(&q2, s); err != nil {
+		t.Fatal(err)
+	}
+	q.Items[0].Tiers = nil
+	q.Refs[0].Tiers = nil
+	if !reflect.DeepEqual(q2, q) {
+		t.Errorf("got %+v, want %+v", q2, q)
+	}
+
+	// The missing indices become zero items.
+	var q3 order
+	if err := um.Unmarshal(&q3, "items[1][qty]=4"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(q3.Items, []item{{}, {Qty: 4}}) {
+		t.Errorf("got %+v", q3.Items)
+	}
+}
+
+func TestNpmQSCompat(t *testing.T) {
+	q := rackQuery{
+		User: rackUser{
+			Name:   "n",
+			Roles:  []string{"admin"},
+			Phones: []rackPhone{{Label: "home", Number: "1"}},
+		},
+		Page: 1,
+	}
+
+	m := NewMarshaler(nil, WithMarshalNpmQSCompat(true), WithMarshalTagKey("form"))
+	vs, err := m.MarshalValues(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{
+		"user.name":             {"n"},
+		"user.roles[]":          {"admin"},
+		"user.address.city":     {""},
+		"user.phones[0].label":  {"home"},
+		"user.phones[0].number": {"1"},
+		"page":                  {"1"},
+	}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	um := NewUnmarshaler(nil, WithUnmarshalNpmQSCompat(true), WithUnmarshalTagKey("form"))
+	var q2 rackQuery
+	err = um.Unmarshal(&q2, "user.name=n&user[roles][]=admin&user.phones[0][label]=home&user[phones][0].number=1&page=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.User.Meta = map[string]string{}
+	if !reflect.DeepEqual(q2.User, q.User) || q2.Page != 1 {
+		t.Errorf("got %+v, want %+v", q2, q)
+	}
+
+	um = NewUnmarshaler(nil, WithUnmarshalNpmQSCompat(false), WithUnmarshalTagKey("form"))
+	if err := um.Unmarshal(&q2, "user[phones][0][label][a][b][c]=x"); !errors.As(err, new(*LimitExceededError)) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := um.Unmarshal(&q2, strings.Repeat("page=1&", 1001)); !errors.As(err, new(*LimitExceededError)) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNestingModeCutIndex(t *testing.T) {
+	tests := []struct {
+		mode NestingMode
+		key  string
+		i    int
+		rest string
+		ok   bool
+	}{
+		{NestingModeDots, "a.1.b", 1, "b", true},
+		{NestingModeDots, "a.1", 1, "", true},
+		{NestingModeDots, "a.x.b", 0, "", false},
+		{NestingModeDots, "a.-1.b", 0, "", false},
+		{NestingModeDots, "ab.1", 0, "", false},
+		{NestingModeDotsIndexBrackets, "a[2].b.c", 2, "b.c", true},
+		{NestingModeDotsIndexBrackets, "a[2]", 2, "", true},
+		{NestingModeDotsIndexBrackets, "a[+2]", 0, "", false},
+		{NestingModeDotsIndexBrackets, "a[2]b", 0, "", false},
+		{NestingModeDotsIndexBrackets, "a.2", 0, "", false},
+		{NestingModeDotsIndexBrackets, "a[]", 0, "", false},
+		{NestingModeBrackets, "a[2][b][c]", 2, "b[c]", true},
+		{NestingModeBrackets, "a[2]", 2, "", true},
+		{NestingModeBrackets, "a[][b]", -1, "b", true},
+		{NestingModeBrackets, "a[]", -1, "", true},
+		{NestingModeBrackets, "a[2].b", 0, "", false},
+		{NestingModeBrackets, "a[2][]", 0, "", false},
+		{NestingModeBracketsAllowDots, "a[2].b[c]", 2, "b[c]", true},
+		{NestingModeBracketsAllowDots, "a[2][b].c", 2, "b.c", true},
+		{NestingModeBracketsAllowDots, "a[]", -1, "", true},
+	}
+	for _, tc := range tests {
+		i, rest, ok := tc.mode.cutIndex(tc.key, "a")
+		if i != tc.i || rest != tc.rest || ok != tc.ok {
+			t.Errorf("%v.cutIndex(%q) == %v, %q, %v, want %v, %q, %v", tc.mode, tc.key, i, rest, ok, tc.i, tc.rest, tc.ok)
+		}
+	}
+}
+
+func TestNestingModeSplitKey(t *testing.T) {
+	tests := []struct {
+		mode     NestingMode
+		key      string
+		segments []string
+		ok       bool
+	}{
+		{NestingModeDots, "a", []string{"a"}, true},
+		{NestingModeDots, "a.b.0", []string{"a", "b", "0"}, true},
+		{NestingModeDots, "a[b]", []string{"a[b]"}, true},
+		{NestingModeDots, "a..b", nil, false},
+		{NestingModeDots, ".a", nil, false},
+		{NestingModeDotsIndexBrackets, "a.b[0].c", []string{"a", "b", "0", "c"}, true},
+		{NestingModeBrackets, "a[b][]", []string{"a", "b", ""}, true},
+		{NestingModeBrackets, "a.b[c]", []string{"a.b", "c"}, true},
+		{NestingModeBrackets, "a[b", nil, false},
+		{NestingModeBrackets, "a[b]c", nil, false},
+		{NestingModeBracketsAllowDots, "a.b[c].d", []string{"a", "b", "c", "d"}, true},
+		{NestingModeNone, "a.b", nil, false},
+	}
+	for _, tc := range tests {
+		segments, ok := tc.mode.splitKey(tc.key)
+		if !reflect.DeepEqual(segments, tc.segments) || ok != tc.ok {
+			t.Errorf("%v.splitKey(%q) == %q, %v, want %q, %v", tc.mode, tc.key, segments, ok, tc.segments, tc.ok)
+		}
+	}
+}
+
+func TestNestingDisabled(t *testing.T) {
+	if err := CheckMarshal(&nestingQuery{}); err == nil {
+		t.Error("unexpected success")
+	}
+	if err := CheckUnmarshal(&nestingQuery{}); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestNestedMapFields(t *testing.T) {
+	type query struct {
+		Filters map[string][]string `qs:"filter,comma"`
+		Counts  map[string]int      `qs:"count,omitempty"`
+		Labels  map[string]string   `qs:"label"`
+	}
+	m := NewMarshaler(nil, WithMarshalNesting(NestingModeDots))
+	um := NewUnmarshaler(nil, WithUnmarshalNesting(NestingModeDots))
+
+	q := query{
+		Filters: map[string][]string{"status": {"open", "closed"}, "owner": {"me"}},
+		Counts:  map[string]int{"a": 1, "b": 0},
+		Labels:  map[string]string{"x": ""},
+	}
+	vs, err := m.MarshalValues(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{
+		"filter.status": {"open,closed"},
+		"filter.owner":  {"me"},
+		"count.a":       {"1"},
+		"label.x":       {""},
+	}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	var q2 query
+	if err := um.UnmarshalValues(&q2, vs); err != nil {
+		t.Fatal(err)
+	}
+	delete(q.Counts, "b")
+	if !reflect.DeepEqual(q2, q) {
+		t.Errorf("got %+v, want %+v", q2, q)
+	}
+
+	if err := CheckMarshal(&query{}); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+type cycleNode struct {
+	Name string
+	Next *cycleNode
+}
+
+type cycleEmbedded struct {
+	*cycleEmbedded
+	X int
+}
+
+type cycleA struct {
+	B []cycleB
+}
+
+type cycleB struct {
+	A *cycleA
+}
+
+func TestRecursiveTypes(t *testing.T) {
+	m := NewMarshaler(nil, WithMarshalNesting(NestingModeDots))
+	um := NewUnmarshaler(nil, WithUnmarshalNesting(NestingModeDots))
+
+	tests := []struct {
+		into interface{}
+		path string
+	}{
+		{&cycleNode{}, "qs.cycleNode.Next"},
+		{&cycleEmbedded{}, "qs.cycleEmbedded.cycleEmbedded"},
+		{&cycleA{}, "qs.cycleA.B -> qs.cycleB.A"},
+		{&cycleB{}, "qs.cycleB.A -> qs.cycleA.B"},
+	}
+	for _, tc := range tests {
+		for _, err := range []error{m.CheckMarshal(tc.into), um.CheckUnmarshal(tc.into)} {
+			if err == nil || !strings.Contains(err.Error(), "recursive type") || !strings.Contains(err.Error(), tc.path) {
+				t.Errorf("%T :: got error %v, want recursive type error with path %q", tc.into, err, tc.path)
+			}
+		}
+	}
+
+	if err := CheckMarshal(&cycleEmbedded{}); err == nil {
+		t.Error("unexpected success")
+	}
+	if err := m.CheckMarshal(&nestingQuery{}); err != nil {
+		t.Error(err)
+	}
+}
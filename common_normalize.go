@@ -0,0 +1,40 @@
+package qs
+
+import (
+	"reflect"
+)
+
+// Normalize marshals the object pointed to by v with the DefaultMarshaler and
+// unmarshals the result back into it with the DefaultUnmarshaler. See
+// NormalizeWith.
+func Normalize(v interface{}) error {
+	return NormalizeWith(DefaultMarshaler, DefaultUnmarshaler, v)
+}
+
+// NormalizeWith marshals the object pointed to by v with m and replaces it
+// with the result of unmarshaling the values with um. The object ends up in
+// the canonical form of its query string: the values are trimmed, parsed and
+// formatted by the tag options of the fields, the empty pointers are set like
+// the unmarshaler sets them and the slices and maps are rebuilt. It is handy
+// to normalize user-supplied filter structs before using them as cache keys.
+//
+// The result is unmarshaled into a new zero value so the fields that aren't
+// marshaled (e.g. the fields with the "-" tag and the omitted empty fields)
+// are reset to their zero values. The object isn't modified if marshaling or
+// unmarshaling fails.
+func NormalizeWith(m *QSMarshaler, um *QSUnmarshaler, v interface{}) error {
+	target, err := targetValue(v)
+	if err != nil {
+		return err
+	}
+	values, err := m.MarshalValues(v)
+	if err != nil {
+		return err
+	}
+	normalized := reflect.New(target.Type())
+	if err := um.UnmarshalValues(normalized.Interface(), values); err != nil {
+		return err
+	}
+	target.Set(normalized.Elem())
+	return nil
+}
@@ -0,0 +1,236 @@
+package qs
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ODataQuery holds the basic system query options of OData. It can be
+// embedded into query structs of services that have to accept the queries of
+// existing OData clients:
+//
+//	type ListProducts struct {
+//		qs.ODataQuery
+//	}
+//
+//	var q ListProducts
+//	err := qs.Unmarshal(&q, "$top=10&$skip=20&$orderby=Price desc,Name&$filter=Price gt 10 and Category eq 'Tools'")
+//
+// Only the simple comparisons of $filter are supported, see ODataFilter.
+type ODataQuery struct {
+	// Top is the maximum number of returned items.
+	Top int `qs:"$top,omitempty"`
+
+	// Skip is the number of skipped items.
+	Skip int `qs:"$skip,omitempty"`
+
+	// OrderBy lists the sort properties, each optionally followed by "asc"
+	// or "desc" ("$orderby=Price desc,Name"). See OrderByFields.
+	OrderBy []string `qs:"$orderby,comma,omitempty"`
+
+	// Select lists the selected properties ("$select=Name,Price").
+	Select []string `qs:"$select,comma,omitempty"`
+
+	// Filter holds the comparisons of $filter.
+	Filter ODataFilter `qs:"$filter,omitempty"`
+}
+
+// ODataOrderBy is a sort property of $orderby.
+type ODataOrderBy struct {
+	Property   string
+	Descending bool
+}
+
+// OrderByFields returns the parsed sort properties of $orderby. It fails if a
+// property is followed by something else than "asc" or "desc".
+func (q ODataQuery) OrderByFields() ([]ODataOrderBy, error) {
+	fields := make([]ODataOrderBy, 0, len(q.OrderBy))
+	for _, s := range q.OrderBy {
+		parts := strings.Fields(s)
+		if len(parts) == 0 || len(parts) > 2 {
+			return nil, fmt.Errorf("invalid $orderby item %q", s)
+		}
+		f := ODataOrderBy{Property: parts[0]}
+		if len(parts) == 2 {
+			switch strings.ToLower(parts[1]) {
+			case "asc":
+			case "desc":
+				f.Descending = true
+			default:
+				return nil, fmt.Errorf("invalid $orderby direction %q", parts[1])
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// ODataComparison is a comparison of a property with a literal value in
+// $filter, e.g. "Price gt 10" or "Name eq 'bob'".
+type ODataComparison struct {
+	Property string
+
+	// Op is one of the comparison operators of OData: eq, ne, gt, ge, lt
+	// or le.
+	Op string
+
+	// Value is the literal value. The quotes of string literals are removed
+	// and their escaped quotes are unescaped.
+	Value string
+
+	// Quoted reports whether the value is a string literal.
+	Quoted bool
+}
+
+// odataOps are the supported comparison operators of $filter.
+var odataOps = []string{"eq", "ne", "gt", "ge", "lt", "le"}
+
+// ODataFilter is the $filter system query option of OData restricted to
+// comparisons of properties with literal values joined by "and":
+//
+//	Price gt 10 and Category eq 'Tools' and Discontinued eq false
+//
+// The other operators (or, not), the parentheses and the functions of OData
+// are rejected by the unmarshaler.
+type ODataFilter []ODataComparison
+
+// Lookup returns the first comparison of the property with the given
+// operator.
+func (f ODataFilter) Lookup(property, op string) (ODataComparison, bool) {
+	for _, c := range f {
+		if c.Property == property && c.Op == op {
+			return c, true
+		}
+	}
+	return ODataComparison{}, false
+}
+
+// String returns the $filter expression.
+func (f ODataFilter) String() string {
+	parts := make([]string, len(f))
+	for i, c := range f {
+		v := c.Value
+		if c.Quoted {
+			v = "'" + strings.ReplaceAll(v, "'", "''") + "'"
+		}
+		parts[i] = c.Property + " " + c.Op + " " + v
+	}
+	return strings.Join(parts, " and ")
+}
+
+// MarshalQS implements the MarshalQS interface.
+func (f ODataFilter) MarshalQS(opts *MarshalOptions) ([]string, error) {
+	if len(f) == 0 {
+		return nil, nil
+	}
+	for _, c := range f {
+		if !slices.Contains(odataOps, c.Op) {
+			return nil, fmt.Errorf("unsupported $filter operator %q", c.Op)
+		}
+	}
+	return []string{f.String()}, nil
+}
+
+// UnmarshalQS implements the UnmarshalQS interface.
+func (f *ODataFilter) UnmarshalQS(a []string, opts *UnmarshalOptions) error {
+	if a == nil {
+		return nil
+	}
+	s, err := opts.SliceToString(a)
+	if err != nil {
+		return err
+	}
+	filter, err := ParseODataFilter(s)
+	if err != nil {
+		return err
+	}
+	*f = filter
+	return nil
+}
+
+// ParseODataFilter parses a $filter expression. See ODataFilter.
+func ParseODataFilter(s string) (ODataFilter, error) {
+	tokens, err := odataTokens(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid $filter %q :: %w", s, err)
+	}
+
+	var f ODataFilter
+	for len(tokens) > 0 {
+		if len(tokens) < 3 {
+			return nil, fmt.Errorf("invalid $filter %q :: incomplete comparison", s)
+		}
+		property, op, value := tokens[0], strings.ToLower(tokens[1].text), tokens[2]
+		if property.quoted || strings.ContainsAny(property.text, "()") {
+			return nil, fmt.Errorf("invalid $filter %q :: unsupported property %q", s, property.text)
+		}
+		if !slices.Contains(odataOps, op) {
+			return nil, fmt.Errorf("invalid $filter %q :: unsupported operator %q", s, tokens[1].text)
+		}
+		f = append(f, ODataComparison{Property: property.text, Op: op, Value: value.text, Quoted: value.quoted})
+		tokens = tokens[3:]
+
+		if len(tokens) > 0 {
+			if tokens[0].quoted || !strings.EqualFold(tokens[0].text, "and") {
+				return nil, fmt.Errorf("invalid $filter %q :: unsupported logical operator %q", s, tokens[0].text)
+			}
+			tokens = tokens[1:]
+			if len(tokens) == 0 {
+				return nil, fmt.Errorf("invalid $filter %q :: incomplete comparison", s)
+			}
+		}
+	}
+	return f, nil
+}
+
+// odataToken is a word or a string literal of a $filter expression.
+type odataToken struct {
+	text   string
+	quoted bool
+}
+
+// odataTokens splits a $filter expression at the spaces outside of the
+// string literals.
+func odataTokens(s string) ([]odataToken, error) {
+	var tokens []odataToken
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return tokens, nil
+		}
+		if s[0] != '\'' {
+			word, rest, _ := strings.Cut(s, " ")
+			if strings.Contains(word, "'") {
+				return nil, fmt.Errorf("unexpected quote in %q", word)
+			}
+			tokens = append(tokens, odataToken{text: word})
+			s = rest
+			continue
+		}
+
+		var b strings.Builder
+		i := 1
+		for {
+			j := strings.IndexByte(s[i:], '\'')
+			if j < 0 {
+				return nil, errors.New("unterminated string literal")
+			}
+			b.WriteString(s[i : i+j])
+			i += j + 1
+			if i < len(s) && s[i] == '\'' {
+				// An escaped quote.
+				b.WriteByte('\'')
+				i++
+				continue
+			}
+			break
+		}
+		if i < len(s) && s[i] != ' ' {
+			return nil, errors.New("missing space after string literal")
+		}
+		tokens = append(tokens, odataToken{text: b.String(), quoted: true})
+		s = s[i:]
+	}
+}
@@ -0,0 +1,67 @@
+package qs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestODataQuery(t *testing.T) {
+	type listProducts struct {
+		ODataQuery
+	}
+
+	var q listProducts
+	err := Unmarshal(&q, "$top=10&$skip=20&$orderby=Price%20desc,Name&$select=Name,Price&$filter=Price%20gt%2010%20and%20Name%20eq%20'O''Neil%20and%20sons'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ODataQuery{
+		Top:     10,
+		Skip:    20,
+		OrderBy: []string{"Price desc", "Name"},
+		Select:  []string{"Name", "Price"},
+		Filter: ODataFilter{
+			{Property: "Price", Op: "gt", Value: "10"},
+			{Property: "Name", Op: "eq", Value: "O'Neil and sons", Quoted: true},
+		},
+	}
+	if !reflect.DeepEqual(q.ODataQuery, want) {
+		t.Errorf("got %+v, want %+v", q.ODataQuery, want)
+	}
+	order, err := q.OrderByFields()
+	if wantOrder := []ODataOrderBy{{Property: "Price", Descending: true}, {Property: "Name"}}; err != nil || !reflect.DeepEqual(order, wantOrder) {
+		t.Errorf("got %+v, %v", order, err)
+	}
+	if c, ok := q.Filter.Lookup("Price", "gt"); !ok || c.Value != "10" {
+		t.Errorf("got %+v, %v", c, ok)
+	}
+
+	vs, err := MarshalValues(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := vs.Get("$filter"); got != "Price gt 10 and Name eq 'O''Neil and sons'" {
+		t.Errorf("got %q", got)
+	}
+	var q2 listProducts
+	if err := UnmarshalValues(&q2, vs); err != nil || !reflect.DeepEqual(q2, q) {
+		t.Errorf("got %+v, %v", q2, err)
+	}
+
+	for _, s := range []string{
+		"Price gt 10 or Price lt 5",
+		"contains(Name,'a') eq true",
+		"Price between 10",
+		"Price gt",
+		"Price gt 10 and",
+		"Name eq 'bob",
+		"Name eq 'bob'x",
+	} {
+		if f, err := ParseODataFilter(s); err == nil {
+			t.Errorf("ParseODataFilter(%q) == %+v, want an error", s, f)
+		}
+	}
+	if _, err := (ODataQuery{OrderBy: []string{"Price up"}}).OrderByFields(); err == nil {
+		t.Error("unexpected success")
+	}
+}
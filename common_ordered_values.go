@@ -0,0 +1,122 @@
+package qs
+
+import (
+	"net/url"
+	"sort"
+)
+
+// OrderedValues is an alternative of url.Values that keeps the order of the
+// parameters. It is meant for protocols where the order matters (e.g. the
+// base strings of OAuth 1.0 signatures or canonical requests). It is
+// returned by MarshalOrdered and it can be unmarshaled with UnmarshalPairs:
+//
+//	ov, err := qs.MarshalOrdered(&q)
+//	...
+//	err = qs.UnmarshalPairs(&q, ov)
+type OrderedValues []KV
+
+// NewOrderedValues converts url.Values into OrderedValues. The keys are
+// sorted because url.Values has no order and the values of a key keep their
+// order.
+func NewOrderedValues(values url.Values) OrderedValues {
+	keys := make([]string, 0, len(values))
+	n := 0
+	for k, a := range values {
+		keys = append(keys, k)
+		n += len(a)
+	}
+	sort.Strings(keys)
+
+	ov := make(OrderedValues, 0, n)
+	for _, k := range keys {
+		for _, v := range values[k] {
+			ov = append(ov, KV{Key: k, Value: v})
+		}
+	}
+	return ov
+}
+
+// ParseOrderedQuery parses a query string like url.ParseQuery but keeps the
+// order of the pairs. The valid pairs are returned even if an error is
+// returned for an invalid pair. See QueryEncoding.ParseOrdered for other
+// encodings.
+func ParseOrderedQuery(query string) (OrderedValues, error) {
+	return QueryEncodingGo.ParseOrdered(query)
+}
+
+// Get returns the first value of the key or "" if the key is missing.
+func (ov OrderedValues) Get(key string) string {
+	for _, kv := range ov {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return ""
+}
+
+// Values returns the values of the key in order.
+func (ov OrderedValues) Values(key string) []string {
+	var a []string
+	for _, kv := range ov {
+		if kv.Key == key {
+			a = append(a, kv.Value)
+		}
+	}
+	return a
+}
+
+// Has reports whether the key is present.
+func (ov OrderedValues) Has(key string) bool {
+	for _, kv := range ov {
+		if kv.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Add appends a pair to the end.
+func (ov *OrderedValues) Add(key, value string) {
+	*ov = append(*ov, KV{Key: key, Value: value})
+}
+
+// Set replaces the values of the key with value. The pair takes the place of
+// the first pair of the key or it is appended if the key is missing.
+func (ov *OrderedValues) Set(key, value string) {
+	for i, kv := range *ov {
+		if kv.Key == key {
+			(*ov)[i].Value = value
+			*ov = append((*ov)[:i+1], (*ov)[i+1:].without(key)...)
+			return
+		}
+	}
+	ov.Add(key, value)
+}
+
+// Del removes the pairs of the key.
+func (ov *OrderedValues) Del(key string) {
+	*ov = ov.without(key)
+}
+
+// without removes the pairs of the key in place.
+func (ov OrderedValues) without(key string) OrderedValues {
+	kept := ov[:0]
+	for _, kv := range ov {
+		if kv.Key != key {
+			kept = append(kept, kv)
+		}
+	}
+	return kept
+}
+
+// URLValues converts the pairs into url.Values.
+func (ov OrderedValues) URLValues() url.Values {
+	return valuesFromPairs(ov)
+}
+
+// Encode encodes the pairs into a query string in their order with the
+// escaping of url.Values.Encode. See QueryEncoding.EncodeOrdered for other
+// encodings.
+func (ov OrderedValues) Encode() string {
+	return QueryEncodingGo.EncodeOrdered(ov)
+}
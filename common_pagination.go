@@ -0,0 +1,113 @@
+package qs
+
+// Pagination holds the pagination parameters of list endpoints. It can be
+// embedded into query structs so their keys are the same in every service:
+//
+//	type ListOrders struct {
+//		qs.Pagination
+//		Status string `qs:"status,omitempty"`
+//	}
+//
+//	var q ListOrders
+//	err := qs.Unmarshal(&q, "page=3&limit=50&status=open")
+//	...
+//	q.Normalize(qs.DefaultPaginationBounds)
+//	rows, err := db.Query(sql, q.Limit, q.StartOffset())
+//
+// Page based (page and limit), offset based (offset and limit) and cursor
+// based (cursor and limit) pagination are supported. The zero fields are
+// omitted by Marshal.
+type Pagination struct {
+	// Page is the 1-based number of the page.
+	Page int `qs:"page,omitempty"`
+
+	// Limit is the number of items per page.
+	Limit int `qs:"limit,omitempty"`
+
+	// Offset is the number of skipped items. It takes precedence over Page.
+	Offset int `qs:"offset,omitempty"`
+
+	// Cursor is the opaque position of the page for cursor based
+	// pagination. See the Cursor type to pass typed state.
+	Cursor string `qs:"cursor,omitempty"`
+}
+
+// PaginationBounds are the defaults and the bounds of the Pagination
+// parameters applied by Pagination.Normalize.
+type PaginationBounds struct {
+	// DefaultLimit replaces a missing (zero) or negative Limit.
+	DefaultLimit int
+
+	// MaxLimit is the largest accepted Limit. Zero means no maximum.
+	MaxLimit int
+
+	// MaxOffset is the largest accepted offset (computed from Page if
+	// Offset is zero). It protects the databases from deep pagination.
+	// Zero means no maximum.
+	MaxOffset int
+}
+
+// DefaultPaginationBounds are the bounds used by most list endpoints.
+var DefaultPaginationBounds = PaginationBounds{
+	DefaultLimit: 20,
+	MaxLimit:     100,
+}
+
+// Normalize applies the defaults and the bounds to the parameters: a
+// missing or negative Limit is replaced with the DefaultLimit and a larger
+// Limit than MaxLimit is lowered to it, a Page less than 1 becomes 1 and a
+// negative Offset becomes 0. The offset is lowered to MaxOffset: the Offset
+// itself or the Page to the last page that starts within the bound.
+func (p *Pagination) Normalize(b PaginationBounds) {
+	if p.Limit <= 0 {
+		p.Limit = b.DefaultLimit
+	}
+	if b.MaxLimit > 0 && p.Limit > b.MaxLimit {
+		p.Limit = b.MaxLimit
+	}
+	if p.Page < 1 {
+		p.Page = 1
+	}
+	if p.Offset < 0 {
+		p.Offset = 0
+	}
+	if b.MaxOffset > 0 && p.StartOffset() > b.MaxOffset {
+		if p.Offset > 0 {
+			p.Offset = b.MaxOffset
+		} else if p.Limit > 0 {
+			p.Page = b.MaxOffset/p.Limit + 1
+		}
+	}
+}
+
+// StartOffset returns the number of items skipped by the page: the Offset if
+// it is set or the offset of the Page otherwise.
+func (p Pagination) StartOffset() int {
+	if p.Offset > 0 {
+		return p.Offset
+	}
+	if p.Page <= 1 || p.Limit <= 0 {
+		return 0
+	}
+	return (p.Page - 1) * p.Limit
+}
+
+// Next returns the parameters of the next page. The Cursor is cleared
+// because the cursor of the next page is returned by the data source.
+func (p Pagination) Next() Pagination {
+	next := Pagination{Limit: p.Limit}
+	if p.Offset > 0 {
+		next.Offset = p.Offset + p.Limit
+	} else {
+		next.Page = max(p.Page, 1) + 1
+	}
+	return next
+}
+
+// TotalPages returns the number of pages of total items.
+func (p Pagination) TotalPages(total int) int {
+	if p.Limit <= 0 || total <= 0 {
+		return 0
+	}
+	return (total + p.Limit - 1) / p.Limit
+}
@@ -0,0 +1,50 @@
+package qs
+
+import (
+	"testing"
+)
+
+func TestPagination(t *testing.T) {
+	type listOrders struct {
+		Pagination
+		Status string `qs:"status,omitempty"`
+	}
+
+	var q listOrders
+	if err := Unmarshal(&q, "page=3&limit=500&status=open"); err != nil {
+		t.Fatal(err)
+	}
+	q.Normalize(DefaultPaginationBounds)
+	if q.Page != 3 || q.Limit != 100 || q.StartOffset() != 200 || q.Status != "open" {
+		t.Errorf("got %+v", q)
+	}
+	if s, err := Marshal(&listOrders{Pagination: q.Next()}); err != nil || s != "limit=100&page=4" {
+		t.Errorf("got %q, %v", s, err)
+	}
+
+	tests := []struct {
+		in, want Pagination
+		offset   int
+	}{
+		{Pagination{}, Pagination{Page: 1, Limit: 20}, 0},
+		{Pagination{Page: -2, Limit: -5, Offset: -1}, Pagination{Page: 1, Limit: 20}, 0},
+		{Pagination{Offset: 40, Limit: 10}, Pagination{Page: 1, Limit: 10, Offset: 40}, 40},
+		{Pagination{Page: 1000, Limit: 10}, Pagination{Page: 101, Limit: 10}, 1000},
+		{Pagination{Offset: 5000, Limit: 10}, Pagination{Page: 1, Limit: 10, Offset: 1000}, 1000},
+	}
+	for _, tc := range tests {
+		p := tc.in
+		p.Normalize(PaginationBounds{DefaultLimit: 20, MaxLimit: 100, MaxOffset: 1000})
+		if p != tc.want || p.StartOffset() != tc.offset {
+			t.Errorf("%+v: got %+v (offset %d), want %+v (offset %d)", tc.in, p, p.StartOffset(), tc.want, tc.offset)
+		}
+	}
+
+	p := Pagination{Offset: 40, Limit: 10, Cursor: "abc"}
+	if next := p.Next(); next != (Pagination{Offset: 50, Limit: 10}) {
+		t.Errorf("got %+v", next)
+	}
+	if n := p.TotalPages(101); n != 11 {
+		t.Errorf("got %d pages", n)
+	}
+}
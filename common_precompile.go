@@ -0,0 +1,167 @@
+package qs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// queryTypes is the registry of RegisterQueryTypes.
+var queryTypes struct {
+	types []reflect.Type
+	mu    sync.Mutex
+}
+
+// RegisterQueryTypes adds the types of the given objects (structs, maps or
+// pointers to them) to a package-level registry of query types. The
+// registered types are compiled by Precompile and MustPrecompile. This makes
+// it possible to list the query types next to their declarations in several
+// packages and to validate all of them at startup:
+//
+//	func init() {
+//		qs.RegisterQueryTypes(ListUsersQuery{}, SearchQuery{})
+//	}
+//
+//	func main() {
+//		qs.MustPrecompile()
+//		...
+//	}
+func RegisterQueryTypes(types ...interface{}) {
+	queryTypes.mu.Lock()
+	defer queryTypes.mu.Unlock()
+
+	for _, v := range types {
+		queryTypes.types = append(queryTypes.types, reflect.TypeOf(v))
+	}
+}
+
+// registeredQueryTypes returns a copy of the registry of RegisterQueryTypes.
+func registeredQueryTypes() []reflect.Type {
+	queryTypes.mu.Lock()
+	defer queryTypes.mu.Unlock()
+
+	return append([]reflect.Type(nil), queryTypes.types...)
+}
+
+// Precompile compiles the types registered with RegisterQueryTypes and the
+// types of the given objects with the DefaultMarshaler and the
+// DefaultUnmarshaler. The compiled objects are cached so the first requests
+// don't pay the cost of compiling them and the tag errors and unsupported
+// field types are reported at startup instead of at the first request. The
+// fields of the LazyFields option are compiled too.
+//
+// The returned error joins the errors of all types.
+func Precompile(types ...interface{}) error {
+	var errs []error
+	for _, t := range registeredQueryTypes() {
+		errs = append(errs, precompileType(t))
+	}
+	for _, v := range types {
+		errs = append(errs, precompileType(reflect.TypeOf(v)))
+	}
+	return errors.Join(errs...)
+}
+
+// MustPrecompile is like Precompile but it panics if a type can't be
+// compiled. It is meant to be called from init functions or at the start of
+// main.
+func MustPrecompile(types ...interface{}) {
+	if err := Precompile(types...); err != nil {
+		panic(err)
+	}
+}
+
+func precompileType(t reflect.Type) error {
+	if t == nil {
+		return errors.New("nil type")
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if err := DefaultMarshaler.Precompile(t); err != nil {
+		return fmt.Errorf("error compiling the marshaler of %v :: %w", t, err)
+	}
+	if err := DefaultUnmarshaler.Precompile(t); err != nil {
+		return fmt.Errorf("error compiling the unmarshaler of %v :: %w", t, err)
+	}
+	return nil
+}
+
+// Precompile compiles the ValuesMarshaler of type t like CompileType and
+// creates the Marshaler objects of its fields even if the LazyFields option
+// is set.
+func (p *QSMarshaler) Precompile(t reflect.Type) error {
+	vm, err := p.CompileType(t)
+	if err != nil {
+		return err
+	}
+	return buildValuesMarshaler(vm)
+}
+
+// Precompile compiles the ValuesUnmarshaler of type t like CompileType and
+// creates the Unmarshaler objects of its fields even if the LazyFields option
+// is set.
+func (p *QSUnmarshaler) Precompile(t reflect.Type) error {
+	vum, err := p.CompileType(t)
+	if err != nil {
+		return err
+	}
+	return buildValuesUnmarshaler(vum)
+}
+
+// buildValuesMarshaler creates the lazily created Marshaler objects of the
+// fields of vm including the fields of embedded and nested structs.
+func buildValuesMarshaler(vm ValuesMarshaler) error {
+	switch vm := vm.(type) {
+	case *structMarshaler:
+		for _, fm := range vm.Fields {
+			if fm.build != nil {
+				if err := fm.build(); err != nil && err != errSkippedField {
+					return fmt.Errorf("field %q :: %w", vm.Type.FieldByIndex(fm.Index).Name, err)
+				}
+			}
+			if fm.Nested != nil {
+				if err := buildValuesMarshaler(fm.Nested); err != nil {
+					return fmt.Errorf("field %q :: %w", vm.Type.FieldByIndex(fm.Index).Name, err)
+				}
+			}
+		}
+		for _, ef := range vm.EmbeddedFields {
+			if err := buildValuesMarshaler(ef.ValuesMarshaler); err != nil {
+				return fmt.Errorf("embedded field %q :: %w", vm.Type.FieldByIndex(ef.Index).Name, err)
+			}
+		}
+	case *ptrValuesMarshaler:
+		return buildValuesMarshaler(vm.ElemMarshaler)
+	}
+	return nil
+}
+
+// buildValuesUnmarshaler creates the lazily created Unmarshaler objects of
+// the fields of vum including the fields of embedded and nested structs.
+func buildValuesUnmarshaler(vum ValuesUnmarshaler) error {
+	switch vum := vum.(type) {
+	case *structUnmarshaler:
+		for _, fum := range vum.Fields {
+			if fum.build != nil {
+				if err := fum.build(); err != nil && err != errSkippedField {
+					return fmt.Errorf("field %q :: %w", vum.Type.FieldByIndex(fum.Index).Name, err)
+				}
+			}
+			if fum.Nested != nil {
+				if err := buildValuesUnmarshaler(fum.Nested); err != nil {
+					return fmt.Errorf("field %q :: %w", vum.Type.FieldByIndex(fum.Index).Name, err)
+				}
+			}
+		}
+		for _, ef := range vum.EmbeddedFields {
+			if err := buildValuesUnmarshaler(ef.ValuesUnmarshaler); err != nil {
+				return fmt.Errorf("embedded field %q :: %w", vum.Type.FieldByIndex(ef.Index).Name, err)
+			}
+		}
+	case *ptrValuesUnmarshaler:
+		return buildValuesUnmarshaler(vum.ElemUnmarshaler)
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package qs
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// QueryEncoding controls how url.Values are encoded into query strings and
+// how query strings are parsed. It can be set with WithMarshalQueryEncoding
+// and WithUnmarshalQueryEncoding. The zero value encodes spaces as "%20" and
+// percent-encodes every byte except ASCII letters and digits.
+//
+// By default the marshaler uses url.Values.Encode and the unmarshaler uses
+// url.ParseQuery which are the same as QueryEncodingGo.
+type QueryEncoding struct {
+	// SpaceAsPlus encodes the spaces as "+" instead of "%20" and decodes
+	// the "+" characters as spaces when parsing. Otherwise "+" is a literal
+	// plus sign.
+	SpaceAsPlus bool
+
+	// Unescaped lists the characters that aren't percent-encoded in
+	// addition to the ASCII letters and digits (e.g. "-._~").
+	Unescaped string
+
+	// SemicolonSeparator makes the parser split the pairs at ";" too, not
+	// only at "&". Otherwise a ";" in a key is rejected like url.ParseQuery
+	// does since Go 1.17.
+	SemicolonSeparator bool
+}
+
+// Presets of QueryEncoding.
+var (
+	// QueryEncodingGo is the encoding of url.Values.Encode and
+	// url.ParseQuery.
+	QueryEncodingGo = QueryEncoding{
+		SpaceAsPlus: true,
+		Unescaped:   "-._~",
+	}
+
+	// QueryEncodingHTML5Form is the application/x-www-form-urlencoded
+	// serializer of the HTML5 (WHATWG URL) standard.
+	QueryEncodingHTML5Form = QueryEncoding{
+		SpaceAsPlus: true,
+		Unescaped:   "*-._",
+	}
+
+	// QueryEncodingRFC3986 leaves only the unreserved characters of RFC 3986
+	// unescaped and encodes the spaces as "%20".
+	QueryEncodingRFC3986 = QueryEncoding{
+		Unescaped: "-._~",
+	}
+
+	// QueryEncodingLegacyPHP mirrors the urlencode function of PHP (which
+	// escapes "~" too) and accepts ";" as a pair separator.
+	QueryEncodingLegacyPHP = QueryEncoding{
+		SpaceAsPlus:        true,
+		Unescaped:          "-._",
+		SemicolonSeparator: true,
+	}
+)
+
+// Encode encodes the values into a query string sorted by key like
+// url.Values.Encode.
+func (e QueryEncoding) Encode(values url.Values) string {
+	if len(values) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		key := e.Escape(k)
+		for _, v := range values[k] {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(key)
+			b.WriteByte('=')
+			b.WriteString(e.Escape(v))
+		}
+	}
+	return b.String()
+}
+
+// EncodeOrdered encodes the pairs into a query string in their order.
+func (e QueryEncoding) EncodeOrdered(ov OrderedValues) string {
+	var b strings.Builder
+	for i, kv := range ov {
+		if i > 0 {
+			b.WriteByte('&')
+		}
+		b.WriteString(e.Escape(kv.Key))
+		b.WriteByte('=')
+		b.WriteString(e.Escape(kv.Value))
+	}
+	return b.String()
+}
+
+// Escape percent-encodes s.
+func (e QueryEncoding) Escape(s string) string {
+	const upperhex = "0123456789ABCDEF"
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c < 0x80 && c != '%' && strings.IndexByte(e.Unescaped, c) >= 0:
+			b.WriteByte(c)
+		case c == ' ' && e.SpaceAsPlus:
+			b.WriteByte('+')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(upperhex[c>>4])
+			b.WriteByte(upperhex[c&15])
+		}
+	}
+	return b.String()
+}
+
+// Parse parses a query string like url.ParseQuery: the valid pairs are
+// stored in the returned url.Values even if an error is returned for an
+// invalid pair.
+func (e QueryEncoding) Parse(query string) (url.Values, error) {
+	var err error
+	values := e.parse(query, false, func(pair string, err1 error) {
+		if err == nil {
+			err = err1
+		}
+	})
+	return values, err
+}
+
+// ParseLenient parses a query string like Parse but it never fails: the
+// malformed pairs (invalid percent-escapes, semicolons and pairs without a
+// key like "=v") are skipped and reported to onSkip if it isn't nil. Bare
+// keys without "=" are stored with an empty value like url.ParseQuery does.
+func (e QueryEncoding) ParseLenient(query string, onSkip func(pair string, err error)) url.Values {
+	return e.parse(query, true, func(pair string, err error) {
+		if onSkip != nil {
+			onSkip(pair, err)
+		}
+	})
+}
+
+// parse stores the valid pairs of the query into the returned url.Values
+// and calls skip with the invalid pairs. See scan.
+func (e QueryEncoding) parse(query string, lenient bool, skip func(pair string, err error)) url.Values {
+	values := make(url.Values)
+	e.scan(query, lenient, func(key, value string) {
+		values[key] = append(values[key], value)
+	}, skip)
+	return values
+}
+
+// scan calls add with the valid pairs of the query in order and skip with
+// the invalid pairs. A pair without a key is invalid only if lenient is
+// true.
+func (e QueryEncoding) scan(query string, lenient bool, add func(key, value string), skip func(pair string, err error)) {
+	for query != "" {
+		var pair string
+		if e.SemicolonSeparator {
+			if i := strings.IndexAny(query, "&;"); i >= 0 {
+				pair, query = query[:i], query[i+1:]
+			} else {
+				pair, query = query, ""
+			}
+		} else {
+			pair, query, _ = strings.Cut(query, "&")
+		}
+		if pair == "" {
+			continue
+		}
+		if !e.SemicolonSeparator && strings.Contains(pair, ";") {
+			skip(pair, errors.New("invalid semicolon separator in query"))
+			continue
+		}
+
+		key, value, _ := strings.Cut(pair, "=")
+		if lenient && key == "" {
+			skip(pair, errors.New("missing key in query"))
+			continue
+		}
+		key, err := e.Unescape(key)
+		if err != nil {
+			skip(pair, err)
+			continue
+		}
+		value, err = e.Unescape(value)
+		if err != nil {
+			skip(pair, err)
+			continue
+		}
+		add(key, value)
+	}
+}
+
+// ParseOrdered parses a query string like Parse but it keeps the order of
+// the pairs.
+func (e QueryEncoding) ParseOrdered(query string) (OrderedValues, error) {
+	var ov OrderedValues
+	var err error
+	e.scan(query, false, func(key, value string) {
+		ov = append(ov, KV{Key: key, Value: value})
+	}, func(pair string, err1 error) {
+		if err == nil {
+			err = err1
+		}
+	})
+	return ov, err
+}
+
+// Unescape decodes the percent-encoded bytes of s (and the "+" characters
+// if SpaceAsPlus is set).
+func (e QueryEncoding) Unescape(s string) (string, error) {
+	if strings.IndexByte(s, '%') < 0 && (!e.SpaceAsPlus || strings.IndexByte(s, '+') < 0) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '%':
+			if i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+				esc := s[i:]
+				if len(esc) > 3 {
+					esc = esc[:3]
+				}
+				return "", fmt.Errorf("invalid URL escape %q", esc)
+			}
+			b.WriteByte(unhex(s[i+1])<<4 | unhex(s[i+2]))
+			i += 2
+		case c == '+' && e.SpaceAsPlus:
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
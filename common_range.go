@@ -0,0 +1,158 @@
+package qs
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// The default suffixes of the keys of the bounds of ranges.
+const (
+	defaultRangeFromSuffix = "_from"
+	defaultRangeToSuffix   = "_to"
+)
+
+// Range is a pair of optional bounds that is marshaled into two keys: the key
+// of the field with the RangeFromSuffix and the RangeToSuffix of the options
+// ("_from" and "_to" by default):
+//
+//	type Query struct {
+//		Price qs.Range[float64] `qs:"price"`
+//	}
+//
+// marshals into "price_from=10&price_to=20". A nil bound is a missing key so
+// the range can be open on both ends. The unmarshaler checks that From isn't
+// greater than To. The tag options of the field (e.g. prec=2 or req) apply to
+// both bounds. Range fields can't be pointers.
+type Range[T cmp.Ordered] struct {
+	From *T
+	To   *T
+}
+
+// NewRange returns a Range with the given bounds.
+func NewRange[T cmp.Ordered](from, to T) Range[T] {
+	return Range[T]{From: &from, To: &to}
+}
+
+// Contains reports whether v is within the bounds of the range.
+func (r Range[T]) Contains(v T) bool {
+	return (r.From == nil || cmp.Compare(*r.From, v) <= 0) &&
+		(r.To == nil || cmp.Compare(v, *r.To) <= 0)
+}
+
+func (r Range[T]) validateRange() error {
+	if r.From != nil && r.To != nil && cmp.Compare(*r.From, *r.To) > 0 {
+		return fmt.Errorf("the lower bound %v is greater than the upper bound %v", *r.From, *r.To)
+	}
+	return nil
+}
+
+// TimeRange is a Range of times. The bounds are marshaled with the time
+// format of the field (see the timefmt tag option).
+type TimeRange struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// NewTimeRange returns a TimeRange with the given bounds.
+func NewTimeRange(from, to time.Time) TimeRange {
+	return TimeRange{From: &from, To: &to}
+}
+
+// Contains reports whether t is within the bounds of the range.
+func (r TimeRange) Contains(t time.Time) bool {
+	return (r.From == nil || !t.Before(*r.From)) &&
+		(r.To == nil || !t.After(*r.To))
+}
+
+func (r TimeRange) validateRange() error {
+	if r.From != nil && r.To != nil && r.From.After(*r.To) {
+		return fmt.Errorf("the lower bound %v is after the upper bound %v", *r.From, *r.To)
+	}
+	return nil
+}
+
+// rangeType is implemented by Range and TimeRange. Their first two fields
+// are the From and To bounds.
+type rangeType interface {
+	validateRange() error
+}
+
+var rangeInterfaceType = reflect.TypeOf((*rangeType)(nil)).Elem()
+
+// isRangeType reports whether the struct fields of type t are marshaled as
+// ranges.
+func isRangeType(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.Implements(rangeInterfaceType)
+}
+
+// rangeBoundTag returns the tag of the bound of a range field: the tag of the
+// field with the suffix appended to the key.
+func rangeBoundTag(tag *ParsedTagInfo, suffix string) *ParsedTagInfo {
+	bound := *tag
+	bound.Name = tag.Name + suffix
+	return &bound
+}
+
+// newRangeFieldMarshalers returns the marshalers of the From and To bounds of
+// the range field sf. Their index paths are relative to the field.
+func newRangeFieldMarshalers(sf reflect.StructField, opts *MarshalOptions, defaults tagDefaults) ([]*fieldMarshaler, error) {
+	tag, err := getStructFieldInfo(sf, opts.fieldNaming(), defaults)
+	if tag == nil || err != nil {
+		return nil, err
+	}
+
+	var fms []*fieldMarshaler
+	for i, suffix := range [...]string{opts.RangeFromSuffix, opts.RangeToSuffix} {
+		m, err := opts.MarshalerFactory.Marshaler(sf.Type.Field(i).Type, opts)
+		if err != nil {
+			return nil, err
+		}
+		fms = append(fms, &fieldMarshaler{
+			Index:     []int{i},
+			Marshaler: m,
+			Tag:       rangeBoundTag(tag, suffix),
+		})
+	}
+	return fms, nil
+}
+
+// newRangeFieldUnmarshalers returns the unmarshalers of the From and To
+// bounds of the range field sf. Their index paths are relative to the field.
+// The missing bounds are left nil unless the field is required.
+func newRangeFieldUnmarshalers(sf reflect.StructField, opts *UnmarshalerDefaultOptions, defaults tagDefaults) ([]*fieldUnmarshaler, error) {
+	tag, err := getStructFieldInfo(sf, opts.fieldNaming(), defaults)
+	if tag == nil || err != nil {
+		return nil, err
+	}
+
+	var fums []*fieldUnmarshaler
+	for i, suffix := range [...]string{opts.RangeFromSuffix, opts.RangeToSuffix} {
+		um, err := opts.UnmarshalerFactory.Unmarshaler(sf.Type.Field(i).Type, NewUnmarshalOptions(opts, nil))
+		if err != nil {
+			return nil, err
+		}
+		bound := rangeBoundTag(tag, suffix)
+		unmarshalOpts := *tag.UnmarshalOpts
+		if unmarshalOpts.Presence != UnmarshalPresenceReq {
+			unmarshalOpts.Presence = UnmarshalPresenceNil
+		}
+		bound.UnmarshalOpts = &unmarshalOpts
+		fums = append(fums, &fieldUnmarshaler{
+			Index:       []int{i},
+			Unmarshaler: um,
+			Tag:         bound,
+		})
+	}
+	return fums, nil
+}
+
+// validateRange checks the bounds of the range field with the given index
+// path and key of struct value v.
+func validateRange(v reflect.Value, index []int, key string) error {
+	if err := v.FieldByIndex(index).Interface().(rangeType).validateRange(); err != nil {
+		return fmt.Errorf("invalid range %q :: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package qs
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRange(t *testing.T) {
+	type query struct {
+		Price   Range[float64] `qs:"price,prec=2"`
+		Created TimeRange      `qs:"created"`
+		Name    string         `qs:"name,omitempty"`
+	}
+
+	created := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	q := query{
+		Price:   NewRange(9.5, 20),
+		Created: TimeRange{From: &created},
+	}
+	vs, err := MarshalValues(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{"price_from": {"9.50"}, "price_to": {"20.00"}, "created_from": {"2024-01-02T00:00:00Z"}}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	var q2 query
+	if err := UnmarshalValues(&q2, vs); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(q2, q) {
+		t.Errorf("got %+v, want %+v", q2, q)
+	}
+	if !q2.Price.Contains(10) || q2.Price.Contains(21) || !q2.Created.Contains(time.Now()) {
+		t.Errorf("unexpected Contains results for %+v", q2)
+	}
+
+	if err := Unmarshal(&q2, "price_from=30&price_to=20"); err == nil {
+		t.Error("unexpected success")
+	}
+	if err := Unmarshal(&q2, "created_from=2024-02-01T00:00:00Z&created_to=2024-01-01T00:00:00Z"); err == nil {
+		t.Error("unexpected success")
+	}
+
+	// The suffixes are configurable.
+	m := NewMarshaler(nil, WithMarshalRangeSuffixes("_min", "_max"))
+	if s, err := m.Marshal(&query{Price: NewRange(1.0, 2.0)}); err != nil || s != "price_max=2.00&price_min=1.00" {
+		t.Errorf("got %q, %v", s, err)
+	}
+	var q3 query
+	um := NewUnmarshaler(nil, WithUnmarshalRangeSuffixes("_min", "_max"))
+	if err := um.Unmarshal(&q3, "price_min=1"); err != nil || q3.Price.From == nil || *q3.Price.From != 1 || q3.Price.To != nil {
+		t.Errorf("got %+v, %v", q3, err)
+	}
+
+	// The req option requires both bounds.
+	type required struct {
+		Age Range[int] `qs:"age,req"`
+	}
+	var r required
+	if err := Unmarshal(&r, "age_from=18"); err == nil {
+		t.Error("unexpected success")
+	} else if name, ok := IsRequiredFieldError(err); !ok || name != "age_to" {
+		t.Errorf("got %v", err)
+	}
+}
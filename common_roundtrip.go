@@ -0,0 +1,105 @@
+package qs
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// RoundTripCheck checks whether v survives a round trip through a query
+// string with the DefaultMarshaler and the DefaultUnmarshaler. See
+// RoundTripCheckWith.
+func RoundTripCheck(v interface{}) error {
+	return RoundTripCheckWith(DefaultMarshaler, DefaultUnmarshaler, v)
+}
+
+// RoundTripCheckWith marshals v (a struct, a map or a pointer to one of
+// these) into a query string with m, unmarshals the query string into a new
+// object of the same type with um and marshals the new object too. It
+// returns a *RoundTripError if the url.Values of the two marshal calls
+// differ, i.e. if some part of v can't be reproduced from its query string.
+// Errors of the marshal and unmarshal calls are returned as is.
+//
+// The check compares queries instead of objects so it isn't affected by
+// differences that aren't visible in query strings (e.g. a nil and an empty
+// slice). It is meant for tests and fuzz targets of user types:
+//
+//	func FuzzQuery(f *testing.F) {
+//		f.Fuzz(func(t *testing.T, page int, search string) {
+//			if err := qs.RoundTripCheck(&Query{Page: page, Search: search}); err != nil {
+//				t.Error(err)
+//			}
+//		})
+//	}
+func RoundTripCheckWith(m *QSMarshaler, um *QSUnmarshaler, v interface{}) error {
+	marshaled, err := m.MarshalValues(v)
+	if err != nil {
+		return err
+	}
+
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	into := reflect.New(t)
+	if err := um.Unmarshal(into.Interface(), m._EncodeValues(marshaled)); err != nil {
+		return fmt.Errorf("error unmarshaling the marshaled %v :: %w", t, err)
+	}
+
+	roundTripped, err := m.MarshalValues(into.Interface())
+	if err != nil {
+		return fmt.Errorf("error marshaling the unmarshaled %v :: %w", t, err)
+	}
+	if keys := diffKeys(marshaled, roundTripped); len(keys) != 0 {
+		return &RoundTripError{
+			Type:         t,
+			Keys:         keys,
+			Marshaled:    marshaled,
+			RoundTripped: roundTripped,
+		}
+	}
+	return nil
+}
+
+// RoundTripError is returned by RoundTripCheck if the query of an object
+// differs from the query of the object unmarshaled from it.
+type RoundTripError struct {
+	Type reflect.Type
+
+	// Keys are the sorted keys whose values differ.
+	Keys []string
+
+	// Marshaled is the query of the checked object and RoundTripped is the
+	// query of the object unmarshaled from Marshaled.
+	Marshaled    url.Values
+	RoundTripped url.Values
+}
+
+func (e *RoundTripError) Error() string {
+	diffs := make([]string, len(e.Keys))
+	for i, k := range e.Keys {
+		diffs[i] = fmt.Sprintf("%q: %q -> %q", k, e.Marshaled[k], e.RoundTripped[k])
+	}
+	return fmt.Sprintf("round trip of %v changed the query: %v", e.Type, strings.Join(diffs, ", "))
+}
+
+// diffKeys returns the sorted keys whose values differ in a and b. A missing
+// key differs from a key with no values.
+func diffKeys(a, b url.Values) []string {
+	var keys []string
+	for k, va := range a {
+		if vb, ok := b[k]; !ok || !slices.Equal(va, vb) {
+			keys = append(keys, k)
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
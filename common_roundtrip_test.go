@@ -0,0 +1,101 @@
+package qs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+type fuzzQuery struct {
+	S   string
+	I   int
+	U   uint8
+	F   float64
+	B   bool
+	P   *int
+	SS  []string
+	Sep []string `qs:",sep=|"`
+	IA  [2]int
+	M   map[string]string `qs:"-"`
+}
+
+func TestRoundTripCheck(t *testing.T) {
+	i := 3
+	if err := RoundTripCheck(&fuzzQuery{S: "a b&c", I: -1, F: 0.1, P: &i, SS: []string{"x", ""}, Sep: []string{"a|b"}}); err != nil {
+		t.Error(err)
+	}
+	if err := RoundTripCheck(map[string][]string{"a": {"1", "2"}}); err != nil {
+		t.Error(err)
+	}
+
+	type lossy struct {
+		A float32
+		B int
+	}
+	m := NewMarshaler(nil)
+	m.RegisterCustomType(reflect.TypeOf(float32(0)), func(v reflect.Value, opts *MarshalOptions) (string, error) {
+		return fmt.Sprint(v.Float()), nil
+	})
+	um := NewUnmarshaler(nil)
+	um.RegisterCustomType(reflect.TypeOf(float32(0)), func(v reflect.Value, s string, opts *UnmarshalOptions) error {
+		return nil
+	})
+	err := RoundTripCheckWith(m, um, &lossy{A: 1.5, B: 1})
+	var rte *RoundTripError
+	if !errors.As(err, &rte) {
+		t.Fatalf("got error %v, want RoundTripError", err)
+	}
+	if !reflect.DeepEqual(rte.Keys, []string{"a"}) || rte.Type != reflect.TypeOf(lossy{}) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := RoundTripCheck(nil); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	for _, s := range []string{
+		"",
+		"s=a&i=-1&u=255&f=1e3&b=true&p=1&ss=x&ss=y&sep=a|b\\|c&ia=1&ia=2",
+		"i=x",
+		"u=256",
+		"ia=1&ia=2&ia=3",
+		"sep=\\",
+		"%zz",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		var q fuzzQuery
+		if err := Unmarshal(&q, s); err != nil {
+			return
+		}
+		if _, err := MarshalValues(&q); err != nil {
+			t.Errorf("error marshaling the unmarshaled %+v :: %v", q, err)
+		}
+	})
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	f.Add("", 0, uint8(0), 0.0, false, "", "")
+	f.Add("a=b&c", -1, uint8(255), math.Inf(-1), true, "x|y", "\\")
+	f.Fuzz(func(t *testing.T, s string, i int, u uint8, fl float64, b bool, item1, item2 string) {
+		q := fuzzQuery{
+			S:   s,
+			I:   i,
+			U:   u,
+			F:   fl,
+			B:   b,
+			P:   &i,
+			SS:  []string{item1, item2},
+			Sep: []string{item1, item2},
+			IA:  [2]int{i, int(u)},
+		}
+		if err := RoundTripCheck(&q); err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,227 @@
+package qs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// SchemaHash returns a hash of the query string schema of t as seen by the
+// DefaultMarshaler. See QSMarshaler.SchemaHash.
+func SchemaHash(t reflect.Type) (string, error) {
+	return DefaultMarshaler.SchemaHash(t)
+}
+
+// SchemaHash returns a hash of the query string schema of t: the keys, the
+// wire types of their values, their slice separators and the keys of the
+// items of their slices. The keys of the fields of nested structs are
+// included with the prefix of the nested field (the keys of the first item
+// stand for the items of slices of structs). Services can compare the hashes
+// of a shared query type at deploy time to detect whether the contract
+// between the producer and the consumer has changed.
+//
+// The hash doesn't depend on the order of the fields, on the Go names of the
+// fields and types or on the presence options of the fields. It is the same
+// as the hash returned by QSUnmarshaler.SchemaHash for the same type when the
+// marshaler and the unmarshaler use the same name transformer and defaults.
+func (p *QSMarshaler) SchemaHash(t reflect.Type) (string, error) {
+	if t == nil {
+		return "", errors.New("nil type")
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	vm, err := p.CompileType(t)
+	if err != nil {
+		return "", err
+	}
+	fields, err := marshalerSchema(vm, p.opts.Nesting, nil)
+	if err != nil {
+		return "", err
+	}
+	return schemaHash(fields), nil
+}
+
+// SchemaHash returns a hash of the query string schema of t. See
+// QSMarshaler.SchemaHash.
+func (p *QSUnmarshaler) SchemaHash(t reflect.Type) (string, error) {
+	if t == nil {
+		return "", errors.New("nil type")
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	vum, err := p.CompileType(t)
+	if err != nil {
+		return "", err
+	}
+	fields, err := unmarshalerSchema(vum, p.opts.Nesting, nil)
+	if err != nil {
+		return "", err
+	}
+	return schemaHash(fields), nil
+}
+
+// schemaField is a query string key of a schema.
+type schemaField struct {
+	key       string
+	wireType  string
+	separator string
+
+	// sliceKeys is the OptionSliceKeys of the field that determines the
+	// keys of the items of slices (e.g. "ids" or "ids[]").
+	sliceKeys string
+
+	// nested is true for the keys of the fields of nested structs.
+	nested bool
+}
+
+func marshalerSchema(vm ValuesMarshaler, nesting NestingMode, fields []schemaField) ([]schemaField, error) {
+	var err error
+	switch vm := vm.(type) {
+	case *structMarshaler:
+		for _, fm := range vm.Fields {
+			if fm.build != nil {
+				if err := fm.build(); err == errSkippedField {
+					continue
+				} else if err != nil {
+					return nil, err
+				}
+			}
+			fields = append(fields, newSchemaField(fm.Tag, vm.Type.FieldByIndex(fm.Index).Type))
+			if fm.Nested != nil {
+				nested, err := marshalerSchema(fm.Nested, nesting, nil)
+				if err != nil {
+					return nil, err
+				}
+				fields = appendNestedSchema(fields, nested, nesting, fm.Tag.Name, fm.Indexed)
+			}
+		}
+		for _, ef := range vm.EmbeddedFields {
+			if fields, err = marshalerSchema(ef.ValuesMarshaler, nesting, fields); err != nil {
+				return nil, err
+			}
+		}
+		return fields, nil
+	case *ptrValuesMarshaler:
+		return marshalerSchema(vm.ElemMarshaler, nesting, fields)
+	case *mapMarshaler:
+		return append(fields, newSchemaField(nil, vm.Type.Elem())), nil
+	default:
+		return nil, fmt.Errorf("can't determine the schema of ValuesMarshaler %T", vm)
+	}
+}
+
+func unmarshalerSchema(vum ValuesUnmarshaler, nesting NestingMode, fields []schemaField) ([]schemaField, error) {
+	var err error
+	switch vum := vum.(type) {
+	case *structUnmarshaler:
+		for _, fum := range vum.Fields {
+			if ok, err := buildField(fum); err != nil {
+				return nil, err
+			} else if !ok {
+				continue
+			}
+			fields = append(fields, newSchemaField(fum.Tag, vum.Type.FieldByIndex(fum.Index).Type))
+			if fum.Nested != nil {
+				nested, err := unmarshalerSchema(fum.Nested, nesting, nil)
+				if err != nil {
+					return nil, err
+				}
+				fields = appendNestedSchema(fields, nested, nesting, fum.Tag.Name, fum.Indexed)
+			}
+		}
+		for _, ef := range vum.EmbeddedFields {
+			if fields, err = unmarshalerSchema(ef.ValuesUnmarshaler, nesting, fields); err != nil {
+				return nil, err
+			}
+		}
+		return fields, nil
+	case *ptrValuesUnmarshaler:
+		return unmarshalerSchema(vum.ElemUnmarshaler, nesting, fields)
+	case *mapUnmarshaler:
+		return append(fields, newSchemaField(nil, vum.ElemType)), nil
+	default:
+		return nil, fmt.Errorf("can't determine the schema of ValuesUnmarshaler %T", vum)
+	}
+}
+
+// appendNestedSchema appends the fields of the schema of a nested field with
+// the given key to fields. Their keys are prefixed with the key of the nested
+// field (and the index of the first item if the field is indexed).
+func appendNestedSchema(fields, nested []schemaField, nesting NestingMode, key string, indexed bool) []schemaField {
+	if indexed {
+		key = nesting.indexKey(key, 0)
+	}
+	for _, f := range nested {
+		f.key = nesting.fieldKey(key, f.key)
+		f.nested = true
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// newSchemaField creates the schemaField of a struct field or, if tag is nil,
+// of the values of a map.
+func newSchemaField(tag *ParsedTagInfo, t reflect.Type) schemaField {
+	if tag == nil {
+		return schemaField{
+			key:       "*",
+			wireType:  wireType(t),
+			separator: OptionSliceSeparatorUnspecified.String(),
+			sliceKeys: OptionSliceKeysSKUnspecified.String(),
+		}
+	}
+	wt := wireType(t)
+	if tag.CommonOpts.Codec != "" {
+		wt = "codec=" + tag.CommonOpts.Codec
+	}
+	return schemaField{
+		key:       tag.Name,
+		wireType:  wt,
+		separator: schemaSeparator(tag.CommonOpts),
+		sliceKeys: tag.CommonOpts.SliceKeys.String(),
+	}
+}
+
+// schemaSeparator describes the slice separator of a field.
+func schemaSeparator(opts *CommonTagOptions) string {
+	if opts.CustomSeparator != "" {
+		return "sep=" + opts.CustomSeparator
+	}
+	return opts.SliceSeparator.String()
+}
+
+// wireType describes how the values of t look like in a query string.
+// Pointers and the names of the types are irrelevant except for types with
+// their own encoding.
+func wireType(t reflect.Type) string {
+	switch {
+	case t == timeType, t == urlType,
+		t.Implements(marshalQSInterfaceType),
+		reflect.PointerTo(t).Implements(unmarshalQSInterfaceType):
+		return t.String()
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return wireType(t.Elem())
+	case reflect.Array, reflect.Slice:
+		return "[]" + wireType(t.Elem())
+	default:
+		return t.Kind().String()
+	}
+}
+
+func schemaHash(fields []schemaField) string {
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].key < fields[j].key
+	})
+	h := sha256.New()
+	for _, f := range fields {
+		fmt.Fprintf(h, "%s\t%s\t%s\t%s\n", f.key, f.wireType, f.separator, f.sliceKeys)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,137 @@
+package qs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSchemaHash(t *testing.T) {
+	type embedded struct {
+		Sort string
+	}
+	type query struct {
+		*embedded
+		Page  int
+		Tags  []string `qs:"tag,comma"`
+		Since time.Time
+	}
+	type otherValueType struct {
+		Since time.Time
+		Tags  []string `qs:"tag,comma"`
+		Page  *uint8   `qs:"page,omitempty,req"`
+		Sort  string
+	}
+	type renamed struct {
+		Page  int      `qs:"p"`
+		Tags  []string `qs:"tag,comma"`
+		Since time.Time
+		Sort  string
+	}
+	type separator struct {
+		Page  int
+		Tags  []string `qs:"tag,semicolon"`
+		Since time.Time
+		Sort  string
+	}
+
+	hash := func(t reflect.Type) string {
+		h, err := SchemaHash(t)
+		if err != nil {
+			panic(err)
+		}
+		return h
+	}
+
+	h := hash(reflect.TypeOf(query{}))
+	if uh, err := DefaultUnmarshaler.SchemaHash(reflect.TypeOf(&query{})); err != nil || uh != h {
+		t.Errorf("unmarshaler hash == %q (%v), want %q", uh, err, h)
+	}
+	if vh := hash(reflect.TypeOf(otherValueType{})); vh == h {
+		t.Error("expected a different hash for a different value type")
+	}
+	if rh := hash(reflect.TypeOf(renamed{})); rh == h {
+		t.Error("expected a different hash for a renamed key")
+	}
+	if sh := hash(reflect.TypeOf(separator{})); sh == h {
+		t.Error("expected a different hash for a different separator")
+	}
+
+	type sliceKeys struct {
+		Page  int
+		Tags  []string `qs:"tag,comma,brackets"`
+		Since time.Time
+		Sort  string
+	}
+	if kh := hash(reflect.TypeOf(sliceKeys{})); kh == h {
+		t.Error("expected a different hash for different slice keys")
+	}
+
+	type sameAsQuery struct {
+		Since time.Time
+		Sort  string
+		Tags  []string `qs:"tag,comma,omitempty"`
+		Page  *int
+	}
+	if sh := hash(reflect.TypeOf(sameAsQuery{})); sh != h {
+		t.Errorf("expected the same hash for the same schema")
+	}
+
+	if _, err := SchemaHash(reflect.TypeOf(MNonMarshalable{})); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestSchemaHashNested(t *testing.T) {
+	type inner struct {
+		A string
+		B string
+	}
+	type query struct {
+		In    inner
+		Items []inner
+	}
+	type changedField struct {
+		In struct {
+			A string
+			C string
+		}
+		Items []inner
+	}
+	type changedItem struct {
+		In    inner
+		Items []struct {
+			A string
+			B int
+		}
+	}
+
+	m := NewMarshaler(nil, WithMarshalNesting(NestingModeBrackets))
+	um := NewUnmarshaler(nil, WithUnmarshalNesting(NestingModeBrackets))
+	hash := func(t reflect.Type) string {
+		h, err := m.SchemaHash(t)
+		if err != nil {
+			panic(err)
+		}
+		return h
+	}
+
+	h := hash(reflect.TypeOf(query{}))
+	if uh, err := um.SchemaHash(reflect.TypeOf(query{})); err != nil || uh != h {
+		t.Errorf("unmarshaler hash == %q (%v), want %q", uh, err, h)
+	}
+	if ch := hash(reflect.TypeOf(changedField{})); ch == h {
+		t.Error("expected a different hash for a changed field of a nested struct")
+	}
+	if ch := hash(reflect.TypeOf(changedItem{})); ch == h {
+		t.Error("expected a different hash for a changed field of the items")
+	}
+	if dh, err := NewMarshaler(nil, WithMarshalNesting(NestingModeDots)).SchemaHash(reflect.TypeOf(query{})); err != nil || dh == h {
+		t.Errorf("expected a different hash for a different nesting mode, got %q (%v)", dh, err)
+	}
+
+	lazy := NewMarshaler(nil, WithMarshalNesting(NestingModeBrackets), WithMarshalLazyFields(true))
+	if lh, err := lazy.SchemaHash(reflect.TypeOf(query{})); err != nil || lh != h {
+		t.Errorf("lazy marshaler hash == %q (%v), want %q", lh, err, h)
+	}
+}
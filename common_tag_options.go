@@ -1,20 +1,96 @@
 package qs
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type CommonTagOptions struct {
-	SliceSeparator OptionSliceSeparator
+	SliceSeparator  OptionSliceSeparator
+	SliceKeys       OptionSliceKeys
+	SliceDuplicates OptionSliceDuplicates
+
+	// CustomSeparator is an arbitrary slice separator set by the sep=<string>
+	// tag option (e.g. `qs:"ids,sep=|"`). When it isn't empty SliceSeparator
+	// is None. Like the rest of the tag the separator can contain escape
+	// sequences (e.g. `qs:"ids,sep=\t"`) but it can't contain a comma or a
+	// backslash.
+	//
+	// Unlike the builtin separators the custom separator is escaped: the
+	// marshaler prefixes the occurrences of the separator and the backslash
+	// in the items with a backslash and the unmarshaler splits the values
+	// only at unescaped separators.
+	CustomSeparator string
+
+	// Codec is the name of the codec set by the codec=<name> tag option
+	// (e.g. `qs:"token,codec=upper"`). The codec has to be registered with
+	// RegisterNamedCodec and it replaces the Marshaler and Unmarshaler of the
+	// type of the field. It isn't inherited from the defaults.
+	//
+	// The builtin codecs don't have to be registered and their names can be
+	// used as tag options without the codec= prefix (e.g.
+	// `qs:"sig,base64url"`):
+	//   - hex, base64, base64url, rawbase64 and rawbase64url encode byte
+	//     slices and byte arrays. The raw variants omit the padding.
+	//   - json encodes any value as compact JSON with encoding/json (e.g.
+	//     `filter={"a":1}`). It is useful for APIs that pass structured
+	//     filters in a single parameter.
+	Codec string
+
+	// Transform is the name of the ValueTransformer set by the
+	// transform=<name> tag option (e.g. `qs:"cursor,transform=seal"`). The
+	// transformer has to be registered with RegisterValueTransformer. It
+	// isn't inherited from the defaults.
+	Transform string
+
+	// Inline is set by the inline tag option. It flattens the keys of a
+	// struct field (or a pointer to struct field) into the keys of the
+	// parent struct like embedding does but with a named field (e.g.
+	// `qs:",inline"`). The name of the field isn't used. It isn't inherited
+	// from the defaults.
+	Inline bool
+
+	// Namespace is set by the ns=<namespace> tag option of embedded and
+	// inlined struct fields (e.g. `qs:",ns=utm"`). The keys of the fields of
+	// the struct are joined to the namespace with an underscore
+	// ("utm_source") or, if the namespace ends with "[]" (e.g.
+	// `qs:",ns=utm[]"`), wrapped in brackets ("utm[source]"). It isn't
+	// inherited from the defaults.
+	Namespace string
+
+	// Null is the sentinel value of nil pointers set by the null=<value> tag
+	// option (e.g. `qs:"parent_id,null=none"`). The unmarshaler sets pointer
+	// fields to nil if their value is the sentinel and the marshaler marshals
+	// nil pointer fields as the sentinel unless their presence option omits
+	// them (e.g. omitempty or omitnil). Empty means no sentinel.
+	Null string
 }
 
 func (o *CommonTagOptions) InitDefaults() {
 	if o.SliceSeparator == OptionSliceSeparatorUnspecified {
 		o.SliceSeparator = OptionSliceSeparatorNone
 	}
+	if o.SliceKeys == OptionSliceKeysSKUnspecified {
+		o.SliceKeys = OptionSliceKeysRepeat
+	}
+	if o.SliceDuplicates == OptionSliceDuplicatesSDUnspecified {
+		o.SliceDuplicates = OptionSliceDuplicatesKeepDuplicates
+	}
 }
 
 func (o *CommonTagOptions) ApplyDefaults(d *CommonTagOptions) {
 	if o.SliceSeparator == OptionSliceSeparatorUnspecified {
 		o.SliceSeparator = d.SliceSeparator
+		o.CustomSeparator = d.CustomSeparator
+	}
+	if o.SliceKeys == OptionSliceKeysSKUnspecified {
+		o.SliceKeys = d.SliceKeys
+	}
+	if o.SliceDuplicates == OptionSliceDuplicatesSDUnspecified {
+		o.SliceDuplicates = d.SliceDuplicates
+	}
+	if o.Null == "" {
+		o.Null = d.Null
 	}
 }
 
@@ -30,11 +106,198 @@ func (o *CommonTagOptions) ParseOption(option string) (bool, error) {
 		bOk = true
 	}
 
+	// CustomSeparator
+	if sep, ok := strings.CutPrefix(option, "sep="); ok {
+		if sep == "" || strings.Contains(sep, `\`) {
+			return false, fmt.Errorf("invalid slice separator: %q", sep)
+		}
+		if o.SliceSeparator != OptionSliceSeparatorUnspecified {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "OptionSliceSeparator", o.SliceSeparator, option)
+		}
+		o.SliceSeparator = OptionSliceSeparatorNone
+		o.CustomSeparator = sep
+		bOk = true
+	}
+
+	// Codec
+	name, ok := strings.CutPrefix(option, "codec=")
+	if _, builtin := builtinMarshalCodecs[option]; builtin {
+		name, ok = option, true
+	}
+	if ok {
+		if name == "" {
+			return false, fmt.Errorf("invalid codec name: %q", name)
+		}
+		if o.Codec != "" {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "Codec", o.Codec, name)
+		}
+		o.Codec = name
+		bOk = true
+	}
+
+	// Transform
+	if name, ok := strings.CutPrefix(option, "transform="); ok {
+		if name == "" {
+			return false, fmt.Errorf("invalid value transformer name: %q", name)
+		}
+		if o.Transform != "" {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "Transform", o.Transform, name)
+		}
+		o.Transform = name
+		bOk = true
+	}
+
+	// Null
+	if null, ok := strings.CutPrefix(option, "null="); ok {
+		if null == "" {
+			return false, fmt.Errorf("invalid null value: %q", null)
+		}
+		if o.Null != "" {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "Null", o.Null, null)
+		}
+		o.Null = null
+		bOk = true
+	}
+
+	// Inline
+	if option == "inline" {
+		if o.Inline {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "Inline", o.Inline, true)
+		}
+		o.Inline = true
+		bOk = true
+	}
+
+	// Namespace
+	if ns, ok := strings.CutPrefix(option, "ns="); ok {
+		if name := strings.TrimSuffix(ns, "[]"); name == "" || strings.ContainsAny(name, "[]") {
+			return false, fmt.Errorf("invalid namespace: %q", ns)
+		}
+		if o.Namespace != "" {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "Namespace", o.Namespace, ns)
+		}
+		o.Namespace = ns
+		bOk = true
+	}
+
+	// OptionSliceKeys
+	if value, err := OptionSliceKeysFromString(option); err == nil {
+		if o.SliceKeys != OptionSliceKeysSKUnspecified {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "OptionSliceKeys", o.SliceKeys, value)
+		}
+		o.SliceKeys = value
+		bOk = true
+	}
+
+	// OptionSliceDuplicates
+	if value, err := OptionSliceDuplicatesFromString(option); err == nil {
+		if o.SliceDuplicates != OptionSliceDuplicatesSDUnspecified {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "OptionSliceDuplicates", o.SliceDuplicates, value)
+		}
+		o.SliceDuplicates = value
+		bOk = true
+	}
+
 	return bOk, nil
 }
 
 func NewUndefinedCommonTagOptions() *CommonTagOptions {
 	return &CommonTagOptions{
-		SliceSeparator: OptionSliceSeparatorUnspecified,
+		SliceSeparator:  OptionSliceSeparatorUnspecified,
+		SliceKeys:       OptionSliceKeysSKUnspecified,
+		SliceDuplicates: OptionSliceDuplicatesSDUnspecified,
+	}
+}
+
+// separator returns the slice separator and reports whether it is a custom
+// separator whose occurrences in the items have to be escaped.
+func (o *CommonTagOptions) separator() (string, bool) {
+	switch o.SliceSeparator {
+	case OptionSliceSeparatorNone:
+		return o.CustomSeparator, o.CustomSeparator != ""
+	case OptionSliceSeparatorComma:
+		return ",", false
+	case OptionSliceSeparatorSemicolon:
+		return ";", false
+	case OptionSliceSeparatorSpace:
+		return " ", false
+	default:
+		panic(fmt.Sprintf("unexpected qs.OptionSliceSeparator: %#v", o.SliceSeparator))
+	}
+}
+
+// escapeSeparator escapes the backslashes and the occurrences of sep in s.
+func escapeSeparator(s, sep string) string {
+	if !strings.Contains(s, sep) && !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for len(s) != 0 {
+		switch {
+		case strings.HasPrefix(s, sep):
+			b.WriteByte('\\')
+			b.WriteString(sep)
+			s = s[len(sep):]
+		case s[0] == '\\':
+			b.WriteString(`\\`)
+			s = s[1:]
+		default:
+			b.WriteByte(s[0])
+			s = s[1:]
+		}
+	}
+	return b.String()
+}
+
+// splitEscaped splits s at the unescaped occurrences of sep and unescapes the
+// items.
+func splitEscaped(s, sep string) []string {
+	var items []string
+	var b strings.Builder
+	for len(s) != 0 {
+		switch {
+		case s[0] == '\\' && len(s) > 1:
+			if strings.HasPrefix(s[1:], sep) {
+				b.WriteString(sep)
+				s = s[1+len(sep):]
+			} else {
+				b.WriteByte(s[1])
+				s = s[2:]
+			}
+		case strings.HasPrefix(s, sep):
+			items = append(items, b.String())
+			b.Reset()
+			s = s[len(sep):]
+		default:
+			b.WriteByte(s[0])
+			s = s[1:]
+		}
+	}
+	return append(items, b.String())
+}
+
+// uniqueItems returns the items of a without the duplicates of preceding
+// items. a itself is returned if it has no duplicates, otherwise a new slice.
+func uniqueItems(a []string) []string {
+	if len(a) < 2 {
+		return a
+	}
+	seen := make(map[string]struct{}, len(a))
+	var u []string
+	for i, s := range a {
+		if _, ok := seen[s]; ok {
+			if u == nil {
+				u = append(make([]string, 0, len(a)-1), a[:i]...)
+			}
+			continue
+		}
+		seen[s] = struct{}{}
+		if u != nil {
+			u = append(u, s)
+		}
+	}
+	if u == nil {
+		return a
 	}
+	return u
 }
@@ -1,6 +1,10 @@
 package qs
 
 import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
 	"reflect"
 	"strings"
 	"testing"
@@ -18,78 +22,78 @@ func TestParseTag_DefaultPresence(t *testing.T) {
 	testCases := []defaultPresenceTestCase{
 		{
 			`qs:"name"`,
-			MarshalTagOptions{MarshalPresenceKeepEmpty},
-			MarshalTagOptions{MarshalPresenceKeepEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceKeepEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceKeepEmpty},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceNil},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceNil},
 		},
 		{
 			`qs:"name"`,
-			MarshalTagOptions{MarshalPresenceOmitEmpty},
-			MarshalTagOptions{MarshalPresenceOmitEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceOmitEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceOmitEmpty},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceOpt},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceOpt},
 		},
 		{
 			`qs:"name"`,
-			MarshalTagOptions{MarshalPresenceOmitEmpty},
-			MarshalTagOptions{MarshalPresenceOmitEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceOmitEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceOmitEmpty},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceReq},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceReq},
 		},
 		{
 			`qs:"name,omitempty"`,
-			MarshalTagOptions{MarshalPresenceKeepEmpty},
-			MarshalTagOptions{MarshalPresenceOmitEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceKeepEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceOmitEmpty},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceNil},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceNil},
 		},
 		{
 			`qs:"name,keepempty"`,
-			MarshalTagOptions{MarshalPresenceKeepEmpty},
-			MarshalTagOptions{MarshalPresenceKeepEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceKeepEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceKeepEmpty},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceNil},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceNil},
 		},
 		{
 			`qs:"name,omitempty"`,
-			MarshalTagOptions{MarshalPresenceOmitEmpty},
-			MarshalTagOptions{MarshalPresenceOmitEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceOmitEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceOmitEmpty},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceNil},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceNil},
 		},
 		{
 			`qs:"name,keepempty"`,
-			MarshalTagOptions{MarshalPresenceOmitEmpty},
-			MarshalTagOptions{MarshalPresenceKeepEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceOmitEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceKeepEmpty},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceNil},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceNil},
 		},
 		{
 			`qs:"name,nil"`,
-			MarshalTagOptions{MarshalPresenceKeepEmpty},
-			MarshalTagOptions{MarshalPresenceKeepEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceKeepEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceKeepEmpty},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceOpt},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceNil},
 		},
 		{
 			`qs:"name,opt"`,
-			MarshalTagOptions{MarshalPresenceKeepEmpty},
-			MarshalTagOptions{MarshalPresenceKeepEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceKeepEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceKeepEmpty},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceNil},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceOpt},
 		},
 		{
 			`qs:"name,req"`,
-			MarshalTagOptions{MarshalPresenceKeepEmpty},
-			MarshalTagOptions{MarshalPresenceKeepEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceKeepEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceKeepEmpty},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceNil},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceReq},
 		},
 		{
 			`qs:"name,keepempty,opt"`,
-			MarshalTagOptions{MarshalPresenceOmitEmpty},
-			MarshalTagOptions{MarshalPresenceKeepEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceOmitEmpty},
+			MarshalTagOptions{Presence: MarshalPresenceKeepEmpty},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceNil},
 			UnmarshalTagOptions{Presence: UnmarshalPresenceOpt},
 		},
@@ -107,7 +111,7 @@ func TestParseTag_DefaultPresence(t *testing.T) {
 				tc.defaultMO.InitDefaults()
 				tc.mo.ApplyDefaults(&tc.defaultMO)
 
-				tag, err := parseFieldTag(tc.tagStr, &tc.defaultMO, &tc.defaultUO, defaultCommon)
+				tag, err := parseFieldTag(tc.tagStr, tagKey, &tc.defaultMO, &tc.defaultUO, defaultCommon)
 				if err != nil {
 					t.Errorf("unexpected error - tag: %q :: %v", tc.tagStr, err)
 					return
@@ -151,7 +155,7 @@ func TestParseTag_SurplusComma(t *testing.T) {
 	defaultMO.InitDefaults()
 
 	for _, tagStr := range tagStrList {
-		_, err := parseFieldTag(tagStr, defaultMO, defaultUO, defaultCommon)
+		_, err := parseFieldTag(tagStr, tagKey, defaultMO, defaultUO, defaultCommon)
 		if err == nil {
 			t.Errorf("unexpected success - tag: %q", tagStr)
 			continue
@@ -190,7 +194,7 @@ func TestParseTag_IncompatibleOptions(t *testing.T) {
 	defaultMO.InitDefaults()
 
 	for _, tagStr := range tagStrList {
-		_, err := parseFieldTag(tagStr, defaultMO, defaultUO, defaultCommon)
+		_, err := parseFieldTag(tagStr, tagKey, defaultMO, defaultUO, defaultCommon)
 		if err == nil {
 			t.Errorf("unexpected success - tag: %q", tagStr)
 			continue
@@ -244,3 +248,933 @@ func TestSnakeCase(t *testing.T) {
 		}
 	}
 }
+
+func TestStructTagDefaults(t *testing.T) {
+	type query struct {
+		_    struct{} `qs:"opts:omitempty,req,comma"`
+		Page int
+		Sort string   `qs:",keepempty,opt"`
+		Tags []string `qs:"tag"`
+	}
+
+	vs, err := MarshalValues(&query{Page: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := vs["sort"]; !ok || len(vs) != 2 {
+		t.Errorf("unexpected result: %v", vs)
+	}
+
+	var q query
+	if err := Unmarshal(&q, "page=1&tag=a,b"); err != nil {
+		t.Fatal(err)
+	}
+	if q.Page != 1 || !reflect.DeepEqual(q.Tags, []string{"a", "b"}) {
+		t.Errorf("unexpected result: %+v", q)
+	}
+	if err := Unmarshal(&q, "tag=a"); err == nil {
+		t.Error("unexpected success")
+	}
+
+	type invalid struct {
+		_    struct{} `qs:"opts:woof"`
+		Page int
+	}
+	if err := CheckMarshal(&invalid{}); err == nil {
+		t.Error("unexpected success")
+	}
+
+	type twoMarkers struct {
+		_ struct{} `qs:"opts:omitempty"`
+		_ struct{} `qs:"opts:req"`
+	}
+	if err := CheckUnmarshal(&twoMarkers{}); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestTagFallbackKeys(t *testing.T) {
+	type query struct {
+		PageSize int    `json:"size,omitempty"`
+		Sort     string `json:"-" form:"sort"`
+		Filter   string `form:"f" qs:"filter"`
+		Cursor   string `json:",omitempty"`
+		Internal string `json:"-"`
+	}
+
+	m := NewMarshaler(nil, WithMarshalTagFallbackKeys("json", "form"))
+	vs, err := m.MarshalValues(&query{PageSize: 10, Sort: "name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{"size": {"10"}, "filter": {""}, "cursor": {""}}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	um := NewUnmarshaler(nil, WithUnmarshalTagFallbackKeys("form", "json"))
+	var q query
+	if err := um.Unmarshal(&q, "size=5&sort=name&filter=x&internal=y"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (query{PageSize: 5, Sort: "name", Filter: "x"}); q != want {
+		t.Errorf("got %+v, want %+v", q, want)
+	}
+
+	// The default marshaler ignores the fallback tags.
+	vs, err = MarshalValues(&query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := vs["page_size"]; !ok {
+		t.Errorf("unexpected result: %v", vs)
+	}
+}
+
+func TestURLCompat(t *testing.T) {
+	type query struct {
+		Q       string   `url:"q"`
+		Page    int      `url:"page,omitempty"`
+		IDs     []int    `url:"ids,comma"`
+		Terms   []string `url:"terms,space"`
+		Tags    []string `url:"tags,brackets"`
+		Sizes   []string `url:"size,numbered"`
+		Skipped string   `url:"-"`
+		Raw     string
+	}
+
+	m := NewMarshaler(nil, WithMarshalURLCompat())
+	q := query{
+		Q:     "foo",
+		IDs:   []int{1, 2},
+		Terms: []string{"a", "b"},
+		Tags:  []string{"x", "y"},
+		Sizes: []string{"s", "m"},
+		Raw:   "r",
+	}
+	s, err := m.Marshal(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Raw=r&ids=1%2C2&q=foo&size0=s&size1=m&tags%5B%5D=x&tags%5B%5D=y&terms=a+b"
+	if s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+
+	um := NewUnmarshaler(nil, WithUnmarshalURLCompat())
+	var q2 query
+	if err := um.Unmarshal(&q2, s); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(q2, q) {
+		t.Errorf("got %+v, want %+v", q2, q)
+	}
+}
+
+func TestCustomSliceSeparator(t *testing.T) {
+	type query struct {
+		IDs   []string `qs:"ids,sep=|"`
+		Words []string `qs:"words,sep=::"`
+		Tabs  []int    `qs:"tabs,sep=\t"`
+	}
+
+	q := query{
+		IDs:   []string{"a|b", `c\d`, ""},
+		Words: []string{"x", "y:z"},
+		Tabs:  []int{1, 2},
+	}
+	vs, err := MarshalValues(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{"ids": {`a\|b|c\\d|`}, "words": {"x::y:z"}, "tabs": {"1\t2"}}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	var q2 query
+	if err := UnmarshalValues(&q2, vs); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(q2, q) {
+		t.Errorf("got %+v, want %+v", q2, q)
+	}
+
+	m := NewMarshaler(nil, WithMarshalCustomSliceSeparator("|"))
+	vs, err = m.MarshalValues(&struct{ A []int }{A: []int{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (url.Values{"a": {"1|2"}}); !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	for _, tag := range []reflect.StructTag{`qs:"a,sep="`, `qs:"a,sep=\\"`, `qs:"a,comma,sep=|"`, `qs:"a,sep=|,comma"`} {
+		if _, err := parseFieldTag(tag, tagKey, NewUndefinedMarshalTagOptions(), NewUndefinedUnmarshalTagOptions(), NewUndefinedCommonTagOptions()); err == nil {
+			t.Errorf("%s :: unexpected success", tag)
+		}
+	}
+}
+
+func TestNamedCodec(t *testing.T) {
+	type query struct {
+		Token  string  `qs:"token,codec=upper"`
+		PToken *string `qs:"ptoken,codec=upper"`
+		Plain  string
+	}
+
+	m := NewMarshaler(nil)
+	um := NewUnmarshaler(nil)
+	if err := m.CheckMarshal(&query{}); err == nil || !strings.Contains(err.Error(), `unknown codec: "upper"`) {
+		t.Errorf("got error %v, want unknown codec error", err)
+	}
+
+	err := m.RegisterNamedCodec("upper", func(v reflect.Value, opts *MarshalOptions) (string, error) {
+		return strings.ToUpper(v.String()), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = um.RegisterNamedCodec("upper", func(v reflect.Value, s string, opts *UnmarshalOptions) error {
+		v.SetString(strings.ToLower(s))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := "b"
+	vs, err := m.MarshalValues(&query{Token: "a", PToken: &p, Plain: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{"token": {"A"}, "ptoken": {"B"}, "plain": {"c"}}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	var q query
+	if err := um.UnmarshalValues(&q, url.Values{"token": {"X"}, "ptoken": {"Y"}, "plain": {"Z"}}); err != nil {
+		t.Fatal(err)
+	}
+	if q.Token != "x" || q.PToken == nil || *q.PToken != "y" || q.Plain != "Z" {
+		t.Errorf("unexpected result: %+v", q)
+	}
+
+	for _, name := range []string{"", "a,b", "a=b"} {
+		if err := m.RegisterNamedCodec(name, func(v reflect.Value, opts *MarshalOptions) (string, error) { return "", nil }); err == nil {
+			t.Errorf("RegisterNamedCodec(%q) :: unexpected success", name)
+		}
+	}
+	type invalid struct {
+		A string `qs:",codec=upper,codec=lower"`
+	}
+	if err := m.CheckMarshal(&invalid{}); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestByteCodecs(t *testing.T) {
+	type query struct {
+		Hex          []byte   `qs:",hex"`
+		Base64       []byte   `qs:",base64"`
+		Base64URL    []byte   `qs:",base64url"`
+		RawBase64    []byte   `qs:",rawbase64"`
+		RawBase64URL *[]byte  `qs:",codec=rawbase64url"`
+		Array        [3]byte  `qs:",hex"`
+		Named        MQSBytes `qs:",hex"`
+	}
+
+	b := []byte{0xfb, 0xff}
+	q := query{
+		Hex:          b,
+		Base64:       b,
+		Base64URL:    b,
+		RawBase64:    b,
+		RawBase64URL: &b,
+		Array:        [3]byte{1, 2, 3},
+		Named:        MQSBytes{4},
+	}
+	vs, err := MarshalValues(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{
+		"hex":           {"fbff"},
+		"base64":        {"+/8="},
+		"base64url":     {"-_8="},
+		"raw_base64":    {"+/8"},
+		"raw_base64url": {"-_8"},
+		"array":         {"010203"},
+		"named":         {"04"},
+	}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	var q2 query
+	if err := UnmarshalValues(&q2, vs); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(q2, q) {
+		t.Errorf("got %+v, want %+v", q2, q)
+	}
+
+	for _, s := range []string{"hex=zz", "array=01", "base64=-_8="} {
+		if err := Unmarshal(&q2, s); err == nil {
+			t.Errorf("Unmarshal(%q) :: unexpected success", s)
+		}
+	}
+
+	type invalid struct {
+		A string `qs:",hex"`
+	}
+	if _, err := MarshalValues(&invalid{}); err == nil {
+		t.Error("unexpected success")
+	}
+	type duplicate struct {
+		A []byte `qs:",hex,base64"`
+	}
+	if err := CheckMarshal(&duplicate{}); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestJSONCodec(t *testing.T) {
+	type filter struct {
+		A int      `json:"a"`
+		B []string `json:"b,omitempty"`
+	}
+	type query struct {
+		Filter  filter            `qs:"filter,json"`
+		Extra   map[string]int    `qs:"extra,json,omitempty"`
+		Nilable *filter           `qs:"nilable,codec=json"`
+		Labels  map[string]string `qs:"labels,json"`
+	}
+
+	q := query{Filter: filter{A: 1}, Labels: map[string]string{"x": "y"}}
+	s, err := Marshal(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "filter=%7B%22a%22%3A1%7D&labels=%7B%22x%22%3A%22y%22%7D"
+	if s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+
+	var q2 query
+	if err := Unmarshal(&q2, `filter={"a":2,"b":["x"]}&nilable={"a":3}&extra={"k":4}`); err != nil {
+		t.Fatal(err)
+	}
+	want2 := query{
+		Filter:  filter{A: 2, B: []string{"x"}},
+		Extra:   map[string]int{"k": 4},
+		Nilable: &filter{A: 3},
+	}
+	if !reflect.DeepEqual(q2, want2) {
+		t.Errorf("got %+v, want %+v", q2, want2)
+	}
+
+	if err := Unmarshal(&q2, `filter={"a":"x"}`); err == nil {
+		t.Error("unexpected success")
+	}
+	type invalid struct {
+		C chan int `qs:",json"`
+	}
+	if _, err := Marshal(&invalid{C: make(chan int)}); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+// signTransformer is a ValueTransformer that appends a fake signature.
+type signTransformer struct{}
+
+func (signTransformer) Encode(s string) string {
+	return s + ".sig"
+}
+
+func (signTransformer) Decode(s string) (string, error) {
+	v, ok := strings.CutSuffix(s, ".sig")
+	if !ok {
+		return "", fmt.Errorf("invalid signature: %q", s)
+	}
+	return v, nil
+}
+
+func TestValueTransformer(t *testing.T) {
+	type query struct {
+		Cursor string   `qs:"cursor,transform=sign"`
+		IDs    []int    `qs:"ids,comma,transform=sign"`
+		Token  []byte   `qs:"token,hex,transform=sign"`
+		Opt    *string  `qs:"opt,transform=sign"`
+		Plain  []string `qs:"plain"`
+	}
+
+	m := NewMarshaler(nil)
+	um := NewUnmarshaler(nil)
+	if err := um.CheckUnmarshal(&query{}); err == nil || !strings.Contains(err.Error(), `unknown value transformer: "sign"`) {
+		t.Errorf("got error %v, want unknown value transformer error", err)
+	}
+	if err := m.RegisterValueTransformer("sign", signTransformer{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := um.RegisterValueTransformer("sign", signTransformer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	q := query{Cursor: "c", IDs: []int{1, 2}, Token: []byte{0xab}, Opt: new(string), Plain: []string{"p"}}
+	vs, err := m.MarshalValues(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{"cursor": {"c.sig"}, "ids": {"1,2.sig"}, "token": {"ab.sig"}, "opt": {".sig"}, "plain": {"p"}}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	var q2 query
+	if err := um.UnmarshalValues(&q2, vs); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(q2, q) {
+		t.Errorf("got %+v, want %+v", q2, q)
+	}
+
+	if err := um.Unmarshal(&q2, "cursor=forged"); err == nil || !strings.Contains(err.Error(), "invalid signature") {
+		t.Errorf("got error %v, want invalid signature error", err)
+	}
+	if err := m.RegisterValueTransformer("a=b", signTransformer{}); err == nil {
+		t.Error("unexpected success")
+	}
+
+	type nested struct {
+		A nestingAddress `qs:"a,transform=sign"`
+	}
+	if err := NewUnmarshaler(nil, WithUnmarshalNesting(NestingModeDots)).CheckUnmarshal(&nested{}); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestQueryEncoding(t *testing.T) {
+	vs := url.Values{"k y": {"a b+c~*'!;/"}, "e": {""}}
+	tests := []struct {
+		enc  QueryEncoding
+		want string
+	}{
+		{QueryEncodingGo, "e=&k+y=a+b%2Bc~%2A%27%21%3B%2F"},
+		{QueryEncodingHTML5Form, "e=&k+y=a+b%2Bc%7E*%27%21%3B%2F"},
+		{QueryEncodingRFC3986, "e=&k%20y=a%20b%2Bc~%2A%27%21%3B%2F"},
+		{QueryEncodingLegacyPHP, "e=&k+y=a+b%2Bc%7E%2A%27%21%3B%2F"},
+	}
+	for _, tc := range tests {
+		s := tc.enc.Encode(vs)
+		if s != tc.want {
+			t.Errorf("%+v :: got %q, want %q", tc.enc, s, tc.want)
+		}
+		parsed, err := tc.enc.Parse(s)
+		if err != nil {
+			t.Errorf("%+v :: %v", tc.enc, err)
+		} else if !reflect.DeepEqual(parsed, vs) {
+			t.Errorf("%+v :: got %v, want %v", tc.enc, parsed, vs)
+		}
+	}
+	if s := QueryEncodingGo.Encode(vs); s != vs.Encode() {
+		t.Errorf("got %q, want %q", s, vs.Encode())
+	}
+
+	parsed, err := QueryEncodingRFC3986.Parse("a=1+2&b=%zz&c=3;d=4")
+	if err == nil {
+		t.Error("unexpected success")
+	}
+	if want := (url.Values{"a": {"1+2"}}); !reflect.DeepEqual(parsed, want) {
+		t.Errorf("got %v, want %v", parsed, want)
+	}
+	parsed, err = QueryEncodingLegacyPHP.Parse("a=1+2;b=%41&&c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (url.Values{"a": {"1 2"}, "b": {"A"}, "c": {""}}); !reflect.DeepEqual(parsed, want) {
+		t.Errorf("got %v, want %v", parsed, want)
+	}
+
+	type query struct {
+		A string
+	}
+	m := NewMarshaler(nil, WithMarshalQueryEncoding(QueryEncodingRFC3986))
+	s, err := m.Marshal(&query{A: "x y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "a=x%20y" {
+		t.Errorf("got %q", s)
+	}
+	var q query
+	um := NewUnmarshaler(nil, WithUnmarshalQueryEncoding(QueryEncodingRFC3986))
+	if err := um.Unmarshal(&q, "a=x+y"); err != nil {
+		t.Fatal(err)
+	}
+	if q.A != "x+y" {
+		t.Errorf("got %q", q.A)
+	}
+}
+
+func TestPrecompile(t *testing.T) {
+	type Valid struct {
+		A string `qs:"a"`
+	}
+	type Invalid struct {
+		A string    `qs:"a"`
+		C chan bool `qs:"c"`
+	}
+
+	RegisterQueryTypes(Valid{})
+	if err := Precompile(); err != nil {
+		t.Fatal(err)
+	}
+	if err := Precompile(&Valid{}, Invalid{}); err == nil {
+		t.Error("unexpected success")
+	}
+
+	m := NewMarshaler(nil, WithMarshalLazyFields(true))
+	if err := m.CheckMarshalType(reflect.TypeFor[Invalid]()); err != nil {
+		t.Fatalf("lazy fields aren't compiled by CheckMarshalType: %v", err)
+	}
+	if err := m.Precompile(reflect.TypeFor[Invalid]()); err == nil {
+		t.Error("unexpected success")
+	}
+	um := NewUnmarshaler(nil, WithUnmarshalLazyFields(true))
+	if err := um.Precompile(reflect.TypeFor[Invalid]()); err == nil {
+		t.Error("unexpected success")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("MustPrecompile didn't panic")
+		}
+	}()
+	MustPrecompile(Invalid{})
+}
+
+func TestLintStruct(t *testing.T) {
+	type Paging struct {
+		Page  int `qs:"page"`
+		Limit int `qs:"limit,omitempty,keepempty"`
+	}
+	type Address struct {
+		City string `qs:"city,bogus"`
+	}
+	type Query struct {
+		_       struct{} `qs:"opts:omitempty"`
+		Paging  `qs:""`
+		Search  string   `qs:"q"`
+		Other   string   `qs:"q"`
+		Count   int      `qs:"page,opt,req"`
+		hidden  string   `qs:"hidden"`
+		Address *Address `qs:"address"`
+		Skipped string   `qs:"-"`
+		Tags    []string `qs:"tags,"`
+	}
+
+	want := []LintIssue{
+		{reflect.TypeFor[Paging](), "Paging.Limit", fmt.Sprintf(fmtOptionNotUniqueError, "OptionPresence", MarshalPresenceOmitEmpty, MarshalPresenceKeepEmpty)},
+		{reflect.TypeFor[Query](), "Other", `duplicate key "q" (used by field Search)`},
+		{reflect.TypeFor[Query](), "Count", fmt.Sprintf(fmtOptionNotUniqueError, "UnmarshalPresence", UnmarshalPresenceOpt, UnmarshalPresenceReq)},
+		{reflect.TypeFor[Query](), "Count", `duplicate key "page" (used by field Paging.Page)`},
+		{reflect.TypeFor[Query](), "hidden", "unexported field has a qs tag"},
+		{reflect.TypeFor[Address](), "Address.City", `unknown option "bogus"`},
+		{reflect.TypeFor[Query](), "Tags", "tag string contains a surplus comma"},
+	}
+	if got := LintStruct(reflect.TypeFor[*Query]()); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got := LintStruct(reflect.TypeFor[Paging]()); len(got) != 1 {
+		t.Errorf("got %v", got)
+	}
+	if got := LintStruct(reflect.TypeFor[int]()); len(got) != 1 {
+		t.Errorf("got %v", got)
+	}
+}
+
+// testDecimal is a fixed-point decimal with two fractional digits that
+// implements encoding.TextMarshaler and encoding.TextUnmarshaler like the
+// decimal types of third-party packages.
+type testDecimal struct {
+	cents int64
+}
+
+func (d testDecimal) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d.%02d", d.cents/100, d.cents%100)), nil
+}
+
+func (d *testDecimal) UnmarshalText(b []byte) error {
+	units, frac, ok := strings.Cut(string(b), ".")
+	if !ok || len(frac) != 2 {
+		return fmt.Errorf("invalid decimal: %q", b)
+	}
+	var u, f int64
+	if _, err := fmt.Sscanf(units+" "+frac, "%d %d", &u, &f); err != nil {
+		return fmt.Errorf("invalid decimal: %q", b)
+	}
+	d.cents = u*100 + f
+	return nil
+}
+
+func TestTextMarshalerTypes(t *testing.T) {
+	type query struct {
+		Price  testDecimal
+		Max    *testDecimal
+		Prices []testDecimal
+	}
+
+	q := query{
+		Price:  testDecimal{1099},
+		Max:    &testDecimal{2000},
+		Prices: []testDecimal{{1}, {250}},
+	}
+	vs, err := MarshalValues(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{"price": {"10.99"}, "max": {"20.00"}, "prices": {"0.01", "2.50"}}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	var q2 query
+	if err := UnmarshalValues(&q2, vs); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(q2, q) {
+		t.Errorf("got %+v, want %+v", q2, q)
+	}
+
+	if err := Unmarshal(&q2, "price=10.9"); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	_, err := Marshal(struct {
+		C chan int `qs:"c"`
+	}{})
+	var ute *UnhandledTypeError
+	if !errors.Is(err, ErrUnhandledType) || !errors.As(err, &ute) || ute.Type.Kind() != reflect.Chan {
+		t.Errorf("got %v, want an unhandled type error", err)
+	}
+
+	var q struct {
+		ID int `qs:"id,req"`
+	}
+	err = fmt.Errorf("wrapped :: %w", Unmarshal(&q, ""))
+	if !errors.Is(err, ErrRequiredField) || errors.Is(err, ErrUnhandledType) {
+		t.Errorf("got %v, want a required field error", err)
+	}
+	if name, ok := IsRequiredFieldError(err); !ok || name != "id" {
+		t.Errorf("got %q, %v, want %q, true", name, ok, "id")
+	}
+
+	err = NewUnmarshaler(nil, WithUnmarshalMaxKeys(1)).Unmarshal(&q, "id=1&x=2")
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("got %v, want a limit exceeded error", err)
+	}
+
+	_, err = newStructUnmarshaler(reflect.TypeOf(0), &UnmarshalerDefaultOptions{})
+	if !errors.Is(err, ErrWrongKind) || errors.Is(err, ErrWrongType) {
+		t.Errorf("got %v, want a wrong kind error", err)
+	}
+
+	err = (&structUnmarshaler{Type: reflect.TypeOf(q)}).UnmarshalValues(reflect.ValueOf(0), nil, &UnmarshalerDefaultOptions{})
+	if !errors.Is(err, ErrWrongType) {
+		t.Errorf("got %v, want a wrong type error", err)
+	}
+}
+
+func TestUnexportedEmbeddedStruct(t *testing.T) {
+	type paging struct {
+		Page  int `qs:"page"`
+		limit int
+	}
+	type sorting struct {
+		Sort string `qs:"sort"`
+	}
+	type level int
+	type query struct {
+		paging
+		*sorting
+		level
+		Name string `qs:"name"`
+	}
+
+	q := query{paging: paging{Page: 2, limit: 10}, sorting: &sorting{Sort: "name"}, level: 3, Name: "a"}
+	s, err := Marshal(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "name=a&page=2&sort=name"; s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+
+	var got query
+	got.sorting = &sorting{}
+	if err := Unmarshal(&got, "name=b&page=3&sort=id&level=4"); err != nil {
+		t.Fatal(err)
+	}
+	if got.Page != 3 || got.Sort != "id" || got.level != 0 || got.Name != "b" {
+		t.Errorf("got %+v", got)
+	}
+
+	if issues := LintStruct(reflect.TypeFor[query]()); len(issues) != 0 {
+		t.Errorf("unexpected issues: %v", issues)
+	}
+}
+
+func TestPromotedFields(t *testing.T) {
+	type Inner struct {
+		Page int `qs:"page,req"`
+	}
+	type Middle struct {
+		Inner
+		Sort string `qs:"sort"`
+	}
+	type Extra struct {
+		Debug bool `qs:"debug"`
+	}
+	type Limits struct {
+		Limit int `qs:"limit"`
+	}
+	type query struct {
+		Middle
+		Extra `qs:",inline"`
+		*Limits
+		Name string `qs:"name"`
+	}
+
+	vm, err := DefaultMarshaler.CompileType(reflect.TypeFor[query]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm := vm.(*structMarshaler)
+	var indices [][]int
+	for _, fm := range sm.Fields {
+		indices = append(indices, fm.Index)
+	}
+	if want := [][]int{{0, 0, 0}, {0, 1}, {1, 0}, {3}}; !reflect.DeepEqual(indices, want) {
+		t.Errorf("got field indices %v, want %v", indices, want)
+	}
+	if len(sm.EmbeddedFields) != 1 || !reflect.DeepEqual(sm.EmbeddedFields[0].Index, []int{2}) {
+		t.Errorf("got embedded fields %+v", sm.EmbeddedFields)
+	}
+
+	for _, lazy := range []bool{false, true} {
+		q := query{Middle: Middle{Inner: Inner{Page: 2}, Sort: "a"}, Extra: Extra{Debug: true}, Name: "b"}
+		var owners []reflect.Type
+		m := NewMarshaler(nil, WithMarshalLazyFields(lazy), WithMarshalFieldFilter(func(info FieldInfo, v reflect.Value) bool {
+			owners = append(owners, info.Struct)
+			return true
+		}))
+		s, err := m.Marshal(&q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "debug=true&name=b&page=2&sort=a"; s != want {
+			t.Errorf("lazy=%v :: got %q, want %q", lazy, s, want)
+		}
+		wantOwners := []reflect.Type{reflect.TypeFor[Inner](), reflect.TypeFor[Middle](), reflect.TypeFor[Extra](), reflect.TypeFor[query]()}
+		if !reflect.DeepEqual(owners, wantOwners) {
+			t.Errorf("lazy=%v :: got owners %v, want %v", lazy, owners, wantOwners)
+		}
+
+		var got query
+		um := NewUnmarshaler(nil, WithUnmarshalLazyFields(lazy))
+		if err := um.Unmarshal(&got, s); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, q) {
+			t.Errorf("lazy=%v :: got %+v, want %+v", lazy, got, q)
+		}
+
+		err = um.Unmarshal(&got, "name=c")
+		if name, ok := IsRequiredFieldError(err); !ok || name != "page" {
+			t.Errorf("lazy=%v :: got %v, want a required field error of page", lazy, err)
+		}
+	}
+}
+
+func TestSkipUnsupportedFields(t *testing.T) {
+	type badTagQuery struct {
+		Hook func() string `qs:"hook"`
+		Bad  string        `qs:"bad,bogus"`
+	}
+	type validQuery struct {
+		Name   string   `qs:"name"`
+		Events chan int `qs:"events"`
+	}
+
+	if _, err := Marshal(&validQuery{}); !errors.Is(err, ErrUnhandledType) {
+		t.Errorf("got %v, want an unhandled type error", err)
+	}
+
+	for _, lazy := range []bool{false, true} {
+		var skipped []string
+		onSkipped := func(st reflect.Type, field reflect.StructField, err error) {
+			if st != reflect.TypeFor[validQuery]() || !errors.Is(err, ErrUnhandledType) {
+				t.Errorf("lazy=%v :: unexpected callback args %v, %v", lazy, st, err)
+			}
+			skipped = append(skipped, field.Name)
+		}
+
+		m := NewMarshaler(nil, WithMarshalLazyFields(lazy), WithMarshalSkipUnsupportedFields(onSkipped))
+		for range 2 {
+			s, err := m.Marshal(&validQuery{Name: "a", Events: make(chan int)})
+			if err != nil || s != "name=a" {
+				t.Errorf("lazy=%v :: got %q, %v", lazy, s, err)
+			}
+		}
+
+		um := NewUnmarshaler(nil, WithUnmarshalLazyFields(lazy), WithUnmarshalSkipUnsupportedFields(onSkipped))
+		var q validQuery
+		if err := um.Unmarshal(&q, "name=b&events=1"); err != nil || q.Name != "b" || q.Events != nil {
+			t.Errorf("lazy=%v :: got %+v, %v", lazy, q, err)
+		}
+		if err := um.Precompile(reflect.TypeFor[validQuery]()); err != nil {
+			t.Errorf("lazy=%v :: unexpected error: %v", lazy, err)
+		}
+
+		if want := []string{"Events", "Events"}; !reflect.DeepEqual(skipped, want) {
+			t.Errorf("lazy=%v :: got skipped fields %v, want %v", lazy, skipped, want)
+		}
+
+		// The tag errors aren't ignored.
+		m = NewMarshaler(nil, WithMarshalLazyFields(lazy), WithMarshalSkipUnsupportedFields(nil))
+		if _, err := m.Marshal(&badTagQuery{}); err == nil {
+			t.Errorf("lazy=%v :: unexpected success", lazy)
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	type diagQuery struct {
+		Name   string   `qs:"name,dupkeys=first"`
+		Small  int8     `qs:"small,clamp"`
+		IDs    []int    `qs:"ids,skip"`
+		Events chan int `qs:"events"`
+	}
+
+	var diags []Diagnostic
+	collect := func(d Diagnostic) { diags = append(diags, d) }
+
+	um := NewUnmarshaler(nil, WithUnmarshalSkipUnsupportedFields(nil), WithUnmarshalDiagnostics(collect))
+	var q diagQuery
+	if err := um.Unmarshal(&q, "name=a&name=b&small=300&ids=1&ids=x&zeta=1&alpha=2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.Name != "a" || q.Small != 127 || !reflect.DeepEqual(q.IDs, []int{1}) {
+		t.Errorf("got %+v", q)
+	}
+
+	type entry struct {
+		Kind DiagnosticKind
+		Key  string
+	}
+	var got []entry
+	for _, d := range diags {
+		if d.Message == "" {
+			t.Errorf("empty message of %+v", d)
+		}
+		got = append(got, entry{d.Kind, d.Key})
+	}
+	want := []entry{
+		{DiagnosticKindSkippedField, ""},
+		{DiagnosticKindUnknownKey, "alpha"},
+		{DiagnosticKindUnknownKey, "zeta"},
+		{DiagnosticKindDroppedValues, "name"},
+		{DiagnosticKindLenientParse, "small"},
+		{DiagnosticKindDroppedValues, "ids"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	diags = nil
+	m := NewMarshaler(nil, WithMarshalSkipUnsupportedFields(nil), WithMarshalDiagnostics(collect))
+	if _, err := m.Marshal(&q); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diags) != 1 || diags[0].Kind != DiagnosticKindSkippedField || diags[0].Type != reflect.TypeFor[diagQuery]() {
+		t.Errorf("got %+v", diags)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	type logQuery struct {
+		Name  string `qs:"name"`
+		Count int    `qs:"count"`
+	}
+
+	var buf strings.Builder
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	um := NewUnmarshaler(nil, WithUnmarshalLogger(logger))
+	for range 2 {
+		var q logQuery
+		if err := um.Unmarshal(&q, "count=1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	var q logQuery
+	if err := um.Unmarshal(&q, "name=a&count=x"); err == nil {
+		t.Fatal("unexpected success")
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`msg="qs: created" object=qs.ValuesUnmarshaler type=qs.logQuery`,
+		`msg="qs: cache hit" object=qs.ValuesUnmarshaler type=qs.logQuery`,
+		`msg="qs: no values for field" type=qs.logQuery key=name`,
+		`msg="qs: field unmarshaling failed" type=qs.logQuery key=count error=`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in the log:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	m := NewMarshaler(nil, WithMarshalLogger(logger))
+	if _, err := m.Marshal(&logQuery{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `msg="qs: created" object=qs.ValuesMarshaler type=qs.logQuery`; !strings.Contains(buf.String(), want) {
+		t.Errorf("missing %q in the log:\n%s", want, buf.String())
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	type filter struct {
+		Status string   `qs:"status,trim"`
+		Price  float64  `qs:"price,prec=2"`
+		Tags   []string `qs:"tag,omitempty"`
+		Page   *int     `qs:"page"`
+		Cached string   `qs:"-"`
+	}
+
+	f := filter{Status: "  open ", Price: 9.999, Tags: []string{}, Cached: "x"}
+	if err := Normalize(&f); err != nil {
+		t.Fatal(err)
+	}
+	if f.Status != "open" || f.Price != 10 || len(f.Tags) != 0 || f.Page == nil || *f.Page != 0 || f.Cached != "" {
+		t.Errorf("unexpected result: %+v", f)
+	}
+
+	if err := Normalize(filter{}); err == nil {
+		t.Error("unexpected success")
+	}
+
+	// The object isn't modified if unmarshaling fails.
+	m := NewMarshaler(nil, WithMarshalPresence(MarshalPresenceOmitEmpty))
+	um := NewUnmarshaler(nil, WithUnmarshalPresence(UnmarshalPresenceReq))
+	g := filter{Status: "open", Cached: "x"}
+	if err := NormalizeWith(m, um, &g); err == nil || g.Cached != "x" {
+		t.Errorf("got %+v, %v", g, err)
+	}
+}
@@ -0,0 +1,138 @@
+package qs
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ValueTransformer transforms the marshaled values of the struct fields that
+// select it with the transform tag option. It can be used to encrypt
+// pagination cursors or to sign parameters:
+//
+//	type Query struct {
+//		Cursor string `qs:"cursor,transform=seal"`
+//	}
+//
+//	err := qs.RegisterValueTransformer("seal", sealer)
+//
+// Encode is applied to every value of the field after marshaling it and
+// Decode to every value of the field before unmarshaling it. An error
+// returned by Decode (e.g. a tampered value) fails the unmarshaling.
+type ValueTransformer interface {
+	Encode(s string) string
+	Decode(s string) (string, error)
+}
+
+// RegisterValueTransformer registers a ValueTransformer with the given name
+// with the DefaultMarshaler and the DefaultUnmarshaler.
+func RegisterValueTransformer(name string, t ValueTransformer) error {
+	if err := DefaultMarshaler.RegisterValueTransformer(name, t); err != nil {
+		return err
+	}
+	return DefaultUnmarshaler.RegisterValueTransformer(name, t)
+}
+
+// RegisterValueTransformer registers a ValueTransformer with the given name.
+// A registration replaces the previous transformer with the same name.
+func (p *QSMarshaler) RegisterValueTransformer(name string, t ValueTransformer) error {
+	if err := checkValueTransformer(name, t); err != nil {
+		return err
+	}
+	p.opts.transformers.register(name, t)
+	p.purgeValuesCache()
+	return nil
+}
+
+// RegisterValueTransformer registers a ValueTransformer with the given name.
+// A registration replaces the previous transformer with the same name.
+func (p *QSUnmarshaler) RegisterValueTransformer(name string, t ValueTransformer) error {
+	if err := checkValueTransformer(name, t); err != nil {
+		return err
+	}
+	p.opts.transformers.register(name, t)
+	p.purgeValuesCache()
+	return nil
+}
+
+func checkValueTransformer(name string, t ValueTransformer) error {
+	if !validRegistryName(name) {
+		return fmt.Errorf("invalid value transformer name: %q", name)
+	}
+	if t == nil {
+		return fmt.Errorf("nil value transformer %q", name)
+	}
+	return nil
+}
+
+// transformMarshaler encodes the values of the wrapped Marshaler with a
+// ValueTransformer.
+type transformMarshaler struct {
+	Marshaler   Marshaler
+	Transformer ValueTransformer
+}
+
+// transformFieldMarshaler wraps m if the tag selects a ValueTransformer.
+func (o *MarshalOptions) transformFieldMarshaler(tag *ParsedTagInfo, m Marshaler) (Marshaler, error) {
+	name := tag.CommonOpts.Transform
+	if name == "" {
+		return m, nil
+	}
+	t, ok := o.transformers.lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown value transformer: %q", name)
+	}
+	return &transformMarshaler{
+		Marshaler:   m,
+		Transformer: t,
+	}, nil
+}
+
+func (p *transformMarshaler) Marshal(v reflect.Value, opts *MarshalOptions) ([]string, error) {
+	a, err := p.Marshaler.Marshal(v, opts)
+	if err != nil {
+		return nil, err
+	}
+	encoded := make([]string, len(a))
+	for i, s := range a {
+		encoded[i] = p.Transformer.Encode(s)
+	}
+	return encoded, nil
+}
+
+// transformUnmarshaler decodes the values with a ValueTransformer before
+// passing them to the wrapped Unmarshaler.
+type transformUnmarshaler struct {
+	Unmarshaler Unmarshaler
+	Transformer ValueTransformer
+}
+
+// transformFieldUnmarshaler wraps um if the tag selects a ValueTransformer.
+func (o *UnmarshalerDefaultOptions) transformFieldUnmarshaler(tag *ParsedTagInfo, um Unmarshaler) (Unmarshaler, error) {
+	name := tag.CommonOpts.Transform
+	if name == "" {
+		return um, nil
+	}
+	t, ok := o.transformers.lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown value transformer: %q", name)
+	}
+	return &transformUnmarshaler{
+		Unmarshaler: um,
+		Transformer: t,
+	}, nil
+}
+
+func (p *transformUnmarshaler) Unmarshal(v reflect.Value, a []string, opts *UnmarshalOptions) error {
+	if a == nil {
+		return p.Unmarshaler.Unmarshal(v, a, opts)
+	}
+	decoded := make([]string, len(a))
+	for i, s := range a {
+		d, err := p.Transformer.Decode(s)
+		if err != nil {
+			return err
+		}
+		decoded[i] = d
+	}
+	return p.Unmarshaler.Unmarshal(v, decoded, opts)
+}
@@ -0,0 +1,94 @@
+package qs
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// UTM holds the UTM parameters of the analytics tools (utm_source,
+// utm_medium, utm_campaign, utm_term and utm_content). Embed it into query
+// structs with the ns tag option to get the usual keys:
+//
+//	type Landing struct {
+//		qs.UTM `qs:",ns=utm"`
+//		Ref    string `qs:"ref,omitempty"`
+//	}
+//
+//	var q Landing
+//	err := qs.Unmarshal(&q, "utm_source=newsletter&utm_medium=email&ref=home")
+//
+// AppendUTM adds the parameters to the links of emails, ads and the like.
+type UTM struct {
+	// Source identifies the referrer, e.g. "newsletter" or "google".
+	Source string `qs:"source,omitempty"`
+
+	// Medium is the marketing medium, e.g. "email" or "cpc".
+	Medium string `qs:"medium,omitempty"`
+
+	// Campaign is the name of the campaign, e.g. "spring_sale".
+	Campaign string `qs:"campaign,omitempty"`
+
+	// Term is the paid search keyword.
+	Term string `qs:"term,omitempty"`
+
+	// Content differentiates the links of the same campaign, e.g. "header"
+	// and "footer".
+	Content string `qs:"content,omitempty"`
+}
+
+// utmQuery puts the keys of UTM into the utm namespace.
+type utmQuery struct {
+	UTM `qs:",ns=utm"`
+}
+
+// AppendUTM adds the UTM parameters to a URL with the DefaultMarshaler. See
+// QSMarshaler.AppendUTM.
+func AppendUTM(rawURL string, utm UTM) (string, error) {
+	return DefaultMarshaler.AppendUTM(rawURL, utm)
+}
+
+// AppendUTM adds the non-empty UTM parameters to the query string of the URL:
+//
+//	u, err := qs.AppendUTM("https://example.com/sale?id=7#top",
+//		qs.UTM{Source: "newsletter", Medium: "email"})
+//	// https://example.com/sale?id=7&utm_medium=email&utm_source=newsletter#top
+//
+// The UTM parameters already present in the URL are replaced by the
+// non-empty ones. The other parameters and the fragment of the URL are kept
+// as they are. The key prefix and suffix of the marshaler aren't added to
+// the UTM keys.
+func (p *QSMarshaler) AppendUTM(rawURL string, utm UTM) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %q :: %w", rawURL, err)
+	}
+	// The UTM keys are fixed by the analytics tools so the key prefix and
+	// suffix of the marshaler don't apply.
+	q := utmQuery{UTM: utm}
+	vm, err := p.CompileType(reflect.TypeOf(q))
+	if err != nil {
+		return "", err
+	}
+	vs, err := vm.MarshalValues(reflect.ValueOf(q), p.opts)
+	if err != nil {
+		return "", err
+	}
+	if len(vs) == 0 {
+		return u.String(), nil
+	}
+
+	var pairs []string
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		k, _, _ := strings.Cut(pair, "=")
+		if uk, err := url.QueryUnescape(k); err == nil && vs.Has(uk) {
+			continue
+		}
+		if pair != "" {
+			pairs = append(pairs, pair)
+		}
+	}
+	u.RawQuery = strings.Join(append(pairs, p._EncodeValues(vs)), "&")
+	return u.String(), nil
+}
@@ -0,0 +1,63 @@
+package qs
+
+import (
+	"testing"
+)
+
+func TestUTM(t *testing.T) {
+	type landing struct {
+		UTM `qs:",ns=utm"`
+		Ref string `qs:"ref,omitempty"`
+	}
+
+	var q landing
+	if err := Unmarshal(&q, "utm_source=newsletter&utm_medium=email&ref=home"); err != nil {
+		t.Fatal(err)
+	}
+	want := landing{UTM: UTM{Source: "newsletter", Medium: "email"}, Ref: "home"}
+	if q != want {
+		t.Errorf("got %+v, want %+v", q, want)
+	}
+
+	tests := []struct {
+		url  string
+		utm  UTM
+		want string
+	}{
+		{
+			url:  "https://example.com/sale?id=7#top",
+			utm:  UTM{Source: "newsletter", Medium: "email"},
+			want: "https://example.com/sale?id=7&utm_medium=email&utm_source=newsletter#top",
+		},
+		{
+			url:  "https://example.com/?utm_source=old&b=%20x&utm_term=kept",
+			utm:  UTM{Source: "new", Campaign: "spring sale"},
+			want: "https://example.com/?b=%20x&utm_term=kept&utm_campaign=spring+sale&utm_source=new",
+		},
+		{
+			url:  "https://example.com/path",
+			utm:  UTM{Content: "footer"},
+			want: "https://example.com/path?utm_content=footer",
+		},
+		{
+			url:  "https://example.com/path?a=1",
+			want: "https://example.com/path?a=1",
+		},
+	}
+	for _, tt := range tests {
+		got, err := AppendUTM(tt.url, tt.utm)
+		if err != nil || got != tt.want {
+			t.Errorf("AppendUTM(%q) = %q, %v, want %q", tt.url, got, err, tt.want)
+		}
+	}
+
+	m := NewMarshaler(nil, WithMarshalKeyPrefix("x_"), WithMarshalKeySuffix("_"))
+	got, err := m.AppendUTM("https://a/?utm_source=old&x_ref_=1", UTM{Source: "new"})
+	if want := "https://a/?x_ref_=1&utm_source=new"; err != nil || got != want {
+		t.Errorf("got %q, %v, want %q", got, err, want)
+	}
+
+	if _, err := AppendUTM("http://[::1", UTM{Source: "x"}); err == nil {
+		t.Error("expected an error")
+	}
+}
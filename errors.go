@@ -1,31 +1,156 @@
 package qs
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 )
 
+// The sentinel errors can be used with errors.Is to detect the kind of an
+// error without matching its message. The returned errors are of the types
+// below that match the sentinels, e.g. errors.Is(err, ErrRequiredField) is
+// true for a *ReqError. Use errors.As to get the details of the error.
+var (
+	ErrRequiredField = errors.New("missing required field")
+	ErrUnhandledType = errors.New("unhandled type")
+	ErrWrongType     = errors.New("wrong type")
+	ErrWrongKind     = errors.New("wrong kind")
+	ErrLimitExceeded = errors.New("limit exceeded")
+)
+
+// errSkippedField is returned by the build funcs of the lazily created fields
+// that are ignored by the SkipUnsupportedFields options.
+var errSkippedField = errors.New("skipped field")
+
 // IsRequiredFieldError returns ok==false if the given error wasn't caused by a
 // required field that was missing from the query string.
 // Otherwise it returns the name of the missing required field with ok==true.
+// The *ReqError is looked up with errors.As so wrapped errors are detected too.
 func IsRequiredFieldError(e error) (string, bool) {
-	if re, ok := e.(*ReqError); ok {
+	var re *ReqError
+	if errors.As(e, &re) {
 		return re.FieldName, true
 	}
 	return "", false
 }
 
 // ReqError is returned when a struct field marked with the 'req' option isn't
-// in the unmarshaled url.Values or query string.
+// in the unmarshaled url.Values or query string. If more required fields are
+// missing then a single ReqError lists all of them. Message, FieldName and
+// CustomMessage describe the first one.
 type ReqError struct {
 	Message   string
 	FieldName string
+
+	// Fields are the keys of all missing required fields. The keys of nested
+	// fields include the keys of their parent fields (e.g. "address.city").
+	Fields []string
+
+	// CustomMessage is the message set by the reqmsg=<message> tag option of
+	// the field (e.g. `qs:"user_id,req,reqmsg=user_id is mandatory"`). It is
+	// meant to be returned by APIs as it is. If it isn't empty then Message
+	// is the same unless the field is in an embedded struct pointer or more
+	// fields are missing.
+	CustomMessage string
 }
 
 func (e *ReqError) Error() string {
 	return e.Message
 }
 
+// Is reports whether target is ErrRequiredField.
+func (e *ReqError) Is(target error) bool {
+	return target == ErrRequiredField
+}
+
+// add adds the missing fields of re to e. It returns re if e is nil.
+func (e *ReqError) add(re *ReqError) *ReqError {
+	if e == nil {
+		return re
+	}
+	e.Fields = append(e.Fields, re.Fields...)
+	quoted := make([]string, len(e.Fields))
+	for i, key := range e.Fields {
+		quoted[i] = strconv.Quote(key)
+	}
+	e.Message = "missing required fields " + strings.Join(quoted, ", ")
+	return e
+}
+
+// UnmarshalErrorInfo is the structured data of a struct field that can't be
+// unmarshaled. It is passed to UnmarshalerDefaultOptions.ErrorFormatter.
+type UnmarshalErrorInfo struct {
+	// Key is the query string key of the field including the keys of the
+	// parent fields of nested fields (e.g. "address.city").
+	Key string
+
+	// Kind is the kind of the failure.
+	Kind UnmarshalErrorKind
+
+	// Values are the offending values. They are nil if the field is missing.
+	Values []string
+
+	// Type is the type of the field.
+	Type reflect.Type
+
+	// Err is the error that is returned for the field without an
+	// ErrorFormatter (e.g. a *ReqError for missing required fields).
+	Err error
+}
+
+// ErrorFormatterFunc produces the error returned by the unmarshaler for a
+// struct field that can't be unmarshaled, e.g. a localized error or an error
+// that conforms to the error format of an API.
+type ErrorFormatterFunc func(info UnmarshalErrorInfo) error
+
+// fieldError carries the UnmarshalErrorInfo of a field to the unmarshaler
+// that passes it to the ErrorFormatter. It is used only if the unmarshaler
+// has an ErrorFormatter. The parent fields of nested fields prefix its key
+// instead of wrapping it.
+type fieldError struct {
+	info UnmarshalErrorInfo
+}
+
+func (e *fieldError) Error() string {
+	return e.info.Err.Error()
+}
+
+func (e *fieldError) Unwrap() error {
+	return e.info.Err
+}
+
+// LimitExceededError is returned when the unmarshaled input exceeds one of the
+// limits of the unmarshaler (e.g. UnmarshalerDefaultOptions.MaxKeys). It can
+// be detected with errors.As.
+type LimitExceededError struct {
+	// Limit is the name of the option or tag option that sets the limit:
+	// MaxKeys, MaxValueLen, MaxNestingDepth, MaxSliceLen or maxitems.
+	Limit string
+
+	// Key is the key of the offending value. It is empty if the limit
+	// applies to the whole input or if the key isn't known.
+	Key string
+
+	// Max is the value of the limit.
+	Max int
+}
+
+func (e *LimitExceededError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("%v limit of %v exceeded", e.Limit, e.Max)
+	}
+	return fmt.Sprintf("%v limit of %v exceeded by key %q", e.Limit, e.Max, e.Key)
+}
+
+// Is reports whether target is ErrLimitExceeded.
+func (e *LimitExceededError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// WrongTypeError is returned when a marshaler or unmarshaler receives a value
+// of another type than the one it has been created for.
 type WrongTypeError struct {
 	Actual   reflect.Type
 	Expected reflect.Type
@@ -35,6 +160,13 @@ func (e *WrongTypeError) Error() string {
 	return fmt.Sprintf("received type %v, want %v", e.Actual, e.Expected)
 }
 
+// Is reports whether target is ErrWrongType.
+func (e *WrongTypeError) Is(target error) bool {
+	return target == ErrWrongType
+}
+
+// WrongKindError is returned when a marshaler or unmarshaler receives a value
+// or a type of another kind than the one it handles.
 type WrongKindError struct {
 	Actual   reflect.Type
 	Expected reflect.Kind
@@ -45,6 +177,13 @@ func (e *WrongKindError) Error() string {
 		e.Actual, e.Actual.Kind(), e.Expected)
 }
 
+// Is reports whether target is ErrWrongKind.
+func (e *WrongKindError) Is(target error) bool {
+	return target == ErrWrongKind
+}
+
+// UnhandledTypeError is returned when the marshaler or unmarshaler factory
+// doesn't support a type (e.g. channels and funcs).
 type UnhandledTypeError struct {
 	Type reflect.Type
 }
@@ -52,3 +191,8 @@ type UnhandledTypeError struct {
 func (e *UnhandledTypeError) Error() string {
 	return fmt.Sprintf("unhandled type: %v", e.Type)
 }
+
+// Is reports whether target is ErrUnhandledType.
+func (e *UnhandledTypeError) Is(target error) bool {
+	return target == ErrUnhandledType
+}
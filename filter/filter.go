@@ -0,0 +1,217 @@
+// Package filter parses the operator-suffixed keys of REST list endpoints
+// (e.g. "age__gte=30" or "name__like=jo%") into a Filter. A Filter field
+// receives the keys of the struct that have the operator separator, the
+// other keys are unmarshaled into the other fields as usual:
+//
+//	type ListUsers struct {
+//		Where filter.Filter
+//		Limit int `qs:"limit"`
+//	}
+//
+//	err := filter.Register(filter.Options{Fields: []string{"age", "name"}})
+//	...
+//	var q ListUsers
+//	err = qs.Unmarshal(&q, "age__gte=30&name__like=jo%25&limit=10")
+//	// q.Where.Conditions == []filter.Condition{
+//	// 	{Field: "age", Op: filter.Gte, Values: []string{"30"}},
+//	// 	{Field: "name", Op: filter.Like, Values: []string{"jo%"}},
+//	// }
+//
+// The Filter type has to be registered with the marshaler and the
+// unmarshaler by Register or RegisterWith. The Filter takes full control of
+// its keys like the other types registered with RegisterValuesCustomType so
+// the other keys of the struct can't contain the separator.
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/dmji/qs"
+)
+
+// Op is a comparison operator of a Condition. It is the suffix of the keys
+// after the separator (e.g. "gte" in "age__gte").
+type Op string
+
+// The builtin operators.
+const (
+	Eq   Op = "eq"
+	Ne   Op = "ne"
+	Gt   Op = "gt"
+	Gte  Op = "gte"
+	Lt   Op = "lt"
+	Lte  Op = "lte"
+	Like Op = "like"
+
+	// In matches a list of values. Its values are comma separated
+	// ("id__in=1,2,3") or repeated ("id__in=1&id__in=2").
+	In Op = "in"
+)
+
+// DefaultOps are the operators accepted if Options.Ops is empty.
+var DefaultOps = []Op{Eq, Ne, Gt, Gte, Lt, Lte, Like, In}
+
+// DefaultSeparator separates the field and the operator of the keys if
+// Options.Separator is empty.
+const DefaultSeparator = "__"
+
+// Condition is a single filter expression, e.g. age >= 30.
+type Condition struct {
+	// Field is the part of the key before the separator.
+	Field string
+
+	// Op is the part of the key after the separator.
+	Op Op
+
+	// Values are the values of the key. The comma separated values of the
+	// In operator are split.
+	Values []string
+}
+
+// Filter holds the conditions of a query in the sorted order of their keys.
+type Filter struct {
+	Conditions []Condition
+}
+
+// Add appends a condition to the filter.
+func (f *Filter) Add(field string, op Op, values ...string) {
+	f.Conditions = append(f.Conditions, Condition{Field: field, Op: op, Values: values})
+}
+
+// Lookup returns the first condition with the given field and operator.
+func (f Filter) Lookup(field string, op Op) (Condition, bool) {
+	for _, c := range f.Conditions {
+		if c.Field == field && c.Op == op {
+			return c, true
+		}
+	}
+	return Condition{}, false
+}
+
+// Options configures the keys of Filter values.
+type Options struct {
+	// Separator separates the field and the operator of the keys. The
+	// default is DefaultSeparator.
+	Separator string
+
+	// Ops are the accepted operators. The default is DefaultOps.
+	Ops []Op
+
+	// Fields are the fields that can be filtered. A key with the separator
+	// and another field is rejected. If this field is empty then every
+	// field is accepted.
+	Fields []string
+}
+
+// Register registers the Filter type with the qs.DefaultMarshaler and the
+// qs.DefaultUnmarshaler. See RegisterWith.
+func Register(o Options) error {
+	return RegisterWith(qs.DefaultMarshaler, qs.DefaultUnmarshaler, o)
+}
+
+// RegisterWith registers the Filter type with the given marshaler and
+// unmarshaler. Either of them can be nil. The keys of the conditions are
+// built and parsed with the given options.
+func RegisterWith(m *qs.QSMarshaler, um *qs.QSUnmarshaler, o Options) error {
+	if o.Separator == "" {
+		o.Separator = DefaultSeparator
+	}
+	if len(o.Ops) == 0 {
+		o.Ops = DefaultOps
+	}
+	c := &codec{opts: o}
+
+	t := reflect.TypeFor[Filter]()
+	if m != nil {
+		err := m.RegisterValuesCustomType(t, func(t reflect.Type, opts *qs.MarshalOptions) (qs.ValuesMarshaler, error) {
+			return c, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if um != nil {
+		err := um.RegisterValuesCustomType(t, func(t reflect.Type, opts *qs.UnmarshalerDefaultOptions) (qs.ValuesUnmarshaler, error) {
+			return c, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// codec implements qs.ValuesMarshaler and qs.ValuesUnmarshaler for Filter.
+type codec struct {
+	opts Options
+}
+
+// check reports an error if the field or the operator isn't accepted.
+func (c *codec) check(field string, op Op) error {
+	if field == "" {
+		return errors.New("empty filter field")
+	}
+	if len(c.opts.Fields) != 0 && !slices.Contains(c.opts.Fields, field) {
+		return fmt.Errorf("unsupported filter field %q", field)
+	}
+	if !slices.Contains(c.opts.Ops, op) {
+		return fmt.Errorf("unsupported filter operator %q of field %q", op, field)
+	}
+	return nil
+}
+
+func (c *codec) MarshalValues(v reflect.Value, opts *qs.MarshalOptions) (url.Values, error) {
+	f := v.Interface().(Filter)
+	vs := make(url.Values, len(f.Conditions))
+	for _, cond := range f.Conditions {
+		if err := c.check(cond.Field, cond.Op); err != nil {
+			return nil, err
+		}
+		k := cond.Field + c.opts.Separator + string(cond.Op)
+		if cond.Op == In {
+			vs[k] = append(vs[k], strings.Join(cond.Values, ","))
+		} else {
+			vs[k] = append(vs[k], cond.Values...)
+		}
+	}
+	return vs, nil
+}
+
+func (c *codec) UnmarshalValues(v reflect.Value, vs url.Values, opts *qs.UnmarshalerDefaultOptions) error {
+	// The keys are sorted to return the conditions in the same order in
+	// every call.
+	keys := make([]string, 0, len(vs))
+	for k := range vs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var f Filter
+	for _, k := range keys {
+		i := strings.LastIndex(k, c.opts.Separator)
+		if i < 0 {
+			continue
+		}
+		field, op := k[:i], Op(k[i+len(c.opts.Separator):])
+		if err := c.check(field, op); err != nil {
+			return fmt.Errorf("invalid filter key %q :: %w", k, err)
+		}
+		values := vs[k]
+		if op == In {
+			var split []string
+			for _, s := range values {
+				split = append(split, strings.Split(s, ",")...)
+			}
+			values = split
+		}
+		f.Add(field, op, values...)
+	}
+	v.Set(reflect.ValueOf(f))
+	return nil
+}
@@ -0,0 +1,84 @@
+package filter
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/dmji/qs"
+)
+
+type listUsers struct {
+	Where Filter
+	Limit int `qs:"limit,omitempty"`
+}
+
+func newCodecs(t *testing.T, o Options) (*qs.QSMarshaler, *qs.QSUnmarshaler) {
+	t.Helper()
+	m, um := qs.NewMarshaler(nil), qs.NewUnmarshaler(nil)
+	if err := RegisterWith(m, um, o); err != nil {
+		t.Fatal(err)
+	}
+	return m, um
+}
+
+func TestFilter(t *testing.T) {
+	m, um := newCodecs(t, Options{Fields: []string{"age", "id", "name"}})
+
+	var q listUsers
+	if err := um.Unmarshal(&q, "age__gte=30&name__like=jo%25&id__in=1,2&id__in=3&limit=10"); err != nil {
+		t.Fatal(err)
+	}
+	want := listUsers{
+		Where: Filter{Conditions: []Condition{
+			{Field: "age", Op: Gte, Values: []string{"30"}},
+			{Field: "id", Op: In, Values: []string{"1", "2", "3"}},
+			{Field: "name", Op: Like, Values: []string{"jo%"}},
+		}},
+		Limit: 10,
+	}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("got %+v, want %+v", q, want)
+	}
+	if c, ok := q.Where.Lookup("age", Gte); !ok || c.Values[0] != "30" {
+		t.Errorf("got %+v, %v", c, ok)
+	}
+
+	vs, err := m.MarshalValues(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantValues := url.Values{"age__gte": {"30"}, "id__in": {"1,2,3"}, "name__like": {"jo%"}, "limit": {"10"}}
+	if !reflect.DeepEqual(vs, wantValues) {
+		t.Errorf("got %v, want %v", vs, wantValues)
+	}
+
+	for _, s := range []string{"email__eq=x", "age__between=1", "__eq=1"} {
+		if err := um.Unmarshal(&q, s); err == nil {
+			t.Errorf("Unmarshal(%q) :: unexpected success", s)
+		}
+	}
+	var f Filter
+	f.Add("age", "between", "1")
+	if _, err := m.Marshal(&listUsers{Where: f}); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestFilterOptions(t *testing.T) {
+	m, um := newCodecs(t, Options{Separator: ":", Ops: []Op{Eq, "contains"}})
+
+	var q listUsers
+	if err := um.Unmarshal(&q, "tag:contains=go&status:eq=open"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := q.Where.Lookup("tag", "contains"); !ok || len(q.Where.Conditions) != 2 {
+		t.Errorf("got %+v", q)
+	}
+	if err := um.Unmarshal(&q, "age:gte=30"); err == nil {
+		t.Error("unexpected success")
+	}
+	if s, err := m.Marshal(&q); err != nil || s != "status%3Aeq=open&tag%3Acontains=go" {
+		t.Errorf("got %q, %v", s, err)
+	}
+}
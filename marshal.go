@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/url"
 	"reflect"
+	"time"
 )
 
 // QSMarshaler objects can be created by calling NewMarshaler and they can be
@@ -13,10 +14,17 @@ type QSMarshaler struct {
 	opts *MarshalOptions
 
 	_EncodeValues func(values url.Values) string
+
+	hook HookFunc
 }
 
-// NewMarshaler returns a new QSMarshaler object.
+// NewMarshaler returns a new QSMarshaler object. The prm parameter can be nil,
+// in that case the marshaler starts with the default options and it can be
+// configured with the option appliers (e.g. WithMarshalNameTransformer).
 func NewMarshaler(prm *MarshalOptions, opts ...func(*QSMarshaler)) *QSMarshaler {
+	if prm == nil {
+		prm = &MarshalOptions{}
+	}
 	p := &QSMarshaler{
 		opts:          prepareMarshalOptions(*prm),
 		_EncodeValues: func(values url.Values) string { return values.Encode() },
@@ -25,53 +33,209 @@ func NewMarshaler(prm *MarshalOptions, opts ...func(*QSMarshaler)) *QSMarshaler
 	for _, opt := range opts {
 		opt(p)
 	}
+	p.opts.updateCacheVariant()
 
 	return p
 }
 
-func (p *QSMarshaler) RegisterSubFactory(k reflect.Kind, fn MarshalerFactoryFunc) error {
-	return p.opts.MarshalerFactory.RegisterSubFactory(k, fn)
+// With returns a copy of the marshaler with the given options applied. The
+// copy shares the registrations and the caches of the original. As long as
+// the options don't change the name transformer or the tag option defaults
+// the copy also reuses the objects compiled by the original, so deriving
+// marshalers for per-request tweaks is cheap.
+func (p *QSMarshaler) With(opts ...func(*QSMarshaler)) *QSMarshaler {
+	c := *p
+	o := *p.opts
+	tagDefaults := *o.TagOptionsDefaults
+	commonDefaults := *o.TagCommonOptionsDefaults
+	o.TagOptionsDefaults = &tagDefaults
+	o.TagCommonOptionsDefaults = &commonDefaults
+	c.opts = &o
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+	o.updateCacheVariant()
 
+	return &c
+}
+
+// Options returns the options used by the marshaler. The returned object can
+// be passed to the ValuesMarshaler objects returned by CompileType and it must
+// not be modified.
+func (p *QSMarshaler) Options() *MarshalOptions {
+	return p.opts
+}
+
+// CompileType returns the ValuesMarshaler that the marshaler uses for values
+// of type t. It can be called at startup to make sure that the query types of
+// an application are supported (fail-fast) and the returned object can be
+// used directly in hot loops with the object returned by Options:
+//
+//	vm, err := marshaler.CompileType(reflect.TypeOf(Query{}))
+//	...
+//	values, err := vm.MarshalValues(reflect.ValueOf(q), marshaler.Options())
+//
+// Note that the key prefix and suffix of the marshaler aren't applied by the
+// returned object.
+func (p *QSMarshaler) CompileType(t reflect.Type) (ValuesMarshaler, error) {
+	if t == nil {
+		return nil, errors.New("nil type")
+	}
+	return p.opts.ValuesMarshalerFactory.ValuesMarshaler(t, p.opts)
+}
+
+// purgeValuesCache is called after the registrations because the cached
+// ValuesMarshaler objects hold the Marshaler objects of their fields. The
+// registrations are safe to call concurrently with marshaling.
+func (p *QSMarshaler) purgeValuesCache() {
+	purgeCache(p.opts.ValuesMarshalerFactory)
+}
+
+// ResetCache removes every compiled Marshaler and ValuesMarshaler object (and
+// memoized error) from the caches of the marshaler. They are created again
+// on demand with the current registrations.
+func (p *QSMarshaler) ResetCache() {
+	purgeCache(p.opts.MarshalerFactory)
+	purgeCache(p.opts.ValuesMarshalerFactory)
+}
+
+// CacheStats returns the counters of the caches of the Marshaler and
+// ValuesMarshaler objects of the marshaler.
+func (p *QSMarshaler) CacheStats() CacheStats {
+	return cacheStats(p.opts.MarshalerFactory).add(cacheStats(p.opts.ValuesMarshalerFactory))
+}
+
+// EvictType removes the compiled objects of type t from the caches. Note that
+// the compiled objects of other types (e.g. structs with fields of type t)
+// keep using the evicted objects until they are evicted too. If in doubt,
+// use ResetCache.
+func (p *QSMarshaler) EvictType(t reflect.Type) {
+	evictCache(p.opts.MarshalerFactory, t)
+	evictCache(p.opts.ValuesMarshalerFactory, t)
+}
+
+func (p *QSMarshaler) RegisterSubFactory(k reflect.Kind, fn MarshalerFactoryFunc) error {
+	err := p.opts.MarshalerFactory.RegisterSubFactory(k, fn)
+	p.purgeValuesCache()
+	return err
 }
 
 func (p *QSMarshaler) RegisterCustomType(k reflect.Type, fn PrimitiveMarshalerFunc) error {
-	return p.opts.MarshalerFactory.RegisterCustomType(k, fn)
+	err := p.opts.MarshalerFactory.RegisterCustomType(k, fn)
+	p.purgeValuesCache()
+	return err
+}
 
+// RegisterValuesCustomType registers a ValuesMarshalerFactoryFunc for the
+// given type. The ValuesMarshaler created by fn takes full control of the
+// keys of the values of the type: the values of a struct field of the type
+// (or of a pointer to it) are marshaled like inline fields and the keys
+// returned by the ValuesMarshaler are merged into the keys of the struct.
+func (p *QSMarshaler) RegisterValuesCustomType(t reflect.Type, fn ValuesMarshalerFactoryFunc) error {
+	err := p.opts.ValuesMarshalerFactory.RegisterCustomType(t, fn)
+	p.purgeValuesCache()
+	return err
 }
 
 func (p *QSMarshaler) RegisterKindOverride(k reflect.Kind, fn PrimitiveMarshalerFunc) error {
-	return p.opts.MarshalerFactory.RegisterKindOverride(k, fn)
+	err := p.opts.MarshalerFactory.RegisterKindOverride(k, fn)
+	p.purgeValuesCache()
+	return err
 }
 
 // Marshal marshals a given object into a query string.
 // See the documentation of the global Marshal func.
-func (p *QSMarshaler) Marshal(i interface{}) (string, error) {
-	values, err := p.MarshalValues(i)
+func (p *QSMarshaler) Marshal(i interface{}) (s string, err error) {
+	if p.hook != nil {
+		defer p.hook.report(reflect.TypeOf(i), time.Now(), &err)
+	}
+	vum, values, err := p.marshalInterface(i)
 	if err != nil {
 		return "", err
 	}
-	return p._EncodeValues(values), nil
+	return p.encodeValues(vum, values), nil
 }
 
 // MarshalValues marshals a given object into a url.Values.
 // See the documentation of the global MarshalValues func.
-func (p *QSMarshaler) MarshalValues(i interface{}) (url.Values, error) {
+func (p *QSMarshaler) MarshalValues(i interface{}) (vs url.Values, err error) {
+	if p.hook != nil {
+		defer p.hook.report(reflect.TypeOf(i), time.Now(), &err)
+	}
+	_, vs, err = p.marshalInterface(i)
+	return vs, err
+}
+
+// marshalInterface is MarshalValues without the hook. It returns the
+// ValuesMarshaler used too.
+func (p *QSMarshaler) marshalInterface(i interface{}) (ValuesMarshaler, url.Values, error) {
 	v := reflect.ValueOf(i)
 	if !v.IsValid() {
-		return nil, errors.New("received an empty interface")
+		return nil, nil, errors.New("received an empty interface")
 	}
 	if v.Kind() == reflect.Ptr {
 		if v.IsNil() {
-			return nil, fmt.Errorf("nil pointer of type %T", i)
+			return nil, nil, fmt.Errorf("nil pointer of type %T", i)
 		}
 		v = v.Elem()
 	}
 
 	vum, err := p.opts.ValuesMarshalerFactory.ValuesMarshaler(v.Type(), p.opts)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	vs, err := p.marshalValues(vum, v)
+	return vum, vs, err
+}
+
+// MarshalWith is the same as Marshal but the given options are applied on top
+// of the options of the marshaler for this call only. The marshaler itself
+// isn't modified. Use With to create a derived marshaler once if the same
+// options are used by many calls.
+func (p *QSMarshaler) MarshalWith(i interface{}, opts ...func(*QSMarshaler)) (string, error) {
+	return p.With(opts...).Marshal(i)
+}
+
+// MarshalValuesWith is the same as MarshalValues but the given options are
+// applied on top of the options of the marshaler for this call only.
+func (p *QSMarshaler) MarshalValuesWith(i interface{}, opts ...func(*QSMarshaler)) (url.Values, error) {
+	return p.With(opts...).MarshalValues(i)
+}
+
+// SetQueryOnURL marshals a given object into the query string of u replacing
+// its RawQuery. u isn't modified if marshaling fails. Use MarshalInto to keep
+// the unrelated parameters of u:
+//
+//	q := u.Query()
+//	err := marshaler.MarshalInto(q, &page)
+//	...
+//	u.RawQuery = q.Encode()
+func (p *QSMarshaler) SetQueryOnURL(u *url.URL, i interface{}) error {
+	if u == nil {
+		return errors.New("nil URL")
+	}
+	s, err := p.Marshal(i)
+	if err != nil {
+		return err
+	}
+	u.RawQuery = s
+	return nil
+}
+
+// marshalValues marshals v with the given ValuesMarshaler and decorates the
+// resulting keys with the key prefix and suffix of the marshaler.
+func (p *QSMarshaler) marshalValues(vum ValuesMarshaler, v reflect.Value) (url.Values, error) {
+	vs, err := vum.MarshalValues(v, p.opts)
+	if err != nil || (p.opts.KeyPrefix == "" && p.opts.KeySuffix == "") {
+		return vs, err
+	}
+
+	decorated := make(url.Values, len(vs))
+	for k, a := range vs {
+		decorated[p.opts.KeyPrefix+k+p.opts.KeySuffix] = a
 	}
-	return vum.MarshalValues(v, p.opts)
+	return decorated, nil
 }
 
 // CheckMarshal check whether the type of the given object supports
@@ -90,6 +254,6 @@ func (p *QSMarshaler) CheckMarshalType(t reflect.Type) error {
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
-	_, err := p.opts.ValuesMarshalerFactory.ValuesMarshaler(t, p.opts)
+	_, err := p.CompileType(t)
 	return err
 }
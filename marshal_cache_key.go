@@ -0,0 +1,32 @@
+package qs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// CacheKey returns a stable hash of the query string of an object marshaled
+// by the DefaultMarshaler. See QSMarshaler.CacheKey.
+func CacheKey(i interface{}) (string, error) {
+	return DefaultMarshaler.CacheKey(i)
+}
+
+// CacheKey returns the hex encoded SHA-256 hash of the canonical query string
+// of an object for HTTP caches and memoization layers. The objects that
+// marshal into the same url.Values have the same key: the keys are sorted,
+// the fields omitted by omitempty don't count and the values are always
+// encoded with QueryEncodingRFC3986 regardless of the query encoding of the
+// marshaler. The order of the values of a key is significant unless the
+// fields sort them (e.g. with the sorted tag option).
+//
+// The key doesn't depend on the type of the object so the objects of
+// different types with the same query string have the same key. Prefix the
+// key with the name of the type or of the endpoint if it matters.
+func (p *QSMarshaler) CacheKey(i interface{}) (string, error) {
+	values, err := p.MarshalValues(i)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(QueryEncodingRFC3986.Encode(values)))
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,59 @@
+package qs
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// MarshalCanonical marshals a given object into a canonical query string
+// with the DefaultMarshaler. See QSMarshaler.MarshalCanonical.
+func MarshalCanonical(i interface{}) (string, error) {
+	return DefaultMarshaler.MarshalCanonical(i)
+}
+
+// MarshalCanonical marshals a given object into the canonical query string
+// defined by AWS Signature Version 4. See CanonicalQueryString. The query
+// encoding and the escape tag options of the marshaler aren't used because
+// the canonical form defines its own encoding.
+func (p *QSMarshaler) MarshalCanonical(i interface{}) (string, error) {
+	values, err := p.MarshalValues(i)
+	if err != nil {
+		return "", err
+	}
+	return CanonicalQueryString(values), nil
+}
+
+// CanonicalQueryString encodes the values into the canonical query string
+// defined by AWS Signature Version 4 that is used in the canonical requests
+// of signatures: every byte except the unreserved characters of RFC 3986
+// ("A-Z", "a-z", "0-9", "-", ".", "_" and "~") is percent-encoded with
+// uppercase hex digits (spaces as "%20") and the pairs are sorted by their
+// encoded keys and then by their encoded values. Keys without values are
+// omitted like url.Values.Encode does.
+func CanonicalQueryString(values url.Values) string {
+	pairs := make([]KV, 0, len(values))
+	for k, a := range values {
+		key := QueryEncodingRFC3986.Escape(k)
+		for _, v := range a {
+			pairs = append(pairs, KV{Key: key, Value: QueryEncodingRFC3986.Escape(v)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Key != pairs[j].Key {
+			return pairs[i].Key < pairs[j].Key
+		}
+		return pairs[i].Value < pairs[j].Value
+	})
+
+	var b strings.Builder
+	for i, kv := range pairs {
+		if i > 0 {
+			b.WriteByte('&')
+		}
+		b.WriteString(kv.Key)
+		b.WriteByte('=')
+		b.WriteString(kv.Value)
+	}
+	return b.String()
+}
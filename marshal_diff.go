@@ -0,0 +1,56 @@
+package qs
+
+import "net/url"
+
+// FieldDiff is a query string key whose values differ in two queries.
+type FieldDiff struct {
+	Key string
+
+	// A and B are the values of Key in the two queries. A nil slice means
+	// that the key is missing from the query.
+	A []string
+	B []string
+}
+
+// Diff marshals a and b with the DefaultMarshaler and returns the keys whose
+// values differ. See QSMarshaler.Diff.
+func Diff(a, b interface{}) ([]FieldDiff, error) {
+	return DefaultMarshaler.Diff(a, b)
+}
+
+// Diff marshals a and b and returns the keys whose values differ sorted by
+// key. It returns an empty slice if the two objects have the same query. The
+// objects don't have to be of the same type. It is useful in tests that
+// assert the construction of API requests and for logging changes:
+//
+//	diffs, err := marshaler.Diff(&oldQuery, &newQuery)
+//	...
+//	for _, d := range diffs {
+//		log.Printf("%v: %q -> %q", d.Key, d.A, d.B)
+//	}
+func (p *QSMarshaler) Diff(a, b interface{}) ([]FieldDiff, error) {
+	va, err := p.MarshalValues(a)
+	if err != nil {
+		return nil, err
+	}
+	vb, err := p.MarshalValues(b)
+	if err != nil {
+		return nil, err
+	}
+	return DiffValues(va, vb), nil
+}
+
+// DiffValues returns the keys whose values differ in a and b sorted by key.
+// A missing key differs from a key with no values.
+func DiffValues(a, b url.Values) []FieldDiff {
+	keys := diffKeys(a, b)
+	diffs := make([]FieldDiff, len(keys))
+	for i, k := range keys {
+		diffs[i] = FieldDiff{
+			Key: k,
+			A:   a[k],
+			B:   b[k],
+		}
+	}
+	return diffs
+}
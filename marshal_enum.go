@@ -1,6 +1,6 @@
 package qs
 
-//go:generate go-stringer -type=MarshalPresence --trimprefix=MarshalPresence -output marshal_string.go -nametransform=lower -fromstringgenfn
+//go:generate go-stringer -type=MarshalPresence,MarshalBoolFormat,MarshalSliceOrder,MarshalEmptySlice,MarshalEscape,MarshalFloatFormat,MarshalFloatZeros --trimprefix=@me -output marshal_string.go -nametransform=lower -fromstringgenfn
 
 // MarshalPresence is an enum that controls the marshaling of empty fields.
 // A field is empty if it has its zero value or it is an empty container.
@@ -18,4 +18,147 @@ const (
 
 	// MarshalPresenceOmitEmpty doesn't marshal the values of empty fields into the marshal output.
 	MarshalPresenceOmitEmpty
+
+	// MarshalPresenceOmitZero doesn't marshal the zero values (see
+	// reflect.Value.IsZero) into the marshal output. Unlike OmitEmpty it
+	// keeps the empty but non-nil slices and maps and it uses the IsZero
+	// method of the types that have one (e.g. time.Time) like the omitzero
+	// option of encoding/json.
+	MarshalPresenceOmitZero
+
+	// MarshalPresenceOmitNil doesn't marshal the nil pointers, slices, maps
+	// and interfaces into the marshal output but it keeps every other value
+	// including the zero values.
+	MarshalPresenceOmitNil
+)
+
+// MarshalBoolFormat is an enum that controls the marshaling of bool values.
+type MarshalBoolFormat int8
+
+const (
+	// MarshalBoolFormatBFUnspecified is the zero value of MarshalBoolFormat.
+	// It results in using the default MarshalBoolFormat which is TrueFalse.
+	MarshalBoolFormatBFUnspecified MarshalBoolFormat = iota
+
+	// MarshalBoolFormatTrueFalse marshals bools as "true" and "false".
+	MarshalBoolFormatTrueFalse
+
+	// MarshalBoolFormatOneZero marshals bools as "1" and "0".
+	MarshalBoolFormatOneZero
+
+	// MarshalBoolFormatYesNo marshals bools as "yes" and "no".
+	MarshalBoolFormatYesNo
+
+	// MarshalBoolFormatOnOff marshals bools as "on" and "off".
+	MarshalBoolFormatOnOff
+)
+
+// MarshalSliceOrder is an enum that controls the order of the marshaled items
+// of slices and arrays.
+type MarshalSliceOrder int8
+
+const (
+	// MarshalSliceOrderSOUnspecified is the zero value of MarshalSliceOrder.
+	// It results in using the default MarshalSliceOrder which is KeepOrder.
+	MarshalSliceOrderSOUnspecified MarshalSliceOrder = iota
+
+	// MarshalSliceOrderKeepOrder marshals the items in their order.
+	MarshalSliceOrderKeepOrder
+
+	// MarshalSliceOrderSorted sorts the marshaled items in ascending order:
+	// numbers and strings by value, other types by their marshaled value.
+	// This makes the query strings built from sets (e.g. cache keys)
+	// canonical.
+	MarshalSliceOrderSorted
+)
+
+// MarshalEmptySlice is an enum that controls the marshaling of slices that
+// are empty but not nil.
+type MarshalEmptySlice int8
+
+const (
+	// MarshalEmptySliceESUnspecified is the zero value of MarshalEmptySlice.
+	// It results in using the default MarshalEmptySlice which is NoMarker.
+	MarshalEmptySliceESUnspecified MarshalEmptySlice = iota
+
+	// MarshalEmptySliceNoMarker marshals no values for empty slices so they
+	// are missing from the query string like nil slices.
+	MarshalEmptySliceNoMarker
+
+	// MarshalEmptySliceEmptyMarker marshals an empty value for the empty but
+	// non-nil slices ("tags=" or "tags[]=" with the brackets option) so the
+	// servers can tell "clear the list" from "not provided". The nil slices
+	// are still missing from the query string.
+	MarshalEmptySliceEmptyMarker
+)
+
+// MarshalEscape is an enum that controls the percent-encoding of the values
+// of a struct field when the marshaler encodes them into a query string. It
+// can be set per field in the tag with the escape option (e.g.
+// `qs:"redirect,escape=path"`) or with the noescape option which is the same
+// as escape=none. It doesn't affect the url.Values returned by MarshalValues.
+type MarshalEscape int8
+
+const (
+	// MarshalEscapeMEUnspecified is the zero value of MarshalEscape.
+	// It results in using the default MarshalEscape which is Query.
+	MarshalEscapeMEUnspecified MarshalEscape = iota
+
+	// MarshalEscapeQuery encodes the values with the query encoding of the
+	// marshaler like the values of any other field.
+	MarshalEscapeQuery
+
+	// MarshalEscapeNone writes the values into the query string as they are.
+	// It is meant for values that are already encoded (e.g. pre-encoded
+	// redirect URLs or signatures computed over the encoded form) and it is
+	// the responsibility of the caller to make sure that they don't contain
+	// "&", "#" or invalid escapes.
+	MarshalEscapeNone
+
+	// MarshalEscapePath keeps the characters that are valid in URL paths and
+	// queries (e.g. "/", ":", "@" and "?") unescaped so URLs remain readable
+	// while "&", "=", "+", "#" and "%" are still encoded.
+	MarshalEscapePath
+)
+
+// MarshalFloatFormat is an enum that controls the format of the marshaled
+// float values. It can be set per field in the tag with the floatfmt option
+// (e.g. `qs:"lat,floatfmt=g"`). The names of the formats are the formats of
+// strconv.FormatFloat.
+type MarshalFloatFormat int8
+
+const (
+	// MarshalFloatFormatFFUnspecified is the zero value of MarshalFloatFormat.
+	// It results in using the default MarshalFloatFormat which is F.
+	MarshalFloatFormatFFUnspecified MarshalFloatFormat = iota
+
+	// MarshalFloatFormatF marshals floats without an exponent (e.g.
+	// "1000000" or "0.000001").
+	MarshalFloatFormatF
+
+	// MarshalFloatFormatE marshals floats with an exponent (e.g. "1e+06").
+	MarshalFloatFormatE
+
+	// MarshalFloatFormatG uses an exponent only for large exponents (e.g.
+	// "1e+21" but "1000000").
+	MarshalFloatFormatG
+)
+
+// MarshalFloatZeros is an enum that controls the trailing zeros of the
+// fractional part of floats marshaled with a fixed precision (e.g. "1.500000"
+// with prec=6). It can be set per field with the keepzeros and trimzeros tag
+// options.
+type MarshalFloatZeros int8
+
+const (
+	// MarshalFloatZerosFZUnspecified is the zero value of MarshalFloatZeros.
+	// It results in using the default MarshalFloatZeros which is KeepZeros.
+	MarshalFloatZerosFZUnspecified MarshalFloatZeros = iota
+
+	// MarshalFloatZerosKeepZeros keeps the trailing zeros ("1.500000").
+	MarshalFloatZerosKeepZeros
+
+	// MarshalFloatZerosTrimZeros removes the trailing zeros and the decimal
+	// point if nothing remains after it ("1.5" and "2").
+	MarshalFloatZerosTrimZeros
 )
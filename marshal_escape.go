@@ -0,0 +1,98 @@
+package qs
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// pathEscaping is the encoding of the values of the fields with
+// MarshalEscapePath. Besides the unreserved characters it keeps the
+// characters of RFC 3986 that are allowed in queries and have no special
+// meaning in url.Values ("&", "=" and "+" are still encoded).
+var pathEscaping = QueryEncoding{
+	Unescaped: "-._~/:@!$'()*,;?",
+}
+
+// fieldEscapes returns the keys of the struct that don't use the default
+// MarshalEscapeQuery. The keys of embedded structs override the keys of
+// the fields like in MarshalValues. The keys of the fields of nested structs
+// aren't included so their values are always encoded with the query encoding
+// of the marshaler.
+func (p *structMarshaler) fieldEscapes() map[string]MarshalEscape {
+	var escapes map[string]MarshalEscape
+	set := func(k string, e MarshalEscape) {
+		if e == MarshalEscapeQuery || e == MarshalEscapeMEUnspecified {
+			delete(escapes, k)
+			return
+		}
+		if escapes == nil {
+			escapes = make(map[string]MarshalEscape)
+		}
+		escapes[k] = e
+	}
+
+	for _, fm := range p.Fields {
+		set(fm.Tag.Name, fm.Tag.MarshalOpts.Escape)
+	}
+	for _, ef := range p.EmbeddedFields {
+		for k, e := range marshalerEscapes(ef.ValuesMarshaler) {
+			set(k, e)
+		}
+	}
+	return escapes
+}
+
+// marshalerEscapes returns the escapes of the struct marshaled by vm.
+func marshalerEscapes(vm ValuesMarshaler) map[string]MarshalEscape {
+	switch vm := vm.(type) {
+	case *structMarshaler:
+		return vm.escapes
+	case *ptrValuesMarshaler:
+		return marshalerEscapes(vm.ElemMarshaler)
+	}
+	return nil
+}
+
+// encodeValues encodes the values marshaled by vm into a query string. The
+// values of the keys with a MarshalEscape other than Query are escaped by
+// their policy and the rest by the query encoding of the marshaler.
+func (p *QSMarshaler) encodeValues(vm ValuesMarshaler, values url.Values) string {
+	escapes := marshalerEscapes(vm)
+	if len(escapes) == 0 {
+		return p._EncodeValues(values)
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		e, ok := escapes[strings.TrimSuffix(strings.TrimPrefix(k, p.opts.KeyPrefix), p.opts.KeySuffix)]
+		if !ok {
+			if s := p._EncodeValues(url.Values{k: values[k]}); s != "" {
+				if b.Len() > 0 {
+					b.WriteByte('&')
+				}
+				b.WriteString(s)
+			}
+			continue
+		}
+		key := url.QueryEscape(k)
+		for _, v := range values[k] {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(key)
+			b.WriteByte('=')
+			if e == MarshalEscapePath {
+				v = pathEscaping.Escape(v)
+			}
+			b.WriteString(v)
+		}
+	}
+	return b.String()
+}
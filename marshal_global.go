@@ -14,13 +14,16 @@ var DefaultMarshaler = NewMarshaler(&MarshalOptions{})
 // structs and maps satisfy this condition without using a custom
 // ValuesMarshalerFactory.
 //
-// If you use a map then the key type has to be string or a type with string as
-// its underlying type and the map value type can be anything that can be used
-// as a struct field for marshaling.
+// If you use a map then the key type has to be string, a type with string as
+// its underlying type, a type that can be used as a struct field for
+// marshaling or a type registered with RegisterMapKeyType. The map value type
+// can be anything that can be used as a struct field for marshaling.
 //
 // A struct value is marshaled by adding its fields one-by-one to the query
-// string. Only exported struct fields are marshaled. The struct field tag can
-// contain qs package specific options in the following format:
+// string. Only exported struct fields are marshaled. The exported fields of
+// embedded structs are promoted like with encoding/json even if the embedded
+// struct type is unexported. The struct field tag can contain qs package
+// specific options in the following format:
 //
 //	FieldName bool `qs:"[name][,option1[,option2[...]]]"`
 //
@@ -28,9 +31,10 @@ var DefaultMarshaler = NewMarshaler(&MarshalOptions{})
 //	- If name is omitted then it defaults to the snake_case of the FieldName.
 //	  The snake_case transformation can be replaced with a field name to query
 //	  string name converter function by creating a custom marshaler.
-//	- For marshaling you can specify one of the keepempty and omitempty options.
-//	  If none of them is specified then the keepempty option is the default but
-//	  this default can be changed by using a custom marshaler object.
+//	- For marshaling you can specify one of the keepempty, omitempty, omitzero
+//	  and omitnil options. If none of them is specified then the keepempty
+//	  option is the default but this default can be changed by using a custom
+//	  marshaler object.
 //
 //	Examples:
 //	FieldName bool `qs:"-"
@@ -54,7 +58,9 @@ var DefaultMarshaler = NewMarshaler(&MarshalOptions{})
 // if it has the zero value of its type.
 // A field is marshaled with the omitempty option when its tag explicitly
 // specifies omitempty or when the tag contains neither omitempty nor keepempty
-// but the marshaler's default marshal option is omitempty.
+// but the marshaler's default marshal option is omitempty. The omitzero option
+// skips only the zero values (keeping the empty but non-nil slices and maps)
+// and the omitnil option skips only the nil pointers, slices and maps.
 func Marshal(i interface{}) (string, error) {
 	return DefaultMarshaler.Marshal(i)
 }
@@ -65,6 +71,24 @@ func MarshalValues(i interface{}) (url.Values, error) {
 	return DefaultMarshaler.MarshalValues(i)
 }
 
+// MarshalWith is the same as Marshal but the given options are applied on top
+// of the options of the DefaultMarshaler for this call only.
+func MarshalWith(i interface{}, opts ...func(*QSMarshaler)) (string, error) {
+	return DefaultMarshaler.MarshalWith(i, opts...)
+}
+
+// MarshalValuesWith is the same as MarshalValues but the given options are
+// applied on top of the options of the DefaultMarshaler for this call only.
+func MarshalValuesWith(i interface{}, opts ...func(*QSMarshaler)) (url.Values, error) {
+	return DefaultMarshaler.MarshalValuesWith(i, opts...)
+}
+
+// SetQueryOnURL is the same as Marshal but it stores the query string in the
+// RawQuery of u.
+func SetQueryOnURL(u *url.URL, i interface{}) error {
+	return DefaultMarshaler.SetQueryOnURL(u, i)
+}
+
 // CheckMarshal returns an error if the type of the given object can't be
 // marshaled into a url.Values or query string. By default only maps and structs
 // can be marshaled into query strings given that all of their fields or values
@@ -85,21 +109,29 @@ func CheckMarshalType(t reflect.Type) error {
 }
 
 func RegisterSubFactoryMarshal(k reflect.Kind, fn MarshalerFactoryFunc) error {
-	return DefaultMarshaler.opts.MarshalerFactory.RegisterSubFactory(k, fn)
+	return DefaultMarshaler.RegisterSubFactory(k, fn)
 
 }
 
 func RegisterCustomTypeMarshal(k reflect.Type, fn PrimitiveMarshalerFunc) error {
-	return DefaultMarshaler.opts.MarshalerFactory.RegisterCustomType(k, fn)
+	return DefaultMarshaler.RegisterCustomType(k, fn)
+
+}
 
+// RegisterValuesCustomTypeMarshal registers a ValuesMarshalerFactoryFunc for
+// the given type with the DefaultMarshaler. See
+// QSMarshaler.RegisterValuesCustomType.
+func RegisterValuesCustomTypeMarshal(t reflect.Type, fn ValuesMarshalerFactoryFunc) error {
+	return DefaultMarshaler.RegisterValuesCustomType(t, fn)
 }
 
 func RegisterKindOverrideMarshal(k reflect.Kind, fn PrimitiveMarshalerFunc) error {
-	return DefaultMarshaler.opts.MarshalerFactory.RegisterKindOverride(k, fn)
+	return DefaultMarshaler.RegisterKindOverride(k, fn)
 }
 
 func ApplyOptionsMarshal(opts ...func(*QSMarshaler)) {
 	for _, opt := range opts {
 		opt(DefaultMarshaler)
 	}
+	DefaultMarshaler.opts.updateCacheVariant()
 }
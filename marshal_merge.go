@@ -0,0 +1,53 @@
+package qs
+
+import (
+	"net/url"
+	"slices"
+)
+
+// MergeValues copies the values of src into dst. The strategy controls the
+// keys that are present in both: see the MergeStrategy constants. Unknown
+// strategies are treated as MergeStrategyReplace. dst must not be nil. The
+// slices of src aren't shared with dst.
+func MergeValues(dst, src url.Values, strategy MergeStrategy) {
+	for k, a := range src {
+		existing, ok := dst[k]
+		switch strategy {
+		case MergeStrategyAppend:
+			dst[k] = append(existing, a...)
+		case MergeStrategyKeepExisting:
+			if !ok {
+				dst[k] = slices.Clone(a)
+			}
+		default:
+			dst[k] = slices.Clone(a)
+		}
+	}
+}
+
+// MarshalInto marshals v with the DefaultMarshaler and overlays it onto
+// existing. See QSMarshaler.MarshalInto.
+func MarshalInto(existing url.Values, v interface{}) error {
+	return DefaultMarshaler.MarshalInto(existing, v)
+}
+
+// MarshalInto marshals v and overlays the result onto existing: the keys of
+// v replace the same keys of existing and the rest of existing is preserved.
+// It is useful to build links that change some parameters of the current
+// query (e.g. "next page" links) without dropping the unrelated ones:
+//
+//	q := r.URL.Query()
+//	err := marshaler.MarshalInto(q, &Page{Offset: offset + limit})
+//	...
+//	next := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+//
+// Note that a key omitted by v (e.g. by omitempty) is left untouched in
+// existing. existing must not be nil. It isn't modified if marshaling fails.
+func (p *QSMarshaler) MarshalInto(existing url.Values, v interface{}) error {
+	vs, err := p.MarshalValues(v)
+	if err != nil {
+		return err
+	}
+	MergeValues(existing, vs, MergeStrategyReplace)
+	return nil
+}
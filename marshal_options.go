@@ -1,6 +1,12 @@
 package qs
 
-import "net/url"
+import (
+	"errors"
+	"log/slog"
+	"net/url"
+	"reflect"
+	"strings"
+)
 
 // MarshalOptions is used as a parameter by the NewMarshaler function.
 type MarshalOptions struct {
@@ -21,9 +27,130 @@ type MarshalOptions struct {
 	// a default builtin factory.
 	MarshalerFactory MarshalerFactory
 
+	// NewCache creates the Cache objects that store the objects compiled by
+	// the factories. It can be used to plug in size-bounded caches. If this
+	// field is nil then NewMarshaler uses NewCache.
+	NewCache func() Cache
+
+	// TagKey is the struct tag key of the field names and options. If this
+	// field is empty then NewMarshaler uses "qs".
+	TagKey string
+
+	// TagFallbackKeys lists the struct tag keys (e.g. "json", "form") whose
+	// name is used as the query string key of the fields that don't have a
+	// qs tag. The first key found in the tag of the field wins and the
+	// options of these tags are ignored. A "-" name excludes the field. The
+	// NameTransformer is used only when none of these tags is present.
+	TagFallbackKeys []string
+
+	// Nesting controls the keys of the fields of nested structs, of the
+	// items of slices of structs and of the entries of map fields (e.g.
+	// "filter.status" for the "status" key of a map[string][]string field
+	// named "filter"). The tag options of a map field apply to its entries.
+	// If this field is unspecified then NestingModeNone is used and map
+	// fields aren't supported.
+	Nesting NestingMode
+
+	// RangeFromSuffix and RangeToSuffix are appended to the key of a Range or
+	// TimeRange field to build the keys of its bounds. The defaults are
+	// "_from" and "_to" (e.g. "price_from" and "price_to").
+	RangeFromSuffix string
+	RangeToSuffix   string
+
+	// KeyPrefix and KeySuffix are added to every key generated by the
+	// marshaler, e.g. to put all the parameters of an SDK into the x_myapi_
+	// namespace without touching the struct tags. They are applied by the
+	// methods of QSMarshaler and not by the ValuesMarshaler objects returned
+	// by CompileType. See WithMarshalKeyPrefix.
+	KeyPrefix string
+	KeySuffix string
+
+	// LazyFields defers the creation of the Marshaler objects of struct fields
+	// until the fields are marshaled for the first time. This cuts the cost
+	// of compiling large structs whose fields are mostly omitted (omitempty).
+	// The downside is that an unsupported field type is reported only when
+	// a value of the field is marshaled so CheckMarshal can't detect it (but
+	// Precompile can).
+	LazyFields bool
+
+	// NilEmbeddedAsZero marshals the fields of the nil pointers of embedded
+	// structs (e.g. *Paging in a struct embedding it) as if they pointed to
+	// zero values. By default the fields of nil embedded pointers are
+	// skipped.
+	NilEmbeddedAsZero bool
+
+	// SkipUnsupportedFields ignores the struct fields whose type isn't
+	// supported (the ones that fail with ErrUnhandledType) instead of
+	// failing the compilation of the whole struct, so an exotic field of a
+	// shared type doesn't break marshaling. Other errors (e.g. invalid tags)
+	// aren't ignored.
+	SkipUnsupportedFields bool
+
+	// OnSkippedField is called with the struct type, the field and the error
+	// of the fields ignored by SkipUnsupportedFields, e.g. to log a warning.
+	// It is called when the struct type is compiled (or when the field is
+	// marshaled for the first time with LazyFields) so it isn't called again
+	// for the cached types.
+	OnSkippedField func(t reflect.Type, field reflect.StructField, err error)
+
+	// Diagnostics receives the non-fatal notices of the marshaler. The
+	// marshaler reports the fields skipped by SkipUnsupportedFields (when
+	// the struct type is compiled). If this field is nil then the notices
+	// are dropped.
+	Diagnostics DiagnosticFunc
+
+	// Logger receives the debug traces of the marshaler: the creation of the
+	// Marshaler and ValuesMarshaler objects and the hits of their caches. If
+	// this field is nil then nothing is logged.
+	Logger *slog.Logger
+
+	// FieldFilter is called with every struct field (including the fields of
+	// embedded and nested structs) before marshaling its value. The field is
+	// omitted if it returns false. It can drop fields dynamically (e.g.
+	// parameters behind feature flags) without defining multiple struct
+	// types. It is called after the omitempty check and it must be safe for
+	// concurrent use.
+	FieldFilter func(field FieldInfo, value reflect.Value) bool
+
+	// StringNormalizer is applied to the marshaled values of string kinds,
+	// e.g. norm.NFC.String of golang.org/x/text/unicode/norm to marshal the
+	// same text always with the same bytes. If this field is nil then the
+	// strings are marshaled as they are.
+	StringNormalizer func(string) string
+
 	// Defaults for tag  options
 	TagOptionsDefaults       *MarshalTagOptions
 	TagCommonOptionsDefaults *CommonTagOptions
+
+	// nameTransformerID identifies the NameTransformer in cache variant keys.
+	// nameTransformerSet is set by the option appliers that replace the
+	// NameTransformer to make updateCacheVariant create a new ID.
+	nameTransformerID  *funcID
+	nameTransformerSet bool
+
+	// variant is the cache variant key of the options (see variantCache).
+	variant interface{}
+
+	// codecs are the named codecs registered with RegisterNamedCodec. They
+	// are shared by the copies of the options like the factories.
+	codecs *namedRegistry[PrimitiveMarshalerFunc]
+
+	// transformers are the value transformers registered with
+	// RegisterValueTransformer.
+	transformers *namedRegistry[ValueTransformer]
+
+	// mapKeys are the map key funcs registered with RegisterMapKeyType.
+	mapKeys *typeRegistry[PrimitiveMarshalerFunc]
+
+	// redact replaces the values of the secret fields with redactedValue.
+	// It is set by MarshalRedacted.
+	redact bool
+
+	// public drops the values of the embedded and inlined fields that aren't
+	// public (see publicValues). It is set by MarshalPublic with the listed
+	// keys in publicKeys.
+	public     bool
+	publicKeys []string
 }
 
 // NewDefaultMarshalOptions creates a new MarshalOptions in which every field
@@ -35,17 +162,20 @@ func NewDefaultMarshalOptions() *MarshalOptions {
 func prepareMarshalOptions(opts MarshalOptions) *MarshalOptions {
 	if opts.NameTransformer == nil {
 		opts.NameTransformer = snakeCase
+		opts.nameTransformerID = snakeCaseID
+	} else {
+		opts.nameTransformerID = new(funcID)
 	}
 
 	if opts.ValuesMarshalerFactory == nil {
 		opts.ValuesMarshalerFactory = newValuesMarshalerFactory()
 	}
-	opts.ValuesMarshalerFactory = newValuesMarshalerCache(opts.ValuesMarshalerFactory)
+	opts.ValuesMarshalerFactory = newValuesMarshalerCache(opts.ValuesMarshalerFactory, opts.NewCache)
 
 	if opts.MarshalerFactory == nil {
 		opts.MarshalerFactory = newMarshalerFactory()
 	}
-	opts.MarshalerFactory = newMarshalerCache(opts.MarshalerFactory)
+	opts.MarshalerFactory = newMarshalerCache(opts.MarshalerFactory, opts.NewCache)
 
 	// Init Unmarshal Tag Options
 	if opts.TagOptionsDefaults == nil {
@@ -61,9 +191,107 @@ func prepareMarshalOptions(opts MarshalOptions) *MarshalOptions {
 
 	opts.TagCommonOptionsDefaults.InitDefaults()
 
+	if opts.TagKey == "" {
+		opts.TagKey = tagKey
+	}
+	if opts.Nesting == NestingModeNMUnspecified {
+		opts.Nesting = NestingModeNone
+	}
+	if opts.RangeFromSuffix == "" {
+		opts.RangeFromSuffix = defaultRangeFromSuffix
+	}
+	if opts.RangeToSuffix == "" {
+		opts.RangeToSuffix = defaultRangeToSuffix
+	}
+
+	if opts.codecs == nil {
+		opts.codecs = &namedRegistry[PrimitiveMarshalerFunc]{builtin: builtinMarshalCodecs}
+	}
+	if opts.transformers == nil {
+		opts.transformers = &namedRegistry[ValueTransformer]{}
+	}
+	if opts.mapKeys == nil {
+		opts.mapKeys = &typeRegistry[PrimitiveMarshalerFunc]{}
+	}
+
+	opts.updateCacheVariant()
+
 	return &opts
 }
 
+// marshalCacheVariantKey is the snapshot of the options that identifies their
+// cache variant.
+type marshalCacheVariantKey struct {
+	nameTransformer *funcID
+	tagKey          string
+	fallbackKeys    string
+	nesting         NestingMode
+	rangeSuffixes   [2]string
+	lazyFields      bool
+	skipFields      bool
+	tag             MarshalTagOptions
+	common          CommonTagOptions
+}
+
+// updateCacheVariant has to be called after changing the options that
+// affect the compiled ValuesMarshaler objects.
+func (o *MarshalOptions) updateCacheVariant() {
+	if o.nameTransformerSet {
+		o.nameTransformerID = new(funcID)
+		o.nameTransformerSet = false
+	}
+	o.variant = marshalCacheVariantKey{
+		nameTransformer: o.nameTransformerID,
+		tagKey:          o.TagKey,
+		fallbackKeys:    strings.Join(o.TagFallbackKeys, " "),
+		nesting:         o.Nesting,
+		rangeSuffixes:   [2]string{o.RangeFromSuffix, o.RangeToSuffix},
+		lazyFields:      o.LazyFields,
+		skipFields:      o.SkipUnsupportedFields,
+		tag:             *o.TagOptionsDefaults,
+		common:          *o.TagCommonOptionsDefaults,
+	}
+}
+
+// forField returns the options used to marshal the field with the given tag:
+// o itself or a copy of o with the options of the tag if they differ from the
+// defaults in a way that affects the Marshaler of the field. (The presence
+// options are handled by the struct marshaler.)
+func (o *MarshalOptions) forField(tag *ParsedTagInfo) *MarshalOptions {
+	if tag.MarshalOpts.BoolFormat == o.TagOptionsDefaults.BoolFormat &&
+		tag.MarshalOpts.SliceOrder == o.TagOptionsDefaults.SliceOrder &&
+		tag.MarshalOpts.FloatFormat == o.TagOptionsDefaults.FloatFormat &&
+		tag.MarshalOpts.FloatPrecision == o.TagOptionsDefaults.FloatPrecision &&
+		tag.MarshalOpts.FloatZeros == o.TagOptionsDefaults.FloatZeros &&
+		*tag.CommonOpts == *o.TagCommonOptionsDefaults {
+		return o
+	}
+	c := *o
+	c.TagOptionsDefaults = tag.MarshalOpts
+	c.TagCommonOptionsDefaults = tag.CommonOpts
+	return &c
+}
+
+// forMapField returns the options used to marshal the entries of the map field
+// with the given tag: a copy of o with the options of the tag as defaults.
+// This makes the presence options of the tag (e.g. omitempty) apply to the
+// individual entries.
+func (o *MarshalOptions) forMapField(tag *ParsedTagInfo) *MarshalOptions {
+	c := *o
+	c.TagOptionsDefaults = tag.MarshalOpts
+	c.TagCommonOptionsDefaults = tag.CommonOpts
+	return &c
+}
+
+// fieldNaming returns the options that determine the keys of struct fields.
+func (o *MarshalOptions) fieldNaming() fieldNaming {
+	return fieldNaming{
+		tagKey:       o.TagKey,
+		fallbackKeys: o.TagFallbackKeys,
+		nt:           o.NameTransformer,
+	}
+}
+
 // option appliers
 func WithMarshalPresence(presence MarshalPresence) func(*QSMarshaler) {
 	return func(m *QSMarshaler) {
@@ -71,14 +299,403 @@ func WithMarshalPresence(presence MarshalPresence) func(*QSMarshaler) {
 	}
 }
 
+// WithMarshalBoolFormat sets the default format of the marshaled bool values.
+// It can be overridden per field with the truefalse, onezero, yesno and onoff
+// tag options.
+func WithMarshalBoolFormat(format MarshalBoolFormat) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.opts.TagOptionsDefaults.BoolFormat = format
+	}
+}
+
+// WithMarshalFloatFormat sets the default format of the marshaled float
+// values. It can be overridden per field with the floatfmt=<f|e|g> tag option.
+func WithMarshalFloatFormat(format MarshalFloatFormat) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.opts.TagOptionsDefaults.FloatFormat = format
+	}
+}
+
+// WithMarshalFloatPrecision sets the default number of digits of the
+// marshaled float values. Zero or a negative value means the smallest number
+// of digits that represents the value exactly. It can be overridden per field
+// with the prec=<n> tag option.
+func WithMarshalFloatPrecision(prec int) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		if prec == 0 {
+			prec = -1
+		}
+		m.opts.TagOptionsDefaults.FloatPrecision = prec
+	}
+}
+
+// WithMarshalFloatZeros sets whether the trailing zeros of floats marshaled
+// with a fixed precision are kept. It can be overridden per field with the
+// keepzeros and trimzeros tag options.
+func WithMarshalFloatZeros(value MarshalFloatZeros) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.opts.TagOptionsDefaults.FloatZeros = value
+	}
+}
+
+// WithMarshalSliceOrder sets the default order of the marshaled items of
+// slices and arrays. It can be overridden per field with the keeporder and
+// sorted tag options.
+func WithMarshalSliceOrder(order MarshalSliceOrder) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.opts.TagOptionsDefaults.SliceOrder = order
+	}
+}
+
+// WithMarshalEmptySlice sets the default marshaling of empty but non-nil
+// slices. It can be overridden per field with the nomarker and emptymarker
+// tag options.
+func WithMarshalEmptySlice(value MarshalEmptySlice) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.opts.TagOptionsDefaults.EmptySlice = value
+	}
+}
+
+// WithMarshalNameTransformer sets MarshalOptions.NameTransformer.
+func WithMarshalNameTransformer(fn NameTransformFunc) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		if fn == nil {
+			m.opts.NameTransformer = snakeCase
+			m.opts.nameTransformerID = snakeCaseID
+			m.opts.nameTransformerSet = false
+			return
+		}
+		m.opts.NameTransformer = fn
+		m.opts.nameTransformerSet = true
+	}
+}
+
+// WithValuesMarshalerFactory sets MarshalOptions.ValuesMarshalerFactory.
+func WithValuesMarshalerFactory(f ValuesMarshalerFactory) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		if f == nil {
+			f = newValuesMarshalerFactory()
+		}
+		m.opts.ValuesMarshalerFactory = newValuesMarshalerCache(f, m.opts.NewCache)
+	}
+}
+
+// WithMarshalerFactory sets MarshalOptions.MarshalerFactory.
+func WithMarshalerFactory(f MarshalerFactory) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		if f == nil {
+			f = newMarshalerFactory()
+		}
+		m.opts.MarshalerFactory = newMarshalerCache(f, m.opts.NewCache)
+	}
+}
+
+// WithMarshalCache sets MarshalOptions.NewCache. The factories of the marshaler get new
+// caches so the objects compiled so far aren't reused.
+func WithMarshalCache(newCache func() Cache) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.opts.NewCache = newCache
+		if c, ok := m.opts.ValuesMarshalerFactory.(*valuesMarshalerCache); ok {
+			m.opts.ValuesMarshalerFactory = newValuesMarshalerCache(c.wrapped, newCache)
+		}
+		if c, ok := m.opts.MarshalerFactory.(*marshalerCache); ok {
+			m.opts.MarshalerFactory = newMarshalerCache(c.wrapped, newCache)
+		}
+	}
+}
+
+// WithMarshalTagOptionsDefaults sets MarshalOptions.TagOptionsDefaults. The
+// unspecified options are set to their defaults.
+func WithMarshalTagOptionsDefaults(value MarshalTagOptions) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		value.InitDefaults()
+		m.opts.TagOptionsDefaults = &value
+	}
+}
+
+// WithMarshalTagCommonOptionsDefaults sets
+// MarshalOptions.TagCommonOptionsDefaults. The unspecified options are set to
+// their defaults.
+func WithMarshalTagCommonOptionsDefaults(value CommonTagOptions) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		value.InitDefaults()
+		m.opts.TagCommonOptionsDefaults = &value
+	}
+}
+
+// WithMarshalTagFallbackKeys sets MarshalOptions.TagFallbackKeys.
+func WithMarshalTagFallbackKeys(keys ...string) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.opts.TagFallbackKeys = keys
+	}
+}
+
+// WithMarshalNilEmbeddedAsZero sets MarshalOptions.NilEmbeddedAsZero.
+func WithMarshalNilEmbeddedAsZero(enabled bool) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.opts.NilEmbeddedAsZero = enabled
+	}
+}
+
+// WithMarshalSkipUnsupportedFields sets MarshalOptions.SkipUnsupportedFields
+// and MarshalOptions.OnSkippedField. The onSkipped func can be nil.
+func WithMarshalSkipUnsupportedFields(onSkipped func(t reflect.Type, field reflect.StructField, err error)) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.opts.SkipUnsupportedFields = true
+		m.opts.OnSkippedField = onSkipped
+	}
+}
+
+// WithMarshalDiagnostics sets MarshalOptions.Diagnostics. A nil fn removes
+// the hook.
+func WithMarshalDiagnostics(fn DiagnosticFunc) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.opts.Diagnostics = fn
+	}
+}
+
+// WithMarshalLogger sets MarshalOptions.Logger. The traces are logged at
+// debug level. A nil logger disables the traces.
+func WithMarshalLogger(logger *slog.Logger) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.opts.Logger = logger
+	}
+}
+
+// WithMarshalLazyFields sets MarshalOptions.LazyFields.
+func WithMarshalLazyFields(lazy bool) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.opts.LazyFields = lazy
+	}
+}
+
+// WithMarshalFieldFilter sets MarshalOptions.FieldFilter.
+func WithMarshalFieldFilter(fn func(field FieldInfo, value reflect.Value) bool) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.opts.FieldFilter = fn
+	}
+}
+
+// WithMarshalStringNormalizer sets MarshalOptions.StringNormalizer.
+func WithMarshalStringNormalizer(fn func(string) string) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.opts.StringNormalizer = fn
+	}
+}
+
+// WithMarshalTagKey sets MarshalOptions.TagKey.
+func WithMarshalTagKey(key string) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		if key == "" {
+			key = tagKey
+		}
+		m.opts.TagKey = key
+	}
+}
+
+// WithMarshalNesting sets MarshalOptions.Nesting.
+func WithMarshalNesting(mode NestingMode) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		if mode == NestingModeNMUnspecified {
+			mode = NestingModeNone
+		}
+		m.opts.Nesting = mode
+	}
+}
+
+// WithMarshalRangeSuffixes sets MarshalOptions.RangeFromSuffix and
+// MarshalOptions.RangeToSuffix. Empty suffixes are replaced with the defaults.
+func WithMarshalRangeSuffixes(from, to string) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		if from == "" {
+			from = defaultRangeFromSuffix
+		}
+		if to == "" {
+			to = defaultRangeToSuffix
+		}
+		m.opts.RangeFromSuffix, m.opts.RangeToSuffix = from, to
+	}
+}
+
+// WithMarshalSchemaCompat configures the marshaler to read the `schema` tags
+// of gorilla/schema and to build the keys like its encoder: the Go field
+// names are used as keys by default and nested structs use dotted keys
+// ("phones.0.number").
+func WithMarshalSchemaCompat() func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		WithMarshalTagKey("schema")(m)
+		WithMarshalNesting(NestingModeDots)(m)
+		WithMarshalNameTransformer(fieldName)(m)
+	}
+}
+
+// WithMarshalFormCompat configures the marshaler to read the `form` tags of
+// go-playground/form and to build the keys like its encoder: the Go field
+// names are used as keys by default and nested structs use dotted keys with
+// bracketed indices ("phones[0].number").
+func WithMarshalFormCompat() func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		WithMarshalTagKey("form")(m)
+		WithMarshalNesting(NestingModeDotsIndexBrackets)(m)
+		WithMarshalNameTransformer(fieldName)(m)
+	}
+}
+
+// WithMarshalRackCompat configures the marshaler to build the keys that
+// Rack::Utils.parse_nested_query (Rails) parses into nested hashes and
+// arrays: nested structs and map fields use bracketed keys
+// ("user[address][city]"), the items of slices of structs use indices
+// ("phones[0][number]") and the items of the other slices use the "[]"
+// suffix ("tags[]=a&tags[]=b"). See NestingModeBrackets.
+func WithMarshalRackCompat() func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		WithMarshalNesting(NestingModeBrackets)(m)
+		WithMarshalOptionSliceKeys(OptionSliceKeysBrackets)(m)
+	}
+}
+
+// WithMarshalJSONAPICompat configures the marshaler to build the query
+// parameters of the JSON:API specification: nested structs and map fields use
+// bracketed keys ("page[number]", "fields[articles]", "filter[author]") and
+// the items of slices are comma separated ("include=author,comments"). See
+// JSONAPIQuery.
+func WithMarshalJSONAPICompat() func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		WithMarshalNesting(NestingModeBrackets)(m)
+		WithMarshalOptionSliceSeparator(OptionSliceSeparatorComma)(m)
+	}
+}
+
+// WithMarshalNpmQSCompat configures the marshaler to build the keys that the
+// qs package of npm parses into nested objects and arrays: bracketed keys for
+// nested structs and map fields ("address[city]"), indices for the items of
+// slices of structs ("phones[0][number]") and the "[]" suffix for the items
+// of the other slices ("tags[]=a"). The allowDots option of qs uses dots for
+// nested structs and map fields ("address.city", "phones[0].number").
+func WithMarshalNpmQSCompat(allowDots bool) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		WithMarshalRackCompat()(m)
+		if allowDots {
+			WithMarshalNesting(NestingModeBracketsAllowDots)(m)
+		}
+	}
+}
+
+// WithMarshalURLCompat configures the marshaler to read the `url` tags of
+// google/go-querystring: the Go field names are used as keys by default and
+// the omitempty, comma, semicolon, space, brackets and numbered options of
+// the tags are honored. Nested structs and the other options of
+// go-querystring (e.g. int and unix) aren't supported.
+func WithMarshalURLCompat() func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		WithMarshalTagKey("url")(m)
+		WithMarshalNameTransformer(fieldName)(m)
+	}
+}
+
 func WithCustomUrlQueryToStringEncoder(fn func(values url.Values) string) func(*QSMarshaler) {
 	return func(m *QSMarshaler) {
 		m._EncodeValues = fn
 	}
 }
 
+// WithMarshalQueryEncoding encodes the query strings with the given
+// QueryEncoding (e.g. QueryEncodingRFC3986) instead of url.Values.Encode.
+func WithMarshalQueryEncoding(enc QueryEncoding) func(*QSMarshaler) {
+	return WithCustomUrlQueryToStringEncoder(enc.Encode)
+}
+
 func WithMarshalOptionSliceSeparator(value OptionSliceSeparator) func(*QSMarshaler) {
 	return func(m *QSMarshaler) {
 		m.opts.TagCommonOptionsDefaults.SliceSeparator = value
+		m.opts.TagCommonOptionsDefaults.CustomSeparator = ""
+	}
+}
+
+// WithMarshalCustomSliceSeparator sets the default slice separator to an
+// arbitrary string. See CommonTagOptions.CustomSeparator. An empty string
+// disables the separator.
+func WithMarshalCustomSliceSeparator(sep string) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.opts.TagCommonOptionsDefaults.SliceSeparator = OptionSliceSeparatorNone
+		m.opts.TagCommonOptionsDefaults.CustomSeparator = sep
+	}
+}
+
+// WithMarshalNullValue sets the default sentinel value of nil pointers. See
+// CommonTagOptions.Null. An empty string disables the sentinel.
+func WithMarshalNullValue(null string) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.opts.TagCommonOptionsDefaults.Null = null
+	}
+}
+
+// WithMarshalOptionSliceKeys sets the default keys of the items of slices
+// and arrays (e.g. OptionSliceKeysBrackets for PHP and jQuery backends). It
+// can be overridden per field with the repeat, brackets and numbered tag
+// options.
+func WithMarshalOptionSliceKeys(value OptionSliceKeys) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.opts.TagCommonOptionsDefaults.SliceKeys = value
+	}
+}
+
+// WithMarshalOptionSliceDuplicates sets the default handling of the duplicate
+// items of slices. It can be overridden per field with the keepduplicates and
+// unique tag options.
+func WithMarshalOptionSliceDuplicates(value OptionSliceDuplicates) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.opts.TagCommonOptionsDefaults.SliceDuplicates = value
+	}
+}
+
+// WithMarshalHook sets a hook that is called after each Marshal and
+// MarshalValues call of the marshaler (including the calls of the
+// TypedMarshaler objects created from it) with the type, the duration and the
+// error of the call. A nil fn removes the hook.
+func WithMarshalHook(fn HookFunc) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.hook = fn
+	}
+}
+
+// WithMarshalKeyPrefix sets MarshalOptions.KeyPrefix: the prefix added to
+// every key generated by the marshaler. It is useful when the parameters of
+// a component are embedded into a page that already owns the un-prefixed
+// namespace.
+func WithMarshalKeyPrefix(prefix string) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.opts.KeyPrefix = prefix
+	}
+}
+
+// WithMarshalKeySuffix sets MarshalOptions.KeySuffix: the suffix added to
+// every key generated by the marshaler.
+func WithMarshalKeySuffix(suffix string) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.opts.KeySuffix = suffix
+	}
+}
+
+// skipField reports whether the field of struct type t that can't be compiled
+// because of err is ignored by SkipUnsupportedFields.
+func (o *MarshalOptions) skipField(t reflect.Type, sf reflect.StructField, err error) bool {
+	if !o.SkipUnsupportedFields || !errors.Is(err, ErrUnhandledType) {
+		return false
+	}
+	if o.OnSkippedField != nil {
+		o.OnSkippedField(t, sf, err)
+	}
+	if o.Diagnostics != nil {
+		o.Diagnostics(skippedFieldDiagnostic(t, sf, err))
+	}
+	return true
+}
+
+// logger returns the Logger of the options. It accepts a nil receiver.
+func (o *MarshalOptions) logger() *slog.Logger {
+	if o == nil {
+		return nil
 	}
+	return o.Logger
 }
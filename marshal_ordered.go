@@ -0,0 +1,93 @@
+package qs
+
+import (
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MarshalOrdered marshals a given object into OrderedValues with the
+// DefaultMarshaler. See QSMarshaler.MarshalOrdered.
+func MarshalOrdered(i interface{}) (OrderedValues, error) {
+	return DefaultMarshaler.MarshalOrdered(i)
+}
+
+// MarshalOrdered marshals a given object into OrderedValues. The keys of the
+// struct fields follow the order of the fields (the fields of embedded
+// structs take the place of the embedded struct) and the keys of the fields
+// of nested structs follow the key of their parent field in sorted order.
+// The keys of maps are sorted.
+func (p *QSMarshaler) MarshalOrdered(i interface{}) (ov OrderedValues, err error) {
+	if p.hook != nil {
+		defer p.hook.report(reflect.TypeOf(i), time.Now(), &err)
+	}
+	vum, values, err := p.marshalInterface(i)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	order := marshalerKeys(vum)
+	rank := func(k string) int {
+		k = strings.TrimSuffix(strings.TrimPrefix(k, p.opts.KeyPrefix), p.opts.KeySuffix)
+		best, bestLen := len(order), -1
+		for i, name := range order {
+			if len(name) > bestLen && strings.HasPrefix(k, name) {
+				best, bestLen = i, len(name)
+			}
+		}
+		return best
+	}
+	ranks := make(map[string]int, len(keys))
+	for _, k := range keys {
+		ranks[k] = rank(k)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return ranks[keys[i]] < ranks[keys[j]]
+	})
+
+	for _, k := range keys {
+		for _, v := range values[k] {
+			ov = append(ov, KV{Key: k, Value: v})
+		}
+	}
+	return ov, nil
+}
+
+// marshalerKeys returns the keys of the fields of the struct marshaled by
+// vm in the order of the fields.
+func marshalerKeys(vm ValuesMarshaler) []string {
+	switch vm := vm.(type) {
+	case *structMarshaler:
+		type field struct {
+			index []int
+			keys  []string
+		}
+		fields := make([]field, 0, len(vm.Fields)+len(vm.EmbeddedFields))
+		for _, fm := range vm.Fields {
+			fields = append(fields, field{fm.Index, []string{fm.Tag.Name}})
+		}
+		for _, ef := range vm.EmbeddedFields {
+			fields = append(fields, field{ef.Index, marshalerKeys(ef.ValuesMarshaler)})
+		}
+		slices.SortFunc(fields, func(a, b field) int {
+			return slices.Compare(a.index, b.index)
+		})
+
+		var keys []string
+		for _, f := range fields {
+			keys = append(keys, f.keys...)
+		}
+		return keys
+	case *ptrValuesMarshaler:
+		return marshalerKeys(vm.ElemMarshaler)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package qs
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+)
+
+// MarshalPublic marshals the public fields of an object with the
+// DefaultMarshaler. See QSMarshaler.MarshalPublic.
+func MarshalPublic(i interface{}, keys ...string) (string, error) {
+	return DefaultMarshaler.MarshalPublic(i, keys...)
+}
+
+// MarshalPublic is the same as Marshal but it includes only the struct fields
+// with the public tag option (`qs:"state,public"`) and the fields whose keys
+// are listed in keys. It is meant for the URLs that leave the service (e.g.
+// redirect and callback URLs) and must not leak internal parameters: a new
+// field is omitted until it is marked as public.
+//
+// The fields of nested structs are included only if both the field of the
+// nested struct and their own fields are public (or listed). The listed keys
+// of nested fields are relative to the nested struct. Inlined maps and types
+// with their own ValuesMarshaler can produce any key: their entries are
+// included only if the field is public (or listed) and their keys are listed
+// too. The FieldFilter of the marshaler still applies to the public fields.
+func (p *QSMarshaler) MarshalPublic(i interface{}, keys ...string) (string, error) {
+	return p.With(withPublicFields(keys)).Marshal(i)
+}
+
+// RedirectURL appends the public fields of an object to a URL with the
+// DefaultMarshaler. See QSMarshaler.RedirectURL.
+func RedirectURL(base string, i interface{}, keys ...string) (string, error) {
+	return DefaultMarshaler.RedirectURL(base, i, keys...)
+}
+
+// RedirectURL appends the public fields of an object (see MarshalPublic) to
+// the query string of the base URL, e.g. to build the redirect and callback
+// URLs of OAuth flows. The query string and the fragment of the base URL are
+// kept.
+func (p *QSMarshaler) RedirectURL(base string, i interface{}, keys ...string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL %q :: %w", base, err)
+	}
+	s, err := p.MarshalPublic(i, keys...)
+	if err != nil {
+		return "", err
+	}
+	if s != "" {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += s
+	}
+	return u.String(), nil
+}
+
+// withPublicFields adds a FieldFilter that drops the fields that aren't
+// public or listed in keys to the marshaler.
+func withPublicFields(keys []string) func(*QSMarshaler) {
+	return func(m *QSMarshaler) {
+		m.opts.public = true
+		m.opts.publicKeys = keys
+		filter := m.opts.FieldFilter
+		m.opts.FieldFilter = func(field FieldInfo, value reflect.Value) bool {
+			if !field.Tag.MarshalOpts.Public && !slices.Contains(keys, field.Tag.Name) {
+				return false
+			}
+			return filter == nil || filter(field, value)
+		}
+	}
+}
+
+// publicValues returns the public entries of the values marshaled by the
+// embedded (or inlined) field ef. The fields of embedded structs are checked
+// one by one by the FieldFilter of withPublicFields. Other ValuesMarshaler
+// objects (e.g. inlined maps) can marshal any key so their values are kept
+// only if the field is public or listed in keys and then only the entries
+// whose keys are listed in keys.
+func publicValues(ef embeddedFieldMarshaler, vs url.Values, keys []string) url.Values {
+	if marshalsStructFields(ef.ValuesMarshaler) {
+		return vs
+	}
+	if ef.Tag == nil || !ef.Tag.MarshalOpts.Public && !slices.Contains(keys, ef.Tag.Name) {
+		return nil
+	}
+	public := make(url.Values)
+	for k, a := range vs {
+		if slices.Contains(keys, k) {
+			public[k] = a
+		}
+	}
+	return public
+}
+
+// marshalsStructFields returns true if vm marshals the fields of a struct.
+func marshalsStructFields(vm ValuesMarshaler) bool {
+	for {
+		switch m := vm.(type) {
+		case *structMarshaler:
+			return true
+		case *ptrValuesMarshaler:
+			vm = m.ElemMarshaler
+		default:
+			return false
+		}
+	}
+}
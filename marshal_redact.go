@@ -0,0 +1,30 @@
+package qs
+
+// redactedValue replaces the values of the secret fields in MarshalRedacted.
+const redactedValue = "***"
+
+// MarshalRedacted marshals an object with the secret fields redacted with the
+// DefaultMarshaler. See QSMarshaler.MarshalRedacted.
+func MarshalRedacted(i interface{}) (string, error) {
+	return DefaultMarshaler.MarshalRedacted(i)
+}
+
+// MarshalRedacted is the same as Marshal but the values of the struct fields
+// with the secret tag option (`qs:"token,secret"`) are replaced with a single
+// "***" value so the query structs of requests can be logged safely:
+//
+//	s, _ := qs.MarshalRedacted(&req)
+//	logger.Info("request", "query", s)
+//
+// The redacted fields are omitted only if they would be omitted by Marshal
+// (e.g. the empty fields with omitempty). A secret field of a nested struct
+// is redacted as a whole. The "***" values are percent-encoded by the query
+// encoding of the marshaler like the other values.
+func (p *QSMarshaler) MarshalRedacted(i interface{}) (string, error) {
+	return p.With(withRedaction).Marshal(i)
+}
+
+// withRedaction makes the marshaler redact the secret fields.
+func withRedaction(m *QSMarshaler) {
+	m.opts.redact = true
+}
@@ -1,4 +1,4 @@
-// Code generated by "go-stringer -type=MarshalPresence --trimprefix=MarshalPresence -output marshal_string.go -nametransform=lower -fromstringgenfn"; DO NOT EDIT.
+// Code generated by "go-stringer -type=MarshalPresence,MarshalBoolFormat,MarshalSliceOrder,MarshalEmptySlice,MarshalEscape,MarshalFloatFormat,MarshalFloatZeros --trimprefix=@me -output marshal_string.go -nametransform=lower -fromstringgenfn"; DO NOT EDIT.
 
 package qs
 
@@ -14,11 +14,13 @@ func _() {
 	_ = x[MarshalPresenceMPUnspecified-0]
 	_ = x[MarshalPresenceKeepEmpty-1]
 	_ = x[MarshalPresenceOmitEmpty-2]
+	_ = x[MarshalPresenceOmitZero-3]
+	_ = x[MarshalPresenceOmitNil-4]
 }
 
-const _MarshalPresence_name = "mpunspecifiedkeepemptyomitempty"
+const _MarshalPresence_name = "mpunspecifiedkeepemptyomitemptyomitzeroomitnil"
 
-var _MarshalPresence_index = [...]uint8{0, 13, 22, 31}
+var _MarshalPresence_index = [...]uint8{0, 13, 22, 31, 39, 46}
 
 func (i MarshalPresence) String() string {
 	if i < 0 || i >= MarshalPresence(len(_MarshalPresence_index)-1) {
@@ -27,10 +29,176 @@ func (i MarshalPresence) String() string {
 	return _MarshalPresence_name[_MarshalPresence_index[i]:_MarshalPresence_index[i+1]]
 }
 func MarshalPresenceFromString(s string) (MarshalPresence, error) {
-	for i := 0; i < 3; i++ {
+	for i := 0; i < 5; i++ {
 		if e := MarshalPresence(i + 0); s == e.String() {
 			return e, nil
 		}
 	}
 	return MarshalPresence(0), errors.New("cannot deternime MarshalPresence from string")
 }
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[MarshalBoolFormatBFUnspecified-0]
+	_ = x[MarshalBoolFormatTrueFalse-1]
+	_ = x[MarshalBoolFormatOneZero-2]
+	_ = x[MarshalBoolFormatYesNo-3]
+	_ = x[MarshalBoolFormatOnOff-4]
+}
+
+const _MarshalBoolFormat_name = "bfunspecifiedtruefalseonezeroyesnoonoff"
+
+var _MarshalBoolFormat_index = [...]uint8{0, 13, 22, 29, 34, 39}
+
+func (i MarshalBoolFormat) String() string {
+	if i < 0 || i >= MarshalBoolFormat(len(_MarshalBoolFormat_index)-1) {
+		return "MarshalBoolFormat(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _MarshalBoolFormat_name[_MarshalBoolFormat_index[i]:_MarshalBoolFormat_index[i+1]]
+}
+func MarshalBoolFormatFromString(s string) (MarshalBoolFormat, error) {
+	for i := 0; i < 5; i++ {
+		if e := MarshalBoolFormat(i + 0); s == e.String() {
+			return e, nil
+		}
+	}
+	return MarshalBoolFormat(0), errors.New("cannot deternime MarshalBoolFormat from string")
+}
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[MarshalSliceOrderSOUnspecified-0]
+	_ = x[MarshalSliceOrderKeepOrder-1]
+	_ = x[MarshalSliceOrderSorted-2]
+}
+
+const _MarshalSliceOrder_name = "sounspecifiedkeepordersorted"
+
+var _MarshalSliceOrder_index = [...]uint8{0, 13, 22, 28}
+
+func (i MarshalSliceOrder) String() string {
+	if i < 0 || i >= MarshalSliceOrder(len(_MarshalSliceOrder_index)-1) {
+		return "MarshalSliceOrder(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _MarshalSliceOrder_name[_MarshalSliceOrder_index[i]:_MarshalSliceOrder_index[i+1]]
+}
+func MarshalSliceOrderFromString(s string) (MarshalSliceOrder, error) {
+	for i := 0; i < 3; i++ {
+		if e := MarshalSliceOrder(i + 0); s == e.String() {
+			return e, nil
+		}
+	}
+	return MarshalSliceOrder(0), errors.New("cannot deternime MarshalSliceOrder from string")
+}
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[MarshalEmptySliceESUnspecified-0]
+	_ = x[MarshalEmptySliceNoMarker-1]
+	_ = x[MarshalEmptySliceEmptyMarker-2]
+}
+
+const _MarshalEmptySlice_name = "esunspecifiednomarkeremptymarker"
+
+var _MarshalEmptySlice_index = [...]uint8{0, 13, 21, 32}
+
+func (i MarshalEmptySlice) String() string {
+	if i < 0 || i >= MarshalEmptySlice(len(_MarshalEmptySlice_index)-1) {
+		return "MarshalEmptySlice(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _MarshalEmptySlice_name[_MarshalEmptySlice_index[i]:_MarshalEmptySlice_index[i+1]]
+}
+func MarshalEmptySliceFromString(s string) (MarshalEmptySlice, error) {
+	for i := 0; i < 3; i++ {
+		if e := MarshalEmptySlice(i + 0); s == e.String() {
+			return e, nil
+		}
+	}
+	return MarshalEmptySlice(0), errors.New("cannot deternime MarshalEmptySlice from string")
+}
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[MarshalEscapeMEUnspecified-0]
+	_ = x[MarshalEscapeQuery-1]
+	_ = x[MarshalEscapeNone-2]
+	_ = x[MarshalEscapePath-3]
+}
+
+const _MarshalEscape_name = "meunspecifiedquerynonepath"
+
+var _MarshalEscape_index = [...]uint8{0, 13, 18, 22, 26}
+
+func (i MarshalEscape) String() string {
+	if i < 0 || i >= MarshalEscape(len(_MarshalEscape_index)-1) {
+		return "MarshalEscape(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _MarshalEscape_name[_MarshalEscape_index[i]:_MarshalEscape_index[i+1]]
+}
+func MarshalEscapeFromString(s string) (MarshalEscape, error) {
+	for i := 0; i < 4; i++ {
+		if e := MarshalEscape(i + 0); s == e.String() {
+			return e, nil
+		}
+	}
+	return MarshalEscape(0), errors.New("cannot deternime MarshalEscape from string")
+}
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[MarshalFloatFormatFFUnspecified-0]
+	_ = x[MarshalFloatFormatF-1]
+	_ = x[MarshalFloatFormatE-2]
+	_ = x[MarshalFloatFormatG-3]
+}
+
+const _MarshalFloatFormat_name = "ffunspecifiedfeg"
+
+var _MarshalFloatFormat_index = [...]uint8{0, 13, 14, 15, 16}
+
+func (i MarshalFloatFormat) String() string {
+	if i < 0 || i >= MarshalFloatFormat(len(_MarshalFloatFormat_index)-1) {
+		return "MarshalFloatFormat(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _MarshalFloatFormat_name[_MarshalFloatFormat_index[i]:_MarshalFloatFormat_index[i+1]]
+}
+func MarshalFloatFormatFromString(s string) (MarshalFloatFormat, error) {
+	for i := 0; i < 4; i++ {
+		if e := MarshalFloatFormat(i + 0); s == e.String() {
+			return e, nil
+		}
+	}
+	return MarshalFloatFormat(0), errors.New("cannot deternime MarshalFloatFormat from string")
+}
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[MarshalFloatZerosFZUnspecified-0]
+	_ = x[MarshalFloatZerosKeepZeros-1]
+	_ = x[MarshalFloatZerosTrimZeros-2]
+}
+
+const _MarshalFloatZeros_name = "fzunspecifiedkeepzerostrimzeros"
+
+var _MarshalFloatZeros_index = [...]uint8{0, 13, 22, 31}
+
+func (i MarshalFloatZeros) String() string {
+	if i < 0 || i >= MarshalFloatZeros(len(_MarshalFloatZeros_index)-1) {
+		return "MarshalFloatZeros(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _MarshalFloatZeros_name[_MarshalFloatZeros_index[i]:_MarshalFloatZeros_index[i+1]]
+}
+func MarshalFloatZerosFromString(s string) (MarshalFloatZeros, error) {
+	for i := 0; i < 3; i++ {
+		if e := MarshalFloatZeros(i + 0); s == e.String() {
+			return e, nil
+		}
+	}
+	return MarshalFloatZeros(0), errors.New("cannot deternime MarshalFloatZeros from string")
+}
@@ -1,11 +1,15 @@
 package qs
 
 import (
+	"cmp"
+	"encoding"
 	"fmt"
 	"net/url"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -40,6 +44,14 @@ func (p *ptrMarshaler) Marshal(v reflect.Value, opts *MarshalOptions) ([]string,
 	return p.ElemMarshaler.Marshal(v.Elem(), opts)
 }
 
+// stringSlicePool holds the temporary []string buffers of the slices that
+// are joined into a single string with a separator.
+var stringSlicePool = sync.Pool{
+	New: func() interface{} {
+		return new([]string)
+	},
+}
+
 type arrayAndSliceMarshaler struct {
 	Type          reflect.Type
 	ElemMarshaler Marshaler
@@ -72,11 +84,38 @@ func (p *arrayAndSliceMarshaler) Marshal(v reflect.Value, opts *MarshalOptions)
 		return nil, nil
 	}
 
-	a := make([]string, vlen)
+	sep, escape := opts.TagCommonOptionsDefaults.separator()
+
+	var a []string
+	if len(sep) != 0 {
+		// The items are needed only until they are joined.
+		buf := stringSlicePool.Get().(*[]string)
+		defer func() {
+			clear(*buf)
+			stringSlicePool.Put(buf)
+		}()
+		a = slices.Grow((*buf)[:0], vlen)[:vlen]
+		*buf = a
+	} else {
+		a = make([]string, vlen)
+	}
+
+	if pm, ok := p.ElemMarshaler.(*primitiveMarshalerFunc); ok {
+		// Primitive items don't need a []string each.
+		for i := 0; i < vlen; i++ {
+			s, err := pm.fn(v.Index(i), opts)
+			if err != nil {
+				return nil, fmt.Errorf("error marshaling array/slice index %v :: %w", i, err)
+			}
+			a[i] = s
+		}
+		return joinSliceItems(p.arrangeItems(v, a, opts), sep, escape), nil
+	}
+
 	for i := 0; i < vlen; i++ {
 		a2, err := p.ElemMarshaler.Marshal(v.Index(i), opts)
 		if err != nil {
-			return nil, fmt.Errorf("error marshaling array/slice index %v :: %v", i, err)
+			return nil, fmt.Errorf("error marshaling array/slice index %v :: %w", i, err)
 		}
 		if len(a2) != 1 {
 			return nil, fmt.Errorf("marshaler returned a slice of length %v for array/slice index %v", len(a2), i)
@@ -84,30 +123,93 @@ func (p *arrayAndSliceMarshaler) Marshal(v reflect.Value, opts *MarshalOptions)
 		a[i] = a2[0]
 	}
 
-	sep := ""
-	switch opts.TagCommonOptionsDefaults.SliceSeparator {
-	case OptionSliceSeparatorNone:
-	case OptionSliceSeparatorComma:
-		sep = ","
-	case OptionSliceSeparatorSemicolon:
-		sep = ";"
-	case OptionSliceSeparatorSpace:
-		sep = " "
+	return joinSliceItems(p.arrangeItems(v, a, opts), sep, escape), nil
+}
+
+// arrangeItems applies the SliceOrder and SliceDuplicates options to the
+// marshaled items a of v.
+func (p *arrayAndSliceMarshaler) arrangeItems(v reflect.Value, a []string, opts *MarshalOptions) []string {
+	if opts.TagOptionsDefaults.SliceOrder == MarshalSliceOrderSorted {
+		sortSliceItems(v, a)
+	}
+	if opts.TagCommonOptionsDefaults.SliceDuplicates == OptionSliceDuplicatesUnique && p.Type.Kind() == reflect.Slice {
+		a = uniqueItems(a)
+	}
+	return a
+}
+
+// sortSliceItems sorts the marshaled items a of v in ascending order. Items of
+// numeric and string kinds are compared by value, other items by their
+// marshaled value. Nil pointers come first.
+func sortSliceItems(v reflect.Value, a []string) {
+	idx := make([]int, len(a))
+	for i := range idx {
+		idx[i] = i
+	}
+	slices.SortStableFunc(idx, func(i, j int) int {
+		if c := compareItems(v.Index(i), v.Index(j)); c != 0 {
+			return c
+		}
+		return strings.Compare(a[i], a[j])
+	})
+
+	sorted := make([]string, len(a))
+	for i, j := range idx {
+		sorted[i] = a[j]
+	}
+	copy(a, sorted)
+}
+
+// compareItems compares the values of x and y if they are of a numeric or
+// string kind. It returns 0 for other kinds.
+func compareItems(x, y reflect.Value) int {
+	for x.Kind() == reflect.Ptr {
+		switch {
+		case x.IsNil() && y.IsNil():
+			return 0
+		case x.IsNil():
+			return -1
+		case y.IsNil():
+			return 1
+		}
+		x, y = x.Elem(), y.Elem()
+	}
+
+	switch x.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(x.Int(), y.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cmp.Compare(x.Uint(), y.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(x.Float(), y.Float())
+	case reflect.String:
+		return strings.Compare(x.String(), y.String())
 	default:
-		panic(fmt.Sprintf("unexpected qs.OptionSliceSeparator: %#v", opts.TagCommonOptionsDefaults.SliceSeparator))
+		return 0
 	}
+}
 
+// joinSliceItems joins the items into a single string if sep isn't empty.
+// The occurrences of sep in the items are escaped if escape is true.
+func joinSliceItems(a []string, sep string, escape bool) []string {
+	if escape {
+		for i, s := range a {
+			a[i] = escapeSeparator(s, sep)
+		}
+	}
 	if len(sep) != 0 {
-		return []string{strings.Join(a, sep)}, nil
+		return []string{strings.Join(a, sep)}
 	}
-
-	return a, nil
+	return a
 }
 
 func marshalString(v reflect.Value, opts *MarshalOptions) (string, error) {
 	if v.Kind() != reflect.String {
 		return "", &WrongKindError{Expected: reflect.String, Actual: v.Type()}
 	}
+	if opts.StringNormalizer != nil {
+		return opts.StringNormalizer(v.String()), nil
+	}
 	return v.String(), nil
 }
 
@@ -115,7 +217,26 @@ func marshalBool(v reflect.Value, opts *MarshalOptions) (string, error) {
 	if v.Kind() != reflect.Bool {
 		return "", &WrongKindError{Expected: reflect.Bool, Actual: v.Type()}
 	}
-	return strconv.FormatBool(v.Bool()), nil
+	b := v.Bool()
+	switch opts.TagOptionsDefaults.BoolFormat {
+	case MarshalBoolFormatOneZero:
+		if b {
+			return "1", nil
+		}
+		return "0", nil
+	case MarshalBoolFormatYesNo:
+		if b {
+			return "yes", nil
+		}
+		return "no", nil
+	case MarshalBoolFormatOnOff:
+		if b {
+			return "on", nil
+		}
+		return "off", nil
+	default:
+		return strconv.FormatBool(b), nil
+	}
 }
 
 func marshalInt(v reflect.Value, opts *MarshalOptions) (string, error) {
@@ -148,7 +269,40 @@ func marshalFloat(v reflect.Value, opts *MarshalOptions) (string, error) {
 		return "", &WrongKindError{Expected: reflect.Float32, Actual: v.Type()}
 	}
 
-	return strconv.FormatFloat(v.Float(), 'f', -1, bitSize), nil
+	tagOpts := opts.TagOptionsDefaults
+	s := strconv.FormatFloat(v.Float(), tagOpts.FloatFormat.verb(), tagOpts.FloatPrecision, bitSize)
+	if tagOpts.FloatZeros == MarshalFloatZerosTrimZeros && tagOpts.FloatPrecision > 0 {
+		s = trimFloatZeros(s)
+	}
+	return s, nil
+}
+
+// verb returns the format byte of strconv.FormatFloat and big.Float.Text.
+func (f MarshalFloatFormat) verb() byte {
+	switch f {
+	case MarshalFloatFormatE:
+		return 'e'
+	case MarshalFloatFormatG:
+		return 'g'
+	default:
+		return 'f'
+	}
+}
+
+// trimFloatZeros removes the trailing zeros of the fractional part of a
+// formatted float and the decimal point if nothing remains after it. The
+// exponent is kept: "1.500000e+06" becomes "1.5e+06".
+func trimFloatZeros(s string) string {
+	mantissa, exp := s, ""
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa, exp = s[:i], s[i:]
+	}
+	if !strings.Contains(mantissa, ".") {
+		return s
+	}
+	mantissa = strings.TrimRight(mantissa, "0")
+	mantissa = strings.TrimSuffix(mantissa, ".")
+	return mantissa + exp
 }
 
 func marshalTime(v reflect.Value, opts *MarshalOptions) (string, error) {
@@ -156,6 +310,10 @@ func marshalTime(v reflect.Value, opts *MarshalOptions) (string, error) {
 	if t != timeType {
 		return "", &WrongTypeError{Actual: t, Expected: timeType}
 	}
+	if v.CanAddr() {
+		// Avoids the allocation of the copy made by Interface.
+		return v.Addr().Interface().(*time.Time).Format(time.RFC3339), nil
+	}
 	return v.Interface().(time.Time).Format(time.RFC3339), nil
 }
 
@@ -164,6 +322,10 @@ func marshalURL(v reflect.Value, opts *MarshalOptions) (string, error) {
 	if t != urlType {
 		return "", &WrongTypeError{Actual: t, Expected: urlType}
 	}
+	if v.CanAddr() {
+		// Avoids the allocation of the copy made by Interface.
+		return v.Addr().Interface().(*url.URL).String(), nil
+	}
 	u := v.Interface().(url.URL)
 	return u.String(), nil
 }
@@ -175,3 +337,43 @@ func marshalWithMarshalQS(v reflect.Value, opts *MarshalOptions) ([]string, erro
 	}
 	return marshalQS.MarshalQS(opts)
 }
+
+// marshalWithPtrMarshalQS marshals the values of types that implement
+// MarshalQS with a pointer receiver.
+func marshalWithPtrMarshalQS(v reflect.Value, opts *MarshalOptions) ([]string, error) {
+	if !v.CanAddr() {
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		v = c
+	}
+	marshalQS, ok := v.Addr().Interface().(MarshalQS)
+	if !ok {
+		return nil, fmt.Errorf("expected a type that implements MarshalQS, got %v", v.Type())
+	}
+	return marshalQS.MarshalQS(opts)
+}
+
+// marshalWithTextMarshaler marshals the values of types that implement
+// encoding.TextMarshaler with a value receiver.
+func marshalWithTextMarshaler(v reflect.Value, opts *MarshalOptions) (string, error) {
+	tm, ok := v.Interface().(encoding.TextMarshaler)
+	if !ok {
+		return "", fmt.Errorf("expected a type that implements encoding.TextMarshaler, got %v", v.Type())
+	}
+	b, err := tm.MarshalText()
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// marshalWithPtrTextMarshaler marshals the values of types that implement
+// encoding.TextMarshaler with a pointer receiver.
+func marshalWithPtrTextMarshaler(v reflect.Value, opts *MarshalOptions) (string, error) {
+	if !v.CanAddr() {
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		v = c
+	}
+	return marshalWithTextMarshaler(v.Addr(), opts)
+}
@@ -1,6 +1,10 @@
 package qs
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 type MarshalTagOptions struct {
 	// DefaultMarshalPresence is used for the marshaling of struct fields that
@@ -8,18 +12,105 @@ type MarshalTagOptions struct {
 	// This option is used for every item when you marshal a map[string]WhateverType
 	// instead of a struct because map items can't have a tag to override this.
 	Presence MarshalPresence
+
+	// BoolFormat is the format of the marshaled bool values.
+	BoolFormat MarshalBoolFormat
+
+	// SliceOrder is the order of the marshaled items of slices and arrays.
+	SliceOrder MarshalSliceOrder
+
+	// EmptySlice controls the marshaling of empty but non-nil slices.
+	EmptySlice MarshalEmptySlice
+
+	// Escape is the percent-encoding of the values of the field in the
+	// query strings returned by Marshal. It is set by the escape=<policy>
+	// and noescape tag options.
+	Escape MarshalEscape
+
+	// FloatFormat is the format of the marshaled float values. It is set by
+	// the floatfmt=<f|e|g> tag option.
+	FloatFormat MarshalFloatFormat
+
+	// FloatPrecision is the number of digits of the marshaled float values
+	// (after the decimal point for the F and E formats). It is set by the
+	// prec=<n> tag option (e.g. `qs:"lat,prec=6"`). Zero means unspecified
+	// and a negative value means the smallest number of digits that
+	// represents the value exactly which is the default.
+	FloatPrecision int
+
+	// FloatZeros controls the trailing zeros of floats marshaled with a
+	// fixed precision.
+	FloatZeros MarshalFloatZeros
+
+	// Public marks the fields that can be included in the URLs built by
+	// MarshalPublic and RedirectURL (e.g. `qs:"state,public"`). It is set by
+	// the public tag option.
+	Public bool
+
+	// Secret marks the fields whose values are replaced with "***" by
+	// MarshalRedacted (e.g. `qs:"token,secret"`). It is set by the secret
+	// tag option.
+	Secret bool
 }
 
 func (o *MarshalTagOptions) InitDefaults() {
 	if o.Presence == MarshalPresenceMPUnspecified {
 		o.Presence = MarshalPresenceKeepEmpty
 	}
+	if o.BoolFormat == MarshalBoolFormatBFUnspecified {
+		o.BoolFormat = MarshalBoolFormatTrueFalse
+	}
+	if o.SliceOrder == MarshalSliceOrderSOUnspecified {
+		o.SliceOrder = MarshalSliceOrderKeepOrder
+	}
+	if o.EmptySlice == MarshalEmptySliceESUnspecified {
+		o.EmptySlice = MarshalEmptySliceNoMarker
+	}
+	if o.Escape == MarshalEscapeMEUnspecified {
+		o.Escape = MarshalEscapeQuery
+	}
+	if o.FloatFormat == MarshalFloatFormatFFUnspecified {
+		o.FloatFormat = MarshalFloatFormatF
+	}
+	if o.FloatPrecision == 0 {
+		o.FloatPrecision = -1
+	}
+	if o.FloatZeros == MarshalFloatZerosFZUnspecified {
+		o.FloatZeros = MarshalFloatZerosKeepZeros
+	}
 }
 
 func (o *MarshalTagOptions) ApplyDefaults(d *MarshalTagOptions) {
 	if o.Presence == MarshalPresenceMPUnspecified {
 		o.Presence = d.Presence
 	}
+	if o.BoolFormat == MarshalBoolFormatBFUnspecified {
+		o.BoolFormat = d.BoolFormat
+	}
+	if o.SliceOrder == MarshalSliceOrderSOUnspecified {
+		o.SliceOrder = d.SliceOrder
+	}
+	if o.EmptySlice == MarshalEmptySliceESUnspecified {
+		o.EmptySlice = d.EmptySlice
+	}
+	if o.Escape == MarshalEscapeMEUnspecified {
+		o.Escape = d.Escape
+	}
+	if o.FloatFormat == MarshalFloatFormatFFUnspecified {
+		o.FloatFormat = d.FloatFormat
+	}
+	if o.FloatPrecision == 0 {
+		o.FloatPrecision = d.FloatPrecision
+	}
+	if o.FloatZeros == MarshalFloatZerosFZUnspecified {
+		o.FloatZeros = d.FloatZeros
+	}
+	if !o.Public {
+		o.Public = d.Public
+	}
+	if !o.Secret {
+		o.Secret = d.Secret
+	}
 }
 
 func (o *MarshalTagOptions) ParseOption(option string) (bool, error) {
@@ -34,11 +125,113 @@ func (o *MarshalTagOptions) ParseOption(option string) (bool, error) {
 		bOk = true
 	}
 
+	// MarshalBoolFormat
+	if value, err := MarshalBoolFormatFromString(option); err == nil {
+		if o.BoolFormat != MarshalBoolFormatBFUnspecified {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "MarshalBoolFormat", o.BoolFormat, value)
+		}
+		o.BoolFormat = value
+		bOk = true
+	}
+
+	// MarshalSliceOrder
+	if value, err := MarshalSliceOrderFromString(option); err == nil {
+		if o.SliceOrder != MarshalSliceOrderSOUnspecified {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "MarshalSliceOrder", o.SliceOrder, value)
+		}
+		o.SliceOrder = value
+		bOk = true
+	}
+
+	// MarshalEmptySlice
+	if value, err := MarshalEmptySliceFromString(option); err == nil {
+		if o.EmptySlice != MarshalEmptySliceESUnspecified {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "MarshalEmptySlice", o.EmptySlice, value)
+		}
+		o.EmptySlice = value
+		bOk = true
+	}
+
+	// MarshalEscape
+	if option == "noescape" {
+		option = "escape=none"
+	}
+	if name, ok := strings.CutPrefix(option, "escape="); ok {
+		value, err := MarshalEscapeFromString(name)
+		if err != nil || value == MarshalEscapeMEUnspecified {
+			return false, fmt.Errorf("invalid escape policy: %q", name)
+		}
+		if o.Escape != MarshalEscapeMEUnspecified {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "MarshalEscape", o.Escape, value)
+		}
+		o.Escape = value
+		bOk = true
+	}
+
+	// MarshalFloatFormat
+	if name, ok := strings.CutPrefix(option, "floatfmt="); ok {
+		value, err := MarshalFloatFormatFromString(name)
+		if err != nil || value == MarshalFloatFormatFFUnspecified {
+			return false, fmt.Errorf("invalid float format: %q", name)
+		}
+		if o.FloatFormat != MarshalFloatFormatFFUnspecified {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "MarshalFloatFormat", o.FloatFormat, value)
+		}
+		o.FloatFormat = value
+		bOk = true
+	}
+
+	// FloatPrecision
+	if s, ok := strings.CutPrefix(option, "prec="); ok {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return false, fmt.Errorf("invalid float precision: %q", s)
+		}
+		if o.FloatPrecision != 0 {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "FloatPrecision", o.FloatPrecision, n)
+		}
+		o.FloatPrecision = n
+		bOk = true
+	}
+
+	// MarshalFloatZeros
+	if value, err := MarshalFloatZerosFromString(option); err == nil {
+		if o.FloatZeros != MarshalFloatZerosFZUnspecified {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "MarshalFloatZeros", o.FloatZeros, value)
+		}
+		o.FloatZeros = value
+		bOk = true
+	}
+
+	// Public
+	if option == "public" {
+		if o.Public {
+			return false, fmt.Errorf("the %s option is specified more than once", option)
+		}
+		o.Public = true
+		bOk = true
+	}
+
+	// Secret
+	if option == "secret" {
+		if o.Secret {
+			return false, fmt.Errorf("the %s option is specified more than once", option)
+		}
+		o.Secret = true
+		bOk = true
+	}
+
 	return bOk, nil
 }
 
 func NewUndefinedMarshalTagOptions() *MarshalTagOptions {
 	return &MarshalTagOptions{
-		Presence: MarshalPresenceMPUnspecified,
+		Presence:    MarshalPresenceMPUnspecified,
+		BoolFormat:  MarshalBoolFormatBFUnspecified,
+		SliceOrder:  MarshalSliceOrderSOUnspecified,
+		EmptySlice:  MarshalEmptySliceESUnspecified,
+		Escape:      MarshalEscapeMEUnspecified,
+		FloatFormat: MarshalFloatFormatFFUnspecified,
+		FloatZeros:  MarshalFloatZerosFZUnspecified,
 	}
 }
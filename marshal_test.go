@@ -3,10 +3,12 @@ package qs
 import (
 	"encoding/hex"
 	"fmt"
+	"math/big"
 	"net/url"
 	"reflect"
 	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -28,6 +30,14 @@ type MEmbedded struct {
 	MEmbedded2
 }
 
+type MPaging struct {
+	Page int `qs:"page,keepempty"`
+}
+
+type mSorting struct {
+	Sort string `qs:"sort,keepempty"`
+}
+
 // MTypes is used by the TestMarshalTypes test to check the marshaling of all
 // supported types.
 type MTypes struct {
@@ -885,6 +895,162 @@ func TestMarshalSlice(t *testing.T) {
 	)
 }
 
+func TestMarshalSliceSeparatorTags(t *testing.T) {
+	type query struct {
+		A []int       `qs:"a,comma"`
+		B [2]string   `qs:"b,semicolon"`
+		C []*int      `qs:"c,space"`
+		D []int       `qs:"d"`
+		E [2]*float64 `qs:"e,comma"`
+	}
+	one, half := 1, 0.5
+	q := query{
+		A: []int{1, 2},
+		B: [2]string{"x", "y"},
+		C: []*int{&one, &one},
+		D: []int{3, 4},
+		E: [2]*float64{&half, &half},
+	}
+
+	vs, err := MarshalValues(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := url.Values{
+		"a": {"1,2"},
+		"b": {"x;y"},
+		"c": {"1 1"},
+		"d": {"3", "4"},
+		"e": {"0.5,0.5"},
+	}
+	if err := expectValues(vs, expected); err != nil {
+		t.Error(err)
+	}
+
+	var q2 query
+	if err := UnmarshalValues(&q2, vs); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(q2, q) {
+		t.Errorf("got %+v, want %+v", q2, q)
+	}
+}
+
+func TestMarshalSliceOrderAndDuplicates(t *testing.T) {
+	two, ten := 2, 10
+	vs, err := MarshalValues(&struct {
+		A []int    `qs:"a,sorted"`
+		B []string `qs:"b,sorted,unique,comma"`
+		C []*int   `qs:"c,sorted"`
+		D [3]int   `qs:"d,unique"`
+		E []int    `qs:"e,unique"`
+	}{
+		A: []int{10, 2, 1},
+		B: []string{"b", "a", "b"},
+		C: []*int{&ten, &two},
+		D: [3]int{1, 1, 2},
+		E: []int{3, 1, 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := url.Values{
+		"a": {"1", "2", "10"},
+		"b": {"a,b"},
+		"c": {"2", "10"},
+		"d": {"1", "1", "2"},
+		"e": {"3", "1"},
+	}
+	if err := expectValues(vs, expected); err != nil {
+		t.Error(err)
+	}
+
+	m := NewMarshaler(nil, WithMarshalSliceOrder(MarshalSliceOrderSorted), WithMarshalOptionSliceDuplicates(OptionSliceDuplicatesUnique))
+	vs, err = m.MarshalValues(&struct {
+		A []string
+		B []string `qs:",keeporder,keepduplicates"`
+	}{
+		A: []string{"y", "x", "y"},
+		B: []string{"y", "x", "y"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = url.Values{
+		"a": {"x", "y"},
+		"b": {"y", "x", "y"},
+	}
+	if err := expectValues(vs, expected); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMapOfSlices(t *testing.T) {
+	m := NewMarshaler(nil, WithMarshalOptionSliceSeparator(OptionSliceSeparatorComma))
+	um := NewUnmarshaler(nil, WithUnmarshalOptionSliceSeparator(OptionSliceSeparatorComma))
+
+	in := map[string][]int{"a": {1, 2}, "b": {3}}
+	vs, err := m.MarshalValues(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := url.Values{"a": {"1,2"}, "b": {"3"}}
+	if err := expectValues(vs, expected); err != nil {
+		t.Error(err)
+	}
+
+	var out map[string][]int
+	if err := um.UnmarshalValues(&out, vs); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("got %v, want %v", out, in)
+	}
+
+	vs, err = MarshalValuesWith(map[string]int{"a": 0, "b": 1}, WithMarshalPresence(MarshalPresenceOmitEmpty))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expectValues(vs, url.Values{"b": {"1"}}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMarshalLazyFields(t *testing.T) {
+	type query struct {
+		A int
+		B map[string]int `qs:",omitempty"`
+	}
+	m := NewMarshaler(nil, WithMarshalLazyFields(true))
+	if err := m.CheckMarshal(&query{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vs, err := m.MarshalValues(&query{A: 1})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if err := expectValues(vs, url.Values{"a": {"1"}}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := m.MarshalValues(&query{B: map[string]int{"x": 1}}); err == nil {
+		t.Error("unexpected success")
+	}
+	if err := CheckMarshal(&query{}); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
 type MIgnoredFields struct {
 	// unexported/private fields are ignored automatically.
 	unexported int
@@ -981,3 +1147,1326 @@ func TestCheckMarshalType(t *testing.T) {
 		},
 	)
 }
+
+func TestTypedMarshaler(t *testing.T) {
+	type query struct {
+		Search string
+		Page   int
+	}
+
+	m, err := NewTypedMarshaler[*query](DefaultMarshaler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := m.Marshal(&query{Search: "a b", Page: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "page=2&search=a+b"; s != want {
+		t.Errorf("Marshal() == %q, want %q", s, want)
+	}
+	if _, err := m.Marshal(nil); err == nil {
+		t.Error("unexpected success")
+	}
+
+	s, err = MarshalT(query{Page: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "page=3&search="; s != want {
+		t.Errorf("MarshalT() == %q, want %q", s, want)
+	}
+
+	if _, err := NewTypedMarshaler[int](DefaultMarshaler); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestMarshalKeyPrefix(t *testing.T) {
+	type query struct {
+		Page  int
+		Items []string
+	}
+
+	marshaler := NewMarshaler(&MarshalOptions{}, WithMarshalKeyPrefix("x_"), WithMarshalKeySuffix("_"))
+	vs, err := marshaler.MarshalValues(&query{Page: 1, Items: []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := url.Values{
+		"x_page_":  {"1"},
+		"x_items_": {"a", "b"},
+	}
+	if err := expectValues(vs, expected); err != nil {
+		t.Error(err)
+	}
+	if o := marshaler.Options(); o.KeyPrefix != "x_" || o.KeySuffix != "_" {
+		t.Errorf("KeyPrefix == %q, KeySuffix == %q", o.KeyPrefix, o.KeySuffix)
+	}
+
+	// The options struct sets the same prefix.
+	marshaler = NewMarshaler(&MarshalOptions{KeyPrefix: "x_myapi_"})
+	s, err := marshaler.Marshal(&query{Page: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "x_myapi_page=1"; s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+	if s, err := marshaler.With(WithMarshalKeyPrefix("")).Marshal(&query{Page: 1}); err != nil || s != "page=1" {
+		t.Errorf("got %q, %v", s, err)
+	}
+}
+
+func TestCompileMarshalType(t *testing.T) {
+	type query struct {
+		Page int
+	}
+
+	marshaler := NewMarshaler(&MarshalOptions{})
+	vm, err := marshaler.CompileType(reflect.TypeOf(query{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs, err := vm.MarshalValues(reflect.ValueOf(query{Page: 3}), marshaler.Options())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expectValues(vs, url.Values{"page": {"3"}}); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := marshaler.CompileType(reflect.TypeOf(MNonMarshalable{})); err == nil {
+		t.Error("unexpected success")
+	}
+	if _, err := marshaler.CompileType(nil); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+type benchFlatStruct struct {
+	Search     string
+	Page       int
+	PageSize   int
+	Offset     uint
+	Score      float64
+	Active     bool
+	Lang       string
+	Sort       string
+	Order      string
+	Since      time.Time
+	Categories []string `qs:"category,comma"`
+	IDs        []int    `qs:"id"`
+}
+
+func newBenchFlatStruct() *benchFlatStruct {
+	return &benchFlatStruct{
+		Search:     "woof",
+		Page:       2,
+		PageSize:   50,
+		Offset:     100,
+		Score:      0.5,
+		Active:     true,
+		Lang:       "en-US",
+		Sort:       "name",
+		Order:      "asc",
+		Since:      time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Categories: []string{"a", "b", "c"},
+		IDs:        []int{1, 2, 3},
+	}
+}
+
+func BenchmarkMarshalValuesFlatStruct(b *testing.B) {
+	v := newBenchFlatStruct()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := MarshalValues(v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTypedMarshalerFlatStruct(b *testing.B) {
+	m, err := NewTypedMarshaler[*benchFlatStruct](DefaultMarshaler)
+	if err != nil {
+		b.Fatal(err)
+	}
+	v := newBenchFlatStruct()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := m.MarshalValues(v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestMarshalerFactoryPrecedence(t *testing.T) {
+	constant := func(result string) PrimitiveMarshalerFunc {
+		return func(v reflect.Value, opts *MarshalOptions) (string, error) {
+			return result, nil
+		}
+	}
+
+	type query struct {
+		QS  MQSBytes
+		Str string
+	}
+
+	// A kind override doesn't take precedence over MarshalQS.
+	m := NewMarshaler(&MarshalOptions{})
+	if err := m.RegisterKindOverride(reflect.Slice, constant("kind")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RegisterKindOverride(reflect.String, constant("kind")); err != nil {
+		t.Fatal(err)
+	}
+	vs, err := m.MarshalValues(&query{QS: MQSBytes{0}, Str: "woof"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expectValues(vs, url.Values{"qs": {"00"}, "str": {"kind"}}); err != nil {
+		t.Error(err)
+	}
+
+	// A custom type takes precedence over MarshalQS.
+	if err := m.RegisterCustomType(reflect.TypeOf(MQSBytes{}), constant("custom")); err != nil {
+		t.Fatal(err)
+	}
+	vs, err = m.MarshalValues(&query{QS: MQSBytes{0}, Str: "woof"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expectValues(vs, url.Values{"qs": {"custom"}, "str": {"kind"}}); err != nil {
+		t.Error(err)
+	}
+
+	// A kind override takes precedence over the builtin types.
+	type timeQuery struct {
+		Time time.Time
+	}
+	m = NewMarshaler(&MarshalOptions{})
+	if err := m.RegisterKindOverride(reflect.Struct, constant("kind")); err != nil {
+		t.Fatal(err)
+	}
+	vs, err = m.MarshalValues(&timeQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expectValues(vs, url.Values{"time": {"kind"}}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMarshalerWith(t *testing.T) {
+	type query struct {
+		Page int
+		Sort string
+	}
+	tp := reflect.TypeOf(query{})
+
+	m := NewMarshaler(&MarshalOptions{})
+	derived := m.With(WithMarshalKeyPrefix("x_"))
+	omitEmpty := m.With(WithMarshalPresence(MarshalPresenceOmitEmpty))
+
+	s, err := derived.Marshal(&query{Page: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "x_page=1&x_sort="; s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+	s, err = omitEmpty.Marshal(&query{Page: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "page=1"; s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+	s, err = m.Marshal(&query{Page: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "page=1&sort="; s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+
+	compile := func(m *QSMarshaler) ValuesMarshaler {
+		vm, err := m.CompileType(tp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return vm
+	}
+	if compile(m) != compile(derived) {
+		t.Error("expected the derived marshaler to share the compiled ValuesMarshaler")
+	}
+	if compile(m) == compile(omitEmpty) {
+		t.Error("expected a different ValuesMarshaler with different defaults")
+	}
+	renamed := m.With(WithMarshalNameTransformer(strings.ToUpper))
+	if compile(m) == compile(renamed) {
+		t.Error("expected a different ValuesMarshaler with a different name transformer")
+	}
+	if compile(renamed) != compile(renamed.With(WithMarshalKeyPrefix("x_"))) {
+		t.Error("expected the derived marshaler to share the name transformer of the original")
+	}
+	if compile(m) != compile(renamed.With(WithMarshalNameTransformer(nil))) {
+		t.Error("expected the default name transformer to share the compiled ValuesMarshaler")
+	}
+}
+
+func TestMarshalWith(t *testing.T) {
+	type query struct {
+		Page int
+		Sort string
+	}
+
+	s, err := MarshalWith(&query{Page: 1}, WithMarshalPresence(MarshalPresenceOmitEmpty))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "page=1"; s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+	s, err = Marshal(&query{Page: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "page=1&sort="; s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+	vs, err := MarshalValuesWith(&query{Page: 1}, WithMarshalKeyPrefix("x_"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (url.Values{"x_page": {"1"}, "x_sort": {""}}); !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+}
+
+func TestNewMarshalerWithOptionAppliers(t *testing.T) {
+	type query struct {
+		PageSize int
+		Tags     []string
+	}
+
+	m := NewMarshaler(nil,
+		WithMarshalNameTransformer(strings.ToUpper),
+		WithMarshalerFactory(newMarshalerFactory()),
+		WithValuesMarshalerFactory(newValuesMarshalerFactory()),
+		WithMarshalTagOptionsDefaults(MarshalTagOptions{Presence: MarshalPresenceOmitEmpty}),
+		WithMarshalTagCommonOptionsDefaults(CommonTagOptions{SliceSeparator: OptionSliceSeparatorComma}),
+	)
+	vs, err := m.MarshalValues(&query{Tags: []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expectValues(vs, url.Values{"TAGS": {"a,b"}}); err != nil {
+		t.Error(err)
+	}
+
+	// nil resets the defaults
+	m = NewMarshaler(nil, WithMarshalNameTransformer(nil), WithMarshalerFactory(nil), WithValuesMarshalerFactory(nil))
+	vs, err = m.MarshalValues(&query{PageSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expectValues(vs, url.Values{"page_size": {"1"}}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMarshalBoolFormat(t *testing.T) {
+	type query struct {
+		A bool
+		B bool `qs:",onezero"`
+		C bool `qs:",yesno"`
+		D bool `qs:",onoff"`
+		E bool `qs:",truefalse"`
+	}
+
+	vs, err := MarshalValues(&query{A: true, B: true, C: true, D: true, E: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{"a": {"true"}, "b": {"1"}, "c": {"yes"}, "d": {"on"}, "e": {"true"}}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	m := NewMarshaler(nil, WithMarshalBoolFormat(MarshalBoolFormatOnOff))
+	vs, err = m.MarshalValues(&query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = url.Values{"a": {"off"}, "b": {"0"}, "c": {"no"}, "d": {"off"}, "e": {"false"}}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	type invalid struct {
+		A bool `qs:",yesno,onoff"`
+	}
+	if err := CheckMarshal(&invalid{}); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestMarshalFloatFormat(t *testing.T) {
+	type query struct {
+		A float64
+		B float64   `qs:",prec=3"`
+		C float64   `qs:",prec=6,trimzeros"`
+		D float64   `qs:",floatfmt=e,prec=2"`
+		E float32   `qs:",floatfmt=g"`
+		F []float64 `qs:",prec=1"`
+	}
+
+	q := &query{A: 1e21, B: 1.5, C: 1.5, D: 1234.5, E: 1e21, F: []float64{0.25, 2}}
+	vs, err := MarshalValues(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{
+		"a": {"1000000000000000000000"},
+		"b": {"1.500"},
+		"c": {"1.5"},
+		"d": {"1.23e+03"},
+		"e": {"1e+21"},
+		"f": {"0.2", "2.0"},
+	}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	m := NewMarshaler(nil,
+		WithMarshalFloatFormat(MarshalFloatFormatE),
+		WithMarshalFloatPrecision(4),
+		WithMarshalFloatZeros(MarshalFloatZerosTrimZeros))
+	vs, err = m.MarshalValues(&query{A: 2, B: 1.25, C: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := vs.Get("a"), "2e+00"; got != want {
+		t.Errorf("a == %q, want %q", got, want)
+	}
+	if got, want := vs.Get("b"), "1.25e+00"; got != want {
+		t.Errorf("b == %q, want %q", got, want)
+	}
+	if got, want := vs.Get("c"), "3e+00"; got != want {
+		t.Errorf("c == %q, want %q", got, want)
+	}
+
+	for _, tag := range []string{`qs:",prec=0"`, `qs:",prec=x"`, `qs:",floatfmt=x"`, `qs:",trimzeros,keepzeros"`} {
+		st := reflect.StructOf([]reflect.StructField{{Name: "A", Type: reflect.TypeFor[float64](), Tag: reflect.StructTag(tag)}})
+		if err := CheckMarshalType(st); err == nil {
+			t.Errorf("%s :: unexpected success", tag)
+		}
+	}
+}
+
+func TestMarshalNullValue(t *testing.T) {
+	type query struct {
+		Parent  *int    `qs:"parent,null=none"`
+		Name    *string `qs:"name,null=-"`
+		Omitted *int    `qs:"omitted,null=none,omitnil"`
+		Plain   *int    `qs:"plain"`
+	}
+
+	vs, err := MarshalValues(&query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{"parent": {"none"}, "name": {"-"}}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	m := NewMarshaler(nil, WithMarshalNullValue("null"))
+	parent := 5
+	vs, err = m.MarshalValues(&query{Parent: &parent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = url.Values{"parent": {"5"}, "name": {"-"}, "plain": {"null"}}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+}
+
+func TestMarshalStringNormalizer(t *testing.T) {
+	type query struct {
+		Name string
+		Tags []string
+	}
+
+	m := NewMarshaler(nil, WithMarshalStringNormalizer(strings.ToLower))
+	vs, err := m.MarshalValues(&query{Name: "Ab", Tags: []string{"X", "y"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{"name": {"ab"}, "tags": {"x", "y"}}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+}
+
+func TestMarshalBigTypes(t *testing.T) {
+	type query struct {
+		Int     *big.Int
+		Float   *big.Float
+		Rat     *big.Rat
+		Amount  *big.Rat `qs:",prec=2"`
+		Value   big.Int
+		Missing *big.Int `qs:",omitempty"`
+	}
+
+	i, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	q := &query{
+		Int:    i,
+		Float:  big.NewFloat(1.5),
+		Rat:    big.NewRat(1, 3),
+		Amount: big.NewRat(25, 2),
+		Value:  *big.NewInt(-7),
+	}
+	vs, err := MarshalValues(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{
+		"int":    {"123456789012345678901234567890"},
+		"float":  {"1.5"},
+		"rat":    {"1/3"},
+		"amount": {"12.50"},
+		"value":  {"-7"},
+	}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+}
+
+func TestMarshalHook(t *testing.T) {
+	type query struct {
+		A int
+		F func()
+	}
+	type valid struct {
+		A int
+	}
+
+	var events []HookEvent
+	m := NewMarshaler(nil, WithMarshalHook(func(e HookEvent) { events = append(events, e) }))
+
+	if _, err := m.Marshal(&valid{A: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.MarshalValues(&query{}); err == nil {
+		t.Error("unexpected success")
+	}
+	tm, err := NewTypedMarshaler[valid](m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tm.Marshal(valid{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.With(WithMarshalHook(nil)).Marshal(&valid{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("got %v events, want 3", len(events))
+	}
+	for i, want := range []reflect.Type{reflect.TypeOf(valid{}), reflect.TypeOf(query{}), reflect.TypeOf(valid{})} {
+		if events[i].Type != want {
+			t.Errorf("events[%v].Type == %v, want %v", i, events[i].Type, want)
+		}
+		if (events[i].Err != nil) != (i == 1) {
+			t.Errorf("events[%v].Err == %v", i, events[i].Err)
+		}
+		if events[i].Duration < 0 {
+			t.Errorf("events[%v].Duration == %v", i, events[i].Duration)
+		}
+	}
+}
+
+func TestMarshalDiff(t *testing.T) {
+	type query struct {
+		A    int
+		B    string `qs:",omitempty"`
+		Tags []string
+	}
+
+	diffs, err := Diff(&query{A: 1, B: "x", Tags: []string{"a"}}, &query{A: 1, Tags: []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FieldDiff{
+		{Key: "b", A: []string{"x"}},
+		{Key: "tags", A: []string{"a"}, B: []string{"a", "b"}},
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Errorf("got %v, want %v", diffs, want)
+	}
+
+	type other struct {
+		Tags []string
+		A    int64
+	}
+	diffs, err = Diff(&query{A: 1}, &other{A: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("unexpected diffs: %v", diffs)
+	}
+
+	if _, err := Diff(&query{}, nil); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+// MQSPtrBytes implements the MarshalQS interface with a pointer receiver.
+type MQSPtrBytes []byte
+
+func (p *MQSPtrBytes) MarshalQS(opts *MarshalOptions) ([]string, error) {
+	return []string{hex.EncodeToString(*p)}, nil
+}
+
+func TestMarshalQSReceivers(t *testing.T) {
+	type query struct {
+		Value    MQSBytes
+		ValuePtr *MQSBytes
+		NilValue *MQSBytes
+		Ptr      MQSPtrBytes
+		PtrPtr   *MQSPtrBytes
+		NilPtr   *MQSPtrBytes
+	}
+
+	vs, err := MarshalValues(query{
+		Value:    MQSBytes{1},
+		ValuePtr: &MQSBytes{2},
+		Ptr:      MQSPtrBytes{3},
+		PtrPtr:   &MQSPtrBytes{4},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{
+		"value":     {"01"},
+		"value_ptr": {"02"},
+		"ptr":       {"03"},
+		"ptr_ptr":   {"04"},
+	}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	vs, err = MarshalValues(map[string]MQSPtrBytes{"a": {5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (url.Values{"a": {"05"}}); !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+}
+
+func TestMergeValues(t *testing.T) {
+	src := url.Values{"a": {"3"}, "c": {"4"}}
+	tests := []struct {
+		strategy MergeStrategy
+		want     url.Values
+	}{
+		{MergeStrategyMSUnspecified, url.Values{"a": {"3"}, "b": {"2"}, "c": {"4"}}},
+		{MergeStrategyReplace, url.Values{"a": {"3"}, "b": {"2"}, "c": {"4"}}},
+		{MergeStrategyAppend, url.Values{"a": {"1", "3"}, "b": {"2"}, "c": {"4"}}},
+		{MergeStrategyKeepExisting, url.Values{"a": {"1"}, "b": {"2"}, "c": {"4"}}},
+		{MergeStrategy(100), url.Values{"a": {"3"}, "b": {"2"}, "c": {"4"}}},
+	}
+	for _, tc := range tests {
+		dst := url.Values{"a": {"1"}, "b": {"2"}}
+		MergeValues(dst, src, tc.strategy)
+		if !reflect.DeepEqual(dst, tc.want) {
+			t.Errorf("%v :: got %v, want %v", tc.strategy, dst, tc.want)
+		}
+		dst["c"][0] = "x"
+		if src["c"][0] != "4" {
+			t.Errorf("%v :: src modified through dst", tc.strategy)
+		}
+	}
+}
+
+func TestMarshalInto(t *testing.T) {
+	type page struct {
+		Offset int
+		Limit  int    `qs:",omitempty"`
+		Sort   string `qs:",omitempty"`
+	}
+
+	q := url.Values{"search": {"x"}, "offset": {"0"}, "limit": {"10"}}
+	if err := MarshalInto(q, &page{Offset: 10}); err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{"search": {"x"}, "offset": {"10"}, "limit": {"10"}}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("got %v, want %v", q, want)
+	}
+
+	if err := MarshalInto(q, nil); err == nil {
+		t.Error("unexpected success")
+	}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("got %v, want %v", q, want)
+	}
+}
+
+func TestSetQueryOnURL(t *testing.T) {
+	type query struct {
+		A int
+		B string
+	}
+
+	u, err := url.Parse("https://example.com/search?old=1#top")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetQueryOnURL(u, &query{A: 1, B: "x y"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := u.String(), "https://example.com/search?a=1&b=x+y#top"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if err := SetQueryOnURL(u, nil); err == nil {
+		t.Error("unexpected success")
+	}
+	if err := SetQueryOnURL(nil, &query{}); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestBuildURL(t *testing.T) {
+	type path struct {
+		ID   int    `qs:"id"`
+		Slug string `qs:"slug"`
+	}
+	type query struct {
+		Page int
+		Tag  string `qs:",omitempty"`
+	}
+
+	tests := []struct {
+		template   string
+		pathParams interface{}
+		query      interface{}
+		want       string
+	}{
+		{"https://api.example.com/users/{id}", map[string]string{"id": "42"}, &query{Page: 2}, "https://api.example.com/users/42?page=2"},
+		{"/users/{id}/posts/{slug}#c", &path{ID: 1, Slug: "a b/c"}, nil, "/users/1/posts/a%20b%2Fc#c"},
+		{"/search?lang=en", nil, &query{Page: 1, Tag: "x"}, "/search?lang=en&page=1&tag=x"},
+		{"/static", nil, map[string]string{}, "/static"},
+	}
+	for _, tc := range tests {
+		got, err := BuildURL(tc.template, tc.pathParams, tc.query)
+		if err != nil {
+			t.Errorf("%q :: %v", tc.template, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%q :: got %q, want %q", tc.template, got, tc.want)
+		}
+	}
+
+	for _, template := range []string{"/users/{id}", "/users/{slug", "/users/{slug}"} {
+		if _, err := BuildURL(template, map[string][]string{"slug": {"a", "b"}}, nil); err == nil {
+			t.Errorf("%q :: unexpected success", template)
+		}
+	}
+	if _, err := BuildURL("/", make(chan int), nil); err == nil {
+		t.Error("unexpected success")
+	}
+
+	for _, slug := range []string{".", ".."} {
+		if got, err := BuildURL("/users/{slug}/posts", &path{Slug: slug}, nil); err == nil {
+			t.Errorf("%q :: unexpected success: %q", slug, got)
+		}
+	}
+	if got, err := BuildURL("/files/{slug}", &path{Slug: "..a"}, nil); err != nil || got != "/files/..a" {
+		t.Errorf("got %q, %v", got, err)
+	}
+
+	// The key prefix and suffix of the marshaler apply only to the query.
+	m := NewMarshaler(nil, WithMarshalKeyPrefix("x_"), WithMarshalKeySuffix("_"))
+	got, err := m.BuildURL("https://a/users/{id}", &path{ID: 42}, &query{Page: 2})
+	if want := "https://a/users/42?x_page_=2"; err != nil || got != want {
+		t.Errorf("got %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestMarshalEscape(t *testing.T) {
+	type Embedded struct {
+		Sig string `qs:"sig,noescape"`
+	}
+	type Query struct {
+		Embedded
+		Redirect string `qs:"redirect,escape=path"`
+		Next     string `qs:"next,escape=query"`
+		A        string `qs:"a"`
+	}
+
+	q := &Query{
+		Embedded: Embedded{Sig: "ab%2Fcd"},
+		Redirect: "https://example.com/cb?x=1&y=a b",
+		Next:     "/a b",
+		A:        "1+1",
+	}
+	want := "a=1%2B1&next=%2Fa+b&redirect=https://example.com/cb?x%3D1%26y%3Da%20b&sig=ab%2Fcd"
+	s, err := Marshal(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+
+	tm, err := NewTypedMarshaler[Query](NewMarshaler(nil, WithMarshalKeyPrefix("q_")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = tm.Marshal(*q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "q_a=1%2B1&q_next=%2Fa+b&q_redirect=https://example.com/cb?x%3D1%26y%3Da%20b&q_sig=ab%2Fcd"; s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+
+	vs, err := MarshalValues(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := vs.Get("sig"); got != "ab%2Fcd" {
+		t.Errorf("sig = %q", got)
+	}
+
+	type Invalid struct {
+		A string `qs:"a,escape=raw"`
+	}
+	if _, err := Marshal(&Invalid{}); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestMarshalOrdered(t *testing.T) {
+	type Auth struct {
+		ConsumerKey string `qs:"oauth_consumer_key"`
+		Nonce       string `qs:"oauth_nonce"`
+	}
+	type Request struct {
+		Zeta string `qs:"zeta"`
+		Tags []string
+		Auth
+		Alpha int `qs:"alpha"`
+	}
+
+	ov, err := MarshalOrdered(&Request{
+		Zeta:  "z z",
+		Tags:  []string{"b", "a"},
+		Auth:  Auth{ConsumerKey: "key", Nonce: "n"},
+		Alpha: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := OrderedValues{
+		{"zeta", "z z"},
+		{"tags", "b"},
+		{"tags", "a"},
+		{"oauth_consumer_key", "key"},
+		{"oauth_nonce", "n"},
+		{"alpha", "1"},
+	}
+	if !reflect.DeepEqual(ov, want) {
+		t.Errorf("got %v, want %v", ov, want)
+	}
+	if s, want := ov.Encode(), "zeta=z+z&tags=b&tags=a&oauth_consumer_key=key&oauth_nonce=n&alpha=1"; s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+
+	parsed, err := ParseOrderedQuery(ov.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, want) {
+		t.Errorf("got %v, want %v", parsed, want)
+	}
+
+	var r Request
+	if err := UnmarshalPairs(&r, ov); err != nil {
+		t.Fatal(err)
+	}
+	if r.Zeta != "z z" || !reflect.DeepEqual(r.Tags, []string{"b", "a"}) || r.Nonce != "n" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+
+	ov.Set("tags", "c")
+	ov.Add("extra", "1")
+	ov.Del("alpha")
+	if s, want := ov.Encode(), "zeta=z+z&tags=c&oauth_consumer_key=key&oauth_nonce=n&extra=1"; s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+	if got := ov.Values("tags"); !reflect.DeepEqual(got, []string{"c"}) || ov.Get("zeta") != "z z" || ov.Has("alpha") {
+		t.Errorf("unexpected values: %v", ov)
+	}
+	if got := NewOrderedValues(ov.URLValues()).Encode(); got != "extra=1&oauth_consumer_key=key&oauth_nonce=n&tags=c&zeta=z+z" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestMarshalCanonical(t *testing.T) {
+	type Request struct {
+		Action  string   `qs:"Action"`
+		Version string   `qs:"Version"`
+		Names   []string `qs:"name"`
+		Prefix  string   `qs:"prefix,omitempty"`
+	}
+
+	s, err := MarshalCanonical(&Request{
+		Action:  "ListUsers",
+		Version: "2010-05-08",
+		Names:   []string{"b c", "a~*", "A"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Action=ListUsers&Version=2010-05-08&name=A&name=a~%2A&name=b%20c"; s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+
+	vs := url.Values{"a b": {"/", "+"}, "a": {"é"}, "empty": {}}
+	if got, want := CanonicalQueryString(vs), "a=%C3%A9&a%20b=%2B&a%20b=%2F"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMarshalInline(t *testing.T) {
+	type Paging struct {
+		Page  int `qs:"page"`
+		Limit int `qs:"limit"`
+	}
+	type Query struct {
+		Search string   `qs:"q"`
+		Paging Paging   `qs:",inline"`
+		Extra  *Paging  `qs:"ignored,inline"`
+		Tags   []string `qs:"tags,omitempty"`
+	}
+
+	for _, m := range []*QSMarshaler{DefaultMarshaler, NewMarshaler(nil, WithMarshalLazyFields(true))} {
+		vs, err := m.MarshalValues(&Query{Search: "x", Paging: Paging{Page: 2, Limit: 10}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (url.Values{"q": {"x"}, "page": {"2"}, "limit": {"10"}}); !reflect.DeepEqual(vs, want) {
+			t.Errorf("got %v, want %v", vs, want)
+		}
+	}
+
+	type Invalid struct {
+		A int `qs:",inline"`
+	}
+	if _, err := Marshal(&Invalid{}); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+// testFilter expands into a filter[<field>][<op>]=<value> key.
+type testFilter struct {
+	Field, Op, Value string
+}
+
+type testFilterMarshaler struct{}
+
+func (testFilterMarshaler) MarshalValues(v reflect.Value, opts *MarshalOptions) (url.Values, error) {
+	f := v.Interface().(testFilter)
+	if f.Field == "" {
+		return nil, nil
+	}
+	return url.Values{"filter[" + f.Field + "][" + f.Op + "]": {f.Value}}, nil
+}
+
+func TestMarshalValuesCustomType(t *testing.T) {
+	type Query struct {
+		Search string      `qs:"q"`
+		Filter testFilter  `qs:"filter"`
+		Extra  *testFilter `qs:"extra"`
+	}
+
+	m := NewMarshaler(nil)
+	err := m.RegisterValuesCustomType(reflect.TypeFor[testFilter](), func(t reflect.Type, opts *MarshalOptions) (ValuesMarshaler, error) {
+		return testFilterMarshaler{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := Query{
+		Search: "x",
+		Filter: testFilter{Field: "age", Op: "gt", Value: "18"},
+		Extra:  &testFilter{Field: "name", Op: "eq", Value: "bob"},
+	}
+	vs, err := m.MarshalValues(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{"q": {"x"}, "filter[age][gt]": {"18"}, "filter[name][eq]": {"bob"}}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	vs, err = m.MarshalValues(&testFilter{Field: "id", Op: "in", Value: "1,2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (url.Values{"filter[id][in]": {"1,2"}}); !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+}
+
+func TestMarshalMapKeyTypes(t *testing.T) {
+	vs, err := MarshalValues(map[int]string{1: "a", 2: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (url.Values{"1": {"a"}, "2": {"b"}}); !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	m := NewMarshaler(nil)
+	err = m.RegisterMapKeyType(stringType, func(v reflect.Value, opts *MarshalOptions) (string, error) {
+		return strings.ToLower(v.String()), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs, err = m.MarshalValues(map[string]int{"Page": 1, "PAGE": 2, "Limit": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(vs["page"])
+	if want := (url.Values{"page": {"1", "2"}, "limit": {"3"}}); !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	if _, err := Marshal(map[struct{}]string{}); err == nil {
+		t.Error("unexpected success")
+	}
+	if _, err := Marshal(map[[2]int]string{{1, 2}: "a"}); err == nil {
+		t.Error("unexpected success")
+	}
+	if err := m.RegisterMapKeyType(stringType, nil); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestMarshalFieldFilter(t *testing.T) {
+	type Base struct {
+		Debug bool `qs:"debug"`
+	}
+	type Query struct {
+		Base
+		Search string `qs:"q"`
+		Beta   string `qs:"beta" flag:"beta"`
+		Empty  string `qs:"empty,omitempty" flag:"beta"`
+	}
+
+	var called []string
+	m := NewMarshaler(nil, WithMarshalFieldFilter(func(field FieldInfo, value reflect.Value) bool {
+		called = append(called, field.Tag.Name)
+		if field.Struct != reflect.TypeOf(Query{}) && field.Struct != reflect.TypeOf(Base{}) {
+			t.Errorf("unexpected struct %v", field.Struct)
+		}
+		return field.Field.Tag.Get("flag") == "" && !(field.Tag.Name == "debug" && !value.Bool())
+	}))
+	vs, err := m.MarshalValues(&Query{Search: "x", Beta: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (url.Values{"q": {"x"}}); !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+	sort.Strings(called)
+	if want := []string{"beta", "debug", "q"}; !reflect.DeepEqual(called, want) {
+		t.Errorf("called with %q, want %q", called, want)
+	}
+}
+
+func TestMarshalOmitZeroAndOmitNil(t *testing.T) {
+	type Query struct {
+		ZeroInt    int        `qs:"zi,omitzero"`
+		ZeroSlice  []string   `qs:"zs,omitzero"`
+		EmptySlice []string   `qs:"es,omitzero"`
+		ZeroTime   time.Time  `qs:"zt,omitzero"`
+		NilPtr     *int       `qs:"np,omitnil"`
+		ZeroPtr    *int       `qs:"zp,omitnil"`
+		NilSlice   []int      `qs:"ns,omitnil"`
+		EmptyInts  []int      `qs:"ei,omitnil"`
+		NilTime    *time.Time `qs:"nt,omitzero"`
+		Str        string     `qs:"str,omitnil"`
+	}
+
+	vs, err := MarshalValues(&Query{
+		EmptySlice: []string{},
+		ZeroPtr:    new(int),
+		EmptyInts:  []int{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The empty slices aren't omitted but they have no values.
+	if want := (url.Values{"zp": {"0"}, "str": {""}}); !reflect.DeepEqual(vs, want) {
+		t.Errorf("got %v, want %v", vs, want)
+	}
+
+	ts := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	vs, err = MarshalValues(&Query{ZeroTime: ts, NilTime: &time.Time{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := vs["zt"]; !ok {
+		t.Errorf("missing zt in %v", vs)
+	}
+	if _, ok := vs["nt"]; ok {
+		t.Errorf("unexpected nt in %v", vs)
+	}
+
+	vs, err = MarshalValuesWith(map[string]int{"a": 0}, WithMarshalPresence(MarshalPresenceOmitZero))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vs) != 0 {
+		t.Errorf("got %v", vs)
+	}
+}
+
+func TestMarshalEmptySliceMarker(t *testing.T) {
+	type Query struct {
+		Tags    []string  `qs:"tags,emptymarker"`
+		IDs     []int     `qs:"ids,emptymarker,brackets"`
+		CSV     []int     `qs:"csv,emptymarker,comma"`
+		Nil     []string  `qs:"nil,emptymarker"`
+		Ptr     *[]string `qs:"ptr,emptymarker"`
+		Default []string  `qs:"default"`
+	}
+
+	s, err := Marshal(&Query{
+		Tags:    []string{},
+		IDs:     []int{},
+		CSV:     []int{},
+		Ptr:     &[]string{},
+		Default: []string{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "csv=&ids%5B%5D=&ptr=&tags="; s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+
+	s, err = MarshalWith(&struct{ A []int }{A: []int{}}, WithMarshalEmptySlice(MarshalEmptySliceEmptyMarker))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "a=" {
+		t.Errorf("got %q", s)
+	}
+}
+
+func TestMarshalEmbeddedPointers(t *testing.T) {
+	type query struct {
+		*MPaging
+		*mSorting
+		Name string `qs:"name"`
+	}
+
+	tests := []struct {
+		q      query
+		asZero bool
+		want   string
+	}{
+		{query{Name: "a"}, false, "name=a"},
+		{query{MPaging: &MPaging{Page: 2}, mSorting: &mSorting{Sort: "x"}, Name: "a"}, false, "name=a&page=2&sort=x"},
+		{query{Name: "a"}, true, "name=a&page=0&sort="},
+	}
+	for _, tc := range tests {
+		s, err := NewMarshaler(nil, WithMarshalNilEmbeddedAsZero(tc.asZero)).Marshal(&tc.q)
+		if err != nil {
+			t.Errorf("%+v :: unexpected error: %v", tc.q, err)
+			continue
+		}
+		if s != tc.want {
+			t.Errorf("%+v :: got %q, want %q", tc.q, s, tc.want)
+		}
+	}
+}
+
+func TestMarshalPublic(t *testing.T) {
+	type target struct {
+		Tab    string `qs:"tab,public"`
+		Secret string `qs:"secret"`
+	}
+	type callback struct {
+		State  string `qs:"state,public"`
+		Code   string `qs:"code"`
+		UserID int    `qs:"user_id"`
+		Target target `qs:"target,public"`
+	}
+
+	m := NewMarshaler(nil, WithMarshalNesting(NestingModeDots))
+	c := callback{State: "s1", Code: "c1", UserID: 42, Target: target{Tab: "home", Secret: "x"}}
+	s, err := m.MarshalPublic(&c)
+	if err != nil || s != "state=s1&target.tab=home" {
+		t.Errorf("got %q, %v", s, err)
+	}
+	s, err = m.MarshalPublic(&c, "code")
+	if err != nil || s != "code=c1&state=s1&target.tab=home" {
+		t.Errorf("got %q, %v", s, err)
+	}
+
+	// The FieldFilter of the marshaler still applies.
+	m2 := m.With(WithMarshalFieldFilter(func(field FieldInfo, value reflect.Value) bool {
+		return field.Tag.Name != "state"
+	}))
+	if s, err := m2.MarshalPublic(&c); err != nil || s != "target.tab=home" {
+		t.Errorf("got %q, %v", s, err)
+	}
+
+	u, err := m.RedirectURL("https://example.com/cb?x=1#top", &c)
+	if err != nil || u != "https://example.com/cb?x=1&state=s1&target.tab=home#top" {
+		t.Errorf("got %q, %v", u, err)
+	}
+	if _, err := m.RedirectURL("://bad", &c); err == nil {
+		t.Error("unexpected success")
+	}
+
+	// The public option can be the default of a struct.
+	type allPublic struct {
+		_ struct{} `qs:"opts:public"`
+		A string   `qs:"a"`
+		B string   `qs:"b"`
+	}
+	if s, err := MarshalPublic(&allPublic{A: "1", B: "2"}); err != nil || s != "a=1&b=2" {
+		t.Errorf("got %q, %v", s, err)
+	}
+}
+
+func TestMarshalPublicInlineFields(t *testing.T) {
+	type Filter = testFilter
+	type query struct {
+		Filter
+		State  string            `qs:"state,public"`
+		Extra  map[string]string `qs:",inline"`
+		Params map[string]string `qs:",inline,public"`
+	}
+
+	m := NewMarshaler(nil)
+	err := m.RegisterValuesCustomType(reflect.TypeFor[testFilter](), func(t reflect.Type, opts *MarshalOptions) (ValuesMarshaler, error) {
+		return testFilterMarshaler{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := query{
+		Filter: Filter{Field: "age", Op: "gt", Value: "18"},
+		State:  "s",
+		Extra:  map[string]string{"admin_key": "x"},
+		Params: map[string]string{"lang": "en", "debug": "1"},
+	}
+	if s, err := m.MarshalPublic(&q); err != nil || s != "state=s" {
+		t.Errorf("got %q, %v", s, err)
+	}
+	// The keys of the public inlined fields have to be listed.
+	if s, err := m.MarshalPublic(&q, "lang", "admin_key", "filter[age][gt]"); err != nil || s != "lang=en&state=s" {
+		t.Errorf("got %q, %v", s, err)
+	}
+	// The listed fields are public.
+	if s, err := m.MarshalPublic(&q, "filter", "filter[age][gt]"); err != nil || s != "filter%5Bage%5D%5Bgt%5D=18&state=s" {
+		t.Errorf("got %q, %v", s, err)
+	}
+}
+
+func TestMarshalRedacted(t *testing.T) {
+	type credentials struct {
+		User     string `qs:"user"`
+		Password string `qs:"password,secret"`
+	}
+	type request struct {
+		Query  string      `qs:"q"`
+		Token  string      `qs:"token,secret"`
+		Keys   []string    `qs:"key,secret"`
+		Hint   string      `qs:"hint,secret,omitempty"`
+		Login  credentials `qs:"login"`
+		Backup credentials `qs:"backup,secret"`
+	}
+
+	m := NewMarshaler(nil, WithMarshalNesting(NestingModeDots), WithMarshalQueryEncoding(QueryEncodingHTML5Form))
+	r := request{
+		Query:  "go",
+		Token:  "t0k3n",
+		Keys:   []string{"k1", "k2"},
+		Login:  credentials{User: "bob", Password: "pw"},
+		Backup: credentials{User: "alice", Password: "pw2"},
+	}
+	s, err := m.MarshalRedacted(&r)
+	if err != nil || s != "backup=***&key=***&login.password=***&login.user=bob&q=go&token=***" {
+		t.Errorf("got %q, %v", s, err)
+	}
+
+	// Marshal doesn't redact the secret fields.
+	s, err = m.Marshal(&r)
+	if err != nil || s != "backup.password=pw2&backup.user=alice&key=k1&key=k2&login.password=pw&login.user=bob&q=go&token=t0k3n" {
+		t.Errorf("got %q, %v", s, err)
+	}
+
+	// The redaction marker is encoded by the query encoding of the
+	// marshaler.
+	if s, err := MarshalRedacted(&credentials{User: "bob", Password: "pw"}); err != nil || s != "password=%2A%2A%2A&user=bob" {
+		t.Errorf("got %q, %v", s, err)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	type filter struct {
+		Status string   `qs:"status,omitempty"`
+		Tags   []string `qs:"tag,sorted"`
+		Page   int      `qs:"page"`
+	}
+
+	k1, err := CacheKey(&filter{Status: "open", Tags: []string{"b", "a"}, Page: 2})
+	if err != nil || len(k1) != 64 {
+		t.Fatalf("got %q, %v", k1, err)
+	}
+	k2, err := CacheKey(filter{Status: "open", Tags: []string{"a", "b"}, Page: 2})
+	if err != nil || k2 != k1 {
+		t.Errorf("got %q, %v, want %q", k2, err, k1)
+	}
+
+	// The query encoding of the marshaler doesn't change the key.
+	m := NewMarshaler(nil, WithMarshalQueryEncoding(QueryEncodingHTML5Form))
+	if k, err := m.CacheKey(&filter{Status: "open", Tags: []string{"a", "b"}, Page: 2}); err != nil || k != k1 {
+		t.Errorf("got %q, %v, want %q", k, err, k1)
+	}
+
+	// The omitted empty fields don't count but the other values do.
+	k3, err := CacheKey(&filter{Page: 2})
+	if err != nil || k3 == k1 {
+		t.Errorf("got %q, %v", k3, err)
+	}
+	if k, err := CacheKey(map[string]string{"page": "2"}); err != nil || k != k3 {
+		t.Errorf("got %q, %v, want %q", k, err, k3)
+	}
+
+	if _, err := CacheKey(nil); err == nil {
+		t.Error("unexpected success")
+	}
+}
@@ -0,0 +1,80 @@
+package qs
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"time"
+)
+
+// TypedMarshaler marshals values of type T. Its ValuesMarshaler is compiled
+// once by NewTypedMarshaler so the marshal calls don't have to look it up
+// through the factories and their caches.
+type TypedMarshaler[T any] struct {
+	p     *QSMarshaler
+	vm    ValuesMarshaler
+	isPtr bool
+}
+
+// NewTypedMarshaler creates a TypedMarshaler that uses the options and
+// factories of p. T has to be a type that p can marshal (a struct, a map or a
+// pointer to one of these) otherwise an error is returned.
+func NewTypedMarshaler[T any](p *QSMarshaler) (*TypedMarshaler[T], error) {
+	t := reflect.TypeFor[T]()
+	isPtr := t.Kind() == reflect.Ptr
+	if isPtr {
+		t = t.Elem()
+	}
+
+	vm, err := p.CompileType(t)
+	if err != nil {
+		return nil, err
+	}
+	return &TypedMarshaler[T]{
+		p:     p,
+		vm:    vm,
+		isPtr: isPtr,
+	}, nil
+}
+
+// Marshal marshals v into a query string.
+func (m *TypedMarshaler[T]) Marshal(v T) (s string, err error) {
+	if m.p.hook != nil {
+		defer m.p.hook.report(reflect.TypeFor[T](), time.Now(), &err)
+	}
+	values, err := m.marshalValues(v)
+	if err != nil {
+		return "", err
+	}
+	return m.p.encodeValues(m.vm, values), nil
+}
+
+// MarshalValues marshals v into a url.Values.
+func (m *TypedMarshaler[T]) MarshalValues(v T) (vs url.Values, err error) {
+	if m.p.hook != nil {
+		defer m.p.hook.report(reflect.TypeFor[T](), time.Now(), &err)
+	}
+	return m.marshalValues(v)
+}
+
+// marshalValues is MarshalValues without the hook.
+func (m *TypedMarshaler[T]) marshalValues(v T) (url.Values, error) {
+	rv := reflect.ValueOf(&v).Elem()
+	if m.isPtr {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("nil pointer of type %v", rv.Type())
+		}
+		rv = rv.Elem()
+	}
+	return m.p.marshalValues(m.vm, rv)
+}
+
+// MarshalT is the type-safe variant of Marshal.
+func MarshalT[T any](v T) (string, error) {
+	return DefaultMarshaler.Marshal(v)
+}
+
+// MarshalValuesT is the type-safe variant of MarshalValues.
+func MarshalValuesT[T any](v T) (url.Values, error) {
+	return DefaultMarshaler.MarshalValues(v)
+}
@@ -0,0 +1,81 @@
+package qs
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// BuildURL builds a URL with the DefaultMarshaler. See QSMarshaler.BuildURL.
+func BuildURL(template string, pathParams, query interface{}) (string, error) {
+	return DefaultMarshaler.BuildURL(template, pathParams, query)
+}
+
+// BuildURL fills the {name} placeholders of the template with the path
+// parameters and appends the marshaled query to the result:
+//
+//	u, err := qs.BuildURL("https://api.example.com/users/{id}/posts",
+//		map[string]string{"id": "42"}, &ListPosts{Page: 2})
+//	// https://api.example.com/users/42/posts?page=2
+//
+// pathParams and query are marshaled like any other object (e.g. structs or
+// maps) and both of them can be nil. The path parameters are escaped with
+// url.PathEscape. An error is returned if a placeholder has no parameter or
+// has more than one value, or if its value is a dot segment ("." or "..")
+// that would change the path. The query is appended to the query string of
+// the template (if any) and the fragment of the template is kept at the end.
+func (p *QSMarshaler) BuildURL(template string, pathParams, query interface{}) (string, error) {
+	var params url.Values
+	if pathParams != nil {
+		var err error
+		if params, err = p.MarshalValues(pathParams); err != nil {
+			return "", fmt.Errorf("error marshaling path parameters :: %w", err)
+		}
+	}
+
+	base, fragment, hasFragment := strings.Cut(template, "#")
+	var b strings.Builder
+	for {
+		before, rest, ok := strings.Cut(base, "{")
+		b.WriteString(before)
+		if !ok {
+			break
+		}
+		name, after, ok := strings.Cut(rest, "}")
+		if !ok {
+			return "", fmt.Errorf("unclosed placeholder in URL template %q", template)
+		}
+		// The keys of the path parameters have the key prefix and suffix
+		// of the marshaler but the placeholders don't.
+		a := params[p.opts.KeyPrefix+name+p.opts.KeySuffix]
+		if len(a) != 1 {
+			return "", fmt.Errorf("URL template %q needs exactly one value for placeholder %q, got %q", template, name, a)
+		}
+		if a[0] == "." || a[0] == ".." {
+			return "", fmt.Errorf("URL template %q doesn't accept the dot segment %q for placeholder %q", template, a[0], name)
+		}
+		b.WriteString(url.PathEscape(a[0]))
+		base = after
+	}
+
+	if query != nil {
+		s, err := p.Marshal(query)
+		if err != nil {
+			return "", fmt.Errorf("error marshaling query :: %w", err)
+		}
+		if s != "" {
+			if strings.Contains(b.String(), "?") {
+				b.WriteByte('&')
+			} else {
+				b.WriteByte('?')
+			}
+			b.WriteString(s)
+		}
+	}
+
+	if hasFragment {
+		b.WriteByte('#')
+		b.WriteString(fragment)
+	}
+	return b.String(), nil
+}
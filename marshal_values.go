@@ -1,9 +1,12 @@
 package qs
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"reflect"
+	"strconv"
+	"sync"
 )
 
 // ValuesMarshaler can marshal a value into a url.Values.
@@ -17,17 +20,65 @@ type structMarshaler struct {
 	Type           reflect.Type
 	EmbeddedFields []embeddedFieldMarshaler
 	Fields         []*fieldMarshaler
+
+	// primitiveFields is the number of Fields with a primitiveMarshalerFunc.
+	// Their single item []string values are allocated in one block by
+	// MarshalValues.
+	primitiveFields int
+
+	// escapes holds the MarshalEscape of the keys of the fields (including
+	// the fields of embedded structs) that aren't encoded with the query
+	// encoding of the marshaler. See marshalerEscapes.
+	escapes map[string]MarshalEscape
+}
+
+// FieldInfo describes a struct field for MarshalOptions.FieldFilter.
+type FieldInfo struct {
+	// Struct is the type of the struct that has the field.
+	Struct reflect.Type
+
+	// Field is the struct field.
+	Field reflect.StructField
+
+	// Tag is the parsed tag of the field. Tag.Name is the query string key
+	// of the field without the key prefix and suffix of the marshaler (and
+	// without the key of the parent field in case of nested structs). It
+	// must not be modified.
+	Tag *ParsedTagInfo
+}
+
+func (p *structMarshaler) fieldInfo(fm *fieldMarshaler) FieldInfo {
+	return FieldInfo{
+		Struct: fieldOwner(p.Type, fm.Index),
+		Field:  p.Type.FieldByIndex(fm.Index),
+		Tag:    fm.Tag,
+	}
 }
 
 type embeddedFieldMarshaler struct {
-	FieldIndex      int
+	// Index is the index path of the embedded field. See fieldMarshaler.
+	Index           []int
 	ValuesMarshaler ValuesMarshaler
+	Tag             *ParsedTagInfo
 }
 
 type fieldMarshaler struct {
-	FieldIndex int
-	Marshaler  Marshaler
-	Tag        *ParsedTagInfo
+	// Index is the index path of the field for reflect.Value.FieldByIndex.
+	// It is longer than one for the fields promoted from embedded structs.
+	Index     []int
+	Marshaler Marshaler
+	Tag       *ParsedTagInfo
+
+	// Nested is used instead of Marshaler when nesting is enabled and the
+	// field is a struct (or a slice of structs if Indexed is true) that has
+	// no Marshaler. See MarshalOptions.Nesting.
+	Nested  ValuesMarshaler
+	Indexed bool
+
+	// build creates Marshaler or Nested when the field is marshaled for the
+	// first time. It is nil if they have been created with the struct
+	// marshaler. See MarshalOptions.LazyFields.
+	build func() error
 }
 
 // newStructMarshaler creates a struct marshaler for a specific struct type.
@@ -36,45 +87,187 @@ func newStructMarshaler(t reflect.Type, opts *MarshalOptions) (ValuesMarshaler,
 		return nil, &WrongKindError{Expected: reflect.Struct, Actual: t}
 	}
 
+	if err := checkTypeCycle(t, opts.structDependencies); err != nil {
+		return nil, err
+	}
+
 	sm := &structMarshaler{
 		Type: t,
 	}
 
+	defaults, err := structTagDefaults(t, opts.TagKey, tagDefaults{
+		marshal:   opts.TagOptionsDefaults,
+		unmarshal: NewUndefinedUnmarshalTagOptions(),
+		common:    opts.TagCommonOptionsDefaults,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	for i, numField := 0, t.NumField(); i < numField; i++ {
 		sf := t.Field(i)
-		vm, fm, err := newFieldMarshaler(sf, opts)
+		if isRangeType(sf.Type) {
+			fms, err := newRangeFieldMarshalers(sf, opts, defaults)
+			if err != nil {
+				if opts.skipField(t, sf, err) {
+					continue
+				}
+				return nil, fmt.Errorf("error creating marshaler for field %v of struct %v :: %w",
+					sf.Name, t, err)
+			}
+			for _, fm := range fms {
+				sm.Fields = append(sm.Fields, fm.promoted(i))
+			}
+			continue
+		}
+
+		var vm ValuesMarshaler
+		var fm *fieldMarshaler
+		if opts.LazyFields && !sf.Anonymous {
+			vm, fm, err = newLazyFieldMarshaler(t, sf, opts, defaults)
+		} else {
+			vm, fm, err = newFieldMarshaler(sf, opts, defaults)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("error creating marshaler for field %v of struct %v :: %v",
+			if opts.skipField(t, sf, err) {
+				continue
+			}
+			return nil, fmt.Errorf("error creating marshaler for field %v of struct %v :: %w",
 				sf.Name, t, err)
 		}
 		if vm != nil {
-			sm.EmbeddedFields = append(sm.EmbeddedFields, embeddedFieldMarshaler{
-				FieldIndex:      i,
-				ValuesMarshaler: vm,
-			})
+			if em, ok := vm.(*structMarshaler); ok && sf.Type.Kind() == reflect.Struct {
+				sm.promote(i, em)
+			} else {
+				tag, _ := getStructFieldInfo(sf, opts.fieldNaming(), defaults)
+				sm.EmbeddedFields = append(sm.EmbeddedFields, embeddedFieldMarshaler{
+					Index:           []int{i},
+					ValuesMarshaler: vm,
+					Tag:             tag,
+				})
+			}
 		}
 		if fm != nil {
-			fm.FieldIndex = i
+			fm.Index = []int{i}
 			sm.Fields = append(sm.Fields, fm)
+			if _, ok := fm.Marshaler.(*primitiveMarshalerFunc); ok {
+				sm.primitiveFields++
+			}
 		}
 	}
+	sm.escapes = sm.fieldEscapes()
 
 	return sm, nil
 }
 
-func newFieldMarshaler(sf reflect.StructField, opts *MarshalOptions) (ValuesMarshaler, *fieldMarshaler, error) {
+// promote adds the fields of the embedded (or inlined) struct field with
+// index i marshaled by em to the fields of p. The promoted fields are
+// accessed with their index paths so the embedded struct isn't marshaled
+// into separate url.Values. The nil pointers of embedded structs need extra
+// care so these remain in EmbeddedFields.
+func (p *structMarshaler) promote(i int, em *structMarshaler) {
+	for _, fm := range em.Fields {
+		p.Fields = append(p.Fields, fm.promoted(i))
+	}
+	p.primitiveFields += em.primitiveFields
+	for _, ef := range em.EmbeddedFields {
+		p.EmbeddedFields = append(p.EmbeddedFields, embeddedFieldMarshaler{
+			Index:           promotedIndex(i, ef.Index),
+			ValuesMarshaler: ef.ValuesMarshaler,
+			Tag:             ef.Tag,
+		})
+	}
+}
+
+// promoted returns a copy of fm with an index path that starts with the
+// index i of the embedded struct that has the field.
+func (fm *fieldMarshaler) promoted(i int) *fieldMarshaler {
+	p := *fm
+	p.Index = promotedIndex(i, fm.Index)
+	if fm.build != nil {
+		p.build = sync.OnceValue(func() error {
+			if err := fm.build(); err != nil {
+				return err
+			}
+			p.Marshaler, p.Nested, p.Indexed = fm.Marshaler, fm.Nested, fm.Indexed
+			return nil
+		})
+	}
+	return &p
+}
+
+// structDependencies returns the fields of struct type t whose values are
+// marshaled by the ValuesMarshaler of another struct type.
+func (o *MarshalOptions) structDependencies(t reflect.Type) []typeDependency {
+	var deps []typeDependency
+	defaults := tagDefaults{
+		marshal:   o.TagOptionsDefaults,
+		unmarshal: NewUndefinedUnmarshalTagOptions(),
+		common:    o.TagCommonOptionsDefaults,
+	}
+	for i, numField := 0, t.NumField(); i < numField; i++ {
+		sf := t.Field(i)
+		tag, err := getStructFieldInfo(sf, o.fieldNaming(), defaults)
+		if tag == nil || err != nil || tag.CommonOpts.Codec != "" || o.customValuesType(sf.Type) {
+			continue
+		}
+		if sf.Anonymous || tag.CommonOpts.Inline {
+			deps = appendStructDependency(deps, sf.Name, sf.Type)
+			continue
+		}
+		if !o.Nesting.enabled() {
+			continue
+		}
+		if _, err := o.MarshalerFactory.Marshaler(sf.Type, o); err == nil {
+			continue
+		}
+		if nt, _, ok := nestedFieldType(sf.Type); ok {
+			deps = appendStructDependency(deps, sf.Name, nt)
+		}
+	}
+	return deps
+}
+
+func newFieldMarshaler(sf reflect.StructField, opts *MarshalOptions, defaults tagDefaults) (ValuesMarshaler, *fieldMarshaler, error) {
 	var vm ValuesMarshaler
 	var fm *fieldMarshaler
 
-	tag, err := getStructFieldInfo(sf, opts.NameTransformer, opts.TagOptionsDefaults, NewUndefinedUnmarshalTagOptions(), opts.TagCommonOptionsDefaults)
+	tag, err := getStructFieldInfo(sf, opts.fieldNaming(), defaults)
 	if tag == nil || err != nil {
 		return vm, fm, err
 	}
+	if err := checkNamespace(sf, tag); err != nil {
+		return vm, fm, err
+	}
 
 	t := sf.Type
+	if tag.CommonOpts.Codec != "" {
+		m, err := opts.codecMarshaler(tag.CommonOpts.Codec, t)
+		if err == nil {
+			m, err = opts.transformFieldMarshaler(tag, m)
+		}
+		if err != nil {
+			return vm, fm, err
+		}
+		fm = &fieldMarshaler{
+			Marshaler: m,
+			Tag:       tag,
+		}
+		return vm, fm, nil
+	}
+	if tag.CommonOpts.Inline || opts.customValuesType(t) {
+		vm, err = opts.ValuesMarshalerFactory.ValuesMarshaler(t, opts)
+		if err == nil && tag.CommonOpts.Namespace != "" {
+			vm, err = namespacedMarshaler(vm, tag.CommonOpts.Namespace)
+		}
+		return vm, fm, err
+	}
 	if sf.Anonymous {
 		vm, err = opts.ValuesMarshalerFactory.ValuesMarshaler(t, opts)
-		if err == nil {
+		if err == nil && tag.CommonOpts.Namespace != "" {
+			vm, err = namespacedMarshaler(vm, tag.CommonOpts.Namespace)
+		}
+		if err == nil || tag.CommonOpts.Namespace != "" {
 			// We can end up here for example in case of an embedded struct.
 			return vm, fm, err
 		}
@@ -82,6 +275,24 @@ func newFieldMarshaler(sf reflect.StructField, opts *MarshalOptions) (ValuesMars
 
 	m, err := opts.MarshalerFactory.Marshaler(t, opts)
 	if err != nil {
+		if nt, indexed, ok := nestedFieldType(t); ok && opts.Nesting.enabled() {
+			if tag.CommonOpts.Transform != "" {
+				return vm, fm, errors.New("the transform tag option isn't supported by nested fields")
+			}
+			nested, err := opts.ValuesMarshalerFactory.ValuesMarshaler(nt, opts)
+			if err != nil {
+				return vm, fm, err
+			}
+			fm = &fieldMarshaler{
+				Tag:     tag,
+				Nested:  nested,
+				Indexed: indexed,
+			}
+			return vm, fm, nil
+		}
+		return vm, fm, err
+	}
+	if m, err = opts.transformFieldMarshaler(tag, m); err != nil {
 		return vm, fm, err
 	}
 	fm = &fieldMarshaler{
@@ -91,6 +302,37 @@ func newFieldMarshaler(sf reflect.StructField, opts *MarshalOptions) (ValuesMars
 	return vm, fm, err
 }
 
+// newLazyFieldMarshaler returns a fieldMarshaler whose Marshaler is created
+// by newFieldMarshaler on first use.
+func newLazyFieldMarshaler(t reflect.Type, sf reflect.StructField, opts *MarshalOptions, defaults tagDefaults) (ValuesMarshaler, *fieldMarshaler, error) {
+	tag, err := getStructFieldInfo(sf, opts.fieldNaming(), defaults)
+	if tag == nil || err != nil {
+		return nil, nil, err
+	}
+	if err := checkNamespace(sf, tag); err != nil {
+		return nil, nil, err
+	}
+	if tag.CommonOpts.Inline || opts.customValuesType(sf.Type) {
+		// The keys of inlined fields are needed by the struct marshaler.
+		return newFieldMarshaler(sf, opts, defaults)
+	}
+	fm := &fieldMarshaler{
+		Tag: tag,
+	}
+	fm.build = sync.OnceValue(func() error {
+		_, built, err := newFieldMarshaler(sf, opts, defaults)
+		if err != nil {
+			if opts.skipField(t, sf, err) {
+				return errSkippedField
+			}
+			return err
+		}
+		fm.Marshaler, fm.Nested, fm.Indexed = built.Marshaler, built.Nested, built.Indexed
+		return nil
+	})
+	return nil, fm, nil
+}
+
 func (p *structMarshaler) MarshalValues(v reflect.Value, opts *MarshalOptions) (url.Values, error) {
 	t := v.Type()
 	if t != p.Type {
@@ -101,32 +343,166 @@ func (p *structMarshaler) MarshalValues(v reflect.Value, opts *MarshalOptions) (
 	// error messages prefixed with the name of the struct type.
 
 	vs := make(url.Values, len(p.Fields))
+	if err := p.marshalFields(v, vs, opts); err != nil {
+		return nil, err
+	}
+
+	for _, ef := range p.EmbeddedFields {
+		fv := v.FieldByIndex(ef.Index)
+		if opts.NilEmbeddedAsZero && fv.Kind() == reflect.Ptr && fv.IsNil() {
+			fv = reflect.New(fv.Type().Elem())
+		}
+		evs, err := ef.ValuesMarshaler.MarshalValues(fv, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling embedded field %q :: %w", t.FieldByIndex(ef.Index).Name, err)
+		}
+		if opts.public {
+			evs = publicValues(ef, evs, opts.publicKeys)
+		}
+		for k, a := range evs {
+			vs[k] = a
+		}
+	}
+
+	return vs, nil
+}
+
+// marshalFields marshals the non-embedded fields of v into vs. The values of
+// the fields with a primitive marshaler are stored in a shared slab to avoid
+// the allocation of a []string per field.
+func (p *structMarshaler) marshalFields(v reflect.Value, vs url.Values, opts *MarshalOptions) error {
+	slab := make([]string, 0, p.primitiveFields)
 
 	for _, fm := range p.Fields {
-		fv := v.Field(fm.FieldIndex)
-		if fm.Tag.MarshalPresence == MarshalPresenceOmitEmpty && isEmpty(fv) {
+		fv := v.FieldByIndex(fm.Index)
+		if fm.Tag.MarshalPresence.omits(fv) {
+			continue
+		}
+		if opts.FieldFilter != nil && !opts.FieldFilter(p.fieldInfo(fm), fv) {
+			continue
+		}
+		if opts.redact && fm.Tag.MarshalOpts.Secret {
+			vs[fm.Tag.Name] = []string{redactedValue}
+			continue
+		}
+
+		if fm.build != nil {
+			if err := fm.build(); err != nil {
+				if err == errSkippedField {
+					continue
+				}
+				return fmt.Errorf("error marshaling url.Values entry %q :: %w", fm.Tag.Name, err)
+			}
+		}
+
+		if fm.Nested != nil {
+			if err := marshalNestedField(fv, fm, vs, opts); err != nil {
+				return fmt.Errorf("error marshaling url.Values entry %q :: %w", fm.Tag.Name, err)
+			}
+			continue
+		}
+
+		if pm, ok := fm.Marshaler.(*primitiveMarshalerFunc); ok {
+			s, err := pm.fn(fv, opts.forField(fm.Tag))
+			if err != nil {
+				return fmt.Errorf("error marshaling url.Values entry %q :: %w", fm.Tag.Name, err)
+			}
+			slab = append(slab, s)
+			vs[fm.Tag.Name] = slab[len(slab)-1 : len(slab) : len(slab)]
 			continue
 		}
-		a, err := fm.Marshaler.Marshal(fv, opts)
+
+		a, err := fm.Marshaler.Marshal(fv, opts.forField(fm.Tag))
 		if err != nil {
-			return nil, fmt.Errorf("error marshaling url.Values entry %q :: %v", fm.Tag.Name, err)
+			return fmt.Errorf("error marshaling url.Values entry %q :: %w", fm.Tag.Name, err)
 		}
 		if len(a) != 0 {
-			vs[fm.Tag.Name] = a
+			setFieldValues(vs, fm.Tag, fv.Type(), a)
+		} else if fm.Tag.MarshalOpts.EmptySlice == MarshalEmptySliceEmptyMarker && isEmptySlice(fv) {
+			setEmptySliceMarker(vs, fm.Tag)
+		} else if fm.Tag.CommonOpts.Null != "" && fv.Kind() == reflect.Ptr && fv.IsNil() {
+			vs[fm.Tag.Name] = []string{fm.Tag.CommonOpts.Null}
 		}
 	}
 
-	for _, ef := range p.EmbeddedFields {
-		evs, err := ef.ValuesMarshaler.MarshalValues(v.Field(ef.FieldIndex), opts)
-		if err != nil {
-			return nil, fmt.Errorf("error marshaling embedded field %q :: %v", v.Type().Field(ef.FieldIndex).Name, err)
+	return nil
+}
+
+// isEmptySlice reports whether v is (or points to) an empty but non-nil
+// slice.
+func isEmptySlice(v reflect.Value) bool {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return false
 		}
-		for k, a := range evs {
-			vs[k] = a
+		v = v.Elem()
+	}
+	return v.Kind() == reflect.Slice && !v.IsNil() && v.Len() == 0
+}
+
+// setEmptySliceMarker stores the empty value that marks an empty slice in
+// vs. The key has the "[]" suffix if the SliceKeys tag option is Brackets.
+func setEmptySliceMarker(vs url.Values, tag *ParsedTagInfo) {
+	key := tag.Name
+	if tag.CommonOpts.SliceKeys == OptionSliceKeysBrackets {
+		key += "[]"
+	}
+	vs[key] = []string{""}
+}
+
+// setFieldValues stores the values of a field in vs. The items of slices and
+// arrays are stored under the keys selected by the SliceKeys tag option.
+func setFieldValues(vs url.Values, tag *ParsedTagInfo, t reflect.Type, a []string) {
+	if tag.CommonOpts.SliceKeys == OptionSliceKeysRepeat {
+		vs[tag.Name] = a
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if k := t.Kind(); k != reflect.Slice && k != reflect.Array {
+		vs[tag.Name] = a
+		return
+	}
+
+	switch tag.CommonOpts.SliceKeys {
+	case OptionSliceKeysBrackets:
+		vs[tag.Name+"[]"] = a
+	case OptionSliceKeysNumbered:
+		for i := range a {
+			vs[tag.Name+strconv.Itoa(i)] = a[i : i+1 : i+1]
+		}
+	default:
+		vs[tag.Name] = a
+	}
+}
+
+// marshalNestedField marshals a field with a Nested ValuesMarshaler into vs.
+func marshalNestedField(fv reflect.Value, fm *fieldMarshaler, vs url.Values, opts *MarshalOptions) error {
+	if fv.Kind() == reflect.Map {
+		return marshalNested(fm.Nested, fv, fm.Tag.Name, vs, opts.forMapField(fm.Tag))
+	}
+	if !fm.Indexed {
+		return marshalNested(fm.Nested, fv, fm.Tag.Name, vs, opts)
+	}
+	for i, n := 0, fv.Len(); i < n; i++ {
+		err := marshalNested(fm.Nested, fv.Index(i), opts.Nesting.indexKey(fm.Tag.Name, i), vs, opts)
+		if err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	return vs, nil
+func marshalNested(vm ValuesMarshaler, v reflect.Value, prefix string, vs url.Values, opts *MarshalOptions) error {
+	nvs, err := vm.MarshalValues(v, opts)
+	if err != nil {
+		return err
+	}
+	for k, a := range nvs {
+		vs[opts.Nesting.fieldKey(prefix, k)] = a
+	}
+	return nil
 }
 
 func isEmpty(v reflect.Value) bool {
@@ -148,8 +524,55 @@ func isEmpty(v reflect.Value) bool {
 	}
 }
 
+// isZeroer is implemented by the types that define their zero values (e.g.
+// time.Time).
+type isZeroer interface {
+	IsZero() bool
+}
+
+var isZeroerType = reflect.TypeFor[isZeroer]()
+
+// omits reports whether a value is omitted by the MarshalPresence.
+func (mp MarshalPresence) omits(v reflect.Value) bool {
+	switch mp {
+	case MarshalPresenceOmitEmpty:
+		return isEmpty(v)
+	case MarshalPresenceOmitZero:
+		return isZero(v)
+	case MarshalPresenceOmitNil:
+		return isNil(v)
+	default:
+		return false
+	}
+}
+
+// isZero reports whether v is zero by its IsZero method or by
+// reflect.Value.IsZero.
+func isZero(v reflect.Value) bool {
+	if isNil(v) {
+		return true
+	}
+	if v.Type().Implements(isZeroerType) {
+		return v.Interface().(isZeroer).IsZero()
+	}
+	if v.CanAddr() && reflect.PointerTo(v.Type()).Implements(isZeroerType) {
+		return v.Addr().Interface().(isZeroer).IsZero()
+	}
+	return v.IsZero()
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
+
 type mapMarshaler struct {
 	Type          reflect.Type
+	KeyMarshaler  PrimitiveMarshalerFunc
 	ElemMarshaler Marshaler
 }
 
@@ -158,8 +581,9 @@ func newMapMarshaler(t reflect.Type, opts *MarshalOptions) (ValuesMarshaler, err
 		return nil, &WrongKindError{Expected: reflect.Map, Actual: t}
 	}
 
-	if t.Key() != stringType {
-		return nil, fmt.Errorf("map key type is expected to be string: %v", t)
+	km, err := opts.mapKeyMarshaler(t.Key())
+	if err != nil {
+		return nil, err
 	}
 
 	et := t.Elem()
@@ -167,11 +591,12 @@ func newMapMarshaler(t reflect.Type, opts *MarshalOptions) (ValuesMarshaler, err
 	if err != nil {
 		// TODO: use a MapError error type in the function to generate
 		// error messages prefixed with the name of the struct type.
-		return nil, fmt.Errorf("error getting marshaler for map value type %v :: %v", et, err)
+		return nil, fmt.Errorf("error getting marshaler for map value type %v :: %w", et, err)
 	}
 
 	return &mapMarshaler{
 		Type:          t,
+		KeyMarshaler:  km,
 		ElemMarshaler: m,
 	}, nil
 }
@@ -190,19 +615,33 @@ func (p *mapMarshaler) MarshalValues(v reflect.Value, opts *MarshalOptions) (url
 	vs := make(url.Values, vlen)
 	for _, key := range v.MapKeys() {
 		val := v.MapIndex(key)
-		if opts.TagOptionsDefaults.Presence == MarshalPresenceOmitEmpty && isEmpty(val) {
+		if opts.TagOptionsDefaults.Presence.omits(val) {
 			continue
 		}
-		keyStr := key.String()
+		keyStr, err := p.marshalKey(key, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling map key %v :: %w", key, err)
+		}
 		a, err := p.ElemMarshaler.Marshal(val, opts)
 		if err != nil {
-			return nil, fmt.Errorf("error marshaling key %q :: %v", keyStr, err)
+			return nil, fmt.Errorf("error marshaling key %q :: %w", keyStr, err)
+		}
+		// Keys normalized by a RegisterMapKeyType func can collide.
+		if prev, ok := vs[keyStr]; ok {
+			a = append(prev, a...)
 		}
 		vs[keyStr] = a
 	}
 	return vs, nil
 }
 
+func (p *mapMarshaler) marshalKey(key reflect.Value, opts *MarshalOptions) (string, error) {
+	if p.KeyMarshaler == nil {
+		return key.String(), nil
+	}
+	return p.KeyMarshaler(key, opts)
+}
+
 type ptrValuesMarshaler struct {
 	Type          reflect.Type
 	ElemMarshaler ValuesMarshaler
@@ -1,53 +1,98 @@
 package qs
 
-import (
-	"reflect"
-	"sync"
-)
+import "reflect"
 
-func newValuesMarshalerCache(wrapped ValuesMarshalerFactory) ValuesMarshalerFactory {
+func newValuesMarshalerCache(wrapped ValuesMarshalerFactory, newCache func() Cache) ValuesMarshalerFactory {
 	return &valuesMarshalerCache{
 		wrapped: wrapped,
+		cache:   variantCache{newCache: newCache},
 	}
 }
 
 type valuesMarshalerCache struct {
 	wrapped ValuesMarshalerFactory
-	cache   sync.Map
+	cache   variantCache
 }
 
 func (o *valuesMarshalerCache) ValuesMarshaler(t reflect.Type, opts *MarshalOptions) (ValuesMarshaler, error) {
-	return cacher(o.wrapped.ValuesMarshaler, &o.cache, t, opts)
+	var variant interface{}
+	if opts != nil {
+		variant = opts.variant
+	}
+	return cacher(o.wrapped.ValuesMarshaler, o.cache.get(variant), t, opts, opts.logger())
+}
+
+func (o *valuesMarshalerCache) purge() {
+	o.cache.purge()
+}
+
+func (o *valuesMarshalerCache) evict(t reflect.Type) {
+	o.cache.evict(t)
+}
+
+func (o *valuesMarshalerCache) stats() CacheStats {
+	return o.cache.stats()
 }
 
 func (p *valuesMarshalerCache) RegisterSubFactory(k reflect.Kind, fn ValuesMarshalerFactoryFunc) error {
-	return p.wrapped.RegisterSubFactory(k, fn)
+	err := p.wrapped.RegisterSubFactory(k, fn)
+	p.purge()
+	return err
+}
+
+func (p *valuesMarshalerCache) RegisterCustomType(t reflect.Type, fn ValuesMarshalerFactoryFunc) error {
+	err := p.wrapped.RegisterCustomType(t, fn)
+	p.purge()
+	return err
 }
 
-func newMarshalerCache(wrapped MarshalerFactory) MarshalerFactory {
+func (p *valuesMarshalerCache) customType(t reflect.Type) bool {
+	c, ok := p.wrapped.(customValuesMarshalerTypeChecker)
+	return ok && c.customType(t)
+}
+
+func newMarshalerCache(wrapped MarshalerFactory, newCache func() Cache) MarshalerFactory {
 	return &marshalerCache{
 		wrapped: wrapped,
+		cache:   newTypeCache(newCache),
 	}
 }
 
 type marshalerCache struct {
 	wrapped MarshalerFactory
-	cache   sync.Map
+	cache   *typeCache
 }
 
 func (o *marshalerCache) Marshaler(t reflect.Type, opts *MarshalOptions) (Marshaler, error) {
-	return cacher(o.wrapped.Marshaler, &o.cache, t, opts)
+	return cacher(o.wrapped.Marshaler, o.cache, t, opts, opts.logger())
+}
+
+func (o *marshalerCache) purge() {
+	o.cache.purge()
+}
+
+func (o *marshalerCache) evict(t reflect.Type) {
+	o.cache.evict(t)
+}
+
+func (o *marshalerCache) stats() CacheStats {
+	return o.cache.stats()
 }
 
 func (p *marshalerCache) RegisterSubFactory(k reflect.Kind, fn MarshalerFactoryFunc) error {
-	return p.wrapped.RegisterSubFactory(k, fn)
+	err := p.wrapped.RegisterSubFactory(k, fn)
+	p.purge()
+	return err
 }
 
 func (p *marshalerCache) RegisterCustomType(k reflect.Type, fn PrimitiveMarshalerFunc) error {
-	return p.wrapped.RegisterCustomType(k, fn)
-
+	err := p.wrapped.RegisterCustomType(k, fn)
+	p.purge()
+	return err
 }
 
 func (p *marshalerCache) RegisterKindOverride(k reflect.Kind, fn PrimitiveMarshalerFunc) error {
-	return p.wrapped.RegisterKindOverride(k, fn)
+	err := p.wrapped.RegisterKindOverride(k, fn)
+	p.purge()
+	return err
 }
@@ -3,7 +3,10 @@ package qs
 import (
 	"errors"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 type fakeValuesMarshalerFactory struct {
@@ -21,10 +24,14 @@ func (p *fakeValuesMarshalerFactory) RegisterSubFactory(k reflect.Kind, fn Value
 	panic("!mock not implemented!")
 }
 
+func (p *fakeValuesMarshalerFactory) RegisterCustomType(t reflect.Type, fn ValuesMarshalerFactoryFunc) error {
+	panic("!mock not implemented!")
+}
+
 func TestValuesMarshalerCacheSuccess(t *testing.T) {
 	expected := &structMarshaler{}
 	wrapped := &fakeValuesMarshalerFactory{m: expected}
-	cache := newValuesMarshalerCache(wrapped)
+	cache := newValuesMarshalerCache(wrapped, nil)
 	tp := reflect.TypeOf((*fakeValuesMarshalerFactory)(nil)).Elem()
 
 	// cache miss
@@ -55,7 +62,7 @@ func TestValuesMarshalerCacheSuccess(t *testing.T) {
 func TestValuesMarshalerCacheError(t *testing.T) {
 	e := errors.New("test error")
 	wrapped := &fakeValuesMarshalerFactory{err: e}
-	cache := newValuesMarshalerCache(wrapped)
+	cache := newValuesMarshalerCache(wrapped, nil)
 	tp := reflect.TypeOf((*fakeValuesMarshalerFactory)(nil)).Elem()
 
 	// cache miss
@@ -111,7 +118,7 @@ func TestMarshalerCacheSuccess(t *testing.T) {
 	// we need a comparable fakeMarshaler object to be able to assert
 	expected := &fakeMarshaler{}
 	wrapped := &fakeMarshalerFactory{m: expected}
-	cache := newMarshalerCache(wrapped)
+	cache := newMarshalerCache(wrapped, nil)
 	tp := reflect.TypeOf((*fakeMarshalerFactory)(nil)).Elem()
 
 	// cache miss
@@ -142,7 +149,7 @@ func TestMarshalerCacheSuccess(t *testing.T) {
 func TestMarshalerCacheError(t *testing.T) {
 	e := errors.New("test error")
 	wrapped := &fakeMarshalerFactory{err: e}
-	cache := newMarshalerCache(wrapped)
+	cache := newMarshalerCache(wrapped, nil)
 	tp := reflect.TypeOf((*fakeMarshalerFactory)(nil)).Elem()
 
 	// cache miss
@@ -163,3 +170,182 @@ func TestMarshalerCacheError(t *testing.T) {
 		t.Fatalf("got %v, want %v", wrapped.calls, []reflect.Type{tp})
 	}
 }
+
+func TestRegisterAfterFirstUse(t *testing.T) {
+	type query struct {
+		Page int
+	}
+
+	m := NewMarshaler(&MarshalOptions{})
+	s, err := m.Marshal(&query{Page: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "page=5" {
+		t.Fatalf("got %q, want %q", s, "page=5")
+	}
+
+	err = m.RegisterKindOverride(reflect.Int, func(v reflect.Value, opts *MarshalOptions) (string, error) {
+		return "int", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = m.Marshal(&query{Page: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "page=int" {
+		t.Errorf("got %q, want %q", s, "page=int")
+	}
+}
+
+func TestConcurrentRegisterAndMarshal(t *testing.T) {
+	type query struct {
+		Page int
+		When time.Time
+	}
+
+	m := NewMarshaler(&MarshalOptions{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if _, err := m.Marshal(&query{Page: j}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = m.RegisterCustomType(timeType, marshalTime)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestMarshalerEvictTypeAndResetCache(t *testing.T) {
+	type query struct {
+		Page int
+	}
+
+	wrapped := &fakeValuesMarshalerFactory{m: &structMarshaler{}}
+	m := NewMarshaler(&MarshalOptions{ValuesMarshalerFactory: wrapped})
+	tp := reflect.TypeOf(query{})
+
+	for i := 0; i < 2; i++ {
+		if _, err := m.CompileType(tp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(wrapped.calls) != 1 {
+		t.Fatalf("got %v calls, want 1", len(wrapped.calls))
+	}
+
+	m.EvictType(tp)
+	if _, err := m.CompileType(tp); err != nil {
+		t.Fatal(err)
+	}
+	if len(wrapped.calls) != 2 {
+		t.Fatalf("got %v calls, want 2", len(wrapped.calls))
+	}
+
+	m.ResetCache()
+	if _, err := m.CompileType(tp); err != nil {
+		t.Fatal(err)
+	}
+	if len(wrapped.calls) != 3 {
+		t.Fatalf("got %v calls, want 3", len(wrapped.calls))
+	}
+}
+
+// boundedCache is a Cache that drops every item when it is full.
+type boundedCache struct {
+	Cache
+	max int
+}
+
+func (c *boundedCache) Put(t reflect.Type, item interface{}) {
+	if c.Len() >= c.max {
+		c.Purge()
+	}
+	c.Cache.Put(t, item)
+}
+
+func TestMarshalerCacheStats(t *testing.T) {
+	type query struct {
+		Page int
+	}
+
+	wrapped := &fakeValuesMarshalerFactory{m: &structMarshaler{}}
+	m := NewMarshaler(&MarshalOptions{ValuesMarshalerFactory: wrapped})
+	tp := reflect.TypeOf(query{})
+	for i := 0; i < 3; i++ {
+		if _, err := m.CompileType(tp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := m.CacheStats(); s != (CacheStats{Hits: 2, Misses: 1, Len: 1}) {
+		t.Errorf("got %+v", s)
+	}
+	if s := m.With().CacheStats(); s != m.CacheStats() {
+		t.Errorf("derived marshaler: got %+v, want %+v", s, m.CacheStats())
+	}
+
+	m = m.With(WithMarshalCache(func() Cache {
+		return &boundedCache{Cache: NewCache(), max: 1}
+	}))
+	tp2 := reflect.TypeOf(struct{ A int }{})
+	for _, tp := range []reflect.Type{tp, tp2, tp} {
+		if _, err := m.CompileType(tp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(wrapped.calls) != 4 {
+		t.Errorf("got %v calls, want 4", len(wrapped.calls))
+	}
+	if s := m.CacheStats(); s != (CacheStats{Hits: 0, Misses: 3, Len: 1}) {
+		t.Errorf("got %+v", s)
+	}
+}
+
+func TestMarshalerCacheVariants(t *testing.T) {
+	type query struct {
+		Page int
+	}
+
+	m := NewMarshaler(nil)
+	marshal := func(opts ...func(*QSMarshaler)) {
+		if _, err := m.MarshalWith(&query{Page: 1}, opts...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	marshal()
+	marshal(WithMarshalPresence(MarshalPresenceOmitEmpty))
+	n := m.CacheStats().Len
+	for i := 0; i < 100; i++ {
+		marshal(WithMarshalPresence(MarshalPresenceOmitEmpty))
+		marshal(WithMarshalKeyPrefix("x_"))
+	}
+	if s := m.CacheStats(); s.Len != n {
+		t.Errorf("got %+v, want Len %v", s, n)
+	}
+
+	// Every name transformer set by an option is a new variant but the
+	// number of cached variants is limited.
+	for i := 0; i < maxCacheVariants; i++ {
+		marshal(WithMarshalNameTransformer(strings.ToUpper))
+	}
+	n = m.CacheStats().Len
+	for i := 0; i < 100; i++ {
+		marshal(WithMarshalNameTransformer(strings.ToUpper))
+	}
+	if s := m.CacheStats(); s.Len != n {
+		t.Errorf("got %+v, want Len %v", s, n)
+	}
+}
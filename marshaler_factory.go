@@ -1,8 +1,12 @@
 package qs
 
 import (
+	"encoding"
 	"errors"
+	"maps"
 	"reflect"
+	"sync"
+	"sync/atomic"
 )
 
 type MarshalerFactoryFunc func(t reflect.Type, opts *MarshalOptions) (Marshaler, error)
@@ -28,20 +32,64 @@ type MarshalerFactory interface {
 	RegisterKindOverride(k reflect.Kind, fn PrimitiveMarshalerFunc) error
 }
 
-// marshalerFactory implements the MarshalerFactory interface.
+// marshalerFactory implements the MarshalerFactory interface. It resolves the
+// Marshaler of a type in the following order:
+//   - custom type registered with RegisterCustomType
+//   - the MarshalQS implementation of the type or of the pointer to the type
+//   - sub-factory registered with RegisterSubFactory for the kind of the type
+//   - Marshaler registered with RegisterKindOverride for the kind of the type
+//   - builtin Marshaler of the type (time.Time, url.URL)
+//   - builtin sub-factory of the kind (pointers, arrays, slices)
+//   - builtin Marshaler of the kind (primitive types)
+//   - the MarshalText method of the type or of the pointer to the type
+//     (encoding.TextMarshaler)
+//
+// The last step is the extension point of the types that aren't handled
+// otherwise, e.g. the decimal types of third-party packages like
+// shopspring/decimal: their values are marshaled with MarshalText so amounts
+// like "price=10.99" keep their exact decimal representation. Types of kinds
+// with a builtin Marshaler (e.g. named strings) keep using that Marshaler.
 type marshalerFactory struct {
 	types             map[reflect.Type]Marshaler
 	kindSubRegistries map[reflect.Kind]MarshalerFactory
 	kinds             map[reflect.Kind]Marshaler
 
-	typesOverriden             map[reflect.Type]Marshaler
-	kindSubRegistriesOverriden map[reflect.Kind]MarshalerFactory
-	kindsOverriden             map[reflect.Kind]Marshaler
+	// overrides holds the registered objects. Registrations replace it with
+	// an updated copy (copy-on-write) so the lookups don't need locking.
+	overrides atomic.Pointer[marshalerFactoryOverrides]
+	mu        sync.Mutex
+}
+
+type marshalerFactoryOverrides struct {
+	types             map[reflect.Type]Marshaler
+	kindSubRegistries map[reflect.Kind]MarshalerFactory
+	kinds             map[reflect.Kind]Marshaler
+}
+
+// register applies fn to a copy of the overrides and replaces them with it.
+func (p *marshalerFactory) register(fn func(o *marshalerFactoryOverrides)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	o := p.overrides.Load()
+	o = &marshalerFactoryOverrides{
+		types:             maps.Clone(o.types),
+		kindSubRegistries: maps.Clone(o.kindSubRegistries),
+		kinds:             maps.Clone(o.kinds),
+	}
+	fn(o)
+	p.overrides.Store(o)
 }
 
 // MarshalQS is an interface that can be implemented by any type that
 // wants to handle its own marshaling instead of relying on the default
 // marshaling provided by this package.
+//
+// MarshalQS can be implemented with a value or a pointer receiver. A pointer
+// receiver is called with the address of the marshaled value (or of a copy
+// if the value isn't addressable). Pointers to implementing types are
+// dereferenced before calling MarshalQS and nil pointers are marshaled as
+// missing values, so MarshalQS is never called with a nil receiver.
 type MarshalQS interface {
 	// MarshalQS is essentially the same as the Marshaler.Marshal
 	// method without its v parameter.
@@ -50,60 +98,104 @@ type MarshalQS interface {
 
 var marshalQSInterfaceType = reflect.TypeOf((*MarshalQS)(nil)).Elem()
 
-func (p *marshalerFactory) Marshaler(t reflect.Type, opts *MarshalOptions) (Marshaler, error) {
-	if marshaler, ok := p.typesOverriden[t]; ok {
-		return marshaler, nil
+// marshalQSMarshaler returns the Marshaler that calls the MarshalQS method of
+// t or nil if neither t nor *t implements MarshalQS. Pointer types are left
+// to the builtin pointer Marshaler that dereferences them.
+func marshalQSMarshaler(t reflect.Type) Marshaler {
+	switch {
+	case t.Kind() == reflect.Ptr:
+		return nil
+	case t.Implements(marshalQSInterfaceType):
+		return &marshalerFunc{marshalWithMarshalQS}
+	case reflect.PointerTo(t).Implements(marshalQSInterfaceType):
+		return &marshalerFunc{marshalWithPtrMarshalQS}
 	}
-	if marshaler, ok := p.types[t]; ok {
+	return nil
+}
+
+var textMarshalerInterfaceType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// textMarshalerMarshaler returns the primitive Marshaler that calls the
+// MarshalText method of t or nil if neither t nor *t implements
+// encoding.TextMarshaler.
+func textMarshalerMarshaler(t reflect.Type) Marshaler {
+	switch {
+	case t.Kind() == reflect.Ptr:
+		return nil
+	case t.Implements(textMarshalerInterfaceType):
+		return &primitiveMarshalerFunc{marshalWithTextMarshaler}
+	case reflect.PointerTo(t).Implements(textMarshalerInterfaceType):
+		return &primitiveMarshalerFunc{marshalWithPtrTextMarshaler}
+	}
+	return nil
+}
+
+func (p *marshalerFactory) Marshaler(t reflect.Type, opts *MarshalOptions) (Marshaler, error) {
+	overrides := p.overrides.Load()
+
+	if marshaler, ok := overrides.types[t]; ok {
 		return marshaler, nil
 	}
 
-	if t.Implements(marshalQSInterfaceType) {
-		return &marshalerFunc{marshalWithMarshalQS}, nil
+	if m := marshalQSMarshaler(t); m != nil {
+		return m, nil
 	}
 
 	k := t.Kind()
-	if subFactory, ok := p.kindSubRegistriesOverriden[k]; ok {
+	if subFactory, ok := overrides.kindSubRegistries[k]; ok {
 		return subFactory.Marshaler(t, opts)
 	}
-	if subFactory, ok := p.kindSubRegistries[k]; ok {
-		return subFactory.Marshaler(t, opts)
+	if marshaler, ok := overrides.kinds[k]; ok {
+		return marshaler, nil
 	}
 
-	if marshaler, ok := p.kindsOverriden[k]; ok {
+	if marshaler, ok := p.types[t]; ok {
 		return marshaler, nil
 	}
+	if subFactory, ok := p.kindSubRegistries[k]; ok {
+		return subFactory.Marshaler(t, opts)
+	}
 	if marshaler, ok := p.kinds[k]; ok {
 		return marshaler, nil
 	}
 
+	if m := textMarshalerMarshaler(t); m != nil {
+		return m, nil
+	}
+
 	return nil, &UnhandledTypeError{Type: t}
 }
 
 func (p *marshalerFactory) RegisterSubFactory(k reflect.Kind, fn MarshalerFactoryFunc) error {
-	p.kindSubRegistriesOverriden[k] = &marshalerFactoryFunc{fn}
+	p.register(func(o *marshalerFactoryOverrides) {
+		o.kindSubRegistries[k] = &marshalerFactoryFunc{fn}
+	})
 	return nil
 }
 
 func (p *marshalerFactory) RegisterCustomType(k reflect.Type, fn PrimitiveMarshalerFunc) error {
-	p.typesOverriden[k] = &primitiveMarshalerFunc{fn}
+	p.register(func(o *marshalerFactoryOverrides) {
+		o.types[k] = &primitiveMarshalerFunc{fn}
+	})
 	return nil
 }
 
 func (p *marshalerFactory) RegisterKindOverride(k reflect.Kind, fn PrimitiveMarshalerFunc) error {
-	p.kindsOverriden[k] = &primitiveMarshalerFunc{fn: fn}
+	p.register(func(o *marshalerFactoryOverrides) {
+		o.kinds[k] = &primitiveMarshalerFunc{fn: fn}
+	})
 	return nil
 }
 
 func newMarshalerFactory() *marshalerFactory {
-	return &marshalerFactory{
-		typesOverriden:             map[reflect.Type]Marshaler{},
-		kindSubRegistriesOverriden: map[reflect.Kind]MarshalerFactory{},
-		kindsOverriden:             map[reflect.Kind]Marshaler{},
-
+	p := &marshalerFactory{
 		types: map[reflect.Type]Marshaler{
 			timeType: &primitiveMarshalerFunc{marshalTime},
 			urlType:  &primitiveMarshalerFunc{marshalURL},
+
+			bigIntType:   &primitiveMarshalerFunc{marshalBigInt},
+			bigFloatType: &primitiveMarshalerFunc{marshalBigFloat},
+			bigRatType:   &primitiveMarshalerFunc{marshalBigRat},
 		},
 		kindSubRegistries: map[reflect.Kind]MarshalerFactory{
 			reflect.Ptr:   &marshalerFactoryFunc{newPtrMarshaler},
@@ -130,6 +222,12 @@ func newMarshalerFactory() *marshalerFactory {
 			reflect.Float64: &primitiveMarshalerFunc{marshalFloat},
 		},
 	}
+	p.overrides.Store(&marshalerFactoryOverrides{
+		types:             map[reflect.Type]Marshaler{},
+		kindSubRegistries: map[reflect.Kind]MarshalerFactory{},
+		kinds:             map[reflect.Kind]Marshaler{},
+	})
+	return p
 }
 
 // marshalerFactoryFunc implements the MarshalerFactory interface.
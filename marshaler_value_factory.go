@@ -2,7 +2,10 @@ package qs
 
 import (
 	"errors"
+	"maps"
 	"reflect"
+	"sync"
+	"sync/atomic"
 )
 
 type ValuesMarshalerFactoryFunc func(t reflect.Type, opts *MarshalOptions) (ValuesMarshaler, error)
@@ -15,16 +18,33 @@ type ValuesMarshalerFactory interface {
 
 	// RegisterSubFactory registers a ValuesUnmarshalerFactory for the given kind
 	RegisterSubFactory(k reflect.Kind, fn ValuesMarshalerFactoryFunc) error
+
+	// RegisterCustomType registers a ValuesMarshalerFactoryFunc for the given
+	// type. It takes precedence over the sub-factory of the kind of the type.
+	RegisterCustomType(t reflect.Type, fn ValuesMarshalerFactoryFunc) error
 }
 
 // valuesMarshalerFactory implements the ValuesMarshalerFactory interface.
 type valuesMarshalerFactory struct {
-	kindSubRegistries          map[reflect.Kind]ValuesMarshalerFactory
-	kindSubRegistriesOverriden map[reflect.Kind]ValuesMarshalerFactory
+	kindSubRegistries map[reflect.Kind]ValuesMarshalerFactory
+
+	// kindSubRegistriesOverriden holds the registered sub-factories. It is
+	// replaced with an updated copy by RegisterSubFactory (copy-on-write) so
+	// the lookups don't need locking.
+	kindSubRegistriesOverriden atomic.Pointer[map[reflect.Kind]ValuesMarshalerFactory]
+
+	// customTypes holds the factories registered by RegisterCustomType. Like
+	// kindSubRegistriesOverriden it is copy-on-write.
+	customTypes atomic.Pointer[map[reflect.Type]ValuesMarshalerFactory]
+	mu          sync.Mutex
 }
 
 func (p *valuesMarshalerFactory) ValuesMarshaler(t reflect.Type, opts *MarshalOptions) (ValuesMarshaler, error) {
-	if subFactory, ok := p.kindSubRegistriesOverriden[t.Kind()]; ok {
+	if factory, ok := (*p.customTypes.Load())[t]; ok {
+		return factory.ValuesMarshaler(t, opts)
+	}
+
+	if subFactory, ok := (*p.kindSubRegistriesOverriden.Load())[t.Kind()]; ok {
 		return subFactory.ValuesMarshaler(t, opts)
 	}
 
@@ -36,18 +56,41 @@ func (p *valuesMarshalerFactory) ValuesMarshaler(t reflect.Type, opts *MarshalOp
 }
 
 func (p *valuesMarshalerFactory) RegisterSubFactory(k reflect.Kind, fn ValuesMarshalerFactoryFunc) error {
-	p.kindSubRegistriesOverriden[k] = &valuesMarshalerFactoryFunc{fn}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	overrides := maps.Clone(*p.kindSubRegistriesOverriden.Load())
+	overrides[k] = &valuesMarshalerFactoryFunc{fn}
+	p.kindSubRegistriesOverriden.Store(&overrides)
 	return nil
 }
 
+func (p *valuesMarshalerFactory) RegisterCustomType(t reflect.Type, fn ValuesMarshalerFactoryFunc) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	customTypes := maps.Clone(*p.customTypes.Load())
+	customTypes[t] = &valuesMarshalerFactoryFunc{fn}
+	p.customTypes.Store(&customTypes)
+	return nil
+}
+
+func (p *valuesMarshalerFactory) customType(t reflect.Type) bool {
+	_, ok := (*p.customTypes.Load())[t]
+	return ok
+}
+
 func newValuesMarshalerFactory() *valuesMarshalerFactory {
-	return &valuesMarshalerFactory{
+	p := &valuesMarshalerFactory{
 		kindSubRegistries: map[reflect.Kind]ValuesMarshalerFactory{
 			reflect.Ptr:    &valuesMarshalerFactoryFunc{newPtrValuesMarshaler},
 			reflect.Struct: &valuesMarshalerFactoryFunc{newStructMarshaler},
 			reflect.Map:    &valuesMarshalerFactoryFunc{newMapMarshaler},
 		},
 	}
+	p.kindSubRegistriesOverriden.Store(&map[reflect.Kind]ValuesMarshalerFactory{})
+	p.customTypes.Store(&map[reflect.Type]ValuesMarshalerFactory{})
+	return p
 }
 
 // valuesMarshalerFactoryFunc implements the ValuesMarshalerFactory interface.
@@ -62,3 +105,31 @@ func (f valuesMarshalerFactoryFunc) ValuesMarshaler(t reflect.Type, opts *Marsha
 func (p *valuesMarshalerFactoryFunc) RegisterSubFactory(k reflect.Kind, fn ValuesMarshalerFactoryFunc) error {
 	return errors.New("not implemented")
 }
+
+func (p *valuesMarshalerFactoryFunc) RegisterCustomType(t reflect.Type, fn ValuesMarshalerFactoryFunc) error {
+	return errors.New("not implemented")
+}
+
+// customValuesMarshalerTypeChecker is implemented by the ValuesMarshalerFactory objects that
+// can report the types registered with RegisterCustomType.
+type customValuesMarshalerTypeChecker interface {
+	customType(t reflect.Type) bool
+}
+
+// customValuesType reports whether t (or the type pointed to by t) is
+// registered with the RegisterCustomType method of the
+// ValuesMarshalerFactory. The fields of these types are marshaled like inline
+// fields: the ValuesMarshaler of the type controls all of their keys.
+func (o *MarshalOptions) customValuesType(t reflect.Type) bool {
+	f, ok := o.ValuesMarshalerFactory.(customValuesMarshalerTypeChecker)
+	if !ok {
+		return false
+	}
+	for t.Kind() == reflect.Ptr {
+		if f.customType(t) {
+			return true
+		}
+		t = t.Elem()
+	}
+	return f.customType(t)
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net/url"
 	"reflect"
+	"strings"
+	"time"
 )
 
 // QSUnmarshaler objects can be created by calling NewUnmarshaler and they can be
@@ -13,10 +15,17 @@ type QSUnmarshaler struct {
 	opts *UnmarshalerDefaultOptions
 
 	stringToQueryParser func(query string) (url.Values, error)
+
+	hook HookFunc
 }
 
-// NewUnmarshaler returns a new QSUnmarshaler object.
+// NewUnmarshaler returns a new QSUnmarshaler object. The prm parameter can be
+// nil, in that case the unmarshaler starts with the default options and it can
+// be configured with the option appliers (e.g. WithUnmarshalNameTransformer).
 func NewUnmarshaler(prm *UnmarshalerDefaultOptions, opts ...func(p *QSUnmarshaler)) *QSUnmarshaler {
+	if prm == nil {
+		prm = &UnmarshalerDefaultOptions{}
+	}
 	p := &QSUnmarshaler{
 		opts:                prepareUnmarshalOptions(*prm),
 		stringToQueryParser: url.ParseQuery,
@@ -25,52 +34,243 @@ func NewUnmarshaler(prm *UnmarshalerDefaultOptions, opts ...func(p *QSUnmarshale
 	for _, opt := range opts {
 		opt(p)
 	}
+	p.opts.updateCacheVariant()
 
 	return p
 }
 
+// With returns a copy of the unmarshaler with the given options applied. The
+// copy shares the registrations and the caches of the original. As long as
+// the options don't change the name transformer or the tag option defaults
+// the copy also reuses the objects compiled by the original, so deriving
+// unmarshalers for per-request tweaks is cheap.
+func (p *QSUnmarshaler) With(opts ...func(*QSUnmarshaler)) *QSUnmarshaler {
+	c := *p
+	o := *p.opts
+	tagDefaults := *o.TagOptionsDefaults
+	commonDefaults := *o.TagCommonOptionsDefaults
+	o.TagOptionsDefaults = &tagDefaults
+	o.TagCommonOptionsDefaults = &commonDefaults
+	c.opts = &o
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+	o.updateCacheVariant()
+
+	return &c
+}
+
+// Options returns the options used by the unmarshaler. The returned object
+// can be passed to the ValuesUnmarshaler objects returned by CompileType and
+// it must not be modified.
+func (p *QSUnmarshaler) Options() *UnmarshalerDefaultOptions {
+	return p.opts
+}
+
+// CompileType returns the ValuesUnmarshaler that the unmarshaler uses to
+// unmarshal into values of type t. Note that t is the type of the target value
+// (e.g. Query) and not the type of the pointer passed to Unmarshal (*Query).
+// It can be called at startup to make sure that the query types of an
+// application are supported (fail-fast) and the returned object can be used
+// directly in hot loops with the object returned by Options:
+//
+//	vum, err := unmarshaler.CompileType(reflect.TypeOf(Query{}))
+//	...
+//	var q Query
+//	err = vum.UnmarshalValues(reflect.ValueOf(&q).Elem(), values, unmarshaler.Options())
+//
+// Note that the key prefix and suffix of the unmarshaler aren't handled by
+// the returned object.
+func (p *QSUnmarshaler) CompileType(t reflect.Type) (ValuesUnmarshaler, error) {
+	if t == nil {
+		return nil, errors.New("nil type")
+	}
+	return p.opts.ValuesUnmarshalerFactory.ValuesUnmarshaler(t, p.opts)
+}
+
+// purgeValuesCache is called after the registrations because the cached
+// ValuesUnmarshaler objects hold the Unmarshaler objects of their fields. The
+// registrations are safe to call concurrently with unmarshaling.
+func (p *QSUnmarshaler) purgeValuesCache() {
+	purgeCache(p.opts.ValuesUnmarshalerFactory)
+}
+
+// ResetCache removes every compiled Unmarshaler and ValuesUnmarshaler object (and
+// memoized error) from the caches of the unmarshaler. They are created again
+// on demand with the current registrations.
+func (p *QSUnmarshaler) ResetCache() {
+	purgeCache(p.opts.UnmarshalerFactory)
+	purgeCache(p.opts.ValuesUnmarshalerFactory)
+}
+
+// CacheStats returns the counters of the caches of the Unmarshaler and
+// ValuesUnmarshaler objects of the unmarshaler.
+func (p *QSUnmarshaler) CacheStats() CacheStats {
+	return cacheStats(p.opts.UnmarshalerFactory).add(cacheStats(p.opts.ValuesUnmarshalerFactory))
+}
+
+// EvictType removes the compiled objects of type t from the caches. Note that
+// the compiled objects of other types (e.g. structs with fields of type t)
+// keep using the evicted objects until they are evicted too. If in doubt,
+// use ResetCache.
+func (p *QSUnmarshaler) EvictType(t reflect.Type) {
+	evictCache(p.opts.UnmarshalerFactory, t)
+	evictCache(p.opts.ValuesUnmarshalerFactory, t)
+}
+
 func (p *QSUnmarshaler) RegisterSubFactory(k reflect.Kind, fn UnmarshalerFactoryFunc) error {
-	return p.opts.UnmarshalerFactory.RegisterSubFactory(k, fn)
+	err := p.opts.UnmarshalerFactory.RegisterSubFactory(k, fn)
+	p.purgeValuesCache()
+	return err
 }
 
 func (p *QSUnmarshaler) RegisterCustomType(k reflect.Type, fn PrimitiveUnmarshalerFunc) error {
-	return p.opts.UnmarshalerFactory.RegisterCustomType(k, fn)
+	err := p.opts.UnmarshalerFactory.RegisterCustomType(k, fn)
+	p.purgeValuesCache()
+	return err
+}
+
+// RegisterValuesCustomType registers a ValuesUnmarshalerFactoryFunc for the
+// given type. The ValuesUnmarshaler created by fn takes full control of the
+// keys of the values of the type: a struct field of the type (or of a pointer
+// to it) is unmarshaled like an inline field and receives all the values of
+// the query.
+func (p *QSUnmarshaler) RegisterValuesCustomType(t reflect.Type, fn ValuesUnmarshalerFactoryFunc) error {
+	err := p.opts.ValuesUnmarshalerFactory.RegisterCustomType(t, fn)
+	p.purgeValuesCache()
+	return err
 }
 
 func (p *QSUnmarshaler) RegisterKindOverride(k reflect.Kind, fn PrimitiveUnmarshalerFunc) error {
-	return p.opts.UnmarshalerFactory.RegisterKindOverride(k, fn)
+	err := p.opts.UnmarshalerFactory.RegisterKindOverride(k, fn)
+	p.purgeValuesCache()
+	return err
 }
 
 // Unmarshal unmarshals an object from a query string.
 // See the documentation of the global Unmarshal func.
-func (p *QSUnmarshaler) Unmarshal(into interface{}, queryString string) error {
-	values, err := p.stringToQueryParser(queryString)
+func (p *QSUnmarshaler) Unmarshal(into interface{}, queryString string) (err error) {
+	if p.hook != nil {
+		defer p.hook.report(reflect.TypeOf(into), time.Now(), &err)
+	}
+	values, err := p.parseQuery(queryString)
+	if err != nil {
+		return err
+	}
+	return p.unmarshalInto(into, values)
+}
+
+// parseQuery parses a query string with the parser of the unmarshaler after
+// checking it against the MaxKeys and MaxValueLen limits.
+func (p *QSUnmarshaler) parseQuery(query string) (url.Values, error) {
+	if err := p.opts.checkQueryLimits(query); err != nil {
+		return nil, err
+	}
+	values, err := p.stringToQueryParser(query)
 	if err != nil {
-		return fmt.Errorf("error parsing query string %q :: %v", queryString, err)
+		return nil, fmt.Errorf("error parsing query string %q :: %w", query, err)
 	}
-	return p.UnmarshalValues(into, values)
+	return values, nil
 }
 
 // UnmarshalValues unmarshals an object from a url.Values.
 // See the documentation of the global UnmarshalValues func.
-func (p *QSUnmarshaler) UnmarshalValues(into interface{}, values url.Values) error {
+func (p *QSUnmarshaler) UnmarshalValues(into interface{}, values url.Values) (err error) {
+	if p.hook != nil {
+		defer p.hook.report(reflect.TypeOf(into), time.Now(), &err)
+	}
+	return p.unmarshalInto(into, values)
+}
+
+// UnmarshalURL unmarshals an object from the query string of u.
+func (p *QSUnmarshaler) UnmarshalURL(into interface{}, u *url.URL) error {
+	if u == nil {
+		return errors.New("nil URL")
+	}
+	return p.Unmarshal(into, u.RawQuery)
+}
+
+// unmarshalInto is UnmarshalValues without the hook.
+func (p *QSUnmarshaler) unmarshalInto(into interface{}, values url.Values) error {
+	v, err := targetValue(into)
+	if err != nil {
+		return err
+	}
+
+	vum, err := p.opts.ValuesUnmarshalerFactory.ValuesUnmarshaler(v.Type(), p.opts)
+	if err != nil {
+		return err
+	}
+	return p.unmarshalValues(vum, v, values)
+}
+
+// UnmarshalWith is the same as Unmarshal but the given options are applied on
+// top of the options of the unmarshaler for this call only. The unmarshaler
+// itself isn't modified. Use With to create a derived unmarshaler once if the
+// same options are used by many calls.
+func (p *QSUnmarshaler) UnmarshalWith(into interface{}, queryString string, opts ...func(*QSUnmarshaler)) error {
+	return p.With(opts...).Unmarshal(into, queryString)
+}
+
+// UnmarshalValuesWith is the same as UnmarshalValues but the given options
+// are applied on top of the options of the unmarshaler for this call only.
+func (p *QSUnmarshaler) UnmarshalValuesWith(into interface{}, values url.Values, opts ...func(*QSUnmarshaler)) error {
+	return p.With(opts...).UnmarshalValues(into, values)
+}
+
+// targetValue returns the value pointed to by into.
+func targetValue(into interface{}) (reflect.Value, error) {
 	pv := reflect.ValueOf(into)
 	if !pv.IsValid() {
-		return errors.New("received an empty interface")
+		return pv, errors.New("received an empty interface")
 	}
 	if pv.Kind() != reflect.Ptr {
-		return fmt.Errorf("expected a pointer, got %T", into)
+		return pv, fmt.Errorf("expected a pointer, got %T", into)
 	}
 	if pv.IsNil() {
-		return fmt.Errorf("nil pointer of type %T", into)
+		return pv, fmt.Errorf("nil pointer of type %T", into)
 	}
-	v := pv.Elem()
+	return pv.Elem(), nil
+}
 
-	vum, err := p.opts.ValuesUnmarshalerFactory.ValuesUnmarshaler(v.Type(), p.opts)
-	if err != nil {
+// unmarshalValues unmarshals the values into v with the given
+// ValuesUnmarshaler.
+func (p *QSUnmarshaler) unmarshalValues(vum ValuesUnmarshaler, v reflect.Value, values url.Values) error {
+	if err := p.opts.checkLimits(values); err != nil {
 		return err
 	}
-	return vum.UnmarshalValues(v, values, p.opts)
+	values = p.stripKeys(values)
+	if p.opts.Diagnostics != nil {
+		p.opts.diagnoseUnknownKeys(vum, values)
+	}
+	err := vum.UnmarshalValues(v, values, p.opts)
+	return p.opts.formatError(err)
+}
+
+// stripKeys drops the keys without the key prefix and suffix of the
+// unmarshaler and strips the prefix and suffix from the rest.
+func (p *QSUnmarshaler) stripKeys(values url.Values) url.Values {
+	if p.opts.KeyPrefix == "" && p.opts.KeySuffix == "" {
+		return values
+	}
+	stripped := make(url.Values, len(values))
+	for k, a := range values {
+		if sk, ok := p.stripKey(k); ok {
+			stripped[sk] = a
+		}
+	}
+	return stripped
+}
+
+// stripKey strips the key prefix and suffix of the unmarshaler from k. It
+// reports false if k doesn't have them.
+func (p *QSUnmarshaler) stripKey(k string) (string, bool) {
+	if len(k) < len(p.opts.KeyPrefix)+len(p.opts.KeySuffix) ||
+		!strings.HasPrefix(k, p.opts.KeyPrefix) || !strings.HasSuffix(k, p.opts.KeySuffix) {
+		return "", false
+	}
+	return k[len(p.opts.KeyPrefix) : len(k)-len(p.opts.KeySuffix)], true
 }
 
 // CheckUnmarshal check whether the type of the given object supports
@@ -89,6 +289,6 @@ func (p *QSUnmarshaler) CheckUnmarshalType(t reflect.Type) error {
 	if t.Kind() != reflect.Ptr {
 		return fmt.Errorf("expected a pointer, got %v", t)
 	}
-	_, err := p.opts.ValuesUnmarshalerFactory.ValuesUnmarshaler(t.Elem(), p.opts)
+	_, err := p.CompileType(t.Elem())
 	return err
 }
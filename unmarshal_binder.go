@@ -0,0 +1,321 @@
+package qs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"time"
+)
+
+// defaultBindMaxMemory is the maxMemory parameter of the
+// http.Request.ParseMultipartForm calls of Binder.
+const defaultBindMaxMemory = 32 << 20
+
+// defaultBindMaxFormSize is the size limit of the urlencoded bodies read by
+// Binder. It is the same as the limit of http.Request.ParseForm.
+const defaultBindMaxFormSize = 10 << 20
+
+// Binder unmarshals the parameters of HTTP requests into structs. It extends
+// the unmarshaling of query strings to several parts of the request: path
+// values, query string, form, headers and cookies.
+//
+// The value of a struct field is looked up in the sources of the Binder in
+// the order they have been passed to NewBinder and the first source that
+// contains the key of the field wins. The src tag option restricts the lookup
+// of a field to a single source:
+//
+//	type Params struct {
+//		ID        int    `qs:"id,src=path"`
+//		Page      int    // looked up in the sources of the Binder
+//		RequestID string `qs:"X-Request-Id,src=header"`
+//		Session   string `qs:"session,src=cookie"`
+//	}
+//
+// The fields without a qs tag can use a path, header or cookie tag instead
+// of the src option. The marshaler skips these fields because they aren't
+// query parameters:
+//
+//	type Params struct {
+//		ID      int    `path:"id"`
+//		Query   string `qs:"q"`
+//		TraceID string `header:"X-Trace-Id"`
+//		Session string `cookie:"session"`
+//	}
+//
+// The path values are read with http.Request.PathValue by default. The path
+// parameters of other routers can be passed to BindPath or read by a
+// replaced PathValue func.
+//
+// The urlencoded forms are checked against the MaxKeys and MaxValueLen
+// limits of the unmarshaler before they are parsed. Multipart forms are
+// parsed by http.Request.ParseMultipartForm before the limits are checked so
+// the handlers have to limit their size with http.MaxBytesReader.
+//
+// The key prefix and suffix of the unmarshaler are applied only to the keys
+// of the query string and the form. The path values, headers and cookies are
+// looked up by the names of the fields.
+type Binder struct {
+	// PathValue returns the path value of the request with the given name or
+	// an empty string if there is no such value. By default it is
+	// http.Request.PathValue but it can be replaced to read the path
+	// variables of third party routers.
+	PathValue func(r *http.Request, name string) string
+
+	um      *QSUnmarshaler
+	sources []BindSource
+}
+
+// PathParamFunc returns the value of the path parameter of a request with
+// the given name and reports whether the parameter exists. It adapts the
+// path parameters of routers without depending on them, e.g.:
+//
+//	// chi
+//	func(name string) (string, bool) {
+//		v := chi.URLParam(r, name)
+//		return v, v != ""
+//	}
+//
+//	// gorilla/mux
+//	qs.PathParamsMap(mux.Vars(r))
+//
+//	// httprouter
+//	func(name string) (string, bool) {
+//		v := httprouter.ParamsFromContext(r.Context()).ByName(name)
+//		return v, v != ""
+//	}
+type PathParamFunc func(name string) (string, bool)
+
+// PathParamsMap returns a PathParamFunc that looks up the path parameters in
+// m (e.g. the mux.Vars of gorilla/mux).
+func PathParamsMap(m map[string]string) PathParamFunc {
+	return func(name string) (string, bool) {
+		v, ok := m[name]
+		return v, ok
+	}
+}
+
+// NewBinder returns a Binder that uses um to unmarshal the values looked up
+// in the given sources. If um is nil then DefaultUnmarshaler is used. If no
+// sources are given then path values, query string and form are used in
+// this order.
+func NewBinder(um *QSUnmarshaler, sources ...BindSource) *Binder {
+	if um == nil {
+		um = DefaultUnmarshaler
+	}
+	if len(sources) == 0 {
+		sources = []BindSource{BindSourcePath, BindSourceQuery, BindSourceForm}
+	}
+	return &Binder{
+		PathValue: (*http.Request).PathValue,
+		um:        um,
+		sources:   sources,
+	}
+}
+
+// Bind unmarshals the parameters of r into the object pointed to by into.
+// Struct fields are looked up one by one in the sources, maps (that can't
+// enumerate path values, headers and cookies) are unmarshaled from the merged
+// query string and form values.
+func (b *Binder) Bind(into interface{}, r *http.Request) error {
+	return b.bind(into, r, nil)
+}
+
+// BindPath is the same as Bind but the path values are looked up with
+// params instead of the PathValue func of the Binder.
+func (b *Binder) BindPath(into interface{}, r *http.Request, params PathParamFunc) error {
+	if params == nil {
+		return errors.New("nil PathParamFunc")
+	}
+	return b.bind(into, r, params)
+}
+
+// bind is Bind with an optional PathParamFunc.
+func (b *Binder) bind(into interface{}, r *http.Request, params PathParamFunc) (err error) {
+	if b.um.hook != nil {
+		defer b.um.hook.report(reflect.TypeOf(into), time.Now(), &err)
+	}
+	v, err := targetValue(into)
+	if err != nil {
+		return err
+	}
+
+	vum, err := b.um.CompileType(v.Type())
+	if err != nil {
+		return err
+	}
+
+	query, err := b.um.parseQuery(r.URL.RawQuery)
+	if err != nil {
+		return err
+	}
+	if err := b.um.opts.checkLimits(query); err != nil {
+		return err
+	}
+
+	src := &requestSource{
+		b:          b,
+		r:          r,
+		query:      query,
+		pathParams: params,
+	}
+	err = unmarshalSource(vum, v, src, b.um.opts)
+	if src.formErr != nil {
+		return fmt.Errorf("error parsing form :: %w", src.formErr)
+	}
+	return b.um.opts.formatError(err)
+}
+
+// BindRequest unmarshals the parameters of r into the object pointed to by
+// into with a Binder that uses the unmarshaler and the default sources of
+// NewBinder. The fields with a path, header or cookie tag or src option are
+// looked up in the path values, headers and cookies of r. See Binder.
+func (p *QSUnmarshaler) BindRequest(into interface{}, r *http.Request) error {
+	return NewBinder(p).Bind(into, r)
+}
+
+// requestSource is the valuesSource of Binder.Bind.
+type requestSource struct {
+	b          *Binder
+	r          *http.Request
+	query      url.Values
+	pathParams PathParamFunc
+
+	formParsed bool
+	formValues url.Values
+	formErr    error
+}
+
+func (s *requestSource) fieldValues(tag *ParsedTagInfo) ([]string, bool) {
+	if tag.UnmarshalOpts.Source != BindSourceBSUnspecified {
+		return s.lookup(tag.UnmarshalOpts.Source, tag.Name)
+	}
+	for _, bs := range s.b.sources {
+		if a, ok := s.lookup(bs, tag.Name); ok {
+			return a, ok
+		}
+	}
+	return nil, false
+}
+
+// requestOnly reports whether the values of the source exist only in HTTP
+// requests: the path values, the headers and the cookies. The fields bound to
+// these sources are never unmarshaled from url.Values or query strings.
+func (bs BindSource) requestOnly() bool {
+	return bs == BindSourcePath || bs == BindSourceHeader || bs == BindSourceCookie
+}
+
+// lookup returns the values of the given key in the source. The key prefix
+// and suffix of the unmarshaler apply only to the keys of the query string
+// and the form: the path values, headers and cookies are looked up by their
+// names.
+func (s *requestSource) lookup(bs BindSource, key string) ([]string, bool) {
+	switch bs {
+	case BindSourcePath:
+		if s.pathParams != nil {
+			if v, ok := s.pathParams(key); ok {
+				return []string{v}, true
+			}
+			return nil, false
+		}
+		if v := s.b.PathValue(s.r, key); v != "" {
+			return []string{v}, true
+		}
+	case BindSourceQuery:
+		a, ok := s.query[s.b.um.opts.KeyPrefix+key+s.b.um.opts.KeySuffix]
+		return a, ok
+	case BindSourceForm:
+		a, ok := s.form()[s.b.um.opts.KeyPrefix+key+s.b.um.opts.KeySuffix]
+		return a, ok
+	case BindSourceHeader:
+		if a := s.r.Header.Values(key); len(a) != 0 {
+			return a, true
+		}
+	case BindSourceCookie:
+		var a []string
+		for _, c := range s.r.CookiesNamed(key) {
+			a = append(a, c.Value)
+		}
+		return a, len(a) != 0
+	}
+	return nil, false
+}
+
+// form parses the request body when it is needed for the first time. See
+// parseForm.
+func (s *requestSource) form() url.Values {
+	if !s.formParsed {
+		s.formParsed = true
+		s.formValues, s.formErr = s.parseForm()
+	}
+	return s.formValues
+}
+
+// parseForm parses the form of the request body. The urlencoded bodies are
+// read up to defaultBindMaxFormSize bytes and checked against MaxKeys and
+// MaxValueLen before parsing like query strings. The parsed form is stored
+// in the PostForm of the request.
+//
+// Other bodies (e.g. multipart forms) are parsed by
+// http.Request.ParseMultipartForm and they are checked against the limits
+// of the unmarshaler only after parsing: the limits don't protect the
+// parsing of these bodies so the handlers have to limit their size with
+// http.MaxBytesReader.
+func (s *requestSource) parseForm() (url.Values, error) {
+	r := s.r
+	if r.PostForm == nil && r.Body != nil && hasFormBody(r) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, defaultBindMaxFormSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(body) > defaultBindMaxFormSize {
+			return nil, errors.New("request body too large")
+		}
+		if err := s.b.um.opts.checkQueryLimits(string(body)); err != nil {
+			return nil, err
+		}
+		vs, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, err
+		}
+		r.PostForm = vs
+	} else if err := r.ParseMultipartForm(defaultBindMaxMemory); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return nil, err
+	}
+	if err := s.b.um.opts.checkLimits(r.PostForm); err != nil {
+		return nil, err
+	}
+	return r.PostForm, nil
+}
+
+// hasFormBody reports whether http.Request.ParseForm would parse the body of
+// r as an urlencoded form.
+func hasFormBody(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return false
+	}
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return ct == "application/x-www-form-urlencoded"
+}
+
+func (s *requestSource) values() url.Values {
+	vs := make(url.Values)
+	for i := len(s.b.sources) - 1; i >= 0; i-- {
+		switch s.b.sources[i] {
+		case BindSourceQuery:
+			for k, a := range s.query {
+				vs[k] = a
+			}
+		case BindSourceForm:
+			for k, a := range s.form() {
+				vs[k] = a
+			}
+		}
+	}
+	return s.b.um.stripKeys(vs)
+}
@@ -0,0 +1,292 @@
+package qs
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestBind(t *testing.T) {
+	type params struct {
+		ID        int `qs:"id,src=path"`
+		Page      int
+		Name      string
+		RequestID string `qs:"X-Request-Id,src=header"`
+		Session   string `qs:"session,src=cookie"`
+	}
+
+	body := url.Values{"name": {"form"}, "page": {"7"}}.Encode()
+	r := httptest.NewRequest(http.MethodPost, "/items/42?page=2&id=1", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("X-Request-Id", "abc")
+	r.AddCookie(&http.Cookie{Name: "session", Value: "s1"})
+	r.SetPathValue("id", "42")
+
+	var p params
+	if err := Bind(&p, r); err != nil {
+		t.Fatal(err)
+	}
+	want := params{ID: 42, Page: 2, Name: "form", RequestID: "abc", Session: "s1"}
+	if p != want {
+		t.Errorf("Bind() == %+v, want %+v", p, want)
+	}
+}
+
+func TestBindLimits(t *testing.T) {
+	type params struct {
+		Name string
+	}
+	b := NewBinder(NewUnmarshaler(nil, WithUnmarshalMaxValueLen(3)))
+
+	body := url.Values{"name": {"long"}}.Encode()
+	r := httptest.NewRequest(http.MethodPost, "/?page=2", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var p params
+	var le *LimitExceededError
+	if err := b.Bind(&p, r); !errors.As(err, &le) {
+		t.Errorf("got error %v, want a LimitExceededError", err)
+	}
+	if p.Name != "" {
+		t.Errorf("Name == %q, want empty", p.Name)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/?name=long", nil)
+	if err := b.Bind(&p, r); !errors.As(err, &le) {
+		t.Errorf("got error %v, want a LimitExceededError", err)
+	}
+
+	// The urlencoded forms are checked before they are parsed.
+	b = NewBinder(NewUnmarshaler(nil, WithUnmarshalMaxKeys(1)))
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name=a&x=1"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := b.Bind(&p, r); !errors.As(err, &le) || le.Limit != "MaxKeys" {
+		t.Errorf("got error %v, want a MaxKeys LimitExceededError", err)
+	}
+	if r.PostForm != nil {
+		t.Errorf("PostForm == %v, want nil", r.PostForm)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name="+strings.Repeat("a", defaultBindMaxFormSize)))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := b.Bind(&p, r); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestBindSourceOrder(t *testing.T) {
+	type params struct {
+		Page int
+	}
+
+	body := url.Values{"page": {"7"}}.Encode()
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/?page=2", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return r
+	}
+
+	var p params
+	if err := NewBinder(nil, BindSourceForm, BindSourceQuery).Bind(&p, newRequest()); err != nil {
+		t.Fatal(err)
+	}
+	if p.Page != 7 {
+		t.Errorf("Page == %v, want 7", p.Page)
+	}
+
+	p = params{}
+	if err := NewBinder(nil, BindSourceHeader).Bind(&p, newRequest()); err != nil {
+		t.Fatal(err)
+	}
+	if p.Page != 0 {
+		t.Errorf("Page == %v, want 0", p.Page)
+	}
+}
+
+func TestBindMap(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?a=1&b=2", nil)
+	var m map[string]int
+	if err := Bind(&m, r); err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 2 || m["a"] != 1 || m["b"] != 2 {
+		t.Errorf("Bind() == %v", m)
+	}
+}
+
+func TestBindReqField(t *testing.T) {
+	type params struct {
+		Token string `qs:"token,req,src=header"`
+	}
+	r := httptest.NewRequest(http.MethodGet, "/?token=1", nil)
+	var p params
+	err := Bind(&p, r)
+	if _, ok := IsRequiredFieldError(err); !ok {
+		t.Errorf("expected a required field error, got %v", err)
+	}
+}
+
+func TestBindInvalidSource(t *testing.T) {
+	type params struct {
+		Page int `qs:"page,src=body"`
+	}
+	if err := CheckUnmarshal(&params{}); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestBindRequestTags(t *testing.T) {
+	type params struct {
+		Query   string   `qs:"q"`
+		Tags    []string `qs:"tags"`
+		TraceID string   `header:"X-Trace-Id"`
+		Session string   `cookie:"session,httponly"`
+		Ignored string   `header:"-"`
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/search?q=shoes&tags=a&tags=b&session=q", nil)
+	r.Header.Set("X-Trace-Id", "t1")
+	r.AddCookie(&http.Cookie{Name: "session", Value: "s1"})
+
+	var p params
+	if err := NewUnmarshaler(nil).BindRequest(&p, r); err != nil {
+		t.Fatal(err)
+	}
+	if p.Query != "shoes" || p.TraceID != "t1" || p.Session != "s1" || len(p.Tags) != 2 || p.Ignored != "" {
+		t.Errorf("got %+v", p)
+	}
+
+	// The marshaler skips the header and cookie fields.
+	s, err := Marshal(&p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "q=shoes&tags=a&tags=b"; s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+}
+
+func TestUnmarshalIgnoresRequestTags(t *testing.T) {
+	type params struct {
+		Query   int    `qs:"q"`
+		Session string `cookie:"session"`
+		Admin   bool   `header:"X-Admin"`
+		Role    string `qs:"role,src=header"`
+	}
+
+	// The header and cookie fields can't be faked with query parameters.
+	var p params
+	if err := Unmarshal(&p, "q=1&session=stolen&X-Admin=true&role=admin"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (params{Query: 1}); p != want {
+		t.Errorf("got %+v, want %+v", p, want)
+	}
+	p = params{}
+	if err := UnmarshalValues(&p, url.Values{"q": {"1"}, "session": {"stolen"}, "X-Admin": {"true"}}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (params{Query: 1}); p != want {
+		t.Errorf("got %+v, want %+v", p, want)
+	}
+}
+
+func TestBindPath(t *testing.T) {
+	type params struct {
+		ID    int    `path:"id"`
+		Slug  string `path:"slug"`
+		Query string `qs:"q"`
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/items/42?q=x&id=1", nil)
+	b := NewBinder(nil)
+
+	var p params
+	if err := b.BindPath(&p, r, PathParamsMap(map[string]string{"id": "42", "slug": ""})); err != nil {
+		t.Fatal(err)
+	}
+	if want := (params{ID: 42, Query: "x"}); p != want {
+		t.Errorf("got %+v, want %+v", p, want)
+	}
+
+	// The path tag reads http.Request.PathValue by default.
+	r.SetPathValue("id", "7")
+	p = params{}
+	if err := b.Bind(&p, r); err != nil {
+		t.Fatal(err)
+	}
+	if want := (params{ID: 7, Query: "x"}); p != want {
+		t.Errorf("got %+v, want %+v", p, want)
+	}
+
+	if err := b.BindPath(&p, r, nil); err == nil {
+		t.Error("unexpected success")
+	}
+
+	if s, err := Marshal(&p); err != nil || s != "q=x" {
+		t.Errorf("got %q, %v", s, err)
+	}
+}
+
+func TestUnmarshalIgnoresPathTags(t *testing.T) {
+	type params struct {
+		ID    int    `path:"id"`
+		Owner string `qs:"owner,src=path"`
+		Query string `qs:"q"`
+	}
+
+	// The path parameters are bound only from the path values of requests.
+	var p params
+	if err := Unmarshal(&p, "id=5&owner=bob&q=x"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (params{Query: "x"}); p != want {
+		t.Errorf("got %+v, want %+v", p, want)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/items?id=5&owner=bob&q=x", nil)
+	p = params{}
+	if err := NewBinder(nil).BindPath(&p, r, PathParamsMap(map[string]string{"id": "42"})); err != nil {
+		t.Fatal(err)
+	}
+	if want := (params{ID: 42, Query: "x"}); p != want {
+		t.Errorf("got %+v, want %+v", p, want)
+	}
+}
+
+func TestBindKeyPrefix(t *testing.T) {
+	type params struct {
+		ID      int    `path:"id"`
+		TraceID string `header:"X-Trace"`
+		Session string `cookie:"session"`
+		Query   string `qs:"q"`
+		Page    int    `qs:"page,src=form"`
+	}
+
+	um := NewUnmarshaler(nil, WithUnmarshalKeyPrefix("f."))
+	r := httptest.NewRequest(http.MethodPost, "/items?f.q=x&q=y", strings.NewReader("f.page=2&page=3"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("X-Trace", "t1")
+	r.AddCookie(&http.Cookie{Name: "session", Value: "s1"})
+
+	// The prefix applies only to the query string and the form.
+	var names []string
+	pathParam := func(name string) (string, bool) {
+		names = append(names, name)
+		return "42", name == "id"
+	}
+	var p params
+	if err := NewBinder(um).BindPath(&p, r, pathParam); err != nil {
+		t.Fatal(err)
+	}
+	if want := (params{ID: 42, TraceID: "t1", Session: "s1", Query: "x", Page: 2}); p != want {
+		t.Errorf("got %+v, want %+v", p, want)
+	}
+	for _, name := range names {
+		if strings.HasPrefix(name, "f.") {
+			t.Errorf("path parameter %q looked up with the key prefix", name)
+		}
+	}
+}
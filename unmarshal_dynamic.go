@@ -0,0 +1,106 @@
+package qs
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
+)
+
+// dynamicMapUnmarshaler unmarshals url.Values into maps with string keys and
+// interface{} values for code that doesn't know the schema of the query
+// (e.g. API gateways). A key with one value becomes a string and a key with
+// more values (or with an empty index like "tags[]") becomes a []string. If
+// nesting is enabled then the nested keys (e.g. "filter.status" or
+// "filter[status]") become nested map[string]interface{} values. The indices
+// of slices are map keys too: "items[0][id]=1" becomes
+// {"items": {"0": {"id": "1"}}}.
+type dynamicMapUnmarshaler struct {
+	Type reflect.Type
+}
+
+// isDynamicMapType reports whether t is a map that is unmarshaled by
+// dynamicMapUnmarshaler.
+func isDynamicMapType(t reflect.Type) bool {
+	et := t.Elem()
+	return t.Key() == stringType && et.Kind() == reflect.Interface && et.NumMethod() == 0
+}
+
+func (p *dynamicMapUnmarshaler) UnmarshalValues(v reflect.Value, vs url.Values, opts *UnmarshalerDefaultOptions) error {
+	t := v.Type()
+	if t != p.Type {
+		return &WrongTypeError{Actual: t, Expected: p.Type}
+	}
+
+	if err := opts.checkRequiredMapKeys(vs); err != nil {
+		return err
+	}
+
+	if v.IsNil() || (len(vs) > 0 && opts.TagOptionsDefaults.MapValues == UnmarshalMapValuesOverrideOld) {
+		v.Set(reflect.MakeMap(t))
+	}
+
+	// The keys are sorted to report the same conflicts in every call.
+	keys := make([]string, 0, len(vs))
+	for k := range vs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	root := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		segments, ok := opts.Nesting.splitKey(k)
+		if !ok {
+			segments = []string{k}
+		}
+		if err := setDynamicValue(root, segments, vs[k]); err != nil {
+			return fmt.Errorf("error unmarshaling key %q :: %w", k, err)
+		}
+	}
+
+	for k, item := range root {
+		v.SetMapIndex(reflect.ValueOf(k), reflect.ValueOf(item))
+	}
+	return nil
+}
+
+// setDynamicValue stores the values of the key with the given segments into
+// the tree of nested maps.
+func setDynamicValue(m map[string]interface{}, segments []string, a []string) error {
+	list := false
+	if n := len(segments); n > 1 && segments[n-1] == "" {
+		segments = segments[:n-1]
+		list = true
+	}
+
+	for _, segment := range segments[:len(segments)-1] {
+		switch next := m[segment].(type) {
+		case nil:
+			nested := make(map[string]interface{})
+			m[segment] = nested
+			m = nested
+		case map[string]interface{}:
+			m = next
+		default:
+			return fmt.Errorf("%q has both a value and nested keys", segment)
+		}
+	}
+
+	last := segments[len(segments)-1]
+	switch prev := m[last].(type) {
+	case nil:
+		if !list && len(a) == 1 {
+			m[last] = a[0]
+		} else {
+			m[last] = slices.Clone(a)
+		}
+	case string:
+		m[last] = append([]string{prev}, a...)
+	case []string:
+		m[last] = append(prev, a...)
+	default:
+		return fmt.Errorf("%q has both a value and nested keys", last)
+	}
+	return nil
+}
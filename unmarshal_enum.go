@@ -1,6 +1,6 @@
 package qs
 
-//go:generate go run github.com/dmji/go-stringer@latest -type=UnmarshalPresence,UnmarshalSliceValues,UnmarshalSliceUnexpectedValue --trimprefix=@me -output unmarshal_enum_string.go -nametransform=lower -fromstringgenfn
+//go:generate go run github.com/dmji/go-stringer@latest -type=UnmarshalPresence,UnmarshalSliceValues,UnmarshalMapValues,UnmarshalSliceUnexpectedValue,UnmarshalBoolParsing,UnmarshalWhitespace,UnmarshalEmptyPointer,DuplicateKeyPolicy,BindSource,UnmarshalErrorKind --trimprefix=@me -output unmarshal_enum_string.go -nametransform=lower -fromstringgenfn
 
 // UnmarshalPresence is an enum that controls the unmarshaling of fields.
 // This option is used by the unmarshaler only if the given field isn't present
@@ -41,6 +41,28 @@ const (
 	UnmarshalSliceValuesOverrideOld
 )
 
+// UnmarshalMapValues is an enum that controls whether unmarshaling into a
+// non-nil map merges the unmarshaled entries into the existing map or
+// replaces it. It uses the keepold and overrideold tag options of
+// UnmarshalSliceValues so a single option works for both slice and map
+// fields.
+type UnmarshalMapValues int8
+
+const (
+	// UnmarshalMapValuesMVUnspecified is the zero value of
+	// UnmarshalMapValues. It results in using the default UnmarshalMapValues
+	// which is KeepOld.
+	UnmarshalMapValuesMVUnspecified UnmarshalMapValues = iota
+
+	// UnmarshalMapValuesKeepOld merges the unmarshaled entries into the
+	// existing map: the entries missing from the query are kept.
+	UnmarshalMapValuesKeepOld
+
+	// UnmarshalMapValuesOverrideOld replaces the existing map with a new one
+	// if the query has entries for it.
+	UnmarshalMapValuesOverrideOld
+)
+
 type UnmarshalSliceUnexpectedValue int8
 
 const (
@@ -48,3 +70,147 @@ const (
 	UnmarshalSliceUnexpectedValueBreakWithError
 	UnmarshalSliceUnexpectedValueSkip
 )
+
+// UnmarshalBoolParsing is an enum that controls the unmarshaling of bool
+// values.
+type UnmarshalBoolParsing int8
+
+const (
+	// UnmarshalBoolParsingUPUnspecified is the zero value of
+	// UnmarshalBoolParsing. It results in using the default
+	// UnmarshalBoolParsing which is StrictBool.
+	UnmarshalBoolParsingUPUnspecified UnmarshalBoolParsing = iota
+
+	// UnmarshalBoolParsingStrictBool accepts the values accepted by
+	// strconv.ParseBool.
+	UnmarshalBoolParsingStrictBool
+
+	// UnmarshalBoolParsingLenientBool accepts true/false, t/f, 1/0, yes/no,
+	// y/n and on/off in any letter case. An empty value is true so a flag
+	// like "?debug" can be passed without a value.
+	UnmarshalBoolParsingLenientBool
+)
+
+// UnmarshalWhitespace is an enum that controls the whitespace around the
+// values. It can be set per field with the trim and notrim tag options.
+type UnmarshalWhitespace int8
+
+const (
+	// UnmarshalWhitespaceUWUnspecified is the zero value of
+	// UnmarshalWhitespace. It results in using the default
+	// UnmarshalWhitespace which is NoTrim.
+	UnmarshalWhitespaceUWUnspecified UnmarshalWhitespace = iota
+
+	// UnmarshalWhitespaceNoTrim passes the values to the unmarshalers as
+	// they are.
+	UnmarshalWhitespaceNoTrim
+
+	// UnmarshalWhitespaceTrim removes the leading and trailing whitespace of
+	// the values before parsing them, e.g. "count=5%20" becomes 5 instead of
+	// failing.
+	UnmarshalWhitespaceTrim
+)
+
+// UnmarshalEmptyPointer is an enum that controls the unmarshaling of a key
+// that is present with an empty value (e.g. "name=") into a pointer field.
+// It can be set per field with the emptyptr=<policy> tag option (e.g.
+// `qs:"name,emptyptr=nil"`) which makes it possible to tell apart the three
+// states of optional API parameters.
+type UnmarshalEmptyPointer int8
+
+const (
+	// UnmarshalEmptyPointerEPUnspecified is the zero value of
+	// UnmarshalEmptyPointer. It results in using the default
+	// UnmarshalEmptyPointer which is Value.
+	UnmarshalEmptyPointerEPUnspecified UnmarshalEmptyPointer = iota
+
+	// UnmarshalEmptyPointerValue unmarshals the empty value into the value
+	// pointed to by the field: a *string field points to an empty string
+	// and a *int field fails like an int field.
+	UnmarshalEmptyPointerValue
+
+	// UnmarshalEmptyPointerNil sets the field to nil.
+	UnmarshalEmptyPointerNil
+
+	// UnmarshalEmptyPointerError fails the unmarshaling.
+	UnmarshalEmptyPointerError
+)
+
+// DuplicateKeyPolicy is an enum that controls the unmarshaling of a key that
+// appears more than once in the query (e.g. "count=5&count=6") into a field
+// that holds a single value. It can be set per field with the
+// dupkeys=<policy> tag option, e.g.: `qs:"count,dupkeys=last"`.
+type DuplicateKeyPolicy int8
+
+const (
+	// DuplicateKeyPolicyDKUnspecified is the zero value of
+	// DuplicateKeyPolicy. It results in using the default DuplicateKeyPolicy
+	// which is Error.
+	DuplicateKeyPolicyDKUnspecified DuplicateKeyPolicy = iota
+
+	// DuplicateKeyPolicyError passes all values to the SliceToString func
+	// of the unmarshaler which fails by default.
+	DuplicateKeyPolicyError
+
+	// DuplicateKeyPolicyFirst uses the first value (first wins).
+	DuplicateKeyPolicyFirst
+
+	// DuplicateKeyPolicyLast uses the last value (last wins).
+	DuplicateKeyPolicyLast
+
+	// DuplicateKeyPolicyJoin joins the values with the slice separator of the
+	// field (e.g. `qs:"names,dupkeys=join,sep=|"`) or with a comma if the
+	// field doesn't have one.
+	DuplicateKeyPolicyJoin
+)
+
+// BindSource is an enum that selects the part of an HTTP request a Binder
+// reads the value of a struct field from. It can be set per field in the tag
+// with the src option, e.g.: `qs:"X-Request-Id,src=header"`.
+type BindSource int8
+
+const (
+	// BindSourceBSUnspecified is the zero value of BindSource. Fields without
+	// an explicit source are looked up in the sources of the Binder in order.
+	BindSourceBSUnspecified BindSource = iota
+
+	// BindSourcePath reads the path values of the request (see
+	// http.Request.PathValue).
+	BindSourcePath
+
+	// BindSourceQuery reads the query string of the request URL.
+	BindSourceQuery
+
+	// BindSourceForm reads the url encoded or multipart form in the request
+	// body.
+	BindSourceForm
+
+	// BindSourceHeader reads the request headers.
+	BindSourceHeader
+
+	// BindSourceCookie reads the request cookies.
+	BindSourceCookie
+)
+
+// UnmarshalErrorKind is an enum that tells the kind of failure of a field in
+// UnmarshalErrorInfo. Its String method returns short names ("required",
+// "invalid", "limit") that can be used as error codes of APIs.
+type UnmarshalErrorKind int8
+
+const (
+	// UnmarshalErrorKindUEUnspecified is the zero value of
+	// UnmarshalErrorKind. It isn't passed to error formatters.
+	UnmarshalErrorKindUEUnspecified UnmarshalErrorKind = iota
+
+	// UnmarshalErrorKindRequired is the failure of a required field (req)
+	// that is missing from the input.
+	UnmarshalErrorKindRequired
+
+	// UnmarshalErrorKindInvalid is the failure of a value that can't be
+	// unmarshaled into the field (e.g. "count=x" into an int field).
+	UnmarshalErrorKindInvalid
+
+	// UnmarshalErrorKindLimit is the failure of a value that exceeds a limit
+	// of the unmarshaler (see LimitExceededError).
+	UnmarshalErrorKindLimit
+)
@@ -1,4 +1,4 @@
-// Code generated by "go-stringer -type=UnmarshalPresence,UnmarshalSliceValues,UnmarshalSliceUnexpectedValue --trimprefix=@me -output unmarshal_enum_string.go -nametransform=lower -fromstringgenfn"; DO NOT EDIT.
+// Code generated by "go-stringer -type=UnmarshalPresence,UnmarshalSliceValues,UnmarshalMapValues,UnmarshalSliceUnexpectedValue,UnmarshalBoolParsing,UnmarshalWhitespace,UnmarshalEmptyPointer,DuplicateKeyPolicy,BindSource,UnmarshalErrorKind --trimprefix=@me -output unmarshal_enum_string.go -nametransform=lower -fromstringgenfn"; DO NOT EDIT.
 
 package qs
 
@@ -62,6 +62,33 @@ func UnmarshalSliceValuesFromString(s string) (UnmarshalSliceValues, error) {
 	}
 	return UnmarshalSliceValues(0), errors.New("cannot deternime UnmarshalSliceValues from string")
 }
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[UnmarshalMapValuesMVUnspecified-0]
+	_ = x[UnmarshalMapValuesKeepOld-1]
+	_ = x[UnmarshalMapValuesOverrideOld-2]
+}
+
+const _UnmarshalMapValues_name = "mvunspecifiedkeepoldoverrideold"
+
+var _UnmarshalMapValues_index = [...]uint8{0, 13, 20, 31}
+
+func (i UnmarshalMapValues) String() string {
+	if i < 0 || i >= UnmarshalMapValues(len(_UnmarshalMapValues_index)-1) {
+		return "UnmarshalMapValues(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _UnmarshalMapValues_name[_UnmarshalMapValues_index[i]:_UnmarshalMapValues_index[i+1]]
+}
+func UnmarshalMapValuesFromString(s string) (UnmarshalMapValues, error) {
+	for i := 0; i < 3; i++ {
+		if e := UnmarshalMapValues(i + 0); s == e.String() {
+			return e, nil
+		}
+	}
+	return UnmarshalMapValues(0), errors.New("cannot deternime UnmarshalMapValues from string")
+}
 func _() {
 	// An "invalid array index" compiler error signifies that the constant values have changed.
 	// Re-run the stringer command to generate them again.
@@ -89,3 +116,172 @@ func UnmarshalSliceUnexpectedValueFromString(s string) (UnmarshalSliceUnexpected
 	}
 	return UnmarshalSliceUnexpectedValue(0), errors.New("cannot deternime UnmarshalSliceUnexpectedValue from string")
 }
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[UnmarshalBoolParsingUPUnspecified-0]
+	_ = x[UnmarshalBoolParsingStrictBool-1]
+	_ = x[UnmarshalBoolParsingLenientBool-2]
+}
+
+const _UnmarshalBoolParsing_name = "upunspecifiedstrictboollenientbool"
+
+var _UnmarshalBoolParsing_index = [...]uint8{0, 13, 23, 34}
+
+func (i UnmarshalBoolParsing) String() string {
+	if i < 0 || i >= UnmarshalBoolParsing(len(_UnmarshalBoolParsing_index)-1) {
+		return "UnmarshalBoolParsing(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _UnmarshalBoolParsing_name[_UnmarshalBoolParsing_index[i]:_UnmarshalBoolParsing_index[i+1]]
+}
+func UnmarshalBoolParsingFromString(s string) (UnmarshalBoolParsing, error) {
+	for i := 0; i < 3; i++ {
+		if e := UnmarshalBoolParsing(i + 0); s == e.String() {
+			return e, nil
+		}
+	}
+	return UnmarshalBoolParsing(0), errors.New("cannot deternime UnmarshalBoolParsing from string")
+}
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[UnmarshalWhitespaceUWUnspecified-0]
+	_ = x[UnmarshalWhitespaceNoTrim-1]
+	_ = x[UnmarshalWhitespaceTrim-2]
+}
+
+const _UnmarshalWhitespace_name = "uwunspecifiednotrimtrim"
+
+var _UnmarshalWhitespace_index = [...]uint8{0, 13, 19, 23}
+
+func (i UnmarshalWhitespace) String() string {
+	if i < 0 || i >= UnmarshalWhitespace(len(_UnmarshalWhitespace_index)-1) {
+		return "UnmarshalWhitespace(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _UnmarshalWhitespace_name[_UnmarshalWhitespace_index[i]:_UnmarshalWhitespace_index[i+1]]
+}
+func UnmarshalWhitespaceFromString(s string) (UnmarshalWhitespace, error) {
+	for i := 0; i < 3; i++ {
+		if e := UnmarshalWhitespace(i + 0); s == e.String() {
+			return e, nil
+		}
+	}
+	return UnmarshalWhitespace(0), errors.New("cannot deternime UnmarshalWhitespace from string")
+}
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[UnmarshalEmptyPointerEPUnspecified-0]
+	_ = x[UnmarshalEmptyPointerValue-1]
+	_ = x[UnmarshalEmptyPointerNil-2]
+	_ = x[UnmarshalEmptyPointerError-3]
+}
+
+const _UnmarshalEmptyPointer_name = "epunspecifiedvaluenilerror"
+
+var _UnmarshalEmptyPointer_index = [...]uint8{0, 13, 18, 21, 26}
+
+func (i UnmarshalEmptyPointer) String() string {
+	if i < 0 || i >= UnmarshalEmptyPointer(len(_UnmarshalEmptyPointer_index)-1) {
+		return "UnmarshalEmptyPointer(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _UnmarshalEmptyPointer_name[_UnmarshalEmptyPointer_index[i]:_UnmarshalEmptyPointer_index[i+1]]
+}
+func UnmarshalEmptyPointerFromString(s string) (UnmarshalEmptyPointer, error) {
+	for i := 0; i < 4; i++ {
+		if e := UnmarshalEmptyPointer(i + 0); s == e.String() {
+			return e, nil
+		}
+	}
+	return UnmarshalEmptyPointer(0), errors.New("cannot deternime UnmarshalEmptyPointer from string")
+}
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[DuplicateKeyPolicyDKUnspecified-0]
+	_ = x[DuplicateKeyPolicyError-1]
+	_ = x[DuplicateKeyPolicyFirst-2]
+	_ = x[DuplicateKeyPolicyLast-3]
+	_ = x[DuplicateKeyPolicyJoin-4]
+}
+
+const _DuplicateKeyPolicy_name = "dkunspecifiederrorfirstlastjoin"
+
+var _DuplicateKeyPolicy_index = [...]uint8{0, 13, 18, 23, 27, 31}
+
+func (i DuplicateKeyPolicy) String() string {
+	if i < 0 || i >= DuplicateKeyPolicy(len(_DuplicateKeyPolicy_index)-1) {
+		return "DuplicateKeyPolicy(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _DuplicateKeyPolicy_name[_DuplicateKeyPolicy_index[i]:_DuplicateKeyPolicy_index[i+1]]
+}
+func DuplicateKeyPolicyFromString(s string) (DuplicateKeyPolicy, error) {
+	for i := 0; i < 5; i++ {
+		if e := DuplicateKeyPolicy(i + 0); s == e.String() {
+			return e, nil
+		}
+	}
+	return DuplicateKeyPolicy(0), errors.New("cannot deternime DuplicateKeyPolicy from string")
+}
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[BindSourceBSUnspecified-0]
+	_ = x[BindSourcePath-1]
+	_ = x[BindSourceQuery-2]
+	_ = x[BindSourceForm-3]
+	_ = x[BindSourceHeader-4]
+	_ = x[BindSourceCookie-5]
+}
+
+const _BindSource_name = "bsunspecifiedpathqueryformheadercookie"
+
+var _BindSource_index = [...]uint8{0, 13, 17, 22, 26, 32, 38}
+
+func (i BindSource) String() string {
+	if i < 0 || i >= BindSource(len(_BindSource_index)-1) {
+		return "BindSource(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _BindSource_name[_BindSource_index[i]:_BindSource_index[i+1]]
+}
+func BindSourceFromString(s string) (BindSource, error) {
+	for i := 0; i < 6; i++ {
+		if e := BindSource(i + 0); s == e.String() {
+			return e, nil
+		}
+	}
+	return BindSource(0), errors.New("cannot deternime BindSource from string")
+}
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[UnmarshalErrorKindUEUnspecified-0]
+	_ = x[UnmarshalErrorKindRequired-1]
+	_ = x[UnmarshalErrorKindInvalid-2]
+	_ = x[UnmarshalErrorKindLimit-3]
+}
+
+const _UnmarshalErrorKind_name = "ueunspecifiedrequiredinvalidlimit"
+
+var _UnmarshalErrorKind_index = [...]uint8{0, 13, 21, 28, 33}
+
+func (i UnmarshalErrorKind) String() string {
+	if i < 0 || i >= UnmarshalErrorKind(len(_UnmarshalErrorKind_index)-1) {
+		return "UnmarshalErrorKind(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _UnmarshalErrorKind_name[_UnmarshalErrorKind_index[i]:_UnmarshalErrorKind_index[i+1]]
+}
+func UnmarshalErrorKindFromString(s string) (UnmarshalErrorKind, error) {
+	for i := 0; i < 4; i++ {
+		if e := UnmarshalErrorKind(i + 0); s == e.String() {
+			return e, nil
+		}
+	}
+	return UnmarshalErrorKind(0), errors.New("cannot deternime UnmarshalErrorKind from string")
+}
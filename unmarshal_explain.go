@@ -0,0 +1,317 @@
+package qs
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ExplainReport describes how a url.Values would be unmarshaled into a struct
+// type. It is returned by QSUnmarshaler.Explain.
+type ExplainReport struct {
+	// Type is the struct type.
+	Type reflect.Type
+
+	// Keys explains the keys of the url.Values in sorted order.
+	Keys []ExplainedKey
+
+	// Fields explains the fields of the struct in the order they are
+	// unmarshaled. The fields of nested structs, the items of slices of
+	// structs and the entries of map fields are listed one by one.
+	Fields []ExplainedField
+}
+
+// ExplainedKey tells which field a query string key binds to.
+type ExplainedKey struct {
+	// Key is the query string key.
+	Key string
+
+	// Field is the path of the field that the key binds to, e.g. "Page",
+	// "Paging.Page", "Filter.Status" or "Items[0].Price". It is empty if
+	// the key is ignored.
+	Field string
+
+	// Reason tells why the key is ignored. It is empty if Field isn't.
+	Reason string
+}
+
+// ExplainedField tells which values a struct field would receive.
+type ExplainedField struct {
+	// Field is the path of the field. See ExplainedKey.Field.
+	Field string
+
+	// Key is the query string key of the field.
+	Key string
+
+	// Type is the type of the field.
+	Type reflect.Type
+
+	// Values are the raw values that the field would receive (before
+	// trimming, splitting and parsing).
+	Values []string
+
+	// Present reports whether the url.Values has a value for the field.
+	Present bool
+
+	// Note tells what happens to a field that isn't present, e.g. that it
+	// is a missing required field.
+	Note string
+}
+
+// Explain reports how the given values would be unmarshaled into the struct
+// type t without unmarshaling them: which field each key binds to (or why it
+// is ignored) and which values each field would receive. It is a debugging
+// aid for complex tag setups. The values aren't parsed so Explain doesn't
+// report invalid values. The type can be a pointer to a struct type too.
+func (p *QSUnmarshaler) Explain(t reflect.Type, values url.Values) (ExplainReport, error) {
+	if t == nil {
+		return ExplainReport{}, errors.New("nil type")
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	vum, err := p.CompileType(t)
+	if err != nil {
+		return ExplainReport{}, err
+	}
+	su, ok := explainedStruct(vum)
+	if !ok {
+		return ExplainReport{}, fmt.Errorf("can't explain the unmarshaling of %v: only struct types are supported", t)
+	}
+
+	report := ExplainReport{Type: t}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		ek := ExplainedKey{Key: k}
+		if sk, ok := p.stripKey(k); !ok {
+			ek.Reason = fmt.Sprintf("the key doesn't have the key prefix %q and suffix %q of the unmarshaler", p.opts.KeyPrefix, p.opts.KeySuffix)
+		} else if ek.Field, err = bindExplainedKey(su, url.Values{sk: values[k]}, "", p.opts); err != nil {
+			return ExplainReport{}, err
+		} else if ek.Field == "" {
+			ek.Reason = "no field has the key"
+		}
+		report.Keys = append(report.Keys, ek)
+	}
+
+	report.Fields, err = explainFields(su, p.stripKeys(values), "", p.opts)
+	if err != nil {
+		return ExplainReport{}, err
+	}
+	for i := range report.Fields {
+		report.Fields[i].Key = p.opts.KeyPrefix + report.Fields[i].Key + p.opts.KeySuffix
+	}
+	return report, nil
+}
+
+// explainedStruct returns the struct unmarshaler of vum.
+func explainedStruct(vum ValuesUnmarshaler) (*structUnmarshaler, bool) {
+	if pum, ok := vum.(*ptrValuesUnmarshaler); ok {
+		vum = pum.ElemUnmarshaler
+	}
+	su, ok := vum.(*structUnmarshaler)
+	return su, ok
+}
+
+// explainedFieldPath returns the path of the field of struct type t with the
+// given index path, e.g. "Paging.Page" for a field of an embedded struct.
+func explainedFieldPath(t reflect.Type, index []int) string {
+	names := make([]string, len(index))
+	for i := range index {
+		names[i] = t.FieldByIndex(index[:i+1]).Name
+	}
+	return strings.Join(names, ".")
+}
+
+// bindExplainedKey returns the path of the field of su that binds the only
+// key of vs or an empty string if no field binds it. The path is prefixed
+// with the given one.
+func bindExplainedKey(su *structUnmarshaler, vs url.Values, prefix string, opts *UnmarshalerDefaultOptions) (string, error) {
+	for _, fum := range su.Fields {
+		if ok, err := buildField(fum); err != nil {
+			return "", err
+		} else if !ok {
+			continue
+		}
+		path := prefix + explainedFieldPath(su.Type, fum.Index)
+
+		if fum.Nested == nil {
+			if _, ok := fieldSourceValues(urlValuesSource(vs), fum, opts); ok {
+				return path, nil
+			}
+			continue
+		}
+
+		// The loops return the first item because vs has a single key.
+		if fum.Indexed {
+			for i, ivs := range opts.Nesting.indexedValues(vs, fum.Tag.Name) {
+				return bindNestedExplainedKey(fum.Nested, ivs, fmt.Sprintf("%s[%d]", path, i), opts)
+			}
+			continue
+		}
+		nvs := opts.Nesting.nestedValues(vs, fum.Tag.Name)
+		if nvs == nil {
+			continue
+		}
+		if su.Type.FieldByIndex(fum.Index).Type.Kind() == reflect.Map {
+			for k := range nvs {
+				return fmt.Sprintf("%s[%s]", path, k), nil
+			}
+		}
+		return bindNestedExplainedKey(fum.Nested, nvs, path, opts)
+	}
+
+	for _, ef := range su.EmbeddedFields {
+		path, err := bindNestedExplainedKey(ef.ValuesUnmarshaler, vs, prefix+explainedFieldPath(su.Type, ef.Index), opts)
+		if path != "" || err != nil {
+			return path, err
+		}
+	}
+	return "", nil
+}
+
+// bindNestedExplainedKey is bindExplainedKey for the nested struct of the
+// field with the given path.
+func bindNestedExplainedKey(vum ValuesUnmarshaler, vs url.Values, path string, opts *UnmarshalerDefaultOptions) (string, error) {
+	su, ok := explainedStruct(vum)
+	if !ok {
+		return path, nil
+	}
+	return bindExplainedKey(su, vs, path+".", opts)
+}
+
+// explainFields explains the fields of su. The paths of the fields are
+// prefixed with the given one and their keys are relative to su.
+func explainFields(su *structUnmarshaler, vs url.Values, prefix string, opts *UnmarshalerDefaultOptions) ([]ExplainedField, error) {
+	var fields []ExplainedField
+	for _, fum := range su.Fields {
+		if ok, err := buildField(fum); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		path := prefix + explainedFieldPath(su.Type, fum.Index)
+		ft := su.Type.FieldByIndex(fum.Index).Type
+
+		missing := ExplainedField{
+			Field: path,
+			Key:   fum.Tag.Name,
+			Type:  ft,
+			Note:  explainMissingField(fum.Tag),
+		}
+
+		if fum.Nested == nil {
+			a, ok := fieldSourceValues(urlValuesSource(vs), fum, opts)
+			if !ok {
+				fields = append(fields, missing)
+				continue
+			}
+			fields = append(fields, ExplainedField{
+				Field:   path,
+				Key:     fum.Tag.Name,
+				Type:    ft,
+				Values:  a,
+				Present: true,
+			})
+			continue
+		}
+
+		if fum.Indexed {
+			items := opts.Nesting.indexedValues(vs, fum.Tag.Name)
+			if items == nil {
+				fields = append(fields, missing)
+				continue
+			}
+			indices := make([]int, 0, len(items))
+			for i := range items {
+				indices = append(indices, i)
+			}
+			slices.Sort(indices)
+			for _, i := range indices {
+				nested, err := explainNestedFields(fum.Nested, items[i], fmt.Sprintf("%s[%d]", path, i), opts.Nesting.indexKey(fum.Tag.Name, i), opts)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, nested...)
+			}
+			continue
+		}
+
+		nvs := opts.Nesting.nestedValues(vs, fum.Tag.Name)
+		if nvs == nil {
+			fields = append(fields, missing)
+			continue
+		}
+		if ft.Kind() == reflect.Map {
+			keys := make([]string, 0, len(nvs))
+			for k := range nvs {
+				keys = append(keys, k)
+			}
+			slices.Sort(keys)
+			for _, k := range keys {
+				fields = append(fields, ExplainedField{
+					Field:   fmt.Sprintf("%s[%s]", path, k),
+					Key:     opts.Nesting.fieldKey(fum.Tag.Name, k),
+					Type:    ft.Elem(),
+					Values:  nvs[k],
+					Present: true,
+				})
+			}
+			continue
+		}
+		nested, err := explainNestedFields(fum.Nested, nvs, path, fum.Tag.Name, opts)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, nested...)
+	}
+
+	for _, ef := range su.EmbeddedFields {
+		esu, ok := explainedStruct(ef.ValuesUnmarshaler)
+		if !ok {
+			continue
+		}
+		embedded, err := explainFields(esu, vs, prefix+explainedFieldPath(su.Type, ef.Index)+".", opts)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, embedded...)
+	}
+	return fields, nil
+}
+
+// explainNestedFields is explainFields for the nested struct of the field
+// with the given path and key.
+func explainNestedFields(vum ValuesUnmarshaler, vs url.Values, path, key string, opts *UnmarshalerDefaultOptions) ([]ExplainedField, error) {
+	su, ok := explainedStruct(vum)
+	if !ok {
+		return nil, nil
+	}
+	fields, err := explainFields(su, vs, path+".", opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range fields {
+		fields[i].Key = opts.Nesting.fieldKey(key, fields[i].Key)
+	}
+	return fields, nil
+}
+
+// explainMissingField tells what happens to a field without values.
+func explainMissingField(tag *ParsedTagInfo) string {
+	switch tag.UnmarshalOpts.Presence {
+	case UnmarshalPresenceReq:
+		return "missing required field"
+	case UnmarshalPresenceNil:
+		return "no values: the field is left as it is"
+	default:
+		return "no values: the field is left as it is but nil pointers are allocated"
+	}
+}
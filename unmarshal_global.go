@@ -1,6 +1,7 @@
 package qs
 
 import (
+	"net/http"
 	"net/url"
 	"reflect"
 )
@@ -24,11 +25,17 @@ var DefaultUnmarshaler = NewUnmarshaler(&UnmarshalerDefaultOptions{})
 //     pointer-like types (pointers, slices) with nil field value it initialises
 //     the field with a newly created object.
 //   - req causes the unmarshal operation to fail with an error that can be
-//     detected using qs.IsRequiredFieldError.
+//     detected using qs.IsRequiredFieldError. The error lists all missing
+//     required fields in ReqError.Fields.
 //
 // When unmarshaling a nil pointer field that is present in the query string
 // the pointer is automatically initialised even if it has the nil option in
 // its tag.
+//
+// A map[string]interface{} receives the query without a schema: the keys with
+// one value become strings, the keys with more values become []string and
+// the nested keys become nested map[string]interface{} values if nesting is
+// enabled (see UnmarshalerDefaultOptions.Nesting).
 func Unmarshal(into interface{}, queryString string) error {
 	return DefaultUnmarshaler.Unmarshal(into, queryString)
 }
@@ -39,6 +46,44 @@ func UnmarshalValues(into interface{}, values url.Values) error {
 	return DefaultUnmarshaler.UnmarshalValues(into, values)
 }
 
+// UnmarshalWith is the same as Unmarshal but the given options are applied on
+// top of the options of the DefaultUnmarshaler for this call only, e.g.:
+//
+//	err := qs.UnmarshalWith(&q, r.URL.RawQuery, qs.WithUnmarshalPresence(qs.UnmarshalPresenceReq))
+func UnmarshalWith(into interface{}, queryString string, opts ...func(*QSUnmarshaler)) error {
+	return DefaultUnmarshaler.UnmarshalWith(into, queryString, opts...)
+}
+
+// UnmarshalValuesWith is the same as UnmarshalValues but the given options
+// are applied on top of the options of the DefaultUnmarshaler for this call
+// only.
+func UnmarshalValuesWith(into interface{}, values url.Values, opts ...func(*QSUnmarshaler)) error {
+	return DefaultUnmarshaler.UnmarshalValuesWith(into, values, opts...)
+}
+
+// UnmarshalURL is the same as Unmarshal but it unmarshals from the RawQuery of
+// u.
+func UnmarshalURL(into interface{}, u *url.URL) error {
+	return DefaultUnmarshaler.UnmarshalURL(into, u)
+}
+
+// UnmarshalPairs is the same as Unmarshal but it unmarshals from already
+// split key-value pairs. See QSUnmarshaler.UnmarshalPairs.
+func UnmarshalPairs(into interface{}, pairs []KV) error {
+	return DefaultUnmarshaler.UnmarshalPairs(into, pairs)
+}
+
+// DefaultBinder is the binder used by the Bind function. It uses the
+// DefaultUnmarshaler and looks up the fields in the path values, query string
+// and form of the request.
+var DefaultBinder = NewBinder(DefaultUnmarshaler)
+
+// Bind unmarshals the parameters of an HTTP request into the object pointed
+// to by the given pointer. See the documentation of Binder.
+func Bind(into interface{}, r *http.Request) error {
+	return DefaultBinder.Bind(into, r)
+}
+
 // CheckUnmarshal returns an error if the type of the given object can't be
 // unmarshaled from a url.Vales or query string. By default only maps and structs
 // can be unmarshaled from query strings given that all of their fields or values
@@ -59,19 +104,27 @@ func CheckUnmarshalType(t reflect.Type) error {
 }
 
 func RegisterSubFactoryUnmarshaler(k reflect.Kind, fn UnmarshalerFactoryFunc) error {
-	return DefaultUnmarshaler.opts.UnmarshalerFactory.RegisterSubFactory(k, fn)
+	return DefaultUnmarshaler.RegisterSubFactory(k, fn)
 }
 
 func RegisterCustomTypeUnmarshaler(k reflect.Type, fn PrimitiveUnmarshalerFunc) error {
-	return DefaultUnmarshaler.opts.UnmarshalerFactory.RegisterCustomType(k, fn)
+	return DefaultUnmarshaler.RegisterCustomType(k, fn)
+}
+
+// RegisterValuesCustomTypeUnmarshaler registers a
+// ValuesUnmarshalerFactoryFunc for the given type with the
+// DefaultUnmarshaler. See QSUnmarshaler.RegisterValuesCustomType.
+func RegisterValuesCustomTypeUnmarshaler(t reflect.Type, fn ValuesUnmarshalerFactoryFunc) error {
+	return DefaultUnmarshaler.RegisterValuesCustomType(t, fn)
 }
 
 func RegisterKindOverrideUnmarshaler(k reflect.Kind, fn PrimitiveUnmarshalerFunc) error {
-	return DefaultUnmarshaler.opts.UnmarshalerFactory.RegisterKindOverride(k, fn)
+	return DefaultUnmarshaler.RegisterKindOverride(k, fn)
 }
 
 func ApplyOptionsUnmarshal(opts ...func(*QSUnmarshaler)) {
 	for _, opt := range opts {
 		opt(DefaultUnmarshaler)
 	}
+	DefaultUnmarshaler.opts.updateCacheVariant()
 }
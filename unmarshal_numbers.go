@@ -0,0 +1,122 @@
+package qs
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// NumberParsing is a set of flags that relax the unmarshaling of int, uint
+// and float values. The flags can be set for all fields with
+// WithUnmarshalNumberParsing and per field with the tag options of the flags
+// (e.g. `qs:"amount,lenientnum,emptyzero"`). The flags of the tag are added
+// to the defaults of the unmarshaler.
+type NumberParsing uint8
+
+const (
+	// NumberParsingLenient accepts the underscores and commas between digits
+	// (e.g. "1,000,000" or "1_000") and a leading + sign for unsigned
+	// values. Its tag option is lenientnum.
+	NumberParsingLenient NumberParsing = 1 << iota
+
+	// NumberParsingEmptyAsZero unmarshals an empty value (e.g. "count=") as
+	// zero instead of failing. Its tag option is emptyzero.
+	NumberParsingEmptyAsZero
+
+	// NumberParsingClamp sets the closest value of the type instead of failing
+	// if the value is out of its range (e.g. "300" into an int8 becomes 127).
+	// Its tag option is clamp.
+	NumberParsingClamp
+)
+
+// numberParsingOptions maps the tag options to the NumberParsing flags.
+var numberParsingOptions = map[string]NumberParsing{
+	"lenientnum": NumberParsingLenient,
+	"emptyzero":  NumberParsingEmptyAsZero,
+	"clamp":      NumberParsingClamp,
+}
+
+// removeDigitSeparators removes the underscores and commas that stand between
+// two digits. The other occurrences are kept so they fail the parsing.
+func removeDigitSeparators(s string) string {
+	if !strings.ContainsAny(s, "_,") {
+		return s
+	}
+	isDigit := func(c byte) bool { return '0' <= c && c <= '9' }
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c == '_' || c == ',') && i > 0 && i+1 < len(s) && isDigit(s[i-1]) && isDigit(s[i+1]) {
+			continue
+		}
+		b = append(b, c)
+	}
+	return string(b)
+}
+
+// parseIntValue is strconv.ParseInt with the NumberParsing flags. It reports
+// whether the value was clamped.
+func parseIntValue(s string, bitSize int, flags NumberParsing) (int64, bool, error) {
+	if s == "" && flags&NumberParsingEmptyAsZero != 0 {
+		return 0, false, nil
+	}
+	if flags&NumberParsingLenient != 0 {
+		s = removeDigitSeparators(s)
+	}
+	i, err := strconv.ParseInt(s, 0, bitSize)
+	if err != nil && flags&NumberParsingClamp != 0 && errors.Is(err, strconv.ErrRange) {
+		// ParseInt returns the closest value on range errors.
+		return i, true, nil
+	}
+	return i, false, err
+}
+
+// parseUintValue is strconv.ParseUint with the NumberParsing flags. It
+// reports whether the value was clamped.
+func parseUintValue(s string, bitSize int, flags NumberParsing) (uint64, bool, error) {
+	if s == "" && flags&NumberParsingEmptyAsZero != 0 {
+		return 0, false, nil
+	}
+	if flags&NumberParsingLenient != 0 {
+		s = removeDigitSeparators(strings.TrimPrefix(s, "+"))
+	}
+	i, err := strconv.ParseUint(s, 0, bitSize)
+	if err != nil && flags&NumberParsingClamp != 0 {
+		if errors.Is(err, strconv.ErrRange) {
+			// ParseUint returns the maximum value on range errors.
+			return i, true, nil
+		}
+		// The negative numbers are clamped to zero.
+		if strings.HasPrefix(s, "-") {
+			if _, ierr := strconv.ParseInt(s, 0, 64); ierr == nil || errors.Is(ierr, strconv.ErrRange) {
+				return 0, true, nil
+			}
+		}
+	}
+	return i, false, err
+}
+
+// parseFloatValue is strconv.ParseFloat with the NumberParsing flags. It
+// reports whether the value was clamped.
+func parseFloatValue(s string, bitSize int, flags NumberParsing) (float64, bool, error) {
+	if s == "" && flags&NumberParsingEmptyAsZero != 0 {
+		return 0, false, nil
+	}
+	if flags&NumberParsingLenient != 0 {
+		s = removeDigitSeparators(s)
+	}
+	f, err := strconv.ParseFloat(s, bitSize)
+	if err != nil && flags&NumberParsingClamp != 0 && errors.Is(err, strconv.ErrRange) {
+		// ParseFloat returns ±Inf or ±0 on range errors.
+		if math.IsInf(f, 0) {
+			maxFloat := math.MaxFloat64
+			if bitSize == 32 {
+				maxFloat = math.MaxFloat32
+			}
+			return math.Copysign(maxFloat, f), true, nil
+		}
+		return f, true, nil
+	}
+	return f, false, err
+}
@@ -1,8 +1,12 @@
 package qs
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/url"
+	"reflect"
+	"strings"
 )
 
 // UnmarshalerDefaultOptions is used as a parameter by the NewUnmarshaler function.
@@ -29,7 +33,9 @@ type UnmarshalerDefaultOptions struct {
 	//
 	// In some cases you might want to provide your own function that is more
 	// forgiving. E.g.: you can provide a function that picks the first or last
-	// item, or concatenates/joins the whole list into a single string.
+	// item, or concatenates/joins the whole list into a single string. These
+	// cases are covered by the DuplicateKeyPolicy option (WithUnmarshalDuplicateKeyPolicy
+	// and the dupkeys=<policy> tag option) which is applied before this func.
 	SliceToString SliceToStringFunc
 
 	// ValuesUnmarshalerFactory is used by QSUnmarshaler to create ValuesUnmarshaler
@@ -42,9 +48,144 @@ type UnmarshalerDefaultOptions struct {
 	// a default builtin factory.
 	UnmarshalerFactory UnmarshalerFactory
 
+	// NewCache creates the Cache objects that store the objects compiled by
+	// the factories. It can be used to plug in size-bounded caches. If this
+	// field is nil then NewUnmarshaler uses NewCache.
+	NewCache func() Cache
+
+	// TagKey is the struct tag key of the field names and options. If this
+	// field is empty then NewUnmarshaler uses "qs".
+	TagKey string
+
+	// TagFallbackKeys lists the struct tag keys (e.g. "json", "form") whose
+	// name is used as the query string key of the fields that don't have a
+	// qs tag. The first key found in the tag of the field wins and the
+	// options of these tags are ignored. A "-" name excludes the field. The
+	// NameTransformer is used only when none of these tags is present.
+	TagFallbackKeys []string
+
+	// Nesting controls the keys of the fields of nested structs, of the
+	// items of slices of structs and of the entries of map fields (e.g.
+	// "filter.status" for the "status" key of a map[string][]string field
+	// named "filter"). The tag options of a map field apply to its entries.
+	// If this field is unspecified then NestingModeNone is used and map
+	// fields aren't supported.
+	Nesting NestingMode
+
+	// RangeFromSuffix and RangeToSuffix are appended to the key of a Range or
+	// TimeRange field to build the keys of its bounds. The defaults are
+	// "_from" and "_to" (e.g. "price_from" and "price_to").
+	RangeFromSuffix string
+	RangeToSuffix   string
+
+	// KeyPrefix and KeySuffix make the unmarshaler consider only the keys
+	// that start with KeyPrefix and end with KeySuffix. They are stripped
+	// from the keys before the keys are matched against the struct fields or
+	// stored into maps. They are handled by the methods of QSUnmarshaler and
+	// not by the ValuesUnmarshaler objects returned by CompileType. See
+	// WithUnmarshalKeyPrefix.
+	KeyPrefix string
+	KeySuffix string
+
+	// MaxSliceLen is the maximum number of items of the unmarshaled slices
+	// unless a field sets its own limit with the maxitems tag option. Query
+	// strings exceeding it are rejected with an error before allocating the
+	// slice, which protects servers from memory exhaustion caused by a huge
+	// number of repeated or separated values. Zero means no limit.
+	MaxSliceLen int
+
+	// MaxKeys is the maximum number of values in the unmarshaled query
+	// string or url.Values. A key counts as many times as it has values.
+	// Zero means no limit. Query strings are checked before they are parsed
+	// so the pairs beyond the limit aren't decoded.
+	MaxKeys int
+
+	// MaxValueLen is the maximum length of the values in bytes (after
+	// decoding). Zero means no limit. Like MaxKeys it is checked before the
+	// query strings are parsed.
+	MaxValueLen int
+
+	// MaxNestingDepth is the maximum depth of the keys of nested fields when
+	// Nesting is enabled: "name" has a depth of 0, "address.city" 1 and
+	// "phones[0].number" 2. Keys deeper than that are rejected even if the
+	// target type doesn't have such a field. Zero means no limit.
+	MaxNestingDepth int
+
+	// LazyFields defers the creation of the Unmarshaler objects of struct
+	// fields until the fields are unmarshaled for the first time. Note that
+	// the fields missing from the input are unmarshaled too unless their
+	// presence option is nil. The downside is that an unsupported field type
+	// is reported only when the field is unmarshaled so CheckUnmarshal can't
+	// detect it (but Precompile can).
+	LazyFields bool
+
+	// AllocEmbeddedPointers allocates the nil pointers of embedded structs
+	// (e.g. *Paging in a struct embedding it) even if the input has no values
+	// for their fields. By default they are allocated only if there is a
+	// value for one of their fields. The nil pointers of unexported embedded
+	// structs can't be allocated so their fields must not be in the input.
+	AllocEmbeddedPointers bool
+
+	// SkipUnsupportedFields ignores the struct fields whose type isn't
+	// supported (the ones that fail with ErrUnhandledType) instead of
+	// failing the compilation of the whole struct. See
+	// MarshalOptions.SkipUnsupportedFields.
+	SkipUnsupportedFields bool
+
+	// OnSkippedField is called with the fields ignored by
+	// SkipUnsupportedFields. See MarshalOptions.OnSkippedField.
+	OnSkippedField func(t reflect.Type, field reflect.StructField, err error)
+
+	// Diagnostics receives the non-fatal notices of the unmarshaler: the
+	// fields skipped by SkipUnsupportedFields, the keys that don't match any
+	// field of the struct they are unmarshaled into, the values dropped by
+	// the skip, dupkeys=first and dupkeys=last tag options and the numbers
+	// clamped by the clamp tag option. If this field is nil then the notices are
+	// dropped and the unknown keys aren't looked for.
+	Diagnostics DiagnosticFunc
+
+	// Logger receives the debug traces of the unmarshaler: the creation of
+	// the Unmarshaler and ValuesUnmarshaler objects, the hits of their
+	// caches and the struct fields that have no values or fail to
+	// unmarshal. It helps to find out why a field stays empty. If this field
+	// is nil then nothing is logged.
+	Logger *slog.Logger
+
+	// RequiredMapKeys are the keys that the unmarshaled maps must contain
+	// like the struct fields with the req option. The keys of map fields
+	// are relative to the field (e.g. "status" for "filter.status"). A
+	// missing key fails the unmarshaling with a ReqError.
+	RequiredMapKeys []string
+
+	// ErrorFormatter produces the errors of the struct fields that can't be
+	// unmarshaled from their UnmarshalErrorInfo. If this field is nil then
+	// the builtin errors are returned. Other errors (e.g. malformed query
+	// strings and exceeded MaxKeys limits) aren't passed to it.
+	ErrorFormatter ErrorFormatterFunc
+
 	// Defaults for tag  options
 	TagOptionsDefaults       *UnmarshalTagOptions
 	TagCommonOptionsDefaults *CommonTagOptions
+
+	// nameTransformerID identifies the NameTransformer in cache variant keys.
+	// nameTransformerSet is set by the option appliers that replace the
+	// NameTransformer to make updateCacheVariant create a new ID.
+	nameTransformerID  *funcID
+	nameTransformerSet bool
+
+	// variant is the cache variant key of the options (see variantCache).
+	variant interface{}
+
+	// codecs are the named codecs registered with RegisterNamedCodec. They
+	// are shared by the copies of the options like the factories.
+	codecs *namedRegistry[PrimitiveUnmarshalerFunc]
+
+	// transformers are the value transformers registered with
+	// RegisterValueTransformer.
+	transformers *namedRegistry[ValueTransformer]
+
+	// mapKeys are the map key funcs registered with RegisterMapKeyType.
+	mapKeys *typeRegistry[PrimitiveUnmarshalerFunc]
 }
 
 // NewDefaultUnmarshalOptions creates a new UnmarshalOptions in which every field
@@ -65,6 +206,9 @@ var defaultSliceToString = func(a []string) (string, error) {
 func prepareUnmarshalOptions(opts UnmarshalerDefaultOptions) *UnmarshalerDefaultOptions {
 	if opts.NameTransformer == nil {
 		opts.NameTransformer = snakeCase
+		opts.nameTransformerID = snakeCaseID
+	} else {
+		opts.nameTransformerID = new(funcID)
 	}
 	if opts.SliceToString == nil {
 		opts.SliceToString = defaultSliceToString
@@ -73,12 +217,12 @@ func prepareUnmarshalOptions(opts UnmarshalerDefaultOptions) *UnmarshalerDefault
 	if opts.ValuesUnmarshalerFactory == nil {
 		opts.ValuesUnmarshalerFactory = newValuesUnmarshalerFactory()
 	}
-	opts.ValuesUnmarshalerFactory = newValuesUnmarshalerCache(opts.ValuesUnmarshalerFactory)
+	opts.ValuesUnmarshalerFactory = newValuesUnmarshalerCache(opts.ValuesUnmarshalerFactory, opts.NewCache)
 
 	if opts.UnmarshalerFactory == nil {
 		opts.UnmarshalerFactory = newUnmarshalerFactory()
 	}
-	opts.UnmarshalerFactory = newUnmarshalerCache(opts.UnmarshalerFactory)
+	opts.UnmarshalerFactory = newUnmarshalerCache(opts.UnmarshalerFactory, opts.NewCache)
 
 	// Init Unmarshal Tag Options
 	if opts.TagOptionsDefaults == nil {
@@ -94,9 +238,161 @@ func prepareUnmarshalOptions(opts UnmarshalerDefaultOptions) *UnmarshalerDefault
 
 	opts.TagCommonOptionsDefaults.InitDefaults()
 
+	if opts.TagKey == "" {
+		opts.TagKey = tagKey
+	}
+	if opts.Nesting == NestingModeNMUnspecified {
+		opts.Nesting = NestingModeNone
+	}
+	if opts.RangeFromSuffix == "" {
+		opts.RangeFromSuffix = defaultRangeFromSuffix
+	}
+	if opts.RangeToSuffix == "" {
+		opts.RangeToSuffix = defaultRangeToSuffix
+	}
+
+	if opts.codecs == nil {
+		opts.codecs = &namedRegistry[PrimitiveUnmarshalerFunc]{builtin: builtinUnmarshalCodecs}
+	}
+	if opts.transformers == nil {
+		opts.transformers = &namedRegistry[ValueTransformer]{}
+	}
+	if opts.mapKeys == nil {
+		opts.mapKeys = &typeRegistry[PrimitiveUnmarshalerFunc]{}
+	}
+
+	opts.updateCacheVariant()
+
 	return &opts
 }
 
+// unmarshalCacheVariantKey is the snapshot of the options that identifies their
+// cache variant.
+type unmarshalCacheVariantKey struct {
+	nameTransformer *funcID
+	tagKey          string
+	fallbackKeys    string
+	nesting         NestingMode
+	rangeSuffixes   [2]string
+	lazyFields      bool
+	skipFields      bool
+	tag             UnmarshalTagOptions
+	common          CommonTagOptions
+}
+
+// updateCacheVariant has to be called after changing the options that
+// affect the compiled ValuesUnmarshaler objects.
+func (o *UnmarshalerDefaultOptions) updateCacheVariant() {
+	if o.nameTransformerSet {
+		o.nameTransformerID = new(funcID)
+		o.nameTransformerSet = false
+	}
+	o.variant = unmarshalCacheVariantKey{
+		nameTransformer: o.nameTransformerID,
+		tagKey:          o.TagKey,
+		fallbackKeys:    strings.Join(o.TagFallbackKeys, " "),
+		nesting:         o.Nesting,
+		rangeSuffixes:   [2]string{o.RangeFromSuffix, o.RangeToSuffix},
+		lazyFields:      o.LazyFields,
+		skipFields:      o.SkipUnsupportedFields,
+		tag:             *o.TagOptionsDefaults,
+		common:          *o.TagCommonOptionsDefaults,
+	}
+}
+
+// maxItems returns the maximum number of items of the slice field with the
+// given tag and the name of the limit or zero if there is no limit.
+func (o *UnmarshalerDefaultOptions) maxItems(tag *ParsedTagInfo) (int, string) {
+	if tag.UnmarshalOpts.MaxItems != 0 {
+		return tag.UnmarshalOpts.MaxItems, "maxitems"
+	}
+	return o.MaxSliceLen, "MaxSliceLen"
+}
+
+// checkQueryLimits checks a query string against MaxKeys and MaxValueLen
+// before it is parsed so the pairs of a hostile query string aren't decoded
+// and allocated. The pairs are split at "&" and ";" and the decoded length of
+// the values is computed from their percent-escapes without unescaping them,
+// which covers the pairs of every query string parser.
+func (o *UnmarshalerDefaultOptions) checkQueryLimits(query string) error {
+	if o.MaxKeys == 0 && o.MaxValueLen == 0 {
+		return nil
+	}
+	n := 0
+	for query != "" {
+		var pair string
+		if i := strings.IndexAny(query, "&;"); i >= 0 {
+			pair, query = query[:i], query[i+1:]
+		} else {
+			pair, query = query, ""
+		}
+		if pair == "" {
+			continue
+		}
+		n++
+		if o.MaxKeys != 0 && n > o.MaxKeys {
+			return &LimitExceededError{Limit: "MaxKeys", Max: o.MaxKeys}
+		}
+		if o.MaxValueLen != 0 {
+			key, value, _ := strings.Cut(pair, "=")
+			if len(value)-2*strings.Count(value, "%") > o.MaxValueLen {
+				if k, err := QueryEncodingGo.Unescape(key); err == nil {
+					key = k
+				}
+				return &LimitExceededError{Limit: "MaxValueLen", Key: key, Max: o.MaxValueLen}
+			}
+		}
+	}
+	return nil
+}
+
+// checkLimits checks the unmarshaled values against MaxKeys, MaxValueLen and
+// MaxNestingDepth. The query strings are checked by checkQueryLimits before
+// parsing too.
+func (o *UnmarshalerDefaultOptions) checkLimits(values url.Values) error {
+	if o.MaxKeys == 0 && o.MaxValueLen == 0 && o.MaxNestingDepth == 0 {
+		return nil
+	}
+	n := 0
+	for k, a := range values {
+		n += len(a)
+		if o.MaxKeys != 0 && n > o.MaxKeys {
+			return &LimitExceededError{Limit: "MaxKeys", Max: o.MaxKeys}
+		}
+		if o.MaxNestingDepth != 0 && o.Nesting.enabled() && o.Nesting.keyDepth(k) > o.MaxNestingDepth {
+			return &LimitExceededError{Limit: "MaxNestingDepth", Key: k, Max: o.MaxNestingDepth}
+		}
+		if o.MaxValueLen != 0 {
+			for _, s := range a {
+				if len(s) > o.MaxValueLen {
+					return &LimitExceededError{Limit: "MaxValueLen", Key: k, Max: o.MaxValueLen}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// forMapField returns the options used to unmarshal the entries of the map
+// field with the given tag: a copy of o with the options of the tag as
+// defaults.
+func (o *UnmarshalerDefaultOptions) forMapField(tag *ParsedTagInfo) *UnmarshalerDefaultOptions {
+	c := *o
+	c.TagOptionsDefaults = tag.UnmarshalOpts
+	c.TagCommonOptionsDefaults = tag.CommonOpts
+	return &c
+}
+
+// fieldNaming returns the options that determine the keys of struct fields.
+func (o *UnmarshalerDefaultOptions) fieldNaming() fieldNaming {
+	return fieldNaming{
+		tagKey:       o.TagKey,
+		fallbackKeys: o.TagFallbackKeys,
+		nt:           o.NameTransformer,
+		requestTags:  true,
+	}
+}
+
 // option appliers
 func WithUnmarshalPresence(value UnmarshalPresence) func(*QSUnmarshaler) {
 	return func(m *QSUnmarshaler) {
@@ -110,20 +406,385 @@ func WithUnmarshalSliceValues(value UnmarshalSliceValues) func(*QSUnmarshaler) {
 	}
 }
 
+// WithUnmarshalMapValues sets whether the unmarshaled entries of maps are
+// merged into the existing maps (default) or replace them. It can be
+// overridden per field with the keepold and overrideold tag options.
+func WithUnmarshalMapValues(value UnmarshalMapValues) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.TagOptionsDefaults.MapValues = value
+	}
+}
+
 func WithUnmarshalSliceUnexpectedValue(value UnmarshalSliceUnexpectedValue) func(*QSUnmarshaler) {
 	return func(m *QSUnmarshaler) {
 		m.opts.TagOptionsDefaults.SliceUnexpectedValue = value
 	}
 }
 
+// WithUnmarshalBoolParsing sets the default parsing of bool values. It can be
+// overridden per field with the strictbool and lenientbool tag options.
+func WithUnmarshalBoolParsing(value UnmarshalBoolParsing) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.TagOptionsDefaults.BoolParsing = value
+	}
+}
+
+// WithUnmarshalWhitespace sets whether the whitespace around the values is
+// trimmed before parsing them. It can be overridden per field with the trim
+// and notrim tag options.
+func WithUnmarshalWhitespace(value UnmarshalWhitespace) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.TagOptionsDefaults.Whitespace = value
+	}
+}
+
+// WithUnmarshalErrorFormatter sets UnmarshalerDefaultOptions.ErrorFormatter.
+func WithUnmarshalErrorFormatter(fn ErrorFormatterFunc) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.ErrorFormatter = fn
+	}
+}
+
+// WithUnmarshalEmptyPointer sets the default unmarshaling of empty values
+// (e.g. "name=") into pointer fields. It can be overridden per field with the
+// emptyptr=<policy> tag option.
+func WithUnmarshalEmptyPointer(value UnmarshalEmptyPointer) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.TagOptionsDefaults.EmptyPointer = value
+	}
+}
+
+// WithUnmarshalDuplicateKeyPolicy sets the default unmarshaling of repeated
+// keys into fields that hold a single value. It can be overridden per field
+// with the dupkeys=<policy> tag option.
+func WithUnmarshalDuplicateKeyPolicy(value DuplicateKeyPolicy) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.TagOptionsDefaults.DuplicateKeys = value
+	}
+}
+
+// WithUnmarshalNumberParsing sets the default NumberParsing flags of int,
+// uint and float values. The flags of the lenientnum, emptyzero and clamp tag
+// options are added to these.
+func WithUnmarshalNumberParsing(flags NumberParsing) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.TagOptionsDefaults.NumberParsing = flags
+	}
+}
+
 func WithUnmarshalOptionSliceSeparator(value OptionSliceSeparator) func(*QSUnmarshaler) {
 	return func(m *QSUnmarshaler) {
 		m.opts.TagCommonOptionsDefaults.SliceSeparator = value
+		m.opts.TagCommonOptionsDefaults.CustomSeparator = ""
+	}
+}
+
+// WithUnmarshalCustomSliceSeparator sets the default slice separator to an
+// arbitrary string. See CommonTagOptions.CustomSeparator. An empty string
+// disables the separator.
+func WithUnmarshalCustomSliceSeparator(sep string) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.TagCommonOptionsDefaults.SliceSeparator = OptionSliceSeparatorNone
+		m.opts.TagCommonOptionsDefaults.CustomSeparator = sep
+	}
+}
+
+// WithUnmarshalNullValue sets the default sentinel value of nil pointers.
+// See CommonTagOptions.Null. An empty string disables the sentinel.
+func WithUnmarshalNullValue(null string) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.TagCommonOptionsDefaults.Null = null
+	}
+}
+
+// WithUnmarshalOptionSliceKeys sets the default keys of the items of slices
+// and arrays (e.g. OptionSliceKeysBrackets for PHP and jQuery backends). It
+// can be overridden per field with the repeat, brackets and numbered tag
+// options.
+func WithUnmarshalOptionSliceKeys(value OptionSliceKeys) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.TagCommonOptionsDefaults.SliceKeys = value
+	}
+}
+
+// WithUnmarshalOptionSliceDuplicates sets the default handling of the duplicate
+// items of slices. It can be overridden per field with the keepduplicates and
+// unique tag options.
+func WithUnmarshalOptionSliceDuplicates(value OptionSliceDuplicates) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.TagCommonOptionsDefaults.SliceDuplicates = value
+	}
+}
+
+// WithUnmarshalMaxSliceLen sets UnmarshalerDefaultOptions.MaxSliceLen.
+func WithUnmarshalMaxSliceLen(n int) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.MaxSliceLen = n
+	}
+}
+
+// WithUnmarshalMaxKeys sets UnmarshalerDefaultOptions.MaxKeys.
+func WithUnmarshalMaxKeys(n int) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.MaxKeys = n
+	}
+}
+
+// WithUnmarshalMaxValueLen sets UnmarshalerDefaultOptions.MaxValueLen.
+func WithUnmarshalMaxValueLen(n int) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.MaxValueLen = n
+	}
+}
+
+// WithUnmarshalMaxNestingDepth sets UnmarshalerDefaultOptions.MaxNestingDepth.
+func WithUnmarshalMaxNestingDepth(n int) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.MaxNestingDepth = n
+	}
+}
+
+// WithUnmarshalNameTransformer sets UnmarshalerDefaultOptions.NameTransformer.
+func WithUnmarshalNameTransformer(fn NameTransformFunc) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		if fn == nil {
+			m.opts.NameTransformer = snakeCase
+			m.opts.nameTransformerID = snakeCaseID
+			m.opts.nameTransformerSet = false
+			return
+		}
+		m.opts.NameTransformer = fn
+		m.opts.nameTransformerSet = true
+	}
+}
+
+// WithValuesUnmarshalerFactory sets
+// UnmarshalerDefaultOptions.ValuesUnmarshalerFactory.
+func WithValuesUnmarshalerFactory(f ValuesUnmarshalerFactory) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		if f == nil {
+			f = newValuesUnmarshalerFactory()
+		}
+		m.opts.ValuesUnmarshalerFactory = newValuesUnmarshalerCache(f, m.opts.NewCache)
+	}
+}
+
+// WithUnmarshalerFactory sets UnmarshalerDefaultOptions.UnmarshalerFactory.
+func WithUnmarshalerFactory(f UnmarshalerFactory) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		if f == nil {
+			f = newUnmarshalerFactory()
+		}
+		m.opts.UnmarshalerFactory = newUnmarshalerCache(f, m.opts.NewCache)
+	}
+}
+
+// WithUnmarshalCache sets UnmarshalerDefaultOptions.NewCache. The factories of the unmarshaler get new
+// caches so the objects compiled so far aren't reused.
+func WithUnmarshalCache(newCache func() Cache) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.NewCache = newCache
+		if c, ok := m.opts.ValuesUnmarshalerFactory.(*valuesUnmarshalerCache); ok {
+			m.opts.ValuesUnmarshalerFactory = newValuesUnmarshalerCache(c.wrapped, newCache)
+		}
+		if c, ok := m.opts.UnmarshalerFactory.(*unmarshalerCache); ok {
+			m.opts.UnmarshalerFactory = newUnmarshalerCache(c.wrapped, newCache)
+		}
+	}
+}
+
+// WithUnmarshalTagOptionsDefaults sets
+// UnmarshalerDefaultOptions.TagOptionsDefaults. The unspecified options are
+// set to their defaults.
+func WithUnmarshalTagOptionsDefaults(value UnmarshalTagOptions) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		value.InitDefaults()
+		m.opts.TagOptionsDefaults = &value
+	}
+}
+
+// WithUnmarshalTagCommonOptionsDefaults sets
+// UnmarshalerDefaultOptions.TagCommonOptionsDefaults. The unspecified options
+// are set to their defaults.
+func WithUnmarshalTagCommonOptionsDefaults(value CommonTagOptions) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		value.InitDefaults()
+		m.opts.TagCommonOptionsDefaults = &value
+	}
+}
+
+// WithUnmarshalTagFallbackKeys sets UnmarshalerDefaultOptions.TagFallbackKeys.
+func WithUnmarshalTagFallbackKeys(keys ...string) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.TagFallbackKeys = keys
+	}
+}
+
+// WithUnmarshalRequiredMapKeys sets UnmarshalerDefaultOptions.RequiredMapKeys.
+func WithUnmarshalRequiredMapKeys(keys ...string) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.RequiredMapKeys = keys
+	}
+}
+
+// WithUnmarshalAllocEmbeddedPointers sets
+// UnmarshalerDefaultOptions.AllocEmbeddedPointers.
+func WithUnmarshalAllocEmbeddedPointers(enabled bool) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.AllocEmbeddedPointers = enabled
+	}
+}
+
+// WithUnmarshalSkipUnsupportedFields sets
+// UnmarshalerDefaultOptions.SkipUnsupportedFields and
+// UnmarshalerDefaultOptions.OnSkippedField. The onSkipped func can be nil.
+func WithUnmarshalSkipUnsupportedFields(onSkipped func(t reflect.Type, field reflect.StructField, err error)) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.SkipUnsupportedFields = true
+		m.opts.OnSkippedField = onSkipped
+	}
+}
+
+// WithUnmarshalDiagnostics sets UnmarshalerDefaultOptions.Diagnostics. A nil
+// fn removes the hook.
+func WithUnmarshalDiagnostics(fn DiagnosticFunc) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.Diagnostics = fn
+	}
+}
+
+// WithUnmarshalLogger sets UnmarshalerDefaultOptions.Logger. The traces are
+// logged at debug level. A nil logger disables the traces.
+func WithUnmarshalLogger(logger *slog.Logger) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.Logger = logger
+	}
+}
+
+// WithUnmarshalLazyFields sets UnmarshalerDefaultOptions.LazyFields.
+func WithUnmarshalLazyFields(lazy bool) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.LazyFields = lazy
+	}
+}
+
+// WithUnmarshalTagKey sets UnmarshalerDefaultOptions.TagKey.
+func WithUnmarshalTagKey(key string) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		if key == "" {
+			key = tagKey
+		}
+		m.opts.TagKey = key
+	}
+}
+
+// WithUnmarshalNesting sets UnmarshalerDefaultOptions.Nesting.
+func WithUnmarshalNesting(mode NestingMode) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		if mode == NestingModeNMUnspecified {
+			mode = NestingModeNone
+		}
+		m.opts.Nesting = mode
+	}
+}
+
+// WithUnmarshalRangeSuffixes sets UnmarshalerDefaultOptions.RangeFromSuffix and
+// UnmarshalerDefaultOptions.RangeToSuffix. Empty suffixes are replaced with the defaults.
+func WithUnmarshalRangeSuffixes(from, to string) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		if from == "" {
+			from = defaultRangeFromSuffix
+		}
+		if to == "" {
+			to = defaultRangeToSuffix
+		}
+		m.opts.RangeFromSuffix, m.opts.RangeToSuffix = from, to
+	}
+}
+
+// WithUnmarshalSchemaCompat configures the unmarshaler to read the `schema`
+// tags of gorilla/schema (including their required option) and to parse the
+// keys like its decoder: the Go field names are used as keys by default and
+// nested structs use dotted keys ("phones.0.number"). The default option of
+// gorilla/schema isn't supported.
+func WithUnmarshalSchemaCompat() func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		WithUnmarshalTagKey("schema")(m)
+		WithUnmarshalNesting(NestingModeDots)(m)
+		WithUnmarshalNameTransformer(fieldName)(m)
+	}
+}
+
+// WithUnmarshalFormCompat configures the unmarshaler to read the `form` tags
+// of go-playground/form and to parse the keys like its decoder: the Go field
+// names are used as keys by default, nested structs use dotted keys with
+// bracketed indices ("phones[0].number") and the items of slices can be
+// passed with indexed keys ("tags[0]=a&tags[1]=b").
+func WithUnmarshalFormCompat() func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		WithUnmarshalTagKey("form")(m)
+		WithUnmarshalNesting(NestingModeDotsIndexBrackets)(m)
+		WithUnmarshalNameTransformer(fieldName)(m)
+	}
+}
+
+// WithUnmarshalRackCompat configures the unmarshaler to parse the nested
+// keys of Rack::Utils.parse_nested_query (Rails): nested structs and map
+// fields use bracketed keys ("user[address][city]"), the items of slices
+// can be passed with empty or numeric indices ("tags[]=a&tags[]=b",
+// "phones[][number]=1" or "phones[0][number]=1") and the plain keys of
+// slices are accepted too. See NestingModeBrackets.
+func WithUnmarshalRackCompat() func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		WithUnmarshalNesting(NestingModeBrackets)(m)
+		WithUnmarshalOptionSliceKeys(OptionSliceKeysBrackets)(m)
+	}
+}
+
+// WithUnmarshalJSONAPICompat configures the unmarshaler to parse the query
+// parameters of the JSON:API specification: bracketed keys for nested structs
+// and map fields and comma separated slice items. See
+// WithMarshalJSONAPICompat and JSONAPIQuery.
+func WithUnmarshalJSONAPICompat() func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		WithUnmarshalNesting(NestingModeBrackets)(m)
+		WithUnmarshalOptionSliceSeparator(OptionSliceSeparatorComma)(m)
+	}
+}
+
+// WithUnmarshalNpmQSCompat configures the unmarshaler to parse the query
+// strings of the qs package of npm with its default limits: bracketed keys
+// for nested structs, map fields and indices (see WithUnmarshalRackCompat),
+// a nesting depth of 5 and 1000 parameters. The allowDots option of qs
+// accepts dotted keys too ("address.city"). Unlike qs the unmarshaler fails
+// with a LimitExceededError instead of ignoring the keys beyond the limits.
+func WithUnmarshalNpmQSCompat(allowDots bool) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		WithUnmarshalRackCompat()(m)
+		if allowDots {
+			WithUnmarshalNesting(NestingModeBracketsAllowDots)(m)
+		}
+		WithUnmarshalMaxNestingDepth(5)(m)
+		WithUnmarshalMaxKeys(1000)(m)
+	}
+}
+
+// WithUnmarshalURLCompat configures the unmarshaler to read the `url` tags of
+// google/go-querystring so it can unmarshal the query strings marshaled from
+// the same structs by go-querystring or by a marshaler configured with
+// WithMarshalURLCompat.
+func WithUnmarshalURLCompat() func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		WithUnmarshalTagKey("url")(m)
+		WithUnmarshalNameTransformer(fieldName)(m)
 	}
 }
 
 func WithCustomSliceToStringFunc(fn SliceToStringFunc) func(*QSUnmarshaler) {
 	return func(m *QSUnmarshaler) {
+		if fn == nil {
+			fn = defaultSliceToString
+		}
 		m.opts.SliceToString = fn
 	}
 }
@@ -134,6 +795,53 @@ func WithCustomStringToUrlQueryParser(fn func(query string) (url.Values, error))
 	}
 }
 
+// WithUnmarshalQueryEncoding parses the query strings with the given
+// QueryEncoding (e.g. QueryEncodingLegacyPHP) instead of url.ParseQuery.
+func WithUnmarshalQueryEncoding(enc QueryEncoding) func(*QSUnmarshaler) {
+	return WithCustomStringToUrlQueryParser(enc.Parse)
+}
+
+// WithLenientQueryParsing makes the unmarshaler skip the malformed pairs of
+// the query strings (see QueryEncoding.ParseLenient) instead of failing the
+// whole Unmarshal call like url.ParseQuery does. The skipped pairs are
+// reported to onSkip if it isn't nil. Use QueryEncoding.ParseLenient with
+// WithCustomStringToUrlQueryParser to parse with another QueryEncoding.
+func WithLenientQueryParsing(onSkip func(pair string, err error)) func(*QSUnmarshaler) {
+	return WithCustomStringToUrlQueryParser(func(query string) (url.Values, error) {
+		return QueryEncodingGo.ParseLenient(query, onSkip), nil
+	})
+}
+
+// WithUnmarshalHook sets a hook that is called after each Unmarshal and
+// UnmarshalValues call of the unmarshaler (including the calls of the
+// TypedUnmarshaler objects created from it and Binder.Bind) with the type,
+// the duration and the error of the call. A nil fn removes the hook.
+func WithUnmarshalHook(fn HookFunc) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.hook = fn
+	}
+}
+
+// WithUnmarshalKeyPrefix sets UnmarshalerDefaultOptions.KeyPrefix: the
+// unmarshaler considers only the keys that start with the given prefix. The
+// prefix is stripped from the keys before they are matched against the
+// struct fields or stored into maps.
+func WithUnmarshalKeyPrefix(prefix string) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.KeyPrefix = prefix
+	}
+}
+
+// WithUnmarshalKeySuffix sets UnmarshalerDefaultOptions.KeySuffix: the
+// unmarshaler considers only the keys that end with the given suffix. The
+// suffix is stripped from the keys before they are matched against the
+// struct fields or stored into maps.
+func WithUnmarshalKeySuffix(suffix string) func(*QSUnmarshaler) {
+	return func(m *QSUnmarshaler) {
+		m.opts.KeySuffix = suffix
+	}
+}
+
 type UnmarshalOptions struct {
 	UnmarshalerOptions *UnmarshalerDefaultOptions
 	ParsedTagInfo      *ParsedTagInfo
@@ -143,10 +851,61 @@ func (o *UnmarshalOptions) NameTransform(s string) string {
 	return o.UnmarshalerOptions.NameTransformer(s)
 }
 
+// SliceToString converts the values of a key into a single string. The
+// repeated keys are resolved by the DuplicateKeyPolicy of the field before
+// calling the SliceToString func of the unmarshaler and the result is trimmed
+// if the UnmarshalWhitespace of the field is Trim.
 func (o *UnmarshalOptions) SliceToString(s []string) (string, error) {
+	str, err := o.sliceToString(s)
+	if err == nil && o.ParsedTagInfo != nil && o.ParsedTagInfo.UnmarshalOpts != nil &&
+		o.ParsedTagInfo.UnmarshalOpts.Whitespace == UnmarshalWhitespaceTrim {
+		str = strings.TrimSpace(str)
+	}
+	return str, err
+}
+
+func (o *UnmarshalOptions) sliceToString(s []string) (string, error) {
+	if len(s) > 1 && o.ParsedTagInfo != nil && o.ParsedTagInfo.UnmarshalOpts != nil {
+		switch o.ParsedTagInfo.UnmarshalOpts.DuplicateKeys {
+		case DuplicateKeyPolicyFirst:
+			o.diagnoseDropped(s[1:])
+			s = s[:1]
+		case DuplicateKeyPolicyLast:
+			o.diagnoseDropped(s[:len(s)-1])
+			s = s[len(s)-1:]
+		case DuplicateKeyPolicyJoin:
+			sep := ","
+			if o.ParsedTagInfo.CommonOpts != nil {
+				if fieldSep, _ := o.ParsedTagInfo.CommonOpts.separator(); fieldSep != "" {
+					sep = fieldSep
+				}
+			}
+			s = []string{strings.Join(s, sep)}
+		}
+	}
 	return o.UnmarshalerOptions.SliceToString(s)
 }
 
+// diagnoseDropped reports the values of the field dropped by the
+// dupkeys=first and dupkeys=last options.
+func (o *UnmarshalOptions) diagnoseDropped(dropped []string) {
+	if o.UnmarshalerOptions.Diagnostics == nil {
+		return
+	}
+	o.UnmarshalerOptions.diagnose(DiagnosticKindDroppedValues, nil, o.ParsedTagInfo.Name,
+		"dropped the duplicate values %q", dropped)
+}
+
+// diagnoseClamped reports the value s of the field that was clamped to the
+// range of type t.
+func (o *UnmarshalOptions) diagnoseClamped(t reflect.Type, s string) {
+	if o.UnmarshalerOptions.Diagnostics == nil {
+		return
+	}
+	o.UnmarshalerOptions.diagnose(DiagnosticKindLenientParse, t, o.ParsedTagInfo.Name,
+		"clamped the out of range value %q to the range of %v", s, t)
+}
+
 func NewUnmarshalOptions(opt *UnmarshalerDefaultOptions, tag *ParsedTagInfo) *UnmarshalOptions {
 	if tag == nil {
 		tag = &ParsedTagInfo{
@@ -162,3 +921,87 @@ func NewUnmarshalOptions(opt *UnmarshalerDefaultOptions, tag *ParsedTagInfo) *Un
 		ParsedTagInfo:      tag,
 	}
 }
+
+// asFieldError returns err as a *fieldError if the options have an
+// ErrorFormatter. Otherwise it returns err.
+func (o *UnmarshalerDefaultOptions) asFieldError(err error, key string, t reflect.Type, a []string) error {
+	if o.ErrorFormatter == nil {
+		return err
+	}
+	if fe, ok := err.(*fieldError); ok {
+		return fe
+	}
+	kind := UnmarshalErrorKindInvalid
+	var le *LimitExceededError
+	if _, ok := IsRequiredFieldError(err); ok {
+		kind = UnmarshalErrorKindRequired
+	} else if errors.As(err, &le) {
+		kind = UnmarshalErrorKindLimit
+	}
+	return &fieldError{UnmarshalErrorInfo{
+		Key:    key,
+		Kind:   kind,
+		Values: a,
+		Type:   t,
+		Err:    err,
+	}}
+}
+
+// formatError passes the UnmarshalErrorInfo of a field error to the
+// ErrorFormatter.
+func (o *UnmarshalerDefaultOptions) formatError(err error) error {
+	if err == nil || o.ErrorFormatter == nil {
+		return err
+	}
+	var fe *fieldError
+	if errors.As(err, &fe) {
+		return o.ErrorFormatter(fe.info)
+	}
+	return err
+}
+
+// skipField reports whether the field of struct type t that can't be compiled
+// because of err is ignored by SkipUnsupportedFields.
+func (o *UnmarshalerDefaultOptions) skipField(t reflect.Type, sf reflect.StructField, err error) bool {
+	if !o.SkipUnsupportedFields || !errors.Is(err, ErrUnhandledType) {
+		return false
+	}
+	if o.OnSkippedField != nil {
+		o.OnSkippedField(t, sf, err)
+	}
+	if o.Diagnostics != nil {
+		o.Diagnostics(skippedFieldDiagnostic(t, sf, err))
+	}
+	return true
+}
+
+// diagnose passes a notice to the Diagnostics hook. The message is formatted
+// only if there is a hook.
+func (o *UnmarshalerDefaultOptions) diagnose(kind DiagnosticKind, t reflect.Type, key string, format string, args ...interface{}) {
+	if o.Diagnostics == nil {
+		return
+	}
+	o.Diagnostics(Diagnostic{
+		Kind:    kind,
+		Type:    t,
+		Key:     key,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// logger returns the Logger of the options. It accepts a nil receiver.
+func (o *UnmarshalerDefaultOptions) logger() *slog.Logger {
+	if o == nil {
+		return nil
+	}
+	return o.Logger
+}
+
+// logger returns the Logger of the unmarshaler options. It accepts a nil
+// receiver.
+func (o *UnmarshalOptions) logger() *slog.Logger {
+	if o == nil {
+		return nil
+	}
+	return o.UnmarshalerOptions.logger()
+}
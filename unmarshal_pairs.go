@@ -0,0 +1,28 @@
+package qs
+
+import "net/url"
+
+// KV is a key-value pair of a query.
+type KV struct {
+	Key   string
+	Value string
+}
+
+// UnmarshalPairs unmarshals an object from already split key-value pairs
+// (e.g. message headers or the rows of a CSV file) that don't come from a
+// URL. The values of duplicate keys are passed to the fields in the order of
+// the pairs so the pairs a=1, b=2, a=3 are the same as the query string
+// "a=1&b=2&a=3".
+func (p *QSUnmarshaler) UnmarshalPairs(into interface{}, pairs []KV) error {
+	return p.UnmarshalValues(into, valuesFromPairs(pairs))
+}
+
+// valuesFromPairs collects the values of the pairs by key keeping their
+// order.
+func valuesFromPairs(pairs []KV) url.Values {
+	values := make(url.Values)
+	for _, kv := range pairs {
+		values[kv.Key] = append(values[kv.Key], kv.Value)
+	}
+	return values
+}
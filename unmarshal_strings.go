@@ -1,6 +1,8 @@
 package qs
 
 import (
+	"encoding"
+	"errors"
 	"fmt"
 	"net/url"
 	"reflect"
@@ -36,6 +38,19 @@ func (p *ptrUnmarshaler) Unmarshal(v reflect.Value, a []string, opts *UnmarshalO
 	if t != p.Type {
 		return &WrongTypeError{Actual: t, Expected: p.Type}
 	}
+	if len(a) == 1 && a[0] != "" && a[0] == opts.ParsedTagInfo.CommonOpts.Null {
+		v.Set(reflect.Zero(t))
+		return nil
+	}
+	if len(a) == 1 && a[0] == "" {
+		switch opts.ParsedTagInfo.UnmarshalOpts.EmptyPointer {
+		case UnmarshalEmptyPointerNil:
+			v.Set(reflect.Zero(t))
+			return nil
+		case UnmarshalEmptyPointerError:
+			return errors.New("empty value")
+		}
+	}
 	if v.IsNil() {
 		v.Set(reflect.New(p.ElemType))
 	}
@@ -73,13 +88,14 @@ func (p *arrayUnmarshaler) Unmarshal(v reflect.Value, a []string, opts *Unmarsha
 	if a == nil {
 		return nil
 	}
+	a = splitArrayBySeparatorWithSameOrder(a, opts.ParsedTagInfo.CommonOpts)
 	if len(a) != p.Len {
 		return fmt.Errorf("array length == %v, want %v", len(a), p.Len)
 	}
 	for i := range a {
 		err := p.ElemUnmarshaler.Unmarshal(v.Index(i), a[i:i+1], opts)
 		if err != nil {
-			return fmt.Errorf("error unmarshaling array index %v :: %v", i, err)
+			return fmt.Errorf("error unmarshaling array index %v :: %w", i, err)
 		}
 	}
 	return nil
@@ -105,26 +121,19 @@ func newSliceUnmarshaler(t reflect.Type, opts *UnmarshalOptions) (Unmarshaler, e
 	}, nil
 }
 
-func splitArrayBySeparatorWithSameOrder(a []string, separatorType OptionSliceSeparator) []string {
-	sep := ""
-	switch separatorType {
-	case OptionSliceSeparatorComma:
-		sep = ","
-	case OptionSliceSeparatorSemicolon:
-		sep = ";"
-	case OptionSliceSeparatorSpace:
-		sep = " "
-	case OptionSliceSeparatorNone:
-	default:
-		panic(fmt.Sprintf("unexpected qs.OptionSliceSeparator: %#v", separatorType))
-	}
+func splitArrayBySeparatorWithSameOrder(a []string, opts *CommonTagOptions) []string {
+	sep, escaped := opts.separator()
 	if len(sep) == 0 {
 		return a
 	}
 
 	vals := make([]string, 0, 2*len(a))
 	for _, s := range a {
-		vals = append(vals, strings.Split(s, sep)...)
+		if escaped {
+			vals = append(vals, splitEscaped(s, sep)...)
+		} else {
+			vals = append(vals, strings.Split(s, sep)...)
+		}
 	}
 	return vals
 }
@@ -135,15 +144,26 @@ func (p *sliceUnmarshaler) Unmarshal(v reflect.Value, a []string, opts *Unmarsha
 		return &WrongTypeError{Actual: t, Expected: p.Type}
 	}
 
-	vals := splitArrayBySeparatorWithSameOrder(a, opts.ParsedTagInfo.CommonOpts.SliceSeparator)
+	vals := splitArrayBySeparatorWithSameOrder(a, opts.ParsedTagInfo.CommonOpts)
+	if opts.ParsedTagInfo.CommonOpts.SliceDuplicates == OptionSliceDuplicatesUnique {
+		vals = uniqueItems(vals)
+	}
+	keepPrevValues := opts.ParsedTagInfo.UnmarshalOpts.SliceValues == UnmarshalSliceValuesKeepOld
+	if limit, name := opts.UnmarshalerOptions.maxItems(opts.ParsedTagInfo); limit != 0 {
+		n := len(vals)
+		if keepPrevValues && !v.IsNil() {
+			n += v.Len()
+		}
+		if n > limit {
+			return &LimitExceededError{Limit: name, Key: opts.ParsedTagInfo.Name, Max: limit}
+		}
+	}
 
 	// resize or create slice
 	n := 0
 	if v.IsNil() {
 		v.Set(reflect.MakeSlice(t, len(vals), len(vals)))
 	} else {
-		keepPrevValues := opts.ParsedTagInfo.UnmarshalOpts.SliceValues == UnmarshalSliceValuesKeepOld
-
 		oldLen := v.Len()
 		newLen := len(vals)
 		if keepPrevValues {
@@ -172,9 +192,13 @@ func (p *sliceUnmarshaler) Unmarshal(v reflect.Value, a []string, opts *Unmarsha
 		}
 
 		if breakOnError {
-			errLoop = fmt.Errorf("error unmarshaling slice index %v :: %v", i, err)
+			errLoop = fmt.Errorf("error unmarshaling slice index %v :: %w", i, err)
 			break
 		}
+		if opts.UnmarshalerOptions.Diagnostics != nil {
+			opts.UnmarshalerOptions.diagnose(DiagnosticKindDroppedValues, t.Elem(), opts.ParsedTagInfo.Name,
+				"dropped the invalid value %q of slice index %v :: %v", vals[i], i, err)
+		}
 	}
 
 	// cut unmarshleable values from slice or clear if error occurred
@@ -203,14 +227,54 @@ func unmarshalBool(v reflect.Value, s string, opts *UnmarshalOptions) error {
 	if v.Kind() != reflect.Bool {
 		return &WrongKindError{Expected: reflect.Bool, Actual: v.Type()}
 	}
+	if opts.ParsedTagInfo.UnmarshalOpts.BoolParsing == UnmarshalBoolParsingLenientBool {
+		b, err := parseLenientBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+		return nil
+	}
 	b, err := strconv.ParseBool(s)
 	if err != nil {
-		return err
+		// The values of the bool format of the field are accepted so that
+		// fields marshaled with e.g. the yesno tag option round-trip.
+		var ok bool
+		if b, ok = parseBoolFormat(s, opts.ParsedTagInfo.MarshalOpts); !ok {
+			return err
+		}
 	}
 	v.SetBool(b)
 	return nil
 }
 
+// parseBoolFormat parses the values of the MarshalBoolFormat of a field that
+// strconv.ParseBool doesn't accept.
+func parseBoolFormat(s string, opts *MarshalTagOptions) (b, ok bool) {
+	if opts == nil {
+		return false, false
+	}
+	switch opts.BoolFormat {
+	case MarshalBoolFormatYesNo:
+		return s == "yes", s == "yes" || s == "no"
+	case MarshalBoolFormatOnOff:
+		return s == "on", s == "on" || s == "off"
+	}
+	return false, false
+}
+
+// parseLenientBool parses the bool values accepted by
+// UnmarshalBoolParsingLenientBool.
+func parseLenientBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "", "1", "t", "true", "y", "yes", "on":
+		return true, nil
+	case "0", "f", "false", "n", "no", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid bool value: %q", s)
+}
+
 // unmarshalInt can unmarshal an ini file entry into a signed integer value
 // with an underlying type (kind) of int, int8, int16, int32 or int64.
 func unmarshalInt(v reflect.Value, s string, opts *UnmarshalOptions) error {
@@ -230,10 +294,13 @@ func unmarshalInt(v reflect.Value, s string, opts *UnmarshalOptions) error {
 		return &WrongKindError{Expected: reflect.Int, Actual: v.Type()}
 	}
 
-	i, err := strconv.ParseInt(s, 0, bitSize)
+	i, clamped, err := parseIntValue(s, bitSize, opts.ParsedTagInfo.UnmarshalOpts.NumberParsing)
 	if err != nil {
 		return err
 	}
+	if clamped {
+		opts.diagnoseClamped(v.Type(), s)
+	}
 
 	v.SetInt(i)
 	return nil
@@ -258,10 +325,13 @@ func unmarshalUint(v reflect.Value, s string, opts *UnmarshalOptions) error {
 		return &WrongKindError{Expected: reflect.Uint, Actual: v.Type()}
 	}
 
-	i, err := strconv.ParseUint(s, 0, bitSize)
+	i, clamped, err := parseUintValue(s, bitSize, opts.ParsedTagInfo.UnmarshalOpts.NumberParsing)
 	if err != nil {
 		return err
 	}
+	if clamped {
+		opts.diagnoseClamped(v.Type(), s)
+	}
 
 	v.SetUint(i)
 	return nil
@@ -279,10 +349,13 @@ func unmarshalFloat(v reflect.Value, s string, opts *UnmarshalOptions) error {
 		return &WrongKindError{Expected: reflect.Float32, Actual: v.Type()}
 	}
 
-	f, err := strconv.ParseFloat(s, bitSize)
+	f, clamped, err := parseFloatValue(s, bitSize, opts.ParsedTagInfo.UnmarshalOpts.NumberParsing)
 	if err != nil {
 		return err
 	}
+	if clamped {
+		opts.diagnoseClamped(v.Type(), s)
+	}
 
 	v.SetFloat(f)
 	return nil
@@ -316,6 +389,19 @@ func unmarshalURL(v reflect.Value, s string, opts *UnmarshalOptions) error {
 	return nil
 }
 
+// unmarshalWithTextUnmarshaler unmarshals the values of types that implement
+// encoding.TextUnmarshaler with a pointer receiver.
+func unmarshalWithTextUnmarshaler(v reflect.Value, s string, opts *UnmarshalOptions) error {
+	if !v.CanAddr() {
+		return fmt.Errorf("expected and addressable value, got %v", v)
+	}
+	tu, ok := v.Addr().Interface().(encoding.TextUnmarshaler)
+	if !ok {
+		return fmt.Errorf("expected a type that implements encoding.TextUnmarshaler, got %v", v.Type())
+	}
+	return tu.UnmarshalText([]byte(s))
+}
+
 func unmarshalWithUnmarshalQS(v reflect.Value, a []string, opts *UnmarshalOptions) error {
 	if !v.CanAddr() {
 		return fmt.Errorf("expected and addressable value, got %v", v)
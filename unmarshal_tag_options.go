@@ -1,6 +1,10 @@
 package qs
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 type UnmarshalTagOptions struct {
 	// DefaultUnmarshalPresence is used for the unmarshaling of struct fields
@@ -9,7 +13,57 @@ type UnmarshalTagOptions struct {
 
 	SliceValues UnmarshalSliceValues
 
+	// MapValues controls whether the entries of a map field are merged into
+	// the existing map or replace it. It is set by the same keepold and
+	// overrideold tag options as SliceValues.
+	MapValues UnmarshalMapValues
+
 	SliceUnexpectedValue UnmarshalSliceUnexpectedValue
+
+	// BoolParsing controls the values accepted by bool fields.
+	BoolParsing UnmarshalBoolParsing
+
+	// Whitespace controls the trimming of the values before parsing them.
+	Whitespace UnmarshalWhitespace
+
+	// EmptyPointer controls the unmarshaling of empty values into pointer
+	// fields. It is set by the emptyptr=<policy> tag option.
+	EmptyPointer UnmarshalEmptyPointer
+
+	// DuplicateKeys controls the unmarshaling of a repeated key into a field
+	// that holds a single value. It is set by the dupkeys=<policy> tag option.
+	DuplicateKeys DuplicateKeyPolicy
+
+	// NumberParsing relaxes the parsing of int, uint and float values. It is
+	// set by the lenientnum, emptyzero and clamp tag options.
+	NumberParsing NumberParsing
+
+	// Source restricts the lookup of the field to a single part of the HTTP
+	// request when it is unmarshaled by a Binder. It is set by the src=<source>
+	// tag option and it is ignored by Unmarshal and UnmarshalValues.
+	Source BindSource
+
+	// MaxItems is the maximum number of items of a slice field. It is set by
+	// the maxitems=<n> tag option (e.g. `qs:"ids,maxitems=100"`) and it
+	// overrides UnmarshalerDefaultOptions.MaxSliceLen. Zero means that the
+	// MaxSliceLen of the unmarshaler is used.
+	MaxItems int
+
+	// ReqMessage is the message of the ReqError of a missing required field.
+	// It is set by the reqmsg=<message> tag option (the message can't
+	// contain commas) and it isn't inherited from the defaults.
+	ReqMessage string
+
+	// Requires is the key of a sibling field that must be present if the
+	// field is present. It is set by the requires=<key> tag option (e.g.
+	// `qs:"from,requires=to"`) and it isn't inherited from the defaults.
+	Requires string
+
+	// RequiredWith is the key of a sibling field that makes the field
+	// required if it is present. It is set by the requiredwith=<key> tag
+	// option (e.g. `qs:"to,requiredwith=from"`) and it isn't inherited from
+	// the defaults.
+	RequiredWith string
 }
 
 func (o *UnmarshalTagOptions) InitDefaults() {
@@ -19,9 +73,24 @@ func (o *UnmarshalTagOptions) InitDefaults() {
 	if o.SliceValues == UnmarshalSliceValuesUPUnspecified {
 		o.SliceValues = UnmarshalSliceValuesOverrideOld
 	}
+	if o.MapValues == UnmarshalMapValuesMVUnspecified {
+		o.MapValues = UnmarshalMapValuesKeepOld
+	}
 	if o.SliceUnexpectedValue == UnmarshalSliceUnexpectedValueUPUnspecified {
 		o.SliceUnexpectedValue = UnmarshalSliceUnexpectedValueBreakWithError
 	}
+	if o.BoolParsing == UnmarshalBoolParsingUPUnspecified {
+		o.BoolParsing = UnmarshalBoolParsingStrictBool
+	}
+	if o.Whitespace == UnmarshalWhitespaceUWUnspecified {
+		o.Whitespace = UnmarshalWhitespaceNoTrim
+	}
+	if o.EmptyPointer == UnmarshalEmptyPointerEPUnspecified {
+		o.EmptyPointer = UnmarshalEmptyPointerValue
+	}
+	if o.DuplicateKeys == DuplicateKeyPolicyDKUnspecified {
+		o.DuplicateKeys = DuplicateKeyPolicyError
+	}
 }
 
 func (o *UnmarshalTagOptions) ApplyDefaults(d *UnmarshalTagOptions) {
@@ -31,9 +100,25 @@ func (o *UnmarshalTagOptions) ApplyDefaults(d *UnmarshalTagOptions) {
 	if o.SliceValues == UnmarshalSliceValuesUPUnspecified {
 		o.SliceValues = d.SliceValues
 	}
+	if o.MapValues == UnmarshalMapValuesMVUnspecified {
+		o.MapValues = d.MapValues
+	}
 	if o.SliceUnexpectedValue == UnmarshalSliceUnexpectedValueUPUnspecified {
 		o.SliceUnexpectedValue = d.SliceUnexpectedValue
 	}
+	if o.BoolParsing == UnmarshalBoolParsingUPUnspecified {
+		o.BoolParsing = d.BoolParsing
+	}
+	if o.Whitespace == UnmarshalWhitespaceUWUnspecified {
+		o.Whitespace = d.Whitespace
+	}
+	if o.EmptyPointer == UnmarshalEmptyPointerEPUnspecified {
+		o.EmptyPointer = d.EmptyPointer
+	}
+	if o.DuplicateKeys == DuplicateKeyPolicyDKUnspecified {
+		o.DuplicateKeys = d.DuplicateKeys
+	}
+	o.NumberParsing |= d.NumberParsing
 }
 
 func (o *UnmarshalTagOptions) ParseOption(option string) (bool, error) {
@@ -57,6 +142,15 @@ func (o *UnmarshalTagOptions) ParseOption(option string) (bool, error) {
 		bOk = true
 	}
 
+	// UnmarshalMapValues
+	if value, err := UnmarshalMapValuesFromString(option); err == nil {
+		if o.MapValues != UnmarshalMapValuesMVUnspecified {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "UnmarshalMapValues", o.MapValues, value)
+		}
+		o.MapValues = value
+		bOk = true
+	}
+
 	// UnmarshalSliceUnexpectedValue
 	if value, err := UnmarshalSliceUnexpectedValueFromString(option); err == nil {
 		if o.SliceUnexpectedValue != UnmarshalSliceUnexpectedValueUPUnspecified {
@@ -66,6 +160,121 @@ func (o *UnmarshalTagOptions) ParseOption(option string) (bool, error) {
 		bOk = true
 	}
 
+	// UnmarshalBoolParsing
+	if value, err := UnmarshalBoolParsingFromString(option); err == nil {
+		if o.BoolParsing != UnmarshalBoolParsingUPUnspecified {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "UnmarshalBoolParsing", o.BoolParsing, value)
+		}
+		o.BoolParsing = value
+		bOk = true
+	}
+
+	// UnmarshalWhitespace
+	if value, err := UnmarshalWhitespaceFromString(option); err == nil {
+		if o.Whitespace != UnmarshalWhitespaceUWUnspecified {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "UnmarshalWhitespace", o.Whitespace, value)
+		}
+		o.Whitespace = value
+		bOk = true
+	}
+
+	// UnmarshalEmptyPointer
+	if name, ok := strings.CutPrefix(option, "emptyptr="); ok {
+		value, err := UnmarshalEmptyPointerFromString(name)
+		if err != nil || value == UnmarshalEmptyPointerEPUnspecified {
+			return false, fmt.Errorf("invalid empty pointer policy: %q", name)
+		}
+		if o.EmptyPointer != UnmarshalEmptyPointerEPUnspecified {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "UnmarshalEmptyPointer", o.EmptyPointer, value)
+		}
+		o.EmptyPointer = value
+		bOk = true
+	}
+
+	// DuplicateKeyPolicy
+	if name, ok := strings.CutPrefix(option, "dupkeys="); ok {
+		value, err := DuplicateKeyPolicyFromString(name)
+		if err != nil || value == DuplicateKeyPolicyDKUnspecified {
+			return false, fmt.Errorf("invalid duplicate key policy: %q", name)
+		}
+		if o.DuplicateKeys != DuplicateKeyPolicyDKUnspecified {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "DuplicateKeyPolicy", o.DuplicateKeys, value)
+		}
+		o.DuplicateKeys = value
+		bOk = true
+	}
+
+	// NumberParsing
+	if flag, ok := numberParsingOptions[option]; ok {
+		if o.NumberParsing&flag != 0 {
+			return false, fmt.Errorf("the %s option is specified more than once", option)
+		}
+		o.NumberParsing |= flag
+		bOk = true
+	}
+
+	// BindSource
+	if name, ok := strings.CutPrefix(option, "src="); ok {
+		value, err := BindSourceFromString(name)
+		if err != nil || value == BindSourceBSUnspecified {
+			return false, fmt.Errorf("invalid binding source: %q", name)
+		}
+		if o.Source != BindSourceBSUnspecified {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "BindSource", o.Source, value)
+		}
+		o.Source = value
+		bOk = true
+	}
+
+	// MaxItems
+	if s, ok := strings.CutPrefix(option, "maxitems="); ok {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return false, fmt.Errorf("invalid maximum number of items: %q", s)
+		}
+		if o.MaxItems != 0 {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "MaxItems", o.MaxItems, n)
+		}
+		o.MaxItems = n
+		bOk = true
+	}
+
+	// ReqMessage
+	if msg, ok := strings.CutPrefix(option, "reqmsg="); ok {
+		if msg == "" {
+			return false, fmt.Errorf("invalid required field message: %q", msg)
+		}
+		if o.ReqMessage != "" {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "ReqMessage", o.ReqMessage, msg)
+		}
+		o.ReqMessage = msg
+		bOk = true
+	}
+
+	// Requires
+	if key, ok := strings.CutPrefix(option, "requires="); ok {
+		if key == "" {
+			return false, fmt.Errorf("invalid required field key: %q", key)
+		}
+		if o.Requires != "" {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "Requires", o.Requires, key)
+		}
+		o.Requires = key
+		bOk = true
+	}
+
+	// RequiredWith
+	if key, ok := strings.CutPrefix(option, "requiredwith="); ok {
+		if key == "" {
+			return false, fmt.Errorf("invalid required with field key: %q", key)
+		}
+		if o.RequiredWith != "" {
+			return false, fmt.Errorf(fmtOptionNotUniqueError, "RequiredWith", o.RequiredWith, key)
+		}
+		o.RequiredWith = key
+		bOk = true
+	}
+
 	return bOk, nil
 }
 
@@ -73,6 +282,12 @@ func NewUndefinedUnmarshalTagOptions() *UnmarshalTagOptions {
 	return &UnmarshalTagOptions{
 		Presence:             UnmarshalPresenceUPUnspecified,
 		SliceValues:          UnmarshalSliceValuesUPUnspecified,
+		MapValues:            UnmarshalMapValuesMVUnspecified,
 		SliceUnexpectedValue: UnmarshalSliceUnexpectedValueUPUnspecified,
+		BoolParsing:          UnmarshalBoolParsingUPUnspecified,
+		Whitespace:           UnmarshalWhitespaceUWUnspecified,
+		EmptyPointer:         UnmarshalEmptyPointerEPUnspecified,
+		DuplicateKeys:        DuplicateKeyPolicyDKUnspecified,
+		Source:               BindSourceBSUnspecified,
 	}
 }
@@ -4,9 +4,12 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"net/url"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -43,6 +46,14 @@ type UEmbedded struct {
 	UEmbedded2
 }
 
+type UPaging struct {
+	Page int `qs:"page"`
+}
+
+type uSorting struct {
+	Sort string `qs:"sort"`
+}
+
 // UTypes is used by the TestMarshalTypes test to check the marshaling of all
 // supported types.
 type UTypes struct {
@@ -1345,6 +1356,229 @@ func TestUnmarshalSlice(t *testing.T) {
 	)
 }
 
+func TestUnmarshalSliceUnique(t *testing.T) {
+	vs := url.Values{
+		"a": {"1", "2", "1"},
+		"b": {"x,y", "y,z"},
+		"c": {"1", "1"},
+	}
+	var s struct {
+		A []int    `qs:"a,unique"`
+		B []string `qs:"b,unique,comma"`
+		C []int    `qs:"c"`
+	}
+	if err := UnmarshalValues(&s, vs); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(s.A, want) {
+		t.Errorf("got %v, want %v", s.A, want)
+	}
+	if want := []string{"x", "y", "z"}; !reflect.DeepEqual(s.B, want) {
+		t.Errorf("got %v, want %v", s.B, want)
+	}
+	if want := []int{1, 1}; !reflect.DeepEqual(s.C, want) {
+		t.Errorf("got %v, want %v", s.C, want)
+	}
+	if want := []string{"1", "2", "1"}; !reflect.DeepEqual(vs["a"], want) {
+		t.Errorf("the input has been modified: %v", vs["a"])
+	}
+
+	um := NewUnmarshaler(nil, WithUnmarshalOptionSliceDuplicates(OptionSliceDuplicatesUnique))
+	var m map[string][]string
+	if err := um.UnmarshalValues(&m, url.Values{"k": {"v", "v"}}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"v"}; !reflect.DeepEqual(m["k"], want) {
+		t.Errorf("got %v, want %v", m["k"], want)
+	}
+}
+
+func TestUnmarshalMaxSliceLen(t *testing.T) {
+	type query struct {
+		A []int `qs:"a"`
+		B []int `qs:"b,maxitems=3,comma"`
+	}
+	um := NewUnmarshaler(nil, WithUnmarshalMaxSliceLen(2))
+
+	tests := []struct {
+		query string
+		ok    bool
+	}{
+		{"a=1&a=2&b=1,2,3", true},
+		{"a=1&a=2&a=3", false},
+		{"b=1,2,3,4", false},
+		{"b=1&b=2,3", true},
+		{"b=1,2&b=3,4", false},
+	}
+	for _, tc := range tests {
+		var q query
+		err := um.Unmarshal(&q, tc.query)
+		if ok := err == nil; ok != tc.ok {
+			t.Errorf("%q :: got error %v, want success == %v", tc.query, err, tc.ok)
+		}
+	}
+
+	var q query
+	if err := Unmarshal(&q, "a=1&a=2&a=3&a=4"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	var m map[string][]int
+	if err := um.Unmarshal(&m, "x=1&x=2&x=3"); err == nil {
+		t.Error("unexpected success")
+	}
+
+	var n struct {
+		Phones []nestingPhone `schema:"phones,maxitems=2"`
+	}
+	schema := NewUnmarshaler(nil, WithUnmarshalSchemaCompat())
+	if err := schema.Unmarshal(&n, "phones.2.number=1"); err == nil {
+		t.Error("unexpected success")
+	}
+	if err := schema.Unmarshal(&n, "phones.1.number=1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	for _, tag := range []reflect.StructTag{`qs:"a,maxitems=0"`, `qs:"a,maxitems=x"`, `qs:"a,maxitems=1,maxitems=2"`} {
+		if _, err := parseFieldTag(tag, tagKey, NewUndefinedMarshalTagOptions(), NewUndefinedUnmarshalTagOptions(), NewUndefinedCommonTagOptions()); err == nil {
+			t.Errorf("%s :: unexpected success", tag)
+		}
+	}
+}
+
+func TestUnmarshalLimits(t *testing.T) {
+	um := NewUnmarshaler(nil,
+		WithUnmarshalMaxKeys(3),
+		WithUnmarshalMaxValueLen(4),
+		WithUnmarshalMaxNestingDepth(1),
+		WithUnmarshalNesting(NestingModeDotsIndexBrackets),
+		WithUnmarshalMaxSliceLen(2),
+	)
+
+	tests := []struct {
+		query string
+		limit string
+		key   string
+	}{
+		{"a=1&b=2&b=3", "", ""},
+		{"a=1&b=2&b=3&c=4", "MaxKeys", ""},
+		{"a=12345", "MaxValueLen", "a"},
+		{"x.y=1", "", ""},
+		{"x.y.z=1", "MaxNestingDepth", "x.y.z"},
+		{"x[0].y=1", "MaxNestingDepth", "x[0].y"},
+		{"a=1&a=2&a=3", "MaxSliceLen", ""},
+	}
+	for _, tc := range tests {
+		var m map[string][]string
+		err := um.Unmarshal(&m, tc.query)
+		if tc.limit == "" {
+			if err != nil {
+				t.Errorf("%q :: unexpected error: %v", tc.query, err)
+			}
+			continue
+		}
+		var le *LimitExceededError
+		if !errors.As(err, &le) {
+			t.Errorf("%q :: got error %v, want a LimitExceededError", tc.query, err)
+			continue
+		}
+		if le.Limit != tc.limit || le.Key != tc.key {
+			t.Errorf("%q :: got %+v, want limit %v and key %q", tc.query, le, tc.limit, tc.key)
+		}
+	}
+
+	var s struct {
+		A []int `qs:"a,maxitems=1"`
+	}
+	err := um.Unmarshal(&s, "a=1&a=2")
+	var le *LimitExceededError
+	if !errors.As(err, &le) || le.Limit != "maxitems" || le.Key != "a" {
+		t.Errorf("got error %v, want a maxitems LimitExceededError", err)
+	}
+
+	if err := NewUnmarshaler(nil, WithUnmarshalMaxNestingDepth(1)).Unmarshal(&s, "x.y.z=1"); err != nil {
+		t.Errorf("nesting is disabled, unexpected error: %v", err)
+	}
+}
+
+func TestUnmarshalLimitsBeforeParsing(t *testing.T) {
+	parsed := false
+	um := NewUnmarshaler(nil,
+		WithUnmarshalMaxKeys(3),
+		WithUnmarshalMaxValueLen(4),
+		WithCustomStringToUrlQueryParser(func(query string) (url.Values, error) {
+			parsed = true
+			return url.ParseQuery(query)
+		}),
+	)
+
+	tests := []struct {
+		query string
+		limit string
+		key   string
+	}{
+		{strings.Repeat("a=1&", 100000), "MaxKeys", ""},
+		{"a=1;b=2;c=3;d=4", "MaxKeys", ""},
+		{"a%20b=" + strings.Repeat("x", 1<<20), "MaxValueLen", "a b"},
+		{"a=%41%41%41%41%41", "MaxValueLen", "a"},
+		{"a=%41%41%41%41&&&b=1&c=2", "", ""},
+	}
+	for _, tc := range tests {
+		parsed = false
+		var m map[string][]string
+		err := um.Unmarshal(&m, tc.query)
+		if tc.limit == "" {
+			if err != nil || !parsed {
+				t.Errorf("%.20q :: unexpected error: %v", tc.query, err)
+			}
+			continue
+		}
+		var le *LimitExceededError
+		if !errors.As(err, &le) || le.Limit != tc.limit || le.Key != tc.key {
+			t.Errorf("%.20q :: got error %v, want limit %v and key %q", tc.query, err, tc.limit, tc.key)
+		}
+		if parsed {
+			t.Errorf("%.20q :: the query has been parsed", tc.query)
+		}
+	}
+}
+
+func TestUnmarshalLazyFields(t *testing.T) {
+	um := NewUnmarshaler(nil, WithUnmarshalLazyFields(true), WithUnmarshalNesting(NestingModeDots))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var q nestingQuery
+			if err := um.Unmarshal(&q, "name=n&address.city=x"); err != nil {
+				t.Error(err)
+				return
+			}
+			if q.Name != "n" || q.Address.City != "x" {
+				t.Errorf("unexpected result: %+v", q)
+			}
+		}()
+	}
+	wg.Wait()
+
+	type query struct {
+		A int
+		B chan int
+	}
+	if err := um.CheckUnmarshal(&query{}); err != nil {
+		t.Fatal(err)
+	}
+	var q query
+	if err := um.Unmarshal(&q, "a=1"); err == nil {
+		t.Error("unexpected success")
+	}
+	if err := CheckUnmarshal(&query{}); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
 type UIgnoredFields struct {
 	// unexported/private fields are ignored automatically.
 	unexported int
@@ -1422,3 +1656,1299 @@ func TestCheckUnmarshalType(t *testing.T) {
 		},
 	)
 }
+
+func TestTypedUnmarshaler(t *testing.T) {
+	type query struct {
+		Search string
+		Page   int
+	}
+
+	u, err := NewTypedUnmarshaler[query](DefaultUnmarshaler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := u.Unmarshal("page=2&search=a+b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Search != "a b" || q.Page != 2 {
+		t.Errorf("Unmarshal() == %+v", q)
+	}
+
+	pq, err := UnmarshalT[*query]("page=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pq == nil || pq.Page != 3 {
+		t.Errorf("UnmarshalT() == %+v", pq)
+	}
+
+	if _, err := NewTypedUnmarshaler[int](DefaultUnmarshaler); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestUnmarshalKeyPrefix(t *testing.T) {
+	type query struct {
+		Page  int
+		Items []string
+	}
+
+	unmarshaler := NewUnmarshaler(&UnmarshalerDefaultOptions{}, WithUnmarshalKeyPrefix("x_"), WithUnmarshalKeySuffix("_"))
+	var q query
+	err := unmarshaler.Unmarshal(&q, "page=5&x_page_=1&x_items_=a&x_items_=b&x_=c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cr comparisonResults
+	cr.compare("page", q.Page, 1)
+	cr.compare("items", q.Items, []string{"a", "b"})
+	if err := cr.finish(); err != nil {
+		t.Error(err)
+	}
+	if o := unmarshaler.Options(); o.KeyPrefix != "x_" || o.KeySuffix != "_" {
+		t.Errorf("KeyPrefix == %q, KeySuffix == %q", o.KeyPrefix, o.KeySuffix)
+	}
+
+	// The options struct sets the same prefix.
+	unmarshaler = NewUnmarshaler(&UnmarshalerDefaultOptions{KeyPrefix: "x_myapi_"})
+	q = query{}
+	if err := unmarshaler.Unmarshal(&q, "page=5&x_myapi_page=1"); err != nil {
+		t.Fatal(err)
+	}
+	if q.Page != 1 {
+		t.Errorf("Page == %v, want 1", q.Page)
+	}
+}
+
+func TestCompileUnmarshalType(t *testing.T) {
+	type query struct {
+		Page int
+	}
+
+	unmarshaler := NewUnmarshaler(&UnmarshalerDefaultOptions{})
+	vum, err := unmarshaler.CompileType(reflect.TypeOf(query{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var q query
+	err = vum.UnmarshalValues(reflect.ValueOf(&q).Elem(), url.Values{"page": {"3"}}, unmarshaler.Options())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Page != 3 {
+		t.Errorf("Page == %v, want 3", q.Page)
+	}
+
+	if _, err := unmarshaler.CompileType(reflect.TypeOf(UNonMarshalable{})); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestUnmarshalerFactoryPrecedence(t *testing.T) {
+	setter := func(result string) PrimitiveUnmarshalerFunc {
+		return func(v reflect.Value, s string, opts *UnmarshalOptions) error {
+			v.Set(reflect.ValueOf(result).Convert(v.Type()))
+			return nil
+		}
+	}
+
+	type query struct {
+		QS  UQSBytes
+		Str string
+	}
+
+	// A kind override doesn't take precedence over UnmarshalQS.
+	um := NewUnmarshaler(&UnmarshalerDefaultOptions{})
+	if err := um.RegisterKindOverride(reflect.Slice, setter("kind")); err != nil {
+		t.Fatal(err)
+	}
+	if err := um.RegisterKindOverride(reflect.String, setter("kind")); err != nil {
+		t.Fatal(err)
+	}
+	var q query
+	if err := um.Unmarshal(&q, "qs=00&str=woof"); err != nil {
+		t.Fatal(err)
+	}
+	if string(q.QS) != "\x00" || q.Str != "kind" {
+		t.Errorf("unexpected result: %q", q)
+	}
+
+	// A custom type takes precedence over UnmarshalQS.
+	if err := um.RegisterCustomType(reflect.TypeOf(UQSBytes{}), func(v reflect.Value, s string, opts *UnmarshalOptions) error {
+		v.SetBytes([]byte("custom"))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := um.Unmarshal(&q, "qs=00&str=woof"); err != nil {
+		t.Fatal(err)
+	}
+	if string(q.QS) != "custom" {
+		t.Errorf("unexpected result: %q", q)
+	}
+
+	// A kind override takes precedence over the builtin types.
+	type timeQuery struct {
+		Time time.Time
+	}
+	um = NewUnmarshaler(&UnmarshalerDefaultOptions{})
+	if err := um.RegisterKindOverride(reflect.Struct, func(v reflect.Value, s string, opts *UnmarshalOptions) error {
+		v.Set(reflect.ValueOf(time.Unix(0, 0).UTC()))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var tq timeQuery
+	if err := um.Unmarshal(&tq, "time=woof"); err != nil {
+		t.Fatal(err)
+	}
+	if !tq.Time.Equal(time.Unix(0, 0)) {
+		t.Errorf("unexpected result: %v", tq.Time)
+	}
+}
+
+func TestUnmarshalerWith(t *testing.T) {
+	type query struct {
+		Page int
+	}
+	tp := reflect.TypeOf(query{})
+
+	um := NewUnmarshaler(&UnmarshalerDefaultOptions{})
+	derived := um.With(WithUnmarshalKeyPrefix("x_"))
+	required := um.With(WithUnmarshalPresence(UnmarshalPresenceReq))
+
+	var q query
+	if err := derived.Unmarshal(&q, "x_page=2&page=3"); err != nil {
+		t.Fatal(err)
+	}
+	if q.Page != 2 {
+		t.Errorf("Page == %v, want 2", q.Page)
+	}
+	if err := required.Unmarshal(&q, ""); err == nil {
+		t.Error("unexpected success")
+	}
+	if err := um.Unmarshal(&q, ""); err != nil {
+		t.Error(err)
+	}
+
+	compile := func(um *QSUnmarshaler) ValuesUnmarshaler {
+		vum, err := um.CompileType(tp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return vum
+	}
+	if compile(um) != compile(derived) {
+		t.Error("expected the derived unmarshaler to share the compiled ValuesUnmarshaler")
+	}
+	if compile(um) == compile(required) {
+		t.Error("expected a different ValuesUnmarshaler with different defaults")
+	}
+}
+
+func TestUnmarshalWith(t *testing.T) {
+	type query struct {
+		Page int
+	}
+
+	var q query
+	if err := UnmarshalWith(&q, "", WithUnmarshalPresence(UnmarshalPresenceReq)); err == nil {
+		t.Error("unexpected success")
+	}
+	if err := Unmarshal(&q, ""); err != nil {
+		t.Error(err)
+	}
+	if err := UnmarshalValuesWith(&q, url.Values{"x_page": {"2"}}, WithUnmarshalKeyPrefix("x_")); err != nil {
+		t.Fatal(err)
+	}
+	if q.Page != 2 {
+		t.Errorf("Page == %v, want 2", q.Page)
+	}
+}
+
+func TestNewUnmarshalerWithOptionAppliers(t *testing.T) {
+	type query struct {
+		PageSize int
+		Tags     []string
+	}
+
+	um := NewUnmarshaler(nil,
+		WithUnmarshalNameTransformer(strings.ToUpper),
+		WithUnmarshalerFactory(newUnmarshalerFactory()),
+		WithValuesUnmarshalerFactory(newValuesUnmarshalerFactory()),
+		WithUnmarshalTagOptionsDefaults(UnmarshalTagOptions{Presence: UnmarshalPresenceNil}),
+		WithUnmarshalTagCommonOptionsDefaults(CommonTagOptions{SliceSeparator: OptionSliceSeparatorComma}),
+		WithCustomSliceToStringFunc(func(a []string) (string, error) {
+			return a[len(a)-1], nil
+		}),
+	)
+	var q query
+	if err := um.Unmarshal(&q, "PAGESIZE=1&PAGESIZE=2&TAGS=a,b"); err != nil {
+		t.Fatal(err)
+	}
+	if q.PageSize != 2 || !reflect.DeepEqual(q.Tags, []string{"a", "b"}) {
+		t.Errorf("unexpected result: %+v", q)
+	}
+	q = query{}
+	if err := um.Unmarshal(&q, ""); err != nil {
+		t.Fatal(err)
+	}
+	if q.Tags != nil {
+		t.Errorf("Tags == %#v, want nil", q.Tags)
+	}
+}
+
+func TestUnmarshalBoolParsing(t *testing.T) {
+	type query struct {
+		Debug   bool `qs:",lenientbool"`
+		Verbose bool
+	}
+
+	for _, s := range []string{"debug", "debug=", "debug=YES", "debug=on", "debug=1", "debug=T"} {
+		var q query
+		if err := Unmarshal(&q, s); err != nil {
+			t.Errorf("%q :: %v", s, err)
+			continue
+		}
+		if !q.Debug {
+			t.Errorf("%q :: Debug == false", s)
+		}
+	}
+	q := query{Debug: true}
+	if err := Unmarshal(&q, "debug=Off"); err != nil || q.Debug {
+		t.Errorf("Debug == %v, err == %v", q.Debug, err)
+	}
+	if err := Unmarshal(&q, "debug=maybe"); err == nil {
+		t.Error("unexpected success")
+	}
+	if err := Unmarshal(&q, "verbose=yes"); err == nil {
+		t.Error("unexpected success")
+	}
+
+	um := NewUnmarshaler(nil, WithUnmarshalBoolParsing(UnmarshalBoolParsingLenientBool))
+	if err := um.Unmarshal(&q, "verbose=yes"); err != nil || !q.Verbose {
+		t.Errorf("Verbose == %v, err == %v", q.Verbose, err)
+	}
+}
+
+func TestUnmarshalBoolFormatRoundTrip(t *testing.T) {
+	type query struct {
+		A bool `qs:"a,yesno"`
+		B bool `qs:"b,onoff"`
+		C bool `qs:"c,onezero"`
+		D bool `qs:"d,yesno"`
+	}
+
+	want := query{A: true, B: true, C: true}
+	s, err := Marshal(&want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "a=yes&b=on&c=1&d=no" {
+		t.Errorf("got %q", s)
+	}
+	got := query{D: true}
+	if err := Unmarshal(&got, s); err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// Only the values of the format of the field are accepted.
+	if err := Unmarshal(&got, "a=on"); err == nil {
+		t.Error("unexpected success")
+	}
+	if err := Unmarshal(&got, "b=YES"); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestUnmarshalNumberParsing(t *testing.T) {
+	type query struct {
+		Amount  int64   `qs:"amount,lenientnum"`
+		Count   uint    `qs:"count,lenientnum,emptyzero"`
+		Small   int8    `qs:"small,clamp"`
+		Size    uint16  `qs:"size,clamp"`
+		Ratio   float32 `qs:"ratio,clamp,emptyzero"`
+		Default int     `qs:"default"`
+	}
+
+	var q query
+	err := Unmarshal(&q, "amount=-1,000,000&count=%2B1_000&small=300&size=-5&ratio=1e100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := query{Amount: -1000000, Count: 1000, Small: 127, Size: 0, Ratio: math.MaxFloat32}
+	if q != want {
+		t.Errorf("got %+v, want %+v", q, want)
+	}
+
+	q = query{Count: 1, Ratio: 1}
+	if err := Unmarshal(&q, "count=&ratio=&small=-300"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (query{Small: -128}); q != want {
+		t.Errorf("got %+v, want %+v", q, want)
+	}
+
+	for _, s := range []string{"amount=1,,000", "amount=,1", "small=", "default=1,000", "default=300000000000000000000"} {
+		if err := Unmarshal(&q, s); err == nil {
+			t.Errorf("%q :: unexpected success", s)
+		}
+	}
+
+	um := NewUnmarshaler(nil, WithUnmarshalNumberParsing(NumberParsingLenient|NumberParsingEmptyAsZero))
+	q = query{Default: 1}
+	if err := um.Unmarshal(&q, "default=1_000"); err != nil || q.Default != 1000 {
+		t.Errorf("Default == %v, err == %v", q.Default, err)
+	}
+	if err := um.Unmarshal(&q, "default="); err != nil || q.Default != 0 {
+		t.Errorf("Default == %v, err == %v", q.Default, err)
+	}
+
+	var bad struct {
+		A int `qs:"a,clamp,clamp"`
+	}
+	if err := Unmarshal(&bad, "a=1"); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestUnmarshalWhitespace(t *testing.T) {
+	type query struct {
+		Count int    `qs:"count,trim"`
+		IDs   []int  `qs:"ids,trim,comma"`
+		Name  string `qs:"name"`
+		Raw   string `qs:"raw,notrim"`
+	}
+
+	var q query
+	if err := Unmarshal(&q, "count=5%20&ids=1,%202&name=%20a%20&raw=%20b"); err != nil {
+		t.Fatal(err)
+	}
+	want := query{Count: 5, IDs: []int{1, 2}, Name: " a ", Raw: " b"}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("got %+v, want %+v", q, want)
+	}
+
+	um := NewUnmarshaler(nil, WithUnmarshalWhitespace(UnmarshalWhitespaceTrim))
+	q = query{}
+	if err := um.Unmarshal(&q, "name=%20a%20&raw=%20b"); err != nil {
+		t.Fatal(err)
+	}
+	if q.Name != "a" || q.Raw != " b" {
+		t.Errorf("Name == %q, Raw == %q", q.Name, q.Raw)
+	}
+}
+
+func TestUnmarshalReqMessage(t *testing.T) {
+	type Paging struct {
+		Page int `qs:"page,req,reqmsg=page is mandatory"`
+	}
+	type query struct {
+		UserID int    `qs:"user_id,req,reqmsg=user_id is mandatory"`
+		Name   string `qs:"name,req"`
+		Paging
+	}
+
+	var q query
+	err := Unmarshal(&q, "name=a&page=1")
+	re, ok := err.(*ReqError)
+	if !ok {
+		t.Fatalf("got %v, want a *ReqError", err)
+	}
+	if re.Error() != "user_id is mandatory" || re.CustomMessage != "user_id is mandatory" || re.FieldName != "user_id" {
+		t.Errorf("got %+v", re)
+	}
+
+	err = Unmarshal(&q, "user_id=1&page=1")
+	if re, ok := err.(*ReqError); !ok || re.CustomMessage != "" || re.FieldName != "name" {
+		t.Errorf("got %#v", err)
+	}
+
+	err = Unmarshal(&q, "user_id=1&name=a")
+	if re, ok := err.(*ReqError); !ok || re.CustomMessage != "page is mandatory" {
+		t.Errorf("got %#v", err)
+	}
+}
+
+func TestUnmarshalRequires(t *testing.T) {
+	type query struct {
+		From   string `qs:"from,requires=to"`
+		To     string `qs:"to"`
+		Lat    string `qs:"lat"`
+		Lon    string `qs:"lon,requiredwith=lat"`
+		Cursor string `qs:"cursor,requires=limit,requiredwith=limit"`
+		Limit  int    `qs:"limit"`
+	}
+
+	tests := []struct {
+		query  string
+		fields []string
+	}{
+		{"", nil},
+		{"to=2", nil},
+		{"from=1&to=2", nil},
+		{"lon=2", nil},
+		{"from=1", []string{"to"}},
+		{"lat=1", []string{"lon"}},
+		{"from=1&lat=1", []string{"to", "lon"}},
+		{"cursor=c", []string{"limit"}},
+		{"limit=10", []string{"cursor"}},
+	}
+	for _, tc := range tests {
+		var q query
+		err := Unmarshal(&q, tc.query)
+		if tc.fields == nil {
+			if err != nil {
+				t.Errorf("%q: unexpected error: %v", tc.query, err)
+			}
+			continue
+		}
+		var re *ReqError
+		if !errors.As(err, &re) || !reflect.DeepEqual(re.Fields, tc.fields) {
+			t.Errorf("%q: got %v, want the missing fields %q", tc.query, err, tc.fields)
+		}
+	}
+
+	var q query
+	if err := Unmarshal(&q, "from=1"); err == nil || err.Error() != `missing field "to" required by field "from" in struct qs.query` {
+		t.Errorf("got %v", err)
+	}
+
+	type unknown struct {
+		From string `qs:"from,requires=until"`
+	}
+	if err := Unmarshal(&unknown{}, "from=1"); err == nil || !strings.Contains(err.Error(), `unknown field "until"`) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestUnmarshalReqErrorFields(t *testing.T) {
+	type Paging struct {
+		Page int `qs:"page,req"`
+	}
+	type address struct {
+		City string `qs:"city,req"`
+		Zip  string `qs:"zip,req"`
+	}
+	type query struct {
+		ID      int       `qs:"id,req"`
+		Name    string    `qs:"name,req"`
+		Limit   int       `qs:"limit"`
+		Address address   `qs:"address"`
+		Items   []address `qs:"items"`
+		Paging
+	}
+	um := NewUnmarshaler(nil, WithUnmarshalNesting(NestingModeDotsIndexBrackets))
+
+	tests := []struct {
+		query  string
+		fields []string
+	}{
+		{"id=1&name=a&address.city=a&address.zip=1&page=1", nil},
+		{"name=a&address.city=a&address.zip=1&page=1", []string{"id"}},
+		{"limit=1&address.zip=1", []string{"id", "name", "address.city", "page"}},
+		{"id=1&name=a&address.city=a&address.zip=1&items[0].zip=1", []string{"items[0].city", "page"}},
+	}
+	for _, tc := range tests {
+		var q query
+		err := um.Unmarshal(&q, tc.query)
+		if tc.fields == nil {
+			if err != nil {
+				t.Errorf("%q :: unexpected error: %v", tc.query, err)
+			}
+			continue
+		}
+		re, ok := err.(*ReqError)
+		if !ok {
+			t.Errorf("%q :: got %v, want a *ReqError", tc.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(re.Fields, tc.fields) {
+			t.Errorf("%q :: got fields %q, want %q", tc.query, re.Fields, tc.fields)
+		}
+	}
+
+	var q query
+	err := um.Unmarshal(&q, "limit=x")
+	if _, ok := err.(*ReqError); ok || err == nil {
+		t.Errorf("got %v, want the error of the invalid limit", err)
+	}
+
+	err = um.Unmarshal(&q, "address.city=a&address.zip=1&page=1")
+	if want := `missing required fields "id", "name"`; err == nil || err.Error() != want {
+		t.Errorf("got %v, want %v", err, want)
+	}
+}
+
+func TestUnmarshalNullValue(t *testing.T) {
+	type query struct {
+		Parent *int    `qs:"parent,null=none"`
+		Name   *string `qs:"name"`
+	}
+
+	parent := 5
+	q := query{Parent: &parent}
+	if err := Unmarshal(&q, "parent=none&name=none"); err != nil {
+		t.Fatal(err)
+	}
+	if q.Parent != nil {
+		t.Errorf("Parent == %v, want nil", *q.Parent)
+	}
+	if q.Name == nil || *q.Name != "none" {
+		t.Errorf("Name == %v, want a pointer to \"none\"", q.Name)
+	}
+
+	um := NewUnmarshaler(nil, WithUnmarshalNullValue("null"))
+	if err := um.Unmarshal(&q, "parent=6&name=null"); err != nil {
+		t.Fatal(err)
+	}
+	if q.Parent == nil || *q.Parent != 6 || q.Name != nil {
+		t.Errorf("Parent == %v, Name == %v", q.Parent, q.Name)
+	}
+}
+
+func TestUnmarshalEmptyPointer(t *testing.T) {
+	type query struct {
+		Name    *string `qs:"name"`
+		Nick    *string `qs:"nick,emptyptr=nil"`
+		Age     *int    `qs:"age,emptyptr=nil"`
+		Country *string `qs:"country,emptyptr=error"`
+	}
+
+	nick := "old"
+	q := query{Nick: &nick}
+	if err := Unmarshal(&q, "name=&nick=&age="); err != nil {
+		t.Fatal(err)
+	}
+	if q.Name == nil || *q.Name != "" {
+		t.Errorf("Name == %v, want a pointer to an empty string", q.Name)
+	}
+	if q.Nick != nil || q.Age != nil {
+		t.Errorf("Nick == %v, Age == %v, want nil", q.Nick, q.Age)
+	}
+
+	if err := Unmarshal(&q, "country="); err == nil {
+		t.Error("unexpected success")
+	}
+	if err := Unmarshal(&q, "country=hu&nick=x"); err != nil || *q.Country != "hu" || *q.Nick != "x" {
+		t.Errorf("Country == %v, Nick == %v, err == %v", q.Country, q.Nick, err)
+	}
+
+	um := NewUnmarshaler(nil, WithUnmarshalEmptyPointer(UnmarshalEmptyPointerNil))
+	q = query{}
+	if err := um.Unmarshal(&q, "name="); err != nil || q.Name != nil {
+		t.Errorf("Name == %v, err == %v", q.Name, err)
+	}
+}
+
+func TestUnmarshalBigTypes(t *testing.T) {
+	type query struct {
+		Int   *big.Int
+		Float *big.Float
+		Rat   *big.Rat
+		Value big.Int
+	}
+
+	var q query
+	if err := Unmarshal(&q, "int=123456789012345678901234567890&float=1.5&rat=0.25&value=0x10"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Int.String(), "123456789012345678901234567890"; got != want {
+		t.Errorf("Int == %v, want %v", got, want)
+	}
+	if got, want := q.Float.Text('g', -1), "1.5"; got != want {
+		t.Errorf("Float == %v, want %v", got, want)
+	}
+	if got, want := q.Rat.RatString(), "1/4"; got != want {
+		t.Errorf("Rat == %v, want %v", got, want)
+	}
+	if got, want := q.Value.Int64(), int64(16); got != want {
+		t.Errorf("Value == %v, want %v", got, want)
+	}
+
+	for _, s := range []string{"int=1.5", "float=x", "rat=1/0"} {
+		if err := Unmarshal(&q, s); err == nil {
+			t.Errorf("%q :: unexpected success", s)
+		}
+	}
+}
+
+func TestUnmarshalHook(t *testing.T) {
+	type query struct {
+		A int
+	}
+
+	var events []HookEvent
+	um := NewUnmarshaler(nil, WithUnmarshalHook(func(e HookEvent) { events = append(events, e) }))
+
+	var q query
+	if err := um.Unmarshal(&q, "a=1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := um.Unmarshal(&q, "a=%zz"); err == nil {
+		t.Error("unexpected success")
+	}
+	if err := um.UnmarshalValues(&q, url.Values{"a": {"x"}}); err == nil {
+		t.Error("unexpected success")
+	}
+	tum, err := NewTypedUnmarshaler[query](um)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tum.Unmarshal("a=2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 4 {
+		t.Fatalf("got %v events, want 4", len(events))
+	}
+	for i, e := range events {
+		if e.Type != reflect.TypeOf(query{}) {
+			t.Errorf("events[%v].Type == %v", i, e.Type)
+		}
+		if (e.Err != nil) != (i == 1 || i == 2) {
+			t.Errorf("events[%v].Err == %v", i, e.Err)
+		}
+	}
+}
+
+// UQSMap implements the UnmarshalQS interface with a value receiver.
+type UQSMap map[string]bool
+
+func (m UQSMap) UnmarshalQS(a []string, opts *UnmarshalOptions) error {
+	for _, s := range a {
+		m[s] = true
+	}
+	return nil
+}
+
+func TestUnmarshalQSReceivers(t *testing.T) {
+	type query struct {
+		Ptr    UQSBytes
+		PtrPtr *UQSBytes
+		Value  UQSMap
+	}
+
+	q := query{Value: UQSMap{}}
+	if err := Unmarshal(&q, "ptr=01&ptr_ptr=02&value=a&value=b"); err != nil {
+		t.Fatal(err)
+	}
+	want := query{
+		Ptr:    UQSBytes{1},
+		PtrPtr: &UQSBytes{2},
+		Value:  UQSMap{"a": true, "b": true},
+	}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("got %+v, want %+v", q, want)
+	}
+}
+
+func TestUnmarshalURL(t *testing.T) {
+	type query struct {
+		A int
+		B string
+	}
+
+	u, err := url.Parse("https://example.com/search?a=1&b=x+y#top")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var q query
+	if err := UnmarshalURL(&q, u); err != nil {
+		t.Fatal(err)
+	}
+	if want := (query{A: 1, B: "x y"}); q != want {
+		t.Errorf("got %+v, want %+v", q, want)
+	}
+
+	if err := UnmarshalURL(&q, nil); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestLenientQueryParsing(t *testing.T) {
+	type query struct {
+		A string
+		B string
+		C []string
+		D *string
+	}
+	const qs = "a=1&b=%zz&c=x&c=%2&=orphan&x;y=1&d&c=y"
+
+	var q query
+	if err := Unmarshal(&q, qs); err == nil {
+		t.Error("unexpected success of the strict parser")
+	}
+
+	var skipped []string
+	um := NewUnmarshaler(nil, WithLenientQueryParsing(func(pair string, err error) {
+		if err == nil {
+			t.Errorf("%q :: nil error", pair)
+		}
+		skipped = append(skipped, pair)
+	}))
+	q = query{}
+	if err := um.Unmarshal(&q, qs); err != nil {
+		t.Fatal(err)
+	}
+	if want := (query{A: "1", C: []string{"x", "y"}, D: new(string)}); !reflect.DeepEqual(q, want) {
+		t.Errorf("got %+v, want %+v", q, want)
+	}
+	if want := []string{"b=%zz", "c=%2", "=orphan", "x;y=1"}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped %q, want %q", skipped, want)
+	}
+
+	if err := NewUnmarshaler(nil, WithLenientQueryParsing(nil)).Unmarshal(&q, "a=%"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUnmarshalPairs(t *testing.T) {
+	type query struct {
+		A []int
+		B string
+		C string `qs:",req"`
+	}
+
+	var q query
+	err := UnmarshalPairs(&q, []KV{{"a", "3"}, {"b", "x y"}, {"a", "1"}, {"c", ""}, {"a", "2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (query{A: []int{3, 1, 2}, B: "x y"}); !reflect.DeepEqual(q, want) {
+		t.Errorf("got %+v, want %+v", q, want)
+	}
+
+	err = UnmarshalPairs(&q, []KV{{"a", "1"}})
+	if _, ok := IsRequiredFieldError(err); !ok {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestUnmarshalInline(t *testing.T) {
+	type Paging struct {
+		Page  int `qs:"page"`
+		Limit int `qs:"limit"`
+	}
+	type Query struct {
+		Search string  `qs:"q"`
+		Paging Paging  `qs:",inline"`
+		Extra  *Paging `qs:",inline"`
+	}
+
+	for _, um := range []*QSUnmarshaler{DefaultUnmarshaler, NewUnmarshaler(nil, WithUnmarshalLazyFields(true))} {
+		var q Query
+		if err := um.Unmarshal(&q, "q=x&page=2&limit=10"); err != nil {
+			t.Fatal(err)
+		}
+		want := Query{Search: "x", Paging: Paging{Page: 2, Limit: 10}, Extra: &Paging{Page: 2, Limit: 10}}
+		if !reflect.DeepEqual(q, want) {
+			t.Errorf("got %+v, want %+v", q, want)
+		}
+	}
+}
+
+type testFilterUnmarshaler struct{}
+
+func (testFilterUnmarshaler) UnmarshalValues(v reflect.Value, vs url.Values, opts *UnmarshalerDefaultOptions) error {
+	for k, a := range vs {
+		rest, ok := strings.CutPrefix(k, "filter[")
+		if !ok {
+			continue
+		}
+		field, op, ok := strings.Cut(strings.TrimSuffix(rest, "]"), "][")
+		if !ok {
+			return fmt.Errorf("invalid filter key: %q", k)
+		}
+		v.Set(reflect.ValueOf(testFilter{Field: field, Op: op, Value: a[0]}))
+	}
+	return nil
+}
+
+func TestUnmarshalValuesCustomType(t *testing.T) {
+	type Query struct {
+		Search string      `qs:"q"`
+		Filter *testFilter `qs:"filter"`
+	}
+
+	um := NewUnmarshaler(nil)
+	err := um.RegisterValuesCustomType(reflect.TypeFor[testFilter](), func(t reflect.Type, opts *UnmarshalerDefaultOptions) (ValuesUnmarshaler, error) {
+		return testFilterUnmarshaler{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var q Query
+	if err := um.Unmarshal(&q, "q=x&filter[age][gt]=18"); err != nil {
+		t.Fatal(err)
+	}
+	want := Query{Search: "x", Filter: &testFilter{Field: "age", Op: "gt", Value: "18"}}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("got %+v, want %+v", q, want)
+	}
+
+	if err := um.Unmarshal(&q, "filter[age]=18"); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestUnmarshalDynamicMap(t *testing.T) {
+	tests := []struct {
+		um    *QSUnmarshaler
+		query string
+		want  map[string]interface{}
+	}{
+		{
+			DefaultUnmarshaler,
+			"a=1&b=2&b=3&c.d=4",
+			map[string]interface{}{"a": "1", "b": []string{"2", "3"}, "c.d": "4"},
+		},
+		{
+			NewUnmarshaler(nil, WithUnmarshalNesting(NestingModeDots)),
+			"a=1&c.d=4&c.e.f=5",
+			map[string]interface{}{"a": "1", "c": map[string]interface{}{"d": "4", "e": map[string]interface{}{"f": "5"}}},
+		},
+		{
+			NewUnmarshaler(nil, WithUnmarshalRackCompat()),
+			"tags[]=x&items[0][id]=7&items[1][id]=8&q=a&q[]=b",
+			map[string]interface{}{
+				"tags":  []string{"x"},
+				"items": map[string]interface{}{"0": map[string]interface{}{"id": "7"}, "1": map[string]interface{}{"id": "8"}},
+				"q":     []string{"a", "b"},
+			},
+		},
+	}
+	for _, tc := range tests {
+		var m map[string]interface{}
+		if err := tc.um.Unmarshal(&m, tc.query); err != nil {
+			t.Errorf("query %q: %v", tc.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(m, tc.want) {
+			t.Errorf("query %q: got %v, want %v", tc.query, m, tc.want)
+		}
+	}
+
+	type Query struct {
+		Extra map[string]any `qs:"extra"`
+	}
+	um := NewUnmarshaler(nil, WithUnmarshalRackCompat())
+	var q Query
+	if err := um.Unmarshal(&q, "extra[a]=1&extra[b][c]=2"); err != nil {
+		t.Fatal(err)
+	}
+	want := Query{Extra: map[string]any{"a": "1", "b": map[string]interface{}{"c": "2"}}}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("got %v, want %v", q, want)
+	}
+
+	var m map[string]interface{}
+	if err := um.Unmarshal(&m, "x=1&x[y]=2"); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestUnmarshalMapKeyTypes(t *testing.T) {
+	var m map[int]string
+	if err := Unmarshal(&m, "1=a&2=b"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (map[int]string{1: "a", 2: "b"}); !reflect.DeepEqual(m, want) {
+		t.Errorf("got %v, want %v", m, want)
+	}
+	if err := Unmarshal(&m, "x=a"); err == nil {
+		t.Error("unexpected success")
+	}
+
+	type Color string
+	um := NewUnmarshaler(nil)
+	err := um.RegisterMapKeyType(reflect.TypeFor[Color](), func(v reflect.Value, s string, opts *UnmarshalOptions) error {
+		v.SetString(strings.ToLower(s))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var colors map[Color]int
+	if err := um.Unmarshal(&colors, "Red=1&BLUE=2"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (map[Color]int{"red": 1, "blue": 2}); !reflect.DeepEqual(colors, want) {
+		t.Errorf("got %v, want %v", colors, want)
+	}
+}
+
+func TestUnmarshalMapValues(t *testing.T) {
+	m := map[string]int{"a": 1, "b": 2}
+	if err := Unmarshal(&m, "b=3&c=4"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (map[string]int{"a": 1, "b": 3, "c": 4}); !reflect.DeepEqual(m, want) {
+		t.Errorf("got %v, want %v", m, want)
+	}
+
+	um := NewUnmarshaler(nil, WithUnmarshalMapValues(UnmarshalMapValuesOverrideOld))
+	m = map[string]int{"a": 1, "b": 2}
+	if err := um.Unmarshal(&m, "b=3&c=4"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (map[string]int{"b": 3, "c": 4}); !reflect.DeepEqual(m, want) {
+		t.Errorf("got %v, want %v", m, want)
+	}
+
+	type query struct {
+		Merged   map[string]string `qs:"merged"`
+		Replaced map[string]string `qs:"replaced,overrideold"`
+		Missing  map[string]string `qs:"missing,overrideold"`
+	}
+	q := query{
+		Merged:   map[string]string{"a": "1"},
+		Replaced: map[string]string{"a": "1"},
+		Missing:  map[string]string{"a": "1"},
+	}
+	um = NewUnmarshaler(nil, WithUnmarshalNesting(NestingModeDots))
+	if err := um.Unmarshal(&q, "merged.b=2&replaced.b=2"); err != nil {
+		t.Fatal(err)
+	}
+	want := query{
+		Merged:   map[string]string{"a": "1", "b": "2"},
+		Replaced: map[string]string{"b": "2"},
+		Missing:  map[string]string{"a": "1"},
+	}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("got %v, want %v", q, want)
+	}
+}
+
+func TestUnmarshalMapEntries(t *testing.T) {
+	n := 1
+	m := map[string]*int{"a": &n}
+	if err := Unmarshal(&m, "a=2&b=3"); err != nil {
+		t.Fatal(err)
+	}
+	if m["a"] != &n || n != 2 || m["b"] == nil || *m["b"] != 3 {
+		t.Errorf("got %v and a=%v", m, n)
+	}
+
+	um := NewUnmarshaler(nil,
+		WithUnmarshalNesting(NestingModeDots),
+		WithUnmarshalRequiredMapKeys("from", "to"),
+	)
+	var r map[string]string
+	if err := um.Unmarshal(&r, "from=a&to=b&via=c"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	r = nil
+	err := um.Unmarshal(&r, "via=c")
+	re, ok := err.(*ReqError)
+	if !ok || !reflect.DeepEqual(re.Fields, []string{"from", "to"}) {
+		t.Errorf("got %v, want a ReqError of from and to", err)
+	}
+	if r != nil {
+		t.Errorf("got %v, want nil map", r)
+	}
+
+	var q struct {
+		Route map[string]string `qs:"route"`
+	}
+	err = um.Unmarshal(&q, "route.from=a")
+	if re, ok := err.(*ReqError); !ok || !reflect.DeepEqual(re.Fields, []string{"route.to"}) {
+		t.Errorf("got %#v, want a ReqError of route.to", err)
+	}
+
+	var d map[string]interface{}
+	if _, ok := IsRequiredFieldError(um.Unmarshal(&d, "to=b")); !ok {
+		t.Error("want a required field error")
+	}
+}
+
+func TestUnmarshalDuplicateKeys(t *testing.T) {
+	type query struct {
+		Default int    `qs:"default"`
+		First   int    `qs:"first,dupkeys=first"`
+		Last    string `qs:"last,dupkeys=last"`
+		Join    string `qs:"join,dupkeys=join"`
+		JoinSep string `qs:"join_sep,dupkeys=join,sep=|"`
+	}
+
+	var q query
+	if err := Unmarshal(&q, "default=1&default=2"); err == nil {
+		t.Error("unexpected success")
+	}
+
+	q = query{}
+	if err := Unmarshal(&q, "default=1&first=1&first=2&last=a&last=b&join=a&join=b&join_sep=a&join_sep=b"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (query{Default: 1, First: 1, Last: "b", Join: "a,b", JoinSep: "a|b"}); q != want {
+		t.Errorf("got %+v, want %+v", q, want)
+	}
+
+	um := NewUnmarshaler(nil, WithUnmarshalDuplicateKeyPolicy(DuplicateKeyPolicyLast))
+	q = query{}
+	if err := um.Unmarshal(&q, "default=1&default=2&first=1&first=2"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (query{Default: 2, First: 1}); q != want {
+		t.Errorf("got %+v, want %+v", q, want)
+	}
+
+	var bad struct {
+		A int `qs:"a,dupkeys=random"`
+	}
+	if err := Unmarshal(&bad, "a=1"); err == nil {
+		t.Error("unexpected success")
+	}
+}
+
+func TestUnmarshalBracketsSliceKeys(t *testing.T) {
+	type query struct {
+		IDs  []int    `qs:"ids,brackets,nil"`
+		Tags []string `qs:"tags,nil"`
+	}
+
+	tests := []struct {
+		query string
+		want  query
+	}{
+		{"ids[]=1&ids[]=2", query{IDs: []int{1, 2}}},
+		{"ids=1&ids=2", query{IDs: []int{1, 2}}},
+		{"ids=1&ids[]=2&ids[]=3", query{IDs: []int{1, 2, 3}}},
+		{"tags[]=a", query{}},
+	}
+	for _, tc := range tests {
+		var q query
+		if err := Unmarshal(&q, tc.query); err != nil {
+			t.Errorf("%q :: %v", tc.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(q, tc.want) {
+			t.Errorf("%q :: got %+v, want %+v", tc.query, q, tc.want)
+		}
+	}
+
+	var q query
+	um := NewUnmarshaler(nil, WithUnmarshalOptionSliceKeys(OptionSliceKeysBrackets))
+	if err := um.Unmarshal(&q, "tags[]=a&tags=b"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"b", "a"}; !reflect.DeepEqual(q.Tags, want) {
+		t.Errorf("got %q, want %q", q.Tags, want)
+	}
+
+	m := NewMarshaler(nil, WithMarshalOptionSliceKeys(OptionSliceKeysBrackets))
+	s, err := m.Marshal(&struct {
+		Tags []string
+		Page int
+	}{Tags: []string{"a", "b"}, Page: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "page=1&tags%5B%5D=a&tags%5B%5D=b"; s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+}
+
+func TestUnmarshalErrorFormatter(t *testing.T) {
+	type address struct {
+		City string `qs:"city,req"`
+		Zip  int    `qs:"zip"`
+	}
+	type query struct {
+		ID      int       `qs:"id,req"`
+		Tags    []int     `qs:"tags,maxitems=2"`
+		Address address   `qs:"address"`
+		Items   []address `qs:"items"`
+	}
+
+	var infos []UnmarshalErrorInfo
+	um := NewUnmarshaler(nil,
+		WithUnmarshalNesting(NestingModeDotsIndexBrackets),
+		WithUnmarshalErrorFormatter(func(info UnmarshalErrorInfo) error {
+			infos = append(infos, info)
+			return fmt.Errorf("%v: %v", info.Key, info.Kind)
+		}),
+	)
+
+	tests := []struct {
+		query  string
+		key    string
+		kind   UnmarshalErrorKind
+		values []string
+	}{
+		{"address.city=a", "id", UnmarshalErrorKindRequired, nil},
+		{"id=x&address.city=a", "id", UnmarshalErrorKindInvalid, []string{"x"}},
+		{"id=1&tags=1&tags=2&tags=3&address.city=a", "tags", UnmarshalErrorKindLimit, []string{"1", "2", "3"}},
+		{"id=1", "address.city", UnmarshalErrorKindRequired, nil},
+		{"id=1&address.city=a&address.zip=x", "address.zip", UnmarshalErrorKindInvalid, []string{"x"}},
+		{"id=1&address.city=a&items[1].city=b&items[0].zip=1", "items[0].city", UnmarshalErrorKindRequired, nil},
+	}
+	for _, tc := range tests {
+		infos = nil
+		var q query
+		err := um.Unmarshal(&q, tc.query)
+		if want := tc.key + ": " + tc.kind.String(); err == nil || err.Error() != want {
+			t.Errorf("%q :: got error %v, want %v", tc.query, err, want)
+			continue
+		}
+		if len(infos) != 1 {
+			t.Fatalf("%q :: got %d calls, want 1", tc.query, len(infos))
+		}
+		if info := infos[0]; !reflect.DeepEqual(info.Values, tc.values) || info.Err == nil {
+			t.Errorf("%q :: got %+v", tc.query, info)
+		}
+	}
+
+	// The errors are returned as they are without an ErrorFormatter.
+	var a address
+	if _, ok := Unmarshal(&a, "zip=1").(*ReqError); !ok {
+		t.Error("want a *ReqError")
+	}
+}
+
+func TestUnmarshalEmbeddedPointers(t *testing.T) {
+	type query struct {
+		*UPaging
+		*uSorting
+		Name string `qs:"name"`
+	}
+
+	var q query
+	if err := Unmarshal(&q, "name=a"); err != nil {
+		t.Fatal(err)
+	}
+	if q.UPaging != nil || q.uSorting != nil {
+		t.Errorf("got %+v, want nil embedded pointers", q)
+	}
+
+	if err := Unmarshal(&q, "name=a&page=2"); err != nil {
+		t.Fatal(err)
+	}
+	if q.UPaging == nil || q.Page != 2 {
+		t.Errorf("got %+v, want page 2", q.UPaging)
+	}
+
+	if err := Unmarshal(&q, "sort=name"); err == nil {
+		t.Error("unexpected success with a nil unexported embedded pointer")
+	}
+	q.uSorting = &uSorting{}
+	if err := Unmarshal(&q, "sort=name"); err != nil || q.Sort != "name" {
+		t.Errorf("got %+v, %v", q.uSorting, err)
+	}
+
+	q = query{}
+	um := NewUnmarshaler(nil, WithUnmarshalAllocEmbeddedPointers(true))
+	if err := um.Unmarshal(&q, "name=a"); err != nil {
+		t.Fatal(err)
+	}
+	if q.UPaging == nil || q.uSorting != nil {
+		t.Errorf("got %+v, want only the exported embedded pointer allocated", q)
+	}
+
+	var r struct {
+		*UReq
+	}
+	if _, ok := IsRequiredFieldError(Unmarshal(&r, "")); !ok || r.UReq != nil {
+		t.Errorf("got %+v, want a required field error", r)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	type item struct {
+		Price int `qs:"price"`
+		Qty   int `qs:"qty"`
+	}
+	type filter struct {
+		Status string `qs:"status"`
+	}
+	type explainQuery struct {
+		*UPaging
+		Name   string            `qs:"name,req"`
+		Tags   []string          `qs:"tags"`
+		Filter filter            `qs:"filter"`
+		Items  []item            `qs:"items"`
+		Extra  map[string]string `qs:"extra"`
+	}
+
+	um := NewUnmarshaler(nil, WithUnmarshalNesting(NestingModeBrackets))
+	values := url.Values{
+		"tags":            {"a", "b"},
+		"filter[status]":  {"open"},
+		"items[1][price]": {"10"},
+		"items[0][qty]":   {"2"},
+		"extra[color]":    {"red"},
+		"page":            {"3"},
+		"bogus":           {"1"},
+	}
+	report, err := um.Explain(reflect.TypeFor[*explainQuery](), values)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Type != reflect.TypeFor[explainQuery]() {
+		t.Errorf("got type %v", report.Type)
+	}
+
+	wantKeys := []ExplainedKey{
+		{Key: "bogus", Reason: "no field has the key"},
+		{Key: "extra[color]", Field: "Extra[color]"},
+		{Key: "filter[status]", Field: "Filter.Status"},
+		{Key: "items[0][qty]", Field: "Items[0].Qty"},
+		{Key: "items[1][price]", Field: "Items[1].Price"},
+		{Key: "page", Field: "UPaging.Page"},
+		{Key: "tags", Field: "Tags"},
+	}
+	if !reflect.DeepEqual(report.Keys, wantKeys) {
+		t.Errorf("got keys %+v, want %+v", report.Keys, wantKeys)
+	}
+
+	type field struct {
+		Field   string
+		Key     string
+		Values  []string
+		Present bool
+	}
+	var fields []field
+	for _, f := range report.Fields {
+		fields = append(fields, field{f.Field, f.Key, f.Values, f.Present})
+		if f.Present == (f.Note != "") {
+			t.Errorf("unexpected note of %+v", f)
+		}
+	}
+	wantFields := []field{
+		{"Name", "name", nil, false},
+		{"Tags", "tags", []string{"a", "b"}, true},
+		{"Filter.Status", "filter[status]", []string{"open"}, true},
+		{"Items[0].Price", "items[0][price]", nil, false},
+		{"Items[0].Qty", "items[0][qty]", []string{"2"}, true},
+		{"Items[1].Price", "items[1][price]", []string{"10"}, true},
+		{"Items[1].Qty", "items[1][qty]", nil, false},
+		{"Extra[color]", "extra[color]", []string{"red"}, true},
+		{"UPaging.Page", "page", []string{"3"}, true},
+	}
+	if !reflect.DeepEqual(fields, wantFields) {
+		t.Errorf("got fields %+v, want %+v", fields, wantFields)
+	}
+	if note := report.Fields[0].Note; note != "missing required field" {
+		t.Errorf("got note %q", note)
+	}
+
+	report, err = um.With(WithUnmarshalKeyPrefix("q_")).Explain(reflect.TypeFor[UPaging](), url.Values{"q_page": {"1"}, "page": {"2"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Keys) != 2 || report.Keys[0].Field != "" || report.Keys[0].Reason == "" || report.Keys[1].Field != "Page" {
+		t.Errorf("got keys %+v", report.Keys)
+	}
+	if len(report.Fields) != 1 || report.Fields[0].Key != "q_page" || !reflect.DeepEqual(report.Fields[0].Values, []string{"1"}) {
+		t.Errorf("got fields %+v", report.Fields)
+	}
+
+	if _, err := um.Explain(reflect.TypeFor[map[string]string](), nil); err == nil {
+		t.Error("unexpected success")
+	}
+}
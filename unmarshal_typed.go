@@ -0,0 +1,66 @@
+package qs
+
+import (
+	"net/url"
+	"reflect"
+	"time"
+)
+
+// TypedUnmarshaler unmarshals query strings into values of type T. Its
+// ValuesUnmarshaler is compiled once by NewTypedUnmarshaler so the unmarshal
+// calls don't have to look it up through the factories and their caches.
+type TypedUnmarshaler[T any] struct {
+	p   *QSUnmarshaler
+	vum ValuesUnmarshaler
+}
+
+// NewTypedUnmarshaler creates a TypedUnmarshaler that uses the options and
+// factories of p. T has to be a type that p can unmarshal into (a struct, a
+// map or a pointer to one of these) otherwise an error is returned.
+func NewTypedUnmarshaler[T any](p *QSUnmarshaler) (*TypedUnmarshaler[T], error) {
+	vum, err := p.CompileType(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, err
+	}
+	return &TypedUnmarshaler[T]{
+		p:   p,
+		vum: vum,
+	}, nil
+}
+
+// Unmarshal unmarshals a query string into a new value of type T.
+func (u *TypedUnmarshaler[T]) Unmarshal(queryString string) (v T, err error) {
+	if u.p.hook != nil {
+		defer u.p.hook.report(reflect.TypeFor[T](), time.Now(), &err)
+	}
+	values, err := u.p.parseQuery(queryString)
+	if err != nil {
+		return v, err
+	}
+	err = u.p.unmarshalValues(u.vum, reflect.ValueOf(&v).Elem(), values)
+	return v, err
+}
+
+// UnmarshalValues unmarshals a url.Values into a new value of type T.
+func (u *TypedUnmarshaler[T]) UnmarshalValues(values url.Values) (v T, err error) {
+	if u.p.hook != nil {
+		defer u.p.hook.report(reflect.TypeFor[T](), time.Now(), &err)
+	}
+	err = u.p.unmarshalValues(u.vum, reflect.ValueOf(&v).Elem(), values)
+	return v, err
+}
+
+// UnmarshalT is the type-safe variant of Unmarshal that returns the
+// unmarshaled value instead of storing it through a pointer.
+func UnmarshalT[T any](queryString string) (T, error) {
+	var v T
+	err := DefaultUnmarshaler.Unmarshal(&v, queryString)
+	return v, err
+}
+
+// UnmarshalValuesT is the type-safe variant of UnmarshalValues.
+func UnmarshalValuesT[T any](values url.Values) (T, error) {
+	var v T
+	err := DefaultUnmarshaler.UnmarshalValues(&v, values)
+	return v, err
+}
@@ -1,9 +1,14 @@
 package qs
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 // ValuesUnmarshaler can unmarshal a url.Values into a value.
@@ -12,22 +17,103 @@ type ValuesUnmarshaler interface {
 	UnmarshalValues(v reflect.Value, vs url.Values, opts *UnmarshalerDefaultOptions) error
 }
 
+// valuesSource provides the values of struct fields to the ValuesUnmarshaler
+// objects that can look up their fields one by one (see sourceUnmarshaler).
+type valuesSource interface {
+	// fieldValues returns the values of the field with the given tag and
+	// reports whether the field is present in the source.
+	fieldValues(tag *ParsedTagInfo) ([]string, bool)
+
+	// values returns the content of the source as a url.Values. It is used
+	// with ValuesUnmarshaler objects that don't implement sourceUnmarshaler.
+	values() url.Values
+}
+
+// urlValuesSource is the valuesSource used by UnmarshalValues. The fields
+// bound to the path values, headers or cookies of requests have no values in
+// it.
+type urlValuesSource url.Values
+
+func (s urlValuesSource) fieldValues(tag *ParsedTagInfo) ([]string, bool) {
+	if tag.UnmarshalOpts.Source.requestOnly() {
+		return nil, false
+	}
+	a, ok := s[tag.Name]
+	return a, ok
+}
+
+func (s urlValuesSource) values() url.Values {
+	return url.Values(s)
+}
+
+// sourceUnmarshaler is implemented by the ValuesUnmarshaler objects that can
+// unmarshal from a valuesSource.
+type sourceUnmarshaler interface {
+	unmarshalSource(v reflect.Value, src valuesSource, opts *UnmarshalerDefaultOptions) error
+}
+
+// unmarshalSource unmarshals src into v using vum.
+func unmarshalSource(vum ValuesUnmarshaler, v reflect.Value, src valuesSource, opts *UnmarshalerDefaultOptions) error {
+	if su, ok := vum.(sourceUnmarshaler); ok {
+		return su.unmarshalSource(v, src, opts)
+	}
+	return vum.UnmarshalValues(v, src.values(), opts)
+}
+
 // structUnmarshaler implements ValuesUnmarshaler.
 type structUnmarshaler struct {
 	Type           reflect.Type
 	EmbeddedFields []embeddedFieldUnmarshaler
 	Fields         []*fieldUnmarshaler
+
+	// dependencies are the field pairs of the requires and requiredwith tag
+	// options.
+	dependencies []fieldDependency
+
+	// ranges are the Range and TimeRange fields whose bounds are checked
+	// after unmarshaling them.
+	ranges []rangeField
+}
+
+// rangeField is a Range or TimeRange field. Its bounds are unmarshaled by two
+// fieldUnmarshalers.
+type rangeField struct {
+	Index []int
+	Key   string
+}
+
+// fieldDependency requires the presence of the required field if field is
+// present.
+type fieldDependency struct {
+	field, required *fieldUnmarshaler
 }
 
 type embeddedFieldUnmarshaler struct {
-	FieldIndex        int
+	// Index is the index path of the embedded field. See fieldUnmarshaler.
+	Index             []int
 	ValuesUnmarshaler ValuesUnmarshaler
 }
 
 type fieldUnmarshaler struct {
-	FieldIndex  int
+	// Index is the index path of the field for reflect.Value.FieldByIndex.
+	// It is longer than one for the fields promoted from embedded structs.
+	Index       []int
 	Unmarshaler Unmarshaler
 	Tag         *ParsedTagInfo
+
+	// Nested is used instead of Unmarshaler when nesting is enabled and the
+	// field is a struct (or a slice of structs if Indexed is true) that has
+	// no Unmarshaler. See UnmarshalerDefaultOptions.Nesting.
+	Nested ValuesUnmarshaler
+
+	// Indexed is true for slices and arrays when nesting is enabled. Their
+	// items can be passed with indexed keys too, e.g.: "tags[0]=a&tags[1]=b".
+	Indexed bool
+
+	// build creates Unmarshaler or Nested when the field is unmarshaled for
+	// the first time. It is nil if they have been created with the struct
+	// unmarshaler. See UnmarshalerDefaultOptions.LazyFields.
+	build func() error
 }
 
 // newStructUnmarshaler creates a struct unmarshaler for a specific struct type.
@@ -36,45 +122,222 @@ func newStructUnmarshaler(t reflect.Type, opts *UnmarshalerDefaultOptions) (Valu
 		return nil, &WrongKindError{Expected: reflect.Struct, Actual: t}
 	}
 
+	if err := checkTypeCycle(t, opts.structDependencies); err != nil {
+		return nil, err
+	}
+
 	su := &structUnmarshaler{
 		Type: t,
 	}
 
+	defaults, err := structTagDefaults(t, opts.TagKey, tagDefaults{
+		marshal:   NewUndefinedMarshalTagOptions(),
+		unmarshal: opts.TagOptionsDefaults,
+		common:    opts.TagCommonOptionsDefaults,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	for i, numField := 0, t.NumField(); i < numField; i++ {
 		sf := t.Field(i)
-		vum, fum, err := newFieldUnmarshaler(sf, opts)
+		if isRangeType(sf.Type) {
+			fums, err := newRangeFieldUnmarshalers(sf, opts, defaults)
+			if err != nil {
+				if opts.skipField(t, sf, err) {
+					continue
+				}
+				return nil, fmt.Errorf("error creating unmarshaler for field %v of struct %v :: %w",
+					sf.Name, t, err)
+			}
+			for _, fum := range fums {
+				su.Fields = append(su.Fields, fum.promoted(i))
+			}
+			if len(fums) != 0 {
+				su.ranges = append(su.ranges, rangeField{
+					Index: []int{i},
+					Key:   strings.TrimSuffix(fums[0].Tag.Name, opts.RangeFromSuffix),
+				})
+			}
+			continue
+		}
+
+		var vum ValuesUnmarshaler
+		var fum *fieldUnmarshaler
+		if opts.LazyFields && !sf.Anonymous {
+			vum, fum, err = newLazyFieldUnmarshaler(t, sf, opts, defaults)
+		} else {
+			vum, fum, err = newFieldUnmarshaler(sf, opts, defaults)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("error creating unmarshaler for field %v of struct %v :: %v",
+			if opts.skipField(t, sf, err) {
+				continue
+			}
+			return nil, fmt.Errorf("error creating unmarshaler for field %v of struct %v :: %w",
 				sf.Name, t, err)
 		}
 		if vum != nil {
-			su.EmbeddedFields = append(su.EmbeddedFields, embeddedFieldUnmarshaler{
-				FieldIndex:        i,
-				ValuesUnmarshaler: vum,
-			})
+			if eu, ok := vum.(*structUnmarshaler); ok && sf.Type.Kind() == reflect.Struct {
+				su.promote(i, eu)
+			} else {
+				su.EmbeddedFields = append(su.EmbeddedFields, embeddedFieldUnmarshaler{
+					Index:             []int{i},
+					ValuesUnmarshaler: vum,
+				})
+			}
 		}
 		if fum != nil {
-			fum.FieldIndex = i
+			fum.Index = []int{i}
 			su.Fields = append(su.Fields, fum)
 		}
 	}
 
+	if err := su.resolveDependencies(); err != nil {
+		return nil, err
+	}
 	return su, nil
 }
 
-func newFieldUnmarshaler(sf reflect.StructField, opts *UnmarshalerDefaultOptions) (ValuesUnmarshaler, *fieldUnmarshaler, error) {
+// resolveDependencies looks up the sibling fields of the requires and
+// requiredwith tag options.
+func (p *structUnmarshaler) resolveDependencies() error {
+	lookup := func(fum *fieldUnmarshaler, option, key string) (*fieldUnmarshaler, error) {
+		for _, sibling := range p.Fields {
+			if sibling.Tag.Name == key && sibling != fum {
+				return sibling, nil
+			}
+		}
+		return nil, fmt.Errorf("the %s option of field %q of struct %v refers to an unknown field %q", option, fum.Tag.Name, p.Type, key)
+	}
+
+	for _, fum := range p.Fields {
+		if key := fum.Tag.UnmarshalOpts.Requires; key != "" {
+			required, err := lookup(fum, "requires", key)
+			if err != nil {
+				return err
+			}
+			p.dependencies = append(p.dependencies, fieldDependency{field: fum, required: required})
+		}
+		if key := fum.Tag.UnmarshalOpts.RequiredWith; key != "" {
+			field, err := lookup(fum, "requiredwith", key)
+			if err != nil {
+				return err
+			}
+			p.dependencies = append(p.dependencies, fieldDependency{field: field, required: fum})
+		}
+	}
+	return nil
+}
+
+// promote adds the fields of the embedded (or inlined) struct field with
+// index i unmarshaled by eu to the fields of p. See structMarshaler.promote.
+func (p *structUnmarshaler) promote(i int, eu *structUnmarshaler) {
+	for _, fum := range eu.Fields {
+		p.Fields = append(p.Fields, fum.promoted(i))
+	}
+	for _, rf := range eu.ranges {
+		p.ranges = append(p.ranges, rangeField{
+			Index: promotedIndex(i, rf.Index),
+			Key:   rf.Key,
+		})
+	}
+	for _, ef := range eu.EmbeddedFields {
+		p.EmbeddedFields = append(p.EmbeddedFields, embeddedFieldUnmarshaler{
+			Index:             promotedIndex(i, ef.Index),
+			ValuesUnmarshaler: ef.ValuesUnmarshaler,
+		})
+	}
+}
+
+// promoted returns a copy of fum with an index path that starts with the
+// index i of the embedded struct that has the field.
+func (fum *fieldUnmarshaler) promoted(i int) *fieldUnmarshaler {
+	p := *fum
+	p.Index = promotedIndex(i, fum.Index)
+	if fum.build != nil {
+		p.build = sync.OnceValue(func() error {
+			if err := fum.build(); err != nil {
+				return err
+			}
+			p.Unmarshaler, p.Nested, p.Indexed = fum.Unmarshaler, fum.Nested, fum.Indexed
+			return nil
+		})
+	}
+	return &p
+}
+
+// structDependencies returns the fields of struct type t whose values are
+// unmarshaled by the ValuesUnmarshaler of another struct type.
+func (o *UnmarshalerDefaultOptions) structDependencies(t reflect.Type) []typeDependency {
+	var deps []typeDependency
+	defaults := tagDefaults{
+		marshal:   NewUndefinedMarshalTagOptions(),
+		unmarshal: o.TagOptionsDefaults,
+		common:    o.TagCommonOptionsDefaults,
+	}
+	for i, numField := 0, t.NumField(); i < numField; i++ {
+		sf := t.Field(i)
+		tag, err := getStructFieldInfo(sf, o.fieldNaming(), defaults)
+		if tag == nil || err != nil || tag.CommonOpts.Codec != "" || o.customValuesType(sf.Type) {
+			continue
+		}
+		if sf.Anonymous || tag.CommonOpts.Inline {
+			deps = appendStructDependency(deps, sf.Name, sf.Type)
+			continue
+		}
+		if !o.Nesting.enabled() {
+			continue
+		}
+		if _, err := o.UnmarshalerFactory.Unmarshaler(sf.Type, NewUnmarshalOptions(o, nil)); err == nil {
+			continue
+		}
+		if nt, _, ok := nestedFieldType(sf.Type); ok {
+			deps = appendStructDependency(deps, sf.Name, nt)
+		}
+	}
+	return deps
+}
+
+func newFieldUnmarshaler(sf reflect.StructField, opts *UnmarshalerDefaultOptions, defaults tagDefaults) (ValuesUnmarshaler, *fieldUnmarshaler, error) {
 	var vum ValuesUnmarshaler
 	var fum *fieldUnmarshaler
 
-	tag, err := getStructFieldInfo(sf, opts.NameTransformer, NewUndefinedMarshalTagOptions(), opts.TagOptionsDefaults, opts.TagCommonOptionsDefaults)
+	tag, err := getStructFieldInfo(sf, opts.fieldNaming(), defaults)
 	if tag == nil || err != nil {
 		return vum, fum, err
 	}
+	if err := checkNamespace(sf, tag); err != nil {
+		return vum, fum, err
+	}
 
 	t := sf.Type
+	if tag.CommonOpts.Codec != "" {
+		um, err := opts.codecUnmarshaler(tag.CommonOpts.Codec, t)
+		if err == nil {
+			um, err = opts.transformFieldUnmarshaler(tag, um)
+		}
+		if err != nil {
+			return vum, fum, err
+		}
+		fum = &fieldUnmarshaler{
+			Unmarshaler: um,
+			Tag:         tag,
+		}
+		return vum, fum, nil
+	}
+	if tag.CommonOpts.Inline || opts.customValuesType(t) {
+		vum, err = opts.ValuesUnmarshalerFactory.ValuesUnmarshaler(t, opts)
+		if err == nil && tag.CommonOpts.Namespace != "" {
+			vum, err = namespacedUnmarshaler(vum, tag.CommonOpts.Namespace)
+		}
+		return vum, fum, err
+	}
 	if sf.Anonymous {
 		vum, err = opts.ValuesUnmarshalerFactory.ValuesUnmarshaler(t, opts)
-		if err == nil {
+		if err == nil && tag.CommonOpts.Namespace != "" {
+			vum, err = namespacedUnmarshaler(vum, tag.CommonOpts.Namespace)
+		}
+		if err == nil || tag.CommonOpts.Namespace != "" {
 			// We can end up here for example in case of an embedded struct.
 			return vum, fum, err
 		}
@@ -82,16 +345,71 @@ func newFieldUnmarshaler(sf reflect.StructField, opts *UnmarshalerDefaultOptions
 
 	um, err := opts.UnmarshalerFactory.Unmarshaler(t, NewUnmarshalOptions(opts, nil))
 	if err != nil {
+		if nt, indexed, ok := nestedFieldType(t); ok && opts.Nesting.enabled() {
+			if tag.CommonOpts.Transform != "" {
+				return vum, fum, errors.New("the transform tag option isn't supported by nested fields")
+			}
+			nested, err := opts.ValuesUnmarshalerFactory.ValuesUnmarshaler(nt, opts)
+			if err != nil {
+				return vum, fum, err
+			}
+			fum = &fieldUnmarshaler{
+				Tag:     tag,
+				Nested:  nested,
+				Indexed: indexed,
+			}
+			return vum, fum, nil
+		}
+		return vum, fum, err
+	}
+	if um, err = opts.transformFieldUnmarshaler(tag, um); err != nil {
 		return vum, fum, err
 	}
+	k := t.Kind()
 	fum = &fieldUnmarshaler{
 		Unmarshaler: um,
 		Tag:         tag,
+		Indexed:     opts.Nesting.enabled() && (k == reflect.Slice || k == reflect.Array),
 	}
 	return vum, fum, err
 }
 
+// newLazyFieldUnmarshaler returns a fieldUnmarshaler whose Unmarshaler is
+// created by newFieldUnmarshaler on first use.
+func newLazyFieldUnmarshaler(t reflect.Type, sf reflect.StructField, opts *UnmarshalerDefaultOptions, defaults tagDefaults) (ValuesUnmarshaler, *fieldUnmarshaler, error) {
+	tag, err := getStructFieldInfo(sf, opts.fieldNaming(), defaults)
+	if tag == nil || err != nil {
+		return nil, nil, err
+	}
+	if err := checkNamespace(sf, tag); err != nil {
+		return nil, nil, err
+	}
+	if tag.CommonOpts.Inline || opts.customValuesType(sf.Type) {
+		// The keys of inlined fields are needed by the struct unmarshaler.
+		return newFieldUnmarshaler(sf, opts, defaults)
+	}
+	fum := &fieldUnmarshaler{
+		Tag: tag,
+	}
+	fum.build = sync.OnceValue(func() error {
+		_, built, err := newFieldUnmarshaler(sf, opts, defaults)
+		if err != nil {
+			if opts.skipField(t, sf, err) {
+				return errSkippedField
+			}
+			return err
+		}
+		fum.Unmarshaler, fum.Nested, fum.Indexed = built.Unmarshaler, built.Nested, built.Indexed
+		return nil
+	})
+	return nil, fum, nil
+}
+
 func (p *structUnmarshaler) UnmarshalValues(v reflect.Value, vs url.Values, opts *UnmarshalerDefaultOptions) error {
+	return p.unmarshalSource(v, urlValuesSource(vs), opts)
+}
+
+func (p *structUnmarshaler) unmarshalSource(v reflect.Value, src valuesSource, opts *UnmarshalerDefaultOptions) error {
 	t := v.Type()
 	if t != p.Type {
 		return &WrongTypeError{Actual: t, Expected: p.Type}
@@ -100,44 +418,354 @@ func (p *structUnmarshaler) UnmarshalValues(v reflect.Value, vs url.Values, opts
 	// TODO: use a StructError error type in the function to generate
 	// error messages prefixed with the name of the struct type.
 
+	// The missing required fields are collected so the error reports all of
+	// them. The other errors are returned immediately.
+	var missing *ReqError
+
 	for _, fum := range p.Fields {
-		a, ok := vs[fum.Tag.Name]
+		if ok, err := buildField(fum); err != nil {
+			opts.logField(t, fum.Tag.Name, "qs: field unmarshaling failed", err)
+			return err
+		} else if !ok {
+			continue
+		}
+
+		if fum.Nested != nil {
+			fv := v.FieldByIndex(fum.Index)
+			if err := unmarshalNestedField(fv, fum, src.values(), opts); err != nil {
+				opts.logField(t, fum.Tag.Name, "qs: field unmarshaling failed", err)
+				if _, ok := err.(*fieldError); ok {
+					return err
+				}
+				if _, ok := IsRequiredFieldError(err); ok {
+					err = opts.asFieldError(err, fum.Tag.Name, fv.Type(), nil)
+					if re, ok := err.(*ReqError); ok {
+						missing = missing.add(re)
+						continue
+					}
+					return err
+				}
+				err = fmt.Errorf("error unmarshaling url.Values entry %q :: %w", fum.Tag.Name, err)
+				return opts.asFieldError(err, fum.Tag.Name, fv.Type(), nil)
+			}
+			continue
+		}
+
+		a, ok := fieldSourceValues(src, fum, opts)
 		if !ok {
+			opts.logField(t, fum.Tag.Name, "qs: no values for field", nil)
 			switch fum.Tag.UnmarshalOpts.Presence {
 			case UnmarshalPresenceNil:
 				continue
 			case UnmarshalPresenceReq:
-				return &ReqError{
-					Message:   fmt.Sprintf("missing required field %q in struct %v", fum.Tag.Name, t),
-					FieldName: fum.Tag.Name,
+				re := newReqError(fmt.Sprintf("missing required field %q in struct %v", fum.Tag.Name, fieldOwner(t, fum.Index)), fum.Tag)
+				err := opts.asFieldError(re, fum.Tag.Name, t.FieldByIndex(fum.Index).Type, nil)
+				if re, ok := err.(*ReqError); ok {
+					missing = missing.add(re)
+					continue
 				}
+				return err
 			}
 		}
-		err := fum.Unmarshaler.Unmarshal(v.Field(fum.FieldIndex), a, NewUnmarshalOptions(opts, fum.Tag))
+		err := fum.Unmarshaler.Unmarshal(v.FieldByIndex(fum.Index), a, NewUnmarshalOptions(opts, fum.Tag))
 		if err != nil {
-			return fmt.Errorf("error unmarshaling url.Values entry %q :: %v", fum.Tag.Name, err)
+			opts.logField(t, fum.Tag.Name, "qs: field unmarshaling failed", err)
+			err = fmt.Errorf("error unmarshaling url.Values entry %q :: %w", fum.Tag.Name, err)
+			return opts.asFieldError(err, fum.Tag.Name, t.FieldByIndex(fum.Index).Type, a)
+		}
+	}
+
+	for _, rf := range p.ranges {
+		if err := validateRange(v, rf.Index, rf.Key); err != nil {
+			return opts.asFieldError(err, rf.Key, t.FieldByIndex(rf.Index).Type, nil)
 		}
 	}
 
+	for _, d := range p.dependencies {
+		key := d.required.Tag.Name
+		if d.required.Tag.UnmarshalOpts.Presence == UnmarshalPresenceReq || (missing != nil && slices.Contains(missing.Fields, key)) {
+			// The missing required field has been reported.
+			continue
+		}
+		if !sourceHasField(src, d.field, opts) || sourceHasField(src, d.required, opts) {
+			continue
+		}
+		re := newReqError(fmt.Sprintf("missing field %q required by field %q in struct %v", key, d.field.Tag.Name, fieldOwner(t, d.required.Index)), d.required.Tag)
+		err := opts.asFieldError(re, key, t.FieldByIndex(d.required.Index).Type, nil)
+		if re, ok := err.(*ReqError); ok {
+			missing = missing.add(re)
+			continue
+		}
+		return err
+	}
+
 	for _, ef := range p.EmbeddedFields {
-		err := ef.ValuesUnmarshaler.UnmarshalValues(v.Field(ef.FieldIndex), vs, opts)
+		fv := v.FieldByIndex(ef.Index)
+		if fv.Kind() == reflect.Ptr && fv.IsNil() {
+			// The nil pointer is allocated only if the source has a value for
+			// one of the fields. Otherwise a temporary pointer is unmarshaled
+			// so the missing required fields are still reported.
+			present := sourceHasFields(ef.ValuesUnmarshaler, src, opts)
+			if present && !fv.CanSet() {
+				return fmt.Errorf("can't allocate the nil pointer of unexported embedded field %q", t.FieldByIndex(ef.Index).Name)
+			}
+			if !fv.CanSet() || !(present || opts.AllocEmbeddedPointers) {
+				fv = reflect.New(fv.Type()).Elem()
+			}
+		}
+		err := unmarshalSource(ef.ValuesUnmarshaler, fv, src, opts)
 		if err != nil {
-			if _, ok := IsRequiredFieldError(err); ok {
-				name := t.Field(ef.FieldIndex).Name
-				return &ReqError{
-					Message:   fmt.Sprintf("embedded field %q :: %v", name, err),
-					FieldName: name,
-				}
+			if _, ok := err.(*fieldError); ok {
+				// The keys of embedded fields aren't prefixed.
+				return err
+			}
+			if re, ok := err.(*ReqError); ok {
+				name := t.FieldByIndex(ef.Index).Name
+				missing = missing.add(&ReqError{
+					Message:       fmt.Sprintf("embedded field %q :: %v", name, err),
+					FieldName:     name,
+					Fields:        re.Fields,
+					CustomMessage: re.CustomMessage,
+				})
+				continue
+			}
+			return fmt.Errorf("error unmarshaling embedded field %q :: %w", t.FieldByIndex(ef.Index).Name, err)
+		}
+	}
+
+	if missing != nil {
+		return missing
+	}
+	return nil
+}
+
+// buildField creates the Unmarshaler or Nested of a lazy field. It reports
+// false if the field is skipped.
+func buildField(fum *fieldUnmarshaler) (bool, error) {
+	if fum.build == nil {
+		return true, nil
+	}
+	if err := fum.build(); err != nil {
+		if err == errSkippedField {
+			return false, nil
+		}
+		return false, fmt.Errorf("error unmarshaling url.Values entry %q :: %w", fum.Tag.Name, err)
+	}
+	return true, nil
+}
+
+// fieldSourceValues returns the values of the field that isn't nested from
+// src and reports whether the field is present in src.
+func fieldSourceValues(src valuesSource, fum *fieldUnmarshaler, opts *UnmarshalerDefaultOptions) ([]string, bool) {
+	a, ok := src.fieldValues(fum.Tag)
+	if !ok && fum.Tag.UnmarshalOpts.Source.requestOnly() {
+		return nil, false
+	}
+	if ok && fum.Tag.CommonOpts.SliceKeys == OptionSliceKeysBrackets {
+		// Both "ids=1" and "ids[]=2" are accepted.
+		if ba, bok := src.values()[fum.Tag.Name+"[]"]; bok {
+			a = append(slices.Clip(a), ba...)
+		}
+	}
+	if !ok && fum.Indexed {
+		a, ok = opts.Nesting.indexedItems(src.values(), fum.Tag.Name)
+	}
+	if !ok && fum.Tag.CommonOpts.SliceKeys != OptionSliceKeysRepeat {
+		a, ok = sliceKeysValues(src.values(), fum.Tag)
+	}
+	return a, ok
+}
+
+// sourceHasFields reports whether src has a value for one of the fields
+// unmarshaled by vum including the fields of embedded structs. It reports
+// true for the ValuesUnmarshaler objects of other types than structs.
+func sourceHasFields(vum ValuesUnmarshaler, src valuesSource, opts *UnmarshalerDefaultOptions) bool {
+	switch vum := vum.(type) {
+	case *structUnmarshaler:
+		for _, fum := range vum.Fields {
+			if sourceHasField(src, fum, opts) {
+				return true
+			}
+		}
+		for _, ef := range vum.EmbeddedFields {
+			if sourceHasFields(ef.ValuesUnmarshaler, src, opts) {
+				return true
+			}
+		}
+		return false
+	case *ptrValuesUnmarshaler:
+		return sourceHasFields(vum.ElemUnmarshaler, src, opts)
+	}
+	return true
+}
+
+// sourceHasField reports whether src has a value for the field unmarshaled
+// by fum (or for one of its nested fields).
+func sourceHasField(src valuesSource, fum *fieldUnmarshaler, opts *UnmarshalerDefaultOptions) bool {
+	if _, ok := src.fieldValues(fum.Tag); ok {
+		return true
+	}
+	if fum.Tag.UnmarshalOpts.Source.requestOnly() {
+		return false
+	}
+	vs := src.values()
+	if _, ok := sliceKeysValues(vs, fum.Tag); ok {
+		return true
+	}
+	if opts.Nesting.enabled() {
+		if opts.Nesting.nestedValues(vs, fum.Tag.Name) != nil || opts.Nesting.indexedValues(vs, fum.Tag.Name) != nil {
+			return true
+		}
+		if _, ok := opts.Nesting.indexedItems(vs, fum.Tag.Name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// logField logs the trace of the field with the given key of struct type t
+// at debug level if the options have a Logger.
+func (o *UnmarshalerDefaultOptions) logField(t reflect.Type, key string, msg string, err error) {
+	if o.Logger == nil {
+		return
+	}
+	if err != nil {
+		o.Logger.Debug(msg, "type", t, "key", key, "error", err)
+	} else {
+		o.Logger.Debug(msg, "type", t, "key", key)
+	}
+}
+
+// diagnoseUnknownKeys reports the keys of vs that don't match any field of
+// the struct unmarshaled by vum. The keys are reported in sorted order.
+func (o *UnmarshalerDefaultOptions) diagnoseUnknownKeys(vum ValuesUnmarshaler, vs url.Values) {
+	keys := make([]string, 0, len(vs))
+	for k, a := range vs {
+		if !sourceHasFields(vum, urlValuesSource{k: a}, o) {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		o.diagnose(DiagnosticKindUnknownKey, nil, k, "unknown key %q", k)
+	}
+}
+
+// prefixFieldError prefixes the key of a *fieldError and the Fields of a
+// *ReqError of a nested field with the key of its parent field. Other errors
+// are returned as they are.
+func prefixFieldError(err error, prefix string, opts *UnmarshalerDefaultOptions) error {
+	switch e := err.(type) {
+	case *fieldError:
+		e.info.Key = opts.Nesting.fieldKey(prefix, e.info.Key)
+	case *ReqError:
+		for i, key := range e.Fields {
+			e.Fields[i] = opts.Nesting.fieldKey(prefix, key)
+		}
+	}
+	return err
+}
+
+// newReqError returns the ReqError of the missing required field with the
+// given tag. The reqmsg tag option of the field replaces the message.
+func newReqError(msg string, tag *ParsedTagInfo) *ReqError {
+	if custom := tag.UnmarshalOpts.ReqMessage; custom != "" {
+		msg = custom
+	}
+	return &ReqError{
+		Message:       msg,
+		FieldName:     tag.Name,
+		Fields:        []string{tag.Name},
+		CustomMessage: tag.UnmarshalOpts.ReqMessage,
+	}
+}
+
+// sliceKeysValues returns the values of the items of a slice stored under the
+// keys selected by the SliceKeys tag option.
+func sliceKeysValues(vs url.Values, tag *ParsedTagInfo) ([]string, bool) {
+	switch tag.CommonOpts.SliceKeys {
+	case OptionSliceKeysBrackets:
+		a, ok := vs[tag.Name+"[]"]
+		return a, ok
+	case OptionSliceKeysNumbered:
+		var a []string
+		for i := 0; ; i++ {
+			ia, ok := vs[tag.Name+strconv.Itoa(i)]
+			if !ok {
+				return a, i != 0
 			}
-			return fmt.Errorf("error unmarshaling embedded field %q :: %v", t.Field(ef.FieldIndex).Name, err)
+			a = append(a, ia...)
 		}
+	default:
+		return nil, false
 	}
+}
 
+// unmarshalNestedField unmarshals the values of a field with a Nested
+// ValuesUnmarshaler from vs.
+func unmarshalNestedField(fv reflect.Value, fum *fieldUnmarshaler, vs url.Values, opts *UnmarshalerDefaultOptions) error {
+	var nvs url.Values
+	var items map[int]url.Values
+	if fum.Indexed {
+		items = opts.Nesting.indexedValues(vs, fum.Tag.Name)
+	} else {
+		nvs = opts.Nesting.nestedValues(vs, fum.Tag.Name)
+	}
+
+	if nvs == nil && items == nil {
+		switch fum.Tag.UnmarshalOpts.Presence {
+		case UnmarshalPresenceNil:
+			return nil
+		case UnmarshalPresenceReq:
+			return newReqError(fmt.Sprintf("missing required field %q", fum.Tag.Name), fum.Tag)
+		}
+		if fum.Indexed {
+			return nil
+		}
+	}
+
+	if fv.Kind() == reflect.Map {
+		err := fum.Nested.UnmarshalValues(fv, nvs, opts.forMapField(fum.Tag))
+		return prefixFieldError(err, fum.Tag.Name, opts)
+	}
+	if !fum.Indexed {
+		err := fum.Nested.UnmarshalValues(fv, nvs, opts)
+		return prefixFieldError(err, fum.Tag.Name, opts)
+	}
+
+	n := 0
+	for i := range items {
+		n = max(n, i+1)
+	}
+	switch fv.Kind() {
+	case reflect.Slice:
+		if limit, name := opts.maxItems(fum.Tag); limit != 0 && n > limit {
+			return &LimitExceededError{Limit: name, Key: fum.Tag.Name, Max: limit}
+		}
+		fv.Set(reflect.MakeSlice(fv.Type(), n, n))
+	case reflect.Array:
+		if n > fv.Len() {
+			return fmt.Errorf("index %v is out of the bounds of %v", n-1, fv.Type())
+		}
+	}
+	for i, ivs := range items {
+		if err := fum.Nested.UnmarshalValues(fv.Index(i), ivs, opts); err != nil {
+			err = prefixFieldError(err, opts.Nesting.indexKey(fum.Tag.Name, i), opts)
+			if _, ok := err.(*fieldError); ok {
+				return err
+			}
+			if _, ok := IsRequiredFieldError(err); ok {
+				return err
+			}
+			return fmt.Errorf("error unmarshaling item %v :: %w", i, err)
+		}
+	}
 	return nil
 }
 
 type mapUnmarshaler struct {
 	Type            reflect.Type
+	KeyUnmarshaler  PrimitiveUnmarshalerFunc
 	ElemType        reflect.Type
 	ElemUnmarshaler Unmarshaler
 }
@@ -147,8 +775,13 @@ func newMapUnmarshaler(t reflect.Type, opts *UnmarshalerDefaultOptions) (ValuesU
 		return nil, &WrongKindError{Expected: reflect.Map, Actual: t}
 	}
 
-	if t.Key() != stringType {
-		return nil, fmt.Errorf("map key type is expected to be string: %v", t)
+	if isDynamicMapType(t) {
+		return &dynamicMapUnmarshaler{Type: t}, nil
+	}
+
+	kum, err := opts.mapKeyUnmarshaler(t.Key())
+	if err != nil {
+		return nil, err
 	}
 
 	et := t.Elem()
@@ -156,11 +789,12 @@ func newMapUnmarshaler(t reflect.Type, opts *UnmarshalerDefaultOptions) (ValuesU
 	if err != nil {
 		// TODO: use a MapError error type in the function to generate
 		// error messages prefixed with the name of the struct type.
-		return nil, fmt.Errorf("error getting unmarshaler for map value type %v :: %v", et, err)
+		return nil, fmt.Errorf("error getting unmarshaler for map value type %v :: %w", et, err)
 	}
 
 	return &mapUnmarshaler{
 		Type:            t,
+		KeyUnmarshaler:  kum,
 		ElemType:        et,
 		ElemUnmarshaler: um,
 	}, nil
@@ -172,22 +806,69 @@ func (p *mapUnmarshaler) UnmarshalValues(v reflect.Value, vs url.Values, opts *U
 		return &WrongTypeError{Actual: t, Expected: p.Type}
 	}
 
-	if v.IsNil() {
+	if err := opts.checkRequiredMapKeys(vs); err != nil {
+		return err
+	}
+
+	if v.IsNil() || (len(vs) > 0 && opts.TagOptionsDefaults.MapValues == UnmarshalMapValuesOverrideOld) {
 		v.Set(reflect.MakeMap(t))
 	}
 
 	for k, a := range vs {
+		key, err := p.unmarshalKey(k, opts)
+		if err != nil {
+			return fmt.Errorf("error unmarshaling map key %q :: %w", k, err)
+		}
 		item := reflect.New(p.ElemType).Elem()
-		err := p.ElemUnmarshaler.Unmarshal(item, a, NewUnmarshalOptions(opts, nil))
+		if p.ElemType.Kind() == reflect.Ptr {
+			// The pointers of the existing entries are reused like the
+			// pointers of struct fields so the values they point to are
+			// updated in place.
+			if old := v.MapIndex(key); old.IsValid() {
+				item.Set(old)
+			}
+		}
+		err = p.ElemUnmarshaler.Unmarshal(item, a, NewUnmarshalOptions(opts, nil))
 		if err != nil {
-			return fmt.Errorf("error unmarshaling key %q :: %v", k, err)
+			return fmt.Errorf("error unmarshaling key %q :: %w", k, err)
 		}
-		v.SetMapIndex(reflect.ValueOf(k), item)
+		v.SetMapIndex(key, item)
 	}
 
 	return nil
 }
 
+// checkRequiredMapKeys returns a ReqError listing the keys of
+// RequiredMapKeys that are missing from the values of a map.
+func (o *UnmarshalerDefaultOptions) checkRequiredMapKeys(vs url.Values) error {
+	var missing *ReqError
+	for _, k := range o.RequiredMapKeys {
+		if _, ok := vs[k]; !ok {
+			missing = missing.add(&ReqError{
+				Message:   fmt.Sprintf("missing required map key %q", k),
+				FieldName: k,
+				Fields:    []string{k},
+			})
+		}
+	}
+	if missing != nil {
+		return missing
+	}
+	return nil
+}
+
+func (p *mapUnmarshaler) unmarshalKey(k string, opts *UnmarshalerDefaultOptions) (reflect.Value, error) {
+	kt := p.Type.Key()
+	if p.KeyUnmarshaler == nil {
+		if kt == stringType {
+			return reflect.ValueOf(k), nil
+		}
+		return reflect.ValueOf(k).Convert(kt), nil
+	}
+	key := reflect.New(kt).Elem()
+	return key, p.KeyUnmarshaler(key, k, NewUnmarshalOptions(opts, nil))
+}
+
 type ptrValuesUnmarshaler struct {
 	Type            reflect.Type
 	ElemType        reflect.Type
@@ -211,6 +892,10 @@ func newPtrValuesUnmarshaler(t reflect.Type, opts *UnmarshalerDefaultOptions) (V
 }
 
 func (p *ptrValuesUnmarshaler) UnmarshalValues(v reflect.Value, vs url.Values, opts *UnmarshalerDefaultOptions) error {
+	return p.unmarshalSource(v, urlValuesSource(vs), opts)
+}
+
+func (p *ptrValuesUnmarshaler) unmarshalSource(v reflect.Value, src valuesSource, opts *UnmarshalerDefaultOptions) error {
 	t := v.Type()
 	if t != p.Type {
 		return &WrongTypeError{Actual: t, Expected: p.Type}
@@ -218,5 +903,5 @@ func (p *ptrValuesUnmarshaler) UnmarshalValues(v reflect.Value, vs url.Values, o
 	if v.IsNil() {
 		v.Set(reflect.New(p.ElemType))
 	}
-	return p.ElemUnmarshaler.UnmarshalValues(v.Elem(), vs, opts)
+	return unmarshalSource(p.ElemUnmarshaler, v.Elem(), src, opts)
 }
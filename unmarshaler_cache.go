@@ -1,52 +1,98 @@
 package qs
 
-import (
-	"reflect"
-	"sync"
-)
+import "reflect"
 
-func newValuesUnmarshalerCache(wrapped ValuesUnmarshalerFactory) ValuesUnmarshalerFactory {
+func newValuesUnmarshalerCache(wrapped ValuesUnmarshalerFactory, newCache func() Cache) ValuesUnmarshalerFactory {
 	return &valuesUnmarshalerCache{
 		wrapped: wrapped,
+		cache:   variantCache{newCache: newCache},
 	}
 }
 
 type valuesUnmarshalerCache struct {
 	wrapped ValuesUnmarshalerFactory
-	cache   sync.Map
+	cache   variantCache
 }
 
 func (o *valuesUnmarshalerCache) ValuesUnmarshaler(t reflect.Type, opts *UnmarshalerDefaultOptions) (ValuesUnmarshaler, error) {
-	return cacher(o.wrapped.ValuesUnmarshaler, &o.cache, t, opts)
+	var variant interface{}
+	if opts != nil {
+		variant = opts.variant
+	}
+	return cacher(o.wrapped.ValuesUnmarshaler, o.cache.get(variant), t, opts, opts.logger())
+}
+
+func (o *valuesUnmarshalerCache) purge() {
+	o.cache.purge()
+}
+
+func (o *valuesUnmarshalerCache) evict(t reflect.Type) {
+	o.cache.evict(t)
+}
+
+func (o *valuesUnmarshalerCache) stats() CacheStats {
+	return o.cache.stats()
 }
 
 func (p *valuesUnmarshalerCache) RegisterSubFactory(k reflect.Kind, fn ValuesUnmarshalerFactoryFunc) error {
-	return p.wrapped.RegisterSubFactory(k, fn)
+	err := p.wrapped.RegisterSubFactory(k, fn)
+	p.purge()
+	return err
+}
+
+func (p *valuesUnmarshalerCache) RegisterCustomType(t reflect.Type, fn ValuesUnmarshalerFactoryFunc) error {
+	err := p.wrapped.RegisterCustomType(t, fn)
+	p.purge()
+	return err
 }
 
-func newUnmarshalerCache(wrapped UnmarshalerFactory) UnmarshalerFactory {
+func (p *valuesUnmarshalerCache) customType(t reflect.Type) bool {
+	c, ok := p.wrapped.(customValuesUnmarshalerTypeChecker)
+	return ok && c.customType(t)
+}
+
+func newUnmarshalerCache(wrapped UnmarshalerFactory, newCache func() Cache) UnmarshalerFactory {
 	return &unmarshalerCache{
 		wrapped: wrapped,
+		cache:   newTypeCache(newCache),
 	}
 }
 
 type unmarshalerCache struct {
 	wrapped UnmarshalerFactory
-	cache   sync.Map
+	cache   *typeCache
 }
 
 func (o *unmarshalerCache) Unmarshaler(t reflect.Type, opts *UnmarshalOptions) (Unmarshaler, error) {
-	return cacher(o.wrapped.Unmarshaler, &o.cache, t, opts)
+	return cacher(o.wrapped.Unmarshaler, o.cache, t, opts, opts.logger())
+}
+
+func (o *unmarshalerCache) purge() {
+	o.cache.purge()
+}
+
+func (o *unmarshalerCache) evict(t reflect.Type) {
+	o.cache.evict(t)
+}
+
+func (o *unmarshalerCache) stats() CacheStats {
+	return o.cache.stats()
 }
 
 func (p *unmarshalerCache) RegisterSubFactory(k reflect.Kind, fn UnmarshalerFactoryFunc) error {
-	return p.wrapped.RegisterSubFactory(k, fn)
+	err := p.wrapped.RegisterSubFactory(k, fn)
+	p.purge()
+	return err
 }
 
 func (p *unmarshalerCache) RegisterCustomType(k reflect.Type, fn PrimitiveUnmarshalerFunc) error {
-	return p.wrapped.RegisterCustomType(k, fn)
+	err := p.wrapped.RegisterCustomType(k, fn)
+	p.purge()
+	return err
 }
 
 func (p *unmarshalerCache) RegisterKindOverride(k reflect.Kind, fn PrimitiveUnmarshalerFunc) error {
-	return p.wrapped.RegisterKindOverride(k, fn)
+	err := p.wrapped.RegisterKindOverride(k, fn)
+	p.purge()
+	return err
 }
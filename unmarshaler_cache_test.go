@@ -3,6 +3,8 @@ package qs
 import (
 	"errors"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -21,10 +23,14 @@ func (p *fakeValuesUnmarshalerFactory) RegisterSubFactory(k reflect.Kind, fn Val
 	panic("!mock not implemented!")
 }
 
+func (p *fakeValuesUnmarshalerFactory) RegisterCustomType(t reflect.Type, fn ValuesUnmarshalerFactoryFunc) error {
+	panic("!mock not implemented!")
+}
+
 func TestValuesUnmarshalerCacheSuccess(t *testing.T) {
 	expected := &structUnmarshaler{}
 	wrapped := &fakeValuesUnmarshalerFactory{u: expected}
-	cache := newValuesUnmarshalerCache(wrapped)
+	cache := newValuesUnmarshalerCache(wrapped, nil)
 	tp := reflect.TypeOf((*fakeValuesUnmarshalerFactory)(nil)).Elem()
 
 	// cache miss
@@ -55,7 +61,7 @@ func TestValuesUnmarshalerCacheSuccess(t *testing.T) {
 func TestValuesUnmarshalerCacheError(t *testing.T) {
 	e := errors.New("test error")
 	wrapped := &fakeValuesUnmarshalerFactory{err: e}
-	cache := newValuesUnmarshalerCache(wrapped)
+	cache := newValuesUnmarshalerCache(wrapped, nil)
 	tp := reflect.TypeOf((*fakeValuesUnmarshalerFactory)(nil)).Elem()
 
 	// cache miss
@@ -110,7 +116,7 @@ func TestUnmarshalerCacheSuccess(t *testing.T) {
 	// we need a comparable fakeUnmarshaler object to be able to assert
 	expected := &fakeUnmarshaler{}
 	wrapped := &fakeUnmarshalerFactory{u: expected}
-	cache := newUnmarshalerCache(wrapped)
+	cache := newUnmarshalerCache(wrapped, nil)
 	tp := reflect.TypeOf((*fakeUnmarshalerFactory)(nil)).Elem()
 
 	// cache miss
@@ -141,7 +147,7 @@ func TestUnmarshalerCacheSuccess(t *testing.T) {
 func TestUnmarshalerCacheError(t *testing.T) {
 	e := errors.New("test error")
 	wrapped := &fakeUnmarshalerFactory{err: e}
-	cache := newUnmarshalerCache(wrapped)
+	cache := newUnmarshalerCache(wrapped, nil)
 	tp := reflect.TypeOf((*fakeUnmarshalerFactory)(nil)).Elem()
 
 	// cache miss
@@ -162,3 +168,163 @@ func TestUnmarshalerCacheError(t *testing.T) {
 		t.Fatalf("got %v, want %v", wrapped.calls, []reflect.Type{tp})
 	}
 }
+
+func TestRegisterUnmarshalerAfterFirstUse(t *testing.T) {
+	type query struct {
+		Page int
+	}
+
+	um := NewUnmarshaler(&UnmarshalerDefaultOptions{})
+	var q query
+	if err := um.Unmarshal(&q, "page=5"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := um.RegisterKindOverride(reflect.Int, func(v reflect.Value, s string, opts *UnmarshalOptions) error {
+		v.SetInt(int64(len(s)))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := um.Unmarshal(&q, "page=woof"); err != nil {
+		t.Fatal(err)
+	}
+	if q.Page != 4 {
+		t.Errorf("Page == %v, want 4", q.Page)
+	}
+}
+
+func TestConcurrentRegisterAndUnmarshal(t *testing.T) {
+	type query struct {
+		Page int
+	}
+
+	um := NewUnmarshaler(&UnmarshalerDefaultOptions{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				var q query
+				if err := um.Unmarshal(&q, "page=1"); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = um.RegisterCustomType(timeType, unmarshalTime)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestUnmarshalerEvictTypeAndResetCache(t *testing.T) {
+	type query struct {
+		Page int
+	}
+
+	wrapped := &fakeValuesUnmarshalerFactory{err: errors.New("test error")}
+	um := NewUnmarshaler(&UnmarshalerDefaultOptions{ValuesUnmarshalerFactory: wrapped})
+	tp := reflect.TypeOf(query{})
+
+	for i := 0; i < 2; i++ {
+		if _, err := um.CompileType(tp); err == nil {
+			t.Fatal("unexpected success")
+		}
+	}
+	if len(wrapped.calls) != 1 {
+		t.Fatalf("got %v calls, want 1", len(wrapped.calls))
+	}
+
+	wrapped.err = nil
+	um.EvictType(tp)
+	if _, err := um.CompileType(tp); err != nil {
+		t.Fatal(err)
+	}
+	if len(wrapped.calls) != 2 {
+		t.Fatalf("got %v calls, want 2", len(wrapped.calls))
+	}
+
+	um.ResetCache()
+	if _, err := um.CompileType(tp); err != nil {
+		t.Fatal(err)
+	}
+	if len(wrapped.calls) != 3 {
+		t.Fatalf("got %v calls, want 3", len(wrapped.calls))
+	}
+}
+
+func TestUnmarshalerCacheStats(t *testing.T) {
+	type query struct {
+		Page int
+	}
+
+	var created int
+	um := NewUnmarshaler(nil, WithUnmarshalCache(func() Cache {
+		created++
+		return NewCache()
+	}))
+	if created != 1 {
+		t.Errorf("got %v caches, want 1", created)
+	}
+
+	var q query
+	for i := 0; i < 2; i++ {
+		if err := um.Unmarshal(&q, "page=1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := um.CacheStats()
+	if s.Hits == 0 || s.Misses == 0 || s.Len == 0 {
+		t.Errorf("got %+v", s)
+	}
+	if created != 2 {
+		t.Errorf("got %v caches, want 2", created)
+	}
+
+	um.ResetCache()
+	if s := um.CacheStats(); s.Len != 0 {
+		t.Errorf("got %+v after ResetCache", s)
+	}
+}
+
+func TestUnmarshalerCacheVariants(t *testing.T) {
+	type query struct {
+		Page int
+	}
+
+	um := NewUnmarshaler(nil)
+	unmarshal := func(opts ...func(*QSUnmarshaler)) {
+		var q query
+		if err := um.UnmarshalWith(&q, "page=1", opts...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	unmarshal()
+	unmarshal(WithUnmarshalPresence(UnmarshalPresenceOpt))
+	n := um.CacheStats().Len
+	for i := 0; i < 100; i++ {
+		unmarshal(WithUnmarshalPresence(UnmarshalPresenceOpt))
+		unmarshal(WithUnmarshalKeyPrefix("x_"))
+	}
+	if s := um.CacheStats(); s.Len != n {
+		t.Errorf("got %+v, want Len %v", s, n)
+	}
+
+	for i := 0; i < maxCacheVariants; i++ {
+		unmarshal(WithUnmarshalNameTransformer(strings.ToUpper))
+	}
+	n = um.CacheStats().Len
+	for i := 0; i < 100; i++ {
+		unmarshal(WithUnmarshalNameTransformer(strings.ToUpper))
+	}
+	if s := um.CacheStats(); s.Len != n {
+		t.Errorf("got %+v, want Len %v", s, n)
+	}
+}
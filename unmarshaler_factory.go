@@ -1,8 +1,12 @@
 package qs
 
 import (
+	"encoding"
 	"errors"
+	"maps"
 	"reflect"
+	"sync"
+	"sync/atomic"
 )
 
 type (
@@ -39,33 +43,73 @@ type UnmarshalerFactory interface {
 	RegisterKindOverride(k reflect.Kind, fn PrimitiveUnmarshalerFunc) error
 }
 
-// unmarshalerFactory implements the UnmarshalerFactory interface.
+// unmarshalerFactory implements the UnmarshalerFactory interface. It resolves
+// the Unmarshaler of a type in the following order:
+//   - custom type registered with RegisterCustomType
+//   - the UnmarshalQS implementation of the type
+//   - sub-factory registered with RegisterSubFactory for the kind of the type
+//   - Unmarshaler registered with RegisterKindOverride for the kind of the type
+//   - builtin Unmarshaler of the type (time.Time, url.URL)
+//   - builtin sub-factory of the kind (pointers, arrays, slices)
+//   - builtin Unmarshaler of the kind (primitive types)
+//   - the UnmarshalText method of the pointer to the type
+//     (encoding.TextUnmarshaler), e.g. decimal types of third-party packages
 type unmarshalerFactory struct {
 	types             map[reflect.Type]Unmarshaler
 	kindSubRegistries map[reflect.Kind]UnmarshalerFactory
 	kinds             map[reflect.Kind]Unmarshaler
 
-	typesOverriden             map[reflect.Type]Unmarshaler
-	kindSubRegistriesOverriden map[reflect.Kind]UnmarshalerFactory
-	kindsOverriden             map[reflect.Kind]Unmarshaler
+	// overrides holds the registered objects. Registrations replace it with
+	// an updated copy (copy-on-write) so the lookups don't need locking.
+	overrides atomic.Pointer[unmarshalerFactoryOverrides]
+	mu        sync.Mutex
+}
+
+type unmarshalerFactoryOverrides struct {
+	types             map[reflect.Type]Unmarshaler
+	kindSubRegistries map[reflect.Kind]UnmarshalerFactory
+	kinds             map[reflect.Kind]Unmarshaler
+}
+
+// register applies fn to a copy of the overrides and replaces them with it.
+func (p *unmarshalerFactory) register(fn func(o *unmarshalerFactoryOverrides)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	o := p.overrides.Load()
+	o = &unmarshalerFactoryOverrides{
+		types:             maps.Clone(o.types),
+		kindSubRegistries: maps.Clone(o.kindSubRegistries),
+		kinds:             maps.Clone(o.kinds),
+	}
+	fn(o)
+	p.overrides.Store(o)
 }
 
 // UnmarshalQS is an interface that can be implemented by any type that
 // wants to handle its own unmarshaling instead of relying on the default
 // unmarshaling provided by this package.
+//
+// UnmarshalQS is called with the address of the unmarshaled value so it can
+// be implemented with a pointer or a value receiver. Pointers to implementing
+// types are allocated (if nil) and dereferenced before calling UnmarshalQS,
+// so UnmarshalQS is never called with a nil receiver. This mirrors the
+// handling of MarshalQS.
 type UnmarshalQS interface {
 	// UnmarshalQS is essentially the same as the Unmarshaler.Unmarshal
 	// method without its v parameter.
 	UnmarshalQS(a []string, opts *UnmarshalOptions) error
 }
 
-var unmarshalQSInterfaceType = reflect.TypeOf((*UnmarshalQS)(nil)).Elem()
+var (
+	unmarshalQSInterfaceType     = reflect.TypeOf((*UnmarshalQS)(nil)).Elem()
+	textUnmarshalerInterfaceType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
 
 func (p *unmarshalerFactory) Unmarshaler(t reflect.Type, opts *UnmarshalOptions) (Unmarshaler, error) {
-	if unmarshaler, ok := p.typesOverriden[t]; ok {
-		return unmarshaler, nil
-	}
-	if unmarshaler, ok := p.types[t]; ok {
+	overrides := p.overrides.Load()
+
+	if unmarshaler, ok := overrides.types[t]; ok {
 		return unmarshaler, nil
 	}
 
@@ -74,47 +118,60 @@ func (p *unmarshalerFactory) Unmarshaler(t reflect.Type, opts *UnmarshalOptions)
 	}
 
 	k := t.Kind()
-	if subFactory, ok := p.kindSubRegistriesOverriden[k]; ok {
+	if subFactory, ok := overrides.kindSubRegistries[k]; ok {
 		return subFactory.Unmarshaler(t, opts)
 	}
-	if subFactory, ok := p.kindSubRegistries[k]; ok {
-		return subFactory.Unmarshaler(t, opts)
+	if unmarshaler, ok := overrides.kinds[k]; ok {
+		return unmarshaler, nil
 	}
 
-	if unmarshaler, ok := p.kindsOverriden[k]; ok {
+	if unmarshaler, ok := p.types[t]; ok {
 		return unmarshaler, nil
 	}
+	if subFactory, ok := p.kindSubRegistries[k]; ok {
+		return subFactory.Unmarshaler(t, opts)
+	}
 	if unmarshaler, ok := p.kinds[k]; ok {
 		return unmarshaler, nil
 	}
 
+	if k != reflect.Ptr && reflect.PointerTo(t).Implements(textUnmarshalerInterfaceType) {
+		return &primitiveUnmarshalerFunc{unmarshalWithTextUnmarshaler}, nil
+	}
+
 	return nil, &UnhandledTypeError{Type: t}
 }
 
 func (p *unmarshalerFactory) RegisterSubFactory(k reflect.Kind, fn UnmarshalerFactoryFunc) error {
-	p.kindSubRegistriesOverriden[k] = &unmarshalerFactoryFunc{fn}
+	p.register(func(o *unmarshalerFactoryOverrides) {
+		o.kindSubRegistries[k] = &unmarshalerFactoryFunc{fn}
+	})
 	return nil
 }
 
 func (p *unmarshalerFactory) RegisterCustomType(k reflect.Type, fn PrimitiveUnmarshalerFunc) error {
-	p.typesOverriden[k] = &primitiveUnmarshalerFunc{fn}
+	p.register(func(o *unmarshalerFactoryOverrides) {
+		o.types[k] = &primitiveUnmarshalerFunc{fn}
+	})
 	return nil
 }
 
 func (p *unmarshalerFactory) RegisterKindOverride(k reflect.Kind, fn PrimitiveUnmarshalerFunc) error {
-	p.kindsOverriden[k] = &primitiveUnmarshalerFunc{fn: fn}
+	p.register(func(o *unmarshalerFactoryOverrides) {
+		o.kinds[k] = &primitiveUnmarshalerFunc{fn: fn}
+	})
 	return nil
 }
 
 func newUnmarshalerFactory() *unmarshalerFactory {
-	return &unmarshalerFactory{
-		typesOverriden:             map[reflect.Type]Unmarshaler{},
-		kindSubRegistriesOverriden: map[reflect.Kind]UnmarshalerFactory{},
-		kindsOverriden:             map[reflect.Kind]Unmarshaler{},
-
+	p := &unmarshalerFactory{
 		types: map[reflect.Type]Unmarshaler{
 			timeType: &primitiveUnmarshalerFunc{unmarshalTime},
 			urlType:  &primitiveUnmarshalerFunc{unmarshalURL},
+
+			bigIntType:   &primitiveUnmarshalerFunc{unmarshalBigInt},
+			bigFloatType: &primitiveUnmarshalerFunc{unmarshalBigFloat},
+			bigRatType:   &primitiveUnmarshalerFunc{unmarshalBigRat},
 		},
 		kindSubRegistries: map[reflect.Kind]UnmarshalerFactory{
 			reflect.Ptr:   &unmarshalerFactoryFunc{newPtrUnmarshaler},
@@ -141,6 +198,12 @@ func newUnmarshalerFactory() *unmarshalerFactory {
 			reflect.Float64: &primitiveUnmarshalerFunc{unmarshalFloat},
 		},
 	}
+	p.overrides.Store(&unmarshalerFactoryOverrides{
+		types:             map[reflect.Type]Unmarshaler{},
+		kindSubRegistries: map[reflect.Kind]UnmarshalerFactory{},
+		kinds:             map[reflect.Kind]Unmarshaler{},
+	})
+	return p
 }
 
 // unmarshalerFactoryFunc implements the UnmarshalerFactory interface.
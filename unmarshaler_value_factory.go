@@ -2,7 +2,10 @@ package qs
 
 import (
 	"errors"
+	"maps"
 	"reflect"
+	"sync"
+	"sync/atomic"
 )
 
 type ValuesUnmarshalerFactoryFunc func(t reflect.Type, opts *UnmarshalerDefaultOptions) (ValuesUnmarshaler, error)
@@ -15,15 +18,32 @@ type ValuesUnmarshalerFactory interface {
 
 	// RegisterSubFactory registers a ValuesUnmarshalerFactory for the given kind
 	RegisterSubFactory(k reflect.Kind, fn ValuesUnmarshalerFactoryFunc) error
+
+	// RegisterCustomType registers a ValuesUnmarshalerFactoryFunc for the given
+	// type. It takes precedence over the sub-factory of the kind of the type.
+	RegisterCustomType(t reflect.Type, fn ValuesUnmarshalerFactoryFunc) error
 }
 
 type valuesUnmarshalerFactory struct {
-	kindSubRegistries          map[reflect.Kind]ValuesUnmarshalerFactory
-	kindSubRegistriesOverriden map[reflect.Kind]ValuesUnmarshalerFactory
+	kindSubRegistries map[reflect.Kind]ValuesUnmarshalerFactory
+
+	// kindSubRegistriesOverriden holds the registered sub-factories. It is
+	// replaced with an updated copy by RegisterSubFactory (copy-on-write) so
+	// the lookups don't need locking.
+	kindSubRegistriesOverriden atomic.Pointer[map[reflect.Kind]ValuesUnmarshalerFactory]
+
+	// customTypes holds the factories registered by RegisterCustomType. Like
+	// kindSubRegistriesOverriden it is copy-on-write.
+	customTypes atomic.Pointer[map[reflect.Type]ValuesUnmarshalerFactory]
+	mu          sync.Mutex
 }
 
 func (p *valuesUnmarshalerFactory) ValuesUnmarshaler(t reflect.Type, opts *UnmarshalerDefaultOptions) (ValuesUnmarshaler, error) {
-	if subFactory, ok := p.kindSubRegistriesOverriden[t.Kind()]; ok {
+	if factory, ok := (*p.customTypes.Load())[t]; ok {
+		return factory.ValuesUnmarshaler(t, opts)
+	}
+
+	if subFactory, ok := (*p.kindSubRegistriesOverriden.Load())[t.Kind()]; ok {
 		return subFactory.ValuesUnmarshaler(t, opts)
 	}
 
@@ -35,18 +55,41 @@ func (p *valuesUnmarshalerFactory) ValuesUnmarshaler(t reflect.Type, opts *Unmar
 }
 
 func (p *valuesUnmarshalerFactory) RegisterSubFactory(k reflect.Kind, fn ValuesUnmarshalerFactoryFunc) error {
-	p.kindSubRegistriesOverriden[k] = &valuesUnmarshalerFactoryFunc{fn}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	overrides := maps.Clone(*p.kindSubRegistriesOverriden.Load())
+	overrides[k] = &valuesUnmarshalerFactoryFunc{fn}
+	p.kindSubRegistriesOverriden.Store(&overrides)
 	return nil
 }
 
+func (p *valuesUnmarshalerFactory) RegisterCustomType(t reflect.Type, fn ValuesUnmarshalerFactoryFunc) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	customTypes := maps.Clone(*p.customTypes.Load())
+	customTypes[t] = &valuesUnmarshalerFactoryFunc{fn}
+	p.customTypes.Store(&customTypes)
+	return nil
+}
+
+func (p *valuesUnmarshalerFactory) customType(t reflect.Type) bool {
+	_, ok := (*p.customTypes.Load())[t]
+	return ok
+}
+
 func newValuesUnmarshalerFactory() *valuesUnmarshalerFactory {
-	return &valuesUnmarshalerFactory{
+	p := &valuesUnmarshalerFactory{
 		kindSubRegistries: map[reflect.Kind]ValuesUnmarshalerFactory{
 			reflect.Ptr:    &valuesUnmarshalerFactoryFunc{newPtrValuesUnmarshaler},
 			reflect.Struct: &valuesUnmarshalerFactoryFunc{newStructUnmarshaler},
 			reflect.Map:    &valuesUnmarshalerFactoryFunc{newMapUnmarshaler},
 		},
 	}
+	p.kindSubRegistriesOverriden.Store(&map[reflect.Kind]ValuesUnmarshalerFactory{})
+	p.customTypes.Store(&map[reflect.Type]ValuesUnmarshalerFactory{})
+	return p
 }
 
 // valuesUnmarshalerFactoryFunc implements the UnmarshalerFactory interface.
@@ -61,3 +104,31 @@ func (f valuesUnmarshalerFactoryFunc) ValuesUnmarshaler(t reflect.Type, opts *Un
 func (p *valuesUnmarshalerFactoryFunc) RegisterSubFactory(k reflect.Kind, fn ValuesUnmarshalerFactoryFunc) error {
 	return errors.New("not implemented")
 }
+
+func (p *valuesUnmarshalerFactoryFunc) RegisterCustomType(t reflect.Type, fn ValuesUnmarshalerFactoryFunc) error {
+	return errors.New("not implemented")
+}
+
+// customValuesUnmarshalerTypeChecker is implemented by the ValuesUnmarshalerFactory objects that
+// can report the types registered with RegisterCustomType.
+type customValuesUnmarshalerTypeChecker interface {
+	customType(t reflect.Type) bool
+}
+
+// customValuesType reports whether t (or the type pointed to by t) is
+// registered with the RegisterCustomType method of the
+// ValuesUnmarshalerFactory. The fields of these types are unmarshaled like inline
+// fields: the ValuesUnmarshaler of the type receives all of the values.
+func (o *UnmarshalerDefaultOptions) customValuesType(t reflect.Type) bool {
+	f, ok := o.ValuesUnmarshalerFactory.(customValuesUnmarshalerTypeChecker)
+	if !ok {
+		return false
+	}
+	for t.Kind() == reflect.Ptr {
+		if f.customType(t) {
+			return true
+		}
+		t = t.Elem()
+	}
+	return f.customType(t)
+}